@@ -0,0 +1,73 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"os"
+	"strings"
+
+	"go.uber.org/zap"
+
+	audit_domain "skyhawk/backend/audit/domain"
+	auth_domain "skyhawk/backend/auth/domain"
+	"skyhawk/backend/config"
+)
+
+// runAPIKey - the "apikey" subcommand: create, list or revoke api keys. create is how the first admin key is issued
+func runAPIKey(cfg config.Config, logger *zap.Logger, args []string) error {
+	if len(args) == 0 {
+		return errors.New("apikey: expected create, list or revoke")
+	}
+
+	flags := flag.NewFlagSet("apikey "+args[0], flag.ContinueOnError)
+	name := flags.String("name", "", "name of the key, shown in the audit log")
+	scopes := flags.String("scopes", "", "comma separated scopes: stats:read, games:write, admin")
+	id := flags.String("id", "", "id of the key to revoke")
+	if err := flags.Parse(args[1:]); err != nil {
+		return err
+	}
+
+	app, err := newApp(cfg, logger)
+	if err != nil {
+		return err
+	}
+	defer app.Close()
+
+	ctx := audit_domain.WithMeta(context.Background(), audit_domain.Meta{Actor: "cli:apikey"})
+
+	var out interface{}
+	switch args[0] {
+	case "create":
+		req := auth_domain.CreateKeyReq{Name: *name}
+		for _, scope := range strings.Split(*scopes, ",") {
+			if scope = strings.TrimSpace(scope); scope != "" {
+				req.Scopes = append(req.Scopes, auth_domain.Scope(scope))
+			}
+		}
+		if out, err = app.keys.Create(ctx, req); err != nil {
+			return err
+		}
+	case "list":
+		if out, err = app.keys.List(ctx); err != nil {
+			return err
+		}
+	case "revoke":
+		if *id == "" {
+			return errors.New("apikey revoke: -id is required")
+		}
+		if err = app.keys.Revoke(ctx, *id); err != nil {
+			return err
+		}
+		out = map[string]string{"revoked": *id}
+	default:
+		return fmt.Errorf("apikey: unknown command %q, expected create, list or revoke", args[0])
+	}
+
+	encoder := json.NewEncoder(os.Stdout)
+	encoder.SetIndent("", "  ")
+
+	return encoder.Encode(out)
+}
@@ -0,0 +1,216 @@
+package main
+
+import (
+	"context"
+
+	"github.com/jmoiron/sqlx"
+	goredis "github.com/redis/go-redis/v9"
+	"go.uber.org/zap"
+
+	auditrepo "skyhawk/backend/audit/db"
+	auditusecase "skyhawk/backend/audit/usecase"
+	authrepo "skyhawk/backend/auth/db"
+	auth_domain "skyhawk/backend/auth/domain"
+	"skyhawk/backend/auth/jwt"
+	authusecase "skyhawk/backend/auth/usecase"
+	"skyhawk/backend/config"
+	eventrepo "skyhawk/backend/event/db"
+	eventusecase "skyhawk/backend/event/usecase"
+	"skyhawk/backend/export"
+	exportrepo "skyhawk/backend/export/db"
+	"skyhawk/backend/game/db"
+	"skyhawk/backend/game/usecase"
+	goose "skyhawk/backend/goose"
+	"skyhawk/backend/metrics"
+	outboxrepo "skyhawk/backend/outbox/db"
+	playerrepo "skyhawk/backend/player/db"
+	"skyhawk/backend/ratelimit"
+	"skyhawk/backend/redis"
+	"skyhawk/backend/retry"
+	"skyhawk/backend/stream"
+	teamrepo "skyhawk/backend/team/db"
+	"skyhawk/backend/tracing"
+	webhookrepo "skyhawk/backend/webhook/db"
+	webhookusecase "skyhawk/backend/webhook/usecase"
+)
+
+// app - the connections and services shared by the server and the operator commands
+type app struct {
+	logger     *zap.Logger
+	db         *sqlx.DB
+	redis      *goredis.Client
+	service    usecase.GameUseCase
+	events     eventusecase.EventUseCase
+	exporter   *export.Exporter
+	webhooks   *webhookusecase.UseCase
+	audit      *auditusecase.UseCase
+	keys       *authusecase.UseCase
+	limiter    *ratelimit.Limiter
+	migrations goose.Service
+	metrics    *metrics.Metrics
+	retries    *retry.Stats
+}
+
+// newApp - the app of an operator command. A command never migrates the schema, it only checks the schema is
+// migrated, and runs nothing in the background: the webhook deliveries and outbox events it records are sent by the servers
+func newApp(cfg config.Config, logger *zap.Logger) (*app, error) {
+	a, err := connect(cfg, logger)
+	if err != nil {
+		return nil, err
+	}
+	if err = a.migrations.Check(context.Background()); err != nil {
+		a.Close()
+		return nil, err
+	}
+	if err = a.wire(cfg, nil); err != nil {
+		a.Close()
+		return nil, err
+	}
+
+	return a, nil
+}
+
+// connect - opens mysql and redis with their metrics and tracing hooks, the schema is left as it is
+func connect(cfg config.Config, logger *zap.Logger) (*app, error) {
+	//prepare DBs
+	logger.Info("connecting to mysql", zap.String("dsn", goose.RedactedDSN(cfg.DB)))
+	DB, err := goose.MustNewDB(cfg.DB)
+	if err != nil {
+		return nil, err
+	}
+
+	//migrations built into the binary, serve runs them or checks them per MIGRATIONS_ON_START
+	migrationService, err := goose.New(DB, logger, cfg.Migrations.LockTimeout)
+	if err != nil {
+		_ = DB.Close()
+		return nil, err
+	}
+
+	redis, err := redis.MustNewRedis(cfg.Redis)
+	if err != nil {
+		_ = DB.Close()
+		return nil, err
+	}
+
+	//prometheus metrics: db pool stats, repository latency, cache hits of the team and player repos, retries
+	metrics := metrics.New()
+	metrics.RegisterDB(DB.DB, cfg.DB.Name)
+	redis.AddHook(metrics.RedisHook())
+	redis.AddHook(tracing.RedisHook())
+
+	return &app{
+		logger:     logger,
+		db:         DB,
+		redis:      redis,
+		migrations: migrationService,
+		metrics:    metrics,
+	}, nil
+}
+
+// wire - builds the repositories and services over the connections, notifier is nil when nobody follows games here
+func (a *app) wire(cfg config.Config, notifier *stream.Notifier) error {
+	logger, DB, redis, metrics := a.logger, a.db, a.redis, a.metrics
+
+	//initiate service
+	playerRepo := playerrepo.NewInstrumented(playerrepo.NewRepo(logger, DB, redis), metrics)
+	teamRepo := teamrepo.NewInstrumented(teamrepo.New(DB, redis, logger), metrics)
+	gameRepo := db.NewInstrumented(db.NewRepo(DB, logger), metrics)
+	retryPolicy := retry.DefaultPolicy()
+	retryPolicy.MaxAttempts = cfg.Retry.MaxAttempts
+	retryPolicy.BaseDelay = cfg.Retry.BaseDelay
+	retryPolicy.MaxDelay = cfg.Retry.MaxDelay
+	retryStats := retry.NewStats()
+	retrier := retry.New(retryPolicy, logger, metrics.RetryObserver(retryStats))
+	batchOptions := usecase.DefaultBatchOptions()
+	batchOptions.MaxGames = cfg.Batch.MaxGames
+	batchOptions.Parallelism = cfg.Batch.Parallelism
+
+	//webhook deliveries are queued in the same transaction as the game and sent by the dispatcher of a server
+	webhooks := webhookusecase.NewUseCase(webhookrepo.NewRepo(DB, logger), logger)
+
+	//domain events are recorded with the change that caused them and relayed by a server
+	outboxRepo := outboxrepo.NewRepo(DB, logger)
+
+	//every write is audited inside the transaction of the change, so a change and its audit row commit or roll back together
+	auditor := auditusecase.NewUseCase(auditrepo.NewRepo(DB, logger), logger)
+
+	//the usecases treat a nil notifier as nobody following games, a typed nil would not be
+	var games usecase.Notifier
+	var events eventusecase.Notifier
+	if notifier != nil {
+		games, events = notifier, notifier
+	}
+	service := usecase.NewUseCase(logger, gameRepo, teamRepo, playerRepo, retrier, batchOptions, games, webhooks, outboxRepo, auditor)
+
+	eventService := eventusecase.NewUseCase(logger, gameRepo, eventrepo.NewRepo(DB, logger), retrier, events, auditor)
+
+	//bearer tokens from the identity provider are accepted next to api keys when a JWKS is configured
+	tokens, err := newTokenVerifier(cfg.JWT, logger)
+	if err != nil {
+		return err
+	}
+
+	//per client token buckets and daily quotas, shared between instances when RATE_LIMIT_STORE=redis
+	limitOptions := ratelimit.DefaultOptions()
+	limitOptions.Read = ratelimit.Limit{Rate: cfg.RateLimit.ReadRPS, Burst: cfg.RateLimit.ReadBurst}
+	limitOptions.Write = ratelimit.Limit{Rate: cfg.RateLimit.WriteRPS, Burst: cfg.RateLimit.WriteBurst}
+	limitOptions.IP = ratelimit.Limit{Rate: cfg.RateLimit.IPRPS, Burst: cfg.RateLimit.IPBurst}
+	limitOptions.DailyQuota = cfg.RateLimit.DailyQuota
+	var buckets ratelimit.Buckets = ratelimit.NewMemoryBuckets()
+	var counters ratelimit.Counters = ratelimit.NewMemoryCounters()
+	if cfg.RateLimit.Store == "redis" {
+		buckets = ratelimit.NewRedisBuckets(redis, "skyhawk:ratelimit:")
+		counters = ratelimit.NewRedisCounters(redis, "skyhawk:usage:")
+	}
+
+	a.service = usecase.NewTraced(service)
+	a.events = eventusecase.NewTraced(eventService)
+	a.exporter = export.New(exportrepo.NewRepo(DB, logger), logger)
+	a.webhooks = webhooks
+	a.audit = auditor
+	a.keys = authusecase.NewUseCase(authrepo.NewRepo(DB, logger), tokens, logger)
+	a.limiter = ratelimit.NewLimiter(buckets, counters, limitOptions, logger)
+	a.retries = retryStats
+
+	return nil
+}
+
+// newTokenVerifier - validates tokens against the configured JWKS file or url, nil when neither is set
+func newTokenVerifier(cfg config.JWT, logger *zap.Logger) (authusecase.TokenVerifier, error) {
+	var keys jwt.KeySet
+	switch {
+	case cfg.JWKSFile != "":
+		static, err := jwt.LoadKeySetFile(cfg.JWKSFile)
+		if err != nil {
+			return nil, err
+		}
+		keys = static
+	case cfg.JWKSURL != "":
+		keys = jwt.NewRemoteKeySet(cfg.JWKSURL, cfg.JWKSTTL, logger)
+	default:
+		return nil, nil
+	}
+
+	options := jwt.DefaultOptions()
+	options.Issuer = cfg.Issuer
+	options.Audience = cfg.Audience
+	options.RolesClaim = cfg.RolesClaim
+	//the claim values the identity provider uses for each role
+	options.Roles = map[string]auth_domain.Role{
+		cfg.RoleViewer:      auth_domain.RoleViewer,
+		cfg.RoleScorekeeper: auth_domain.RoleScorekeeper,
+		cfg.RoleLeagueAdmin: auth_domain.RoleLeagueAdmin,
+	}
+
+	return jwt.NewVerifier(keys, options), nil
+}
+
+// Close - closes the connections, the workers of a server are stopped before
+func (a *app) Close() {
+	if err := a.redis.Close(); err != nil {
+		a.logger.Warn("failed closing redis", zap.Error(err))
+	}
+	if err := a.db.Close(); err != nil {
+		a.logger.Warn("failed closing db", zap.Error(err))
+	}
+}
@@ -0,0 +1,116 @@
+package apperror
+
+import (
+	"context"
+	"errors"
+	"fmt"
+)
+
+// Kind - the category of a failure, used by the transport layer to pick a status code
+type Kind string
+
+const (
+	KindInternal    Kind = "internal"
+	KindNotFound    Kind = "not_found"
+	KindValidation  Kind = "validation"
+	KindConflict    Kind = "conflict"
+	KindUnavailable Kind = "unavailable"
+	KindTimeout     Kind = "timeout"
+	// KindCanceled - the client went away before the request finished, not a failure of the service
+	KindCanceled     Kind = "canceled"
+	KindUnauthorized Kind = "unauthorized"
+	KindForbidden    Kind = "forbidden"
+	KindRateLimited  Kind = "rate_limited"
+)
+
+// FieldError - a single invalid field of a request
+type FieldError struct {
+	Field   string `json:"field"`
+	Message string `json:"message"`
+}
+
+// Error - a domain error carrying a stable code and a client safe message,
+// the wrapped cause is kept for logging and is never sent to clients
+type Error struct {
+	Kind    Kind
+	Code    string
+	Message string
+	Fields  []FieldError
+	Err     error
+}
+
+func (e *Error) Error() string {
+	if e.Err != nil {
+		return fmt.Sprintf("%s: %s: %v", e.Code, e.Message, e.Err)
+	}
+
+	return fmt.Sprintf("%s: %s", e.Code, e.Message)
+}
+
+func (e *Error) Unwrap() error {
+	return e.Err
+}
+
+func NotFound(code, message string, err error) *Error {
+	return &Error{Kind: KindNotFound, Code: code, Message: message, Err: err}
+}
+
+func Validation(code, message string, fields ...FieldError) *Error {
+	return &Error{Kind: KindValidation, Code: code, Message: message, Fields: fields}
+}
+
+func Conflict(code, message string, err error) *Error {
+	return &Error{Kind: KindConflict, Code: code, Message: message, Err: err}
+}
+
+func Unavailable(code, message string, err error) *Error {
+	return &Error{Kind: KindUnavailable, Code: code, Message: message, Err: err}
+}
+
+// Unauthorized - the request carries no credentials or credentials that are not valid
+func Unauthorized(code, message string) *Error {
+	return &Error{Kind: KindUnauthorized, Code: code, Message: message}
+}
+
+// Forbidden - the caller is known but not allowed to do this
+func Forbidden(code, message string) *Error {
+	return &Error{Kind: KindForbidden, Code: code, Message: message}
+}
+
+// RateLimited - the caller made too many requests and should retry later
+func RateLimited(code, message string) *Error {
+	return &Error{Kind: KindRateLimited, Code: code, Message: message}
+}
+
+func Internal(err error) *Error {
+	return &Error{Kind: KindInternal, Code: "internal_error", Message: "internal server error", Err: err}
+}
+
+// From - returns err as a domain error, anything unknown becomes an internal error
+func From(err error) *Error {
+	var appErr *Error
+	if errors.As(err, &appErr) {
+		return appErr
+	}
+
+	if errors.Is(err, context.DeadlineExceeded) {
+		return &Error{Kind: KindTimeout, Code: "timeout", Message: "request timed out", Err: err}
+	}
+	if errors.Is(err, context.Canceled) {
+		return &Error{Kind: KindCanceled, Code: "client_closed_request", Message: "client closed the request", Err: err}
+	}
+
+	return Internal(err)
+}
+
+// KindOf - returns the kind of err, KindInternal when err is not a domain error
+func KindOf(err error) Kind {
+	return From(err).Kind
+}
+
+// IsNotFound - reports whether err is a not found domain error
+func IsNotFound(err error) bool {
+	var appErr *Error
+
+	return errors.As(err, &appErr) && appErr.Kind == KindNotFound
+}
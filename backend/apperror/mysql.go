@@ -0,0 +1,69 @@
+package apperror
+
+import (
+	"context"
+	"database/sql"
+	"database/sql/driver"
+	"errors"
+
+	"github.com/go-sql-driver/mysql"
+)
+
+// MySQL server error numbers we translate into domain errors
+const (
+	mysqlDuplicateEntry       = 1062
+	mysqlLockWaitTimeout      = 1205
+	mysqlDeadlock             = 1213
+	mysqlRowIsReferenced      = 1451
+	mysqlNoReferencedRow      = 1452
+	mysqlCheckConstraintFails = 3819
+	mysqlDataTooLong          = 1406
+	mysqlOutOfRange           = 1264
+)
+
+// FromDB - translates a database error into a domain error, notFound is returned for sql.ErrNoRows,
+// the driver message is kept only as the wrapped cause so SQL text never reaches a client
+func FromDB(err error, notFound *Error) error {
+	if err == nil {
+		return nil
+	}
+
+	var appErr *Error
+	if errors.As(err, &appErr) {
+		return err
+	}
+
+	if errors.Is(err, sql.ErrNoRows) {
+		if notFound != nil {
+			notFound.Err = err
+			return notFound
+		}
+		return NotFound("not_found", "resource not found", err)
+	}
+
+	// kept as they are, From makes them a timeout or a client closed request
+	if errors.Is(err, context.DeadlineExceeded) || errors.Is(err, context.Canceled) {
+		return err
+	}
+
+	var mysqlErr *mysql.MySQLError
+	if errors.As(err, &mysqlErr) {
+		switch mysqlErr.Number {
+		case mysqlDuplicateEntry:
+			return Conflict("duplicate_entry", "resource already exists", err)
+		case mysqlRowIsReferenced, mysqlNoReferencedRow:
+			return Conflict("reference_violation", "referenced resource does not exist or is still in use", err)
+		case mysqlCheckConstraintFails, mysqlDataTooLong, mysqlOutOfRange:
+			return &Error{Kind: KindValidation, Code: "constraint_violation", Message: "value violates a data constraint", Err: err}
+		case mysqlDeadlock, mysqlLockWaitTimeout:
+			return Unavailable("database_busy", "database is busy, try again", err)
+		}
+		return Internal(err)
+	}
+
+	if errors.Is(err, driver.ErrBadConn) || errors.Is(err, mysql.ErrInvalidConn) || errors.Is(err, sql.ErrConnDone) {
+		return Unavailable("database_unavailable", "database is unavailable", err)
+	}
+
+	return Internal(err)
+}
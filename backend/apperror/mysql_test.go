@@ -0,0 +1,83 @@
+package apperror
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"testing"
+
+	"github.com/go-sql-driver/mysql"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestFromDB(t *testing.T) {
+	t.Run("nil error", func(t *testing.T) {
+		assert.NoError(t, FromDB(nil, nil))
+	})
+
+	t.Run("no rows uses the given not found error", func(t *testing.T) {
+		// Test
+		err := FromDB(sql.ErrNoRows, NotFound("player_not_found", "player not found", nil))
+
+		// Assert
+		assert.True(t, IsNotFound(err))
+		assert.Equal(t, "player_not_found", From(err).Code)
+		assert.ErrorIs(t, err, sql.ErrNoRows)
+	})
+
+	t.Run("mysql errors are classified by number", func(t *testing.T) {
+		cases := map[uint16]Kind{
+			1062: KindConflict,
+			1452: KindConflict,
+			3819: KindValidation,
+			1213: KindUnavailable,
+			1205: KindUnavailable,
+			1064: KindInternal,
+		}
+
+		for number, kind := range cases {
+			// Test
+			err := FromDB(fmt.Errorf("wrapped: %w", &mysql.MySQLError{Number: number, Message: "raw sql text"}), nil)
+
+			// Assert
+			assert.Equal(t, kind, KindOf(err), "mysql error %d", number)
+			assert.NotContains(t, From(err).Message, "raw sql text")
+		}
+	})
+
+	t.Run("connection errors are unavailable", func(t *testing.T) {
+		assert.Equal(t, KindUnavailable, KindOf(FromDB(mysql.ErrInvalidConn, nil)))
+		assert.Equal(t, KindUnavailable, KindOf(FromDB(sql.ErrConnDone, nil)))
+	})
+
+	t.Run("deadline is kept as timeout", func(t *testing.T) {
+		// Test
+		err := FromDB(context.DeadlineExceeded, nil)
+
+		// Assert
+		assert.ErrorIs(t, err, context.DeadlineExceeded)
+		assert.Equal(t, KindTimeout, KindOf(err))
+	})
+
+	t.Run("cancellation is kept as a client closed request", func(t *testing.T) {
+		// Test
+		err := FromDB(fmt.Errorf("query games: %w", context.Canceled), nil)
+
+		// Assert
+		assert.ErrorIs(t, err, context.Canceled)
+		assert.Equal(t, KindCanceled, KindOf(err))
+		assert.Equal(t, "client_closed_request", From(err).Code)
+	})
+
+	t.Run("domain errors pass through", func(t *testing.T) {
+		// Setup
+		original := Validation("invalid_game", "game stats are invalid")
+
+		// Test
+		err := FromDB(original, nil)
+
+		// Assert
+		assert.True(t, errors.Is(err, original))
+	})
+}
@@ -0,0 +1,149 @@
+package db
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/jmoiron/sqlx"
+	"go.uber.org/zap"
+
+	"skyhawk/backend/apperror"
+	"skyhawk/backend/audit/domain"
+)
+
+type Repository interface {
+	Append(ctx context.Context, tx *sql.Tx, entries []domain.Entry) error
+	List(ctx context.Context, query domain.Query) ([]domain.Entry, error)
+}
+
+type Repo struct {
+	db     *sqlx.DB
+	logger *zap.Logger
+}
+
+func NewRepo(db *sqlx.DB, logger *zap.Logger) Repository {
+
+	return &Repo{db: db, logger: logger}
+}
+
+// Append - inserts entries inside tx, they commit or roll back with the change they describe
+func (r *Repo) Append(ctx context.Context, tx *sql.Tx, entries []domain.Entry) error {
+	if len(entries) == 0 {
+		return nil
+	}
+
+	placeHolders := make([]string, 0, len(entries))
+	values := make([]interface{}, 0, len(entries)*12)
+	for _, entry := range entries {
+		diff, err := nullableDiff(entry.Diff)
+		if err != nil {
+			return apperror.Internal(err)
+		}
+
+		placeHolders = append(placeHolders, "(?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?)")
+		values = append(values, entry.ID, entry.Entity, entry.EntityID, entry.Action, entry.Actor, nullable(entry.RequestID), nullable(entry.SourceIP),
+			entry.At, nullableJSON(entry.Before), nullableJSON(entry.After), diff)
+	}
+
+	q := fmt.Sprintf("INSERT INTO audit_log (id, entity, entity_id, action, actor, request_id, source_ip, at, before_state, after_state, diff) VALUES %s", strings.Join(placeHolders, ","))
+	if _, err := tx.ExecContext(ctx, q, values...); err != nil {
+		r.logger.Error("failed inserting audit entries", zap.Error(err))
+		return apperror.FromDB(err, nil)
+	}
+
+	return nil
+}
+
+// List - entries matching query, oldest first
+func (r *Repo) List(ctx context.Context, query domain.Query) ([]domain.Entry, error) {
+	var conditions []string
+	var args []interface{}
+
+	if query.Entity != "" {
+		conditions = append(conditions, "entity = ?")
+		args = append(args, query.Entity)
+	}
+	if query.EntityID != "" {
+		conditions = append(conditions, "entity_id = ?")
+		args = append(args, query.EntityID)
+	}
+	if !query.Since.IsZero() {
+		conditions = append(conditions, "at >= ?")
+		args = append(args, query.Since.UTC().Format(time.DateTime))
+	}
+	if query.After > 0 {
+		conditions = append(conditions, "seq > ?")
+		args = append(args, query.After)
+	}
+
+	q := "SELECT seq, id, entity, entity_id, action, actor, request_id, source_ip, at, before_state, after_state, diff FROM audit_log"
+	if len(conditions) > 0 {
+		q += " WHERE " + strings.Join(conditions, " AND ")
+	}
+	q += " ORDER BY seq LIMIT ?"
+	args = append(args, query.Limit)
+
+	rows, err := r.db.QueryContext(ctx, q, args...)
+	if err != nil {
+		return nil, apperror.FromDB(err, nil)
+	}
+	defer rows.Close()
+
+	entries := []domain.Entry{}
+	for rows.Next() {
+		var entry domain.Entry
+		var requestID, sourceIP sql.NullString
+		var at string
+		var before, after, diff []byte
+		if err = rows.Scan(&entry.Seq, &entry.ID, &entry.Entity, &entry.EntityID, &entry.Action, &entry.Actor, &requestID, &sourceIP, &at, &before, &after, &diff); err != nil {
+			return nil, apperror.FromDB(err, nil)
+		}
+
+		entry.RequestID = requestID.String
+		entry.SourceIP = sourceIP.String
+		if entry.At, err = time.Parse(time.DateTime, at); err != nil {
+			return nil, apperror.Internal(err)
+		}
+		entry.Before = before
+		entry.After = after
+		if len(diff) > 0 {
+			if err = json.Unmarshal(diff, &entry.Diff); err != nil {
+				return nil, apperror.Internal(err)
+			}
+		}
+		entries = append(entries, entry)
+	}
+	if err = rows.Err(); err != nil {
+		return nil, apperror.FromDB(err, nil)
+	}
+
+	return entries, nil
+}
+
+func nullable(value string) interface{} {
+	if value == "" {
+		return nil
+	}
+
+	return value
+}
+
+func nullableJSON(doc json.RawMessage) interface{} {
+	if len(doc) == 0 {
+		return nil
+	}
+
+	return []byte(doc)
+}
+
+func nullableDiff(diff []domain.FieldDiff) (interface{}, error) {
+	if len(diff) == 0 {
+		return nil, nil
+	}
+
+	return json.Marshal(diff)
+}
@@ -0,0 +1,81 @@
+package db
+
+import (
+	"context"
+	"encoding/json"
+	"testing"
+	"time"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/jmoiron/sqlx"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap/zaptest"
+
+	"skyhawk/backend/audit/domain"
+)
+
+func TestRepo_Append(t *testing.T) {
+	// Setup
+	db, dbMock := createMockDB(t)
+	repo := NewRepo(db, zaptest.NewLogger(t))
+	at := time.Date(2024, 11, 2, 19, 30, 0, 0, time.UTC)
+	entry := domain.Entry{
+		ID: "a1", Entity: domain.EntityGame, EntityID: "g1", Action: domain.ActionVoid, Actor: "scorer", RequestID: "req-1", At: at,
+		Before: json.RawMessage(`{"status":"final"}`), After: json.RawMessage(`{"status":"voided"}`),
+		Diff: []domain.FieldDiff{{Path: "status", From: json.RawMessage(`"final"`), To: json.RawMessage(`"voided"`)}},
+	}
+
+	dbMock.ExpectBegin()
+	dbMock.ExpectExec("INSERT INTO audit_log \\(id, entity, entity_id, action, actor, request_id, source_ip, at, before_state, after_state, diff\\) VALUES").
+		WithArgs("a1", domain.EntityGame, "g1", domain.ActionVoid, "scorer", "req-1", nil, at,
+			[]byte(`{"status":"final"}`), []byte(`{"status":"voided"}`), []byte(`[{"path":"status","from":"final","to":"voided"}]`)).
+		WillReturnResult(sqlmock.NewResult(1, 1))
+	tx, err := db.Begin()
+	require.NoError(t, err)
+
+	// Test
+	err = repo.Append(context.Background(), tx, []domain.Entry{entry})
+
+	// Assert
+	assert.NoError(t, err)
+	assert.NoError(t, dbMock.ExpectationsWereMet())
+}
+
+func TestRepo_List(t *testing.T) {
+	// Setup
+	db, dbMock := createMockDB(t)
+	repo := NewRepo(db, zaptest.NewLogger(t))
+
+	rows := sqlmock.NewRows([]string{"seq", "id", "entity", "entity_id", "action", "actor", "request_id", "source_ip", "at", "before_state", "after_state", "diff"}).
+		AddRow(7, "a1", "game", "g1", "create", "cli:import", nil, nil, "2024-11-02 19:30:00", nil, []byte(`{"id":"g1"}`), nil).
+		AddRow(9, "a2", "game", "g1", "void", "scorer", "req-1", "10.0.0.1", "2024-11-03 08:00:00", []byte(`{"status":"final"}`), []byte(`{"status":"voided"}`),
+			[]byte(`[{"path":"status","from":"final","to":"voided"}]`))
+	dbMock.ExpectQuery("FROM audit_log WHERE entity = \\? AND entity_id = \\? AND at >= \\? AND seq > \\? ORDER BY seq LIMIT \\?").
+		WithArgs(domain.EntityGame, "g1", "2024-11-01 00:00:00", int64(5), 100).
+		WillReturnRows(rows)
+
+	// Test
+	entries, err := repo.List(context.Background(), domain.Query{Entity: domain.EntityGame, EntityID: "g1", Since: time.Date(2024, 11, 1, 0, 0, 0, 0, time.UTC), After: 5, Limit: 100})
+
+	// Assert
+	require.NoError(t, err)
+	require.Len(t, entries, 2)
+	assert.Nil(t, entries[0].Before)
+	assert.Empty(t, entries[0].RequestID)
+	assert.Equal(t, "10.0.0.1", entries[1].SourceIP)
+	assert.Equal(t, time.Date(2024, 11, 3, 8, 0, 0, 0, time.UTC), entries[1].At)
+	require.Len(t, entries[1].Diff, 1)
+	assert.Equal(t, "status", entries[1].Diff[0].Path)
+}
+
+// Helper functions for creating mocks
+func createMockDB(t *testing.T) (*sqlx.DB, sqlmock.Sqlmock) {
+	db, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("Failed to create mock DB: %v", err)
+	}
+	t.Cleanup(func() { db.Close() })
+
+	return sqlx.NewDb(db, "sqlmock"), mock
+}
@@ -0,0 +1,214 @@
+package domain
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"reflect"
+	"sort"
+	"time"
+
+	"skyhawk/backend/apperror"
+)
+
+// Entity - the kind of record a change was made to
+type Entity string
+
+const (
+	EntityGame   Entity = "game"
+	EntityTeam   Entity = "team"
+	EntityPlayer Entity = "player"
+)
+
+func (e Entity) Valid() bool {
+	return e == EntityGame || e == EntityTeam || e == EntityPlayer
+}
+
+// Action - what the change did
+type Action string
+
+const (
+	ActionCreate       Action = "create"
+	ActionSchedule     Action = "schedule"
+	ActionStart        Action = "start"
+	ActionFinalize     Action = "finalize"
+	ActionVoid         Action = "void"
+	ActionLiveUpdate   Action = "live_update"
+	ActionCorrect      Action = "correct"
+	ActionAppendEvents Action = "append_events"
+	ActionRecompute    Action = "recompute"
+	ActionMerge        Action = "merge"
+)
+
+// Meta - who made a request and from where, carried on the request context
+type Meta struct {
+	Actor     string
+	RequestID string
+	SourceIP  string
+}
+
+type metaKey struct{}
+
+// WithMeta - ctx carrying meta, every change recorded under ctx is attributed to it
+func WithMeta(ctx context.Context, meta Meta) context.Context {
+	return context.WithValue(ctx, metaKey{}, meta)
+}
+
+// MetaFrom - the meta of ctx, changes made outside a request are attributed to "system"
+func MetaFrom(ctx context.Context) Meta {
+	if meta, ok := ctx.Value(metaKey{}).(Meta); ok {
+		return meta
+	}
+
+	return Meta{Actor: "system"}
+}
+
+// Change - one write to an entity. Before is nil for creations, After for deletions
+type Change struct {
+	Entity   Entity
+	EntityID string
+	Action   Action
+	Before   interface{}
+	After    interface{}
+}
+
+// FieldDiff - one field that differs between the before and after state, From or To is absent when the field was added or removed
+type FieldDiff struct {
+	Path string          `json:"path"`
+	From json.RawMessage `json:"from,omitempty"`
+	To   json.RawMessage `json:"to,omitempty"`
+}
+
+// Entry - a row of the audit log
+type Entry struct {
+	Seq       int64           `json:"seq"`
+	ID        string          `json:"id"`
+	Entity    Entity          `json:"entity"`
+	EntityID  string          `json:"entity_id"`
+	Action    Action          `json:"action"`
+	Actor     string          `json:"actor"`
+	RequestID string          `json:"request_id,omitempty"`
+	SourceIP  string          `json:"source_ip,omitempty"`
+	At        time.Time       `json:"at"`
+	Before    json.RawMessage `json:"before,omitempty"`
+	After     json.RawMessage `json:"after,omitempty"`
+	Diff      []FieldDiff     `json:"diff,omitempty"`
+}
+
+// Diff - the fields that differ between two JSON documents, objects are walked by key and arrays by index.
+// Paths look like "lines.<player id>.points", sorted
+func Diff(before, after json.RawMessage) ([]FieldDiff, error) {
+	from, err := flatten(before)
+	if err != nil {
+		return nil, err
+	}
+	to, err := flatten(after)
+	if err != nil {
+		return nil, err
+	}
+
+	paths := make(map[string]bool, len(from)+len(to))
+	for path := range from {
+		paths[path] = true
+	}
+	for path := range to {
+		paths[path] = true
+	}
+
+	var diffs []FieldDiff
+	for path := range paths {
+		a, inFrom := from[path]
+		b, inTo := to[path]
+		if inFrom && inTo && reflect.DeepEqual(a, b) {
+			continue
+		}
+
+		diff := FieldDiff{Path: path}
+		if inFrom {
+			diff.From, _ = json.Marshal(a)
+		}
+		if inTo {
+			diff.To, _ = json.Marshal(b)
+		}
+		diffs = append(diffs, diff)
+	}
+	sort.Slice(diffs, func(i, j int) bool { return diffs[i].Path < diffs[j].Path })
+
+	return diffs, nil
+}
+
+func flatten(doc json.RawMessage) (map[string]interface{}, error) {
+	out := make(map[string]interface{})
+	if len(doc) == 0 {
+		return out, nil
+	}
+
+	var value interface{}
+	if err := json.Unmarshal(doc, &value); err != nil {
+		return nil, err
+	}
+	walk("", value, out)
+
+	return out, nil
+}
+
+func walk(path string, value interface{}, out map[string]interface{}) {
+	switch v := value.(type) {
+	case map[string]interface{}:
+		for key, child := range v {
+			walk(join(path, key), child, out)
+		}
+	case []interface{}:
+		for i, child := range v {
+			walk(fmt.Sprintf("%s[%d]", path, i), child, out)
+		}
+	default:
+		out[path] = v
+	}
+}
+
+func join(path, key string) string {
+	if path == "" {
+		return key
+	}
+
+	return path + "." + key
+}
+
+const (
+	defaultLimit = 100
+	maxLimit     = 1000
+)
+
+// Query - filters of the audit log, every field is optional. After is the seq of the last entry of the previous page
+type Query struct {
+	Entity   Entity
+	EntityID string
+	Since    time.Time
+	After    int64
+	Limit    int
+}
+
+func (q *Query) Validate() error {
+	var fields []apperror.FieldError
+
+	if q.Entity != "" && !q.Entity.Valid() {
+		fields = append(fields, apperror.FieldError{Field: "entity", Message: "entity must be game, team or player"})
+	}
+	if q.EntityID != "" && q.Entity == "" {
+		fields = append(fields, apperror.FieldError{Field: "id", Message: "id needs an entity"})
+	}
+	if q.Limit < 0 || q.Limit > maxLimit {
+		fields = append(fields, apperror.FieldError{Field: "limit", Message: fmt.Sprintf("limit must be between 1 and %d", maxLimit)})
+	}
+
+	if len(fields) > 0 {
+		return apperror.Validation("invalid_audit_query", "audit query is invalid", fields...)
+	}
+
+	if q.Limit == 0 {
+		q.Limit = defaultLimit
+	}
+
+	return nil
+}
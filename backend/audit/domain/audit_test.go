@@ -0,0 +1,88 @@
+package domain
+
+import (
+	"context"
+	"encoding/json"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"skyhawk/backend/apperror"
+)
+
+func TestDiff(t *testing.T) {
+	t.Run("changed, added and removed fields", func(t *testing.T) {
+		// Setup
+		before := json.RawMessage(`{"status":"live","lines":{"p1":{"points":10,"fouls":2},"p2":{"points":4}}}`)
+		after := json.RawMessage(`{"status":"live","lines":{"p1":{"points":12,"fouls":2},"p3":{"points":1}}}`)
+
+		// Test
+		diff, err := Diff(before, after)
+
+		// Assert
+		require.NoError(t, err)
+		assert.Equal(t, []FieldDiff{
+			{Path: "lines.p1.points", From: json.RawMessage(`10`), To: json.RawMessage(`12`)},
+			{Path: "lines.p2.points", From: json.RawMessage(`4`)},
+			{Path: "lines.p3.points", To: json.RawMessage(`1`)},
+		}, diff)
+	})
+
+	t.Run("arrays are compared by index", func(t *testing.T) {
+		// Test
+		diff, err := Diff(json.RawMessage(`{"tags":["a","b"]}`), json.RawMessage(`{"tags":["a","c"]}`))
+
+		// Assert
+		require.NoError(t, err)
+		require.Len(t, diff, 1)
+		assert.Equal(t, "tags[1]", diff[0].Path)
+	})
+
+	t.Run("identical documents", func(t *testing.T) {
+		// Test
+		diff, err := Diff(json.RawMessage(`{"id":"g1"}`), json.RawMessage(`{"id":"g1"}`))
+
+		// Assert
+		require.NoError(t, err)
+		assert.Empty(t, diff)
+	})
+}
+
+func TestQuery_Validate(t *testing.T) {
+	t.Run("defaults the limit", func(t *testing.T) {
+		// Setup
+		query := Query{Entity: EntityGame, EntityID: "g1"}
+
+		// Test
+		err := query.Validate()
+
+		// Assert
+		require.NoError(t, err)
+		assert.Equal(t, defaultLimit, query.Limit)
+	})
+
+	t.Run("rejects unknown entities and ids without an entity", func(t *testing.T) {
+		// Test
+		errEntity := (&Query{Entity: "webhook"}).Validate()
+		errID := (&Query{EntityID: "g1"}).Validate()
+
+		// Assert
+		assert.Equal(t, "entity", apperror.From(errEntity).Fields[0].Field)
+		assert.Equal(t, "id", apperror.From(errID).Fields[0].Field)
+	})
+}
+
+func TestMetaFrom(t *testing.T) {
+	// Setup
+	ctx := WithMeta(context.Background(), Meta{Actor: "scorer", RequestID: "req-1"})
+
+	// Test
+	meta := MetaFrom(ctx)
+	system := MetaFrom(context.Background())
+
+	// Assert
+	assert.Equal(t, "scorer", meta.Actor)
+	assert.Equal(t, "req-1", meta.RequestID)
+	assert.Equal(t, "system", system.Actor)
+}
@@ -0,0 +1,72 @@
+package handler
+
+import (
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/labstack/echo/v4"
+	"go.uber.org/zap"
+
+	"skyhawk/backend/apperror"
+	"skyhawk/backend/audit/domain"
+	"skyhawk/backend/audit/usecase"
+)
+
+type Handler struct {
+	useCase *usecase.UseCase
+	logger  *zap.Logger
+}
+
+func NewHandler(useCase *usecase.UseCase, logger *zap.Logger) *Handler {
+	return &Handler{useCase: useCase, logger: logger}
+}
+
+// ListHandler - GET /audit?entity=&id=&since=&after=&limit=, since is RFC3339 or a date
+func (h *Handler) ListHandler(c echo.Context) error {
+	query := domain.Query{
+		Entity:   domain.Entity(c.QueryParam("entity")),
+		EntityID: c.QueryParam("id"),
+	}
+
+	if since := c.QueryParam("since"); since != "" {
+		parsed, err := parseSince(since)
+		if err != nil {
+			return apperror.Validation("invalid_audit_query", "since must be RFC3339 or YYYY-MM-DD",
+				apperror.FieldError{Field: "since", Message: "since must be RFC3339 or YYYY-MM-DD"})
+		}
+		query.Since = parsed
+	}
+	if after := c.QueryParam("after"); after != "" {
+		seq, err := strconv.ParseInt(after, 10, 64)
+		if err != nil || seq < 0 {
+			return apperror.Validation("invalid_audit_query", "after must be the seq of an entry",
+				apperror.FieldError{Field: "after", Message: "after must be a positive integer"})
+		}
+		query.After = seq
+	}
+	if limit := c.QueryParam("limit"); limit != "" {
+		n, err := strconv.Atoi(limit)
+		if err != nil || n < 1 {
+			return apperror.Validation("invalid_audit_query", "limit must be a positive integer",
+				apperror.FieldError{Field: "limit", Message: "limit must be a positive integer"})
+		}
+		query.Limit = n
+	}
+
+	entries, err := h.useCase.List(c.Request().Context(), query)
+
+	if err != nil {
+		return err
+	}
+
+	return c.JSON(http.StatusOK, entries)
+}
+
+func parseSince(value string) (time.Time, error) {
+	if parsed, err := time.Parse(time.RFC3339, value); err == nil {
+		return parsed, nil
+	}
+
+	return time.Parse(time.DateOnly, value)
+}
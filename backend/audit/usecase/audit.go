@@ -0,0 +1,102 @@
+package usecase
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"time"
+
+	"github.com/google/uuid"
+	"go.uber.org/zap"
+
+	"skyhawk/backend/apperror"
+	"skyhawk/backend/audit/domain"
+	"skyhawk/backend/logging"
+)
+
+type Repository interface {
+	Append(ctx context.Context, tx *sql.Tx, entries []domain.Entry) error
+	List(ctx context.Context, query domain.Query) ([]domain.Entry, error)
+}
+
+type UseCase struct {
+	repo   Repository
+	logger *zap.Logger
+}
+
+func NewUseCase(repo Repository, logger *zap.Logger) *UseCase {
+
+	return &UseCase{repo: repo, logger: logger}
+}
+
+// Record - writes changes to the audit log inside tx, attributed to the request meta of ctx
+func (s *UseCase) Record(ctx context.Context, tx *sql.Tx, changes ...domain.Change) error {
+	if len(changes) == 0 {
+		return nil
+	}
+
+	meta := domain.MetaFrom(ctx)
+	at := time.Now().UTC()
+
+	entries := make([]domain.Entry, 0, len(changes))
+	for _, change := range changes {
+		entry, err := toEntry(change, meta, at)
+		if err != nil {
+			return apperror.Internal(err)
+		}
+		entries = append(entries, entry)
+	}
+
+	return s.repo.Append(ctx, tx, entries)
+}
+
+func (s *UseCase) List(ctx context.Context, query domain.Query) ([]domain.Entry, error) {
+	if err := query.Validate(); err != nil {
+		return nil, err
+	}
+
+	entries, err := s.repo.List(ctx, query)
+	if err != nil {
+		logging.From(ctx, s.logger).Error("UseCase.List failed fetching audit entries", zap.Error(err))
+		return nil, err
+	}
+
+	return entries, nil
+}
+
+// toEntry - a creation keeps only its after state, the diff of an update lists the changed fields
+func toEntry(change domain.Change, meta domain.Meta, at time.Time) (domain.Entry, error) {
+	entry := domain.Entry{
+		ID:        uuid.New().String(),
+		Entity:    change.Entity,
+		EntityID:  change.EntityID,
+		Action:    change.Action,
+		Actor:     meta.Actor,
+		RequestID: meta.RequestID,
+		SourceIP:  meta.SourceIP,
+		At:        at,
+	}
+
+	var err error
+	if entry.Before, err = marshal(change.Before); err != nil {
+		return domain.Entry{}, err
+	}
+	if entry.After, err = marshal(change.After); err != nil {
+		return domain.Entry{}, err
+	}
+	if entry.Before != nil && entry.After != nil {
+		if entry.Diff, err = domain.Diff(entry.Before, entry.After); err != nil {
+			return domain.Entry{}, err
+		}
+	}
+
+	return entry, nil
+}
+
+func marshal(state interface{}) (json.RawMessage, error) {
+	if state == nil {
+		return nil, nil
+	}
+
+	return json.Marshal(state)
+}
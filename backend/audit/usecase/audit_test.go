@@ -0,0 +1,89 @@
+package usecase
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap/zaptest"
+
+	"skyhawk/backend/apperror"
+	"skyhawk/backend/audit/domain"
+)
+
+type fakeRepo struct {
+	entries []domain.Entry
+	query   domain.Query
+}
+
+func (f *fakeRepo) Append(ctx context.Context, tx *sql.Tx, entries []domain.Entry) error {
+	f.entries = append(f.entries, entries...)
+	return nil
+}
+
+func (f *fakeRepo) List(ctx context.Context, query domain.Query) ([]domain.Entry, error) {
+	f.query = query
+	return f.entries, nil
+}
+
+type status struct {
+	Status string `json:"status"`
+}
+
+func TestUseCase_Record(t *testing.T) {
+	// Setup
+	repo := &fakeRepo{}
+	useCase := NewUseCase(repo, zaptest.NewLogger(t))
+	ctx := domain.WithMeta(context.Background(), domain.Meta{Actor: "scorer", RequestID: "req-1", SourceIP: "10.0.0.1"})
+
+	// Test
+	err := useCase.Record(ctx, nil,
+		domain.Change{Entity: domain.EntityTeam, EntityID: "t1", Action: domain.ActionCreate, After: map[string]string{"name": "Lakers"}},
+		domain.Change{Entity: domain.EntityGame, EntityID: "g1", Action: domain.ActionVoid, Before: status{"final"}, After: status{"voided"}},
+	)
+
+	// Assert
+	require.NoError(t, err)
+	require.Len(t, repo.entries, 2)
+
+	created := repo.entries[0]
+	assert.Equal(t, "scorer", created.Actor)
+	assert.Equal(t, "req-1", created.RequestID)
+	assert.Equal(t, "10.0.0.1", created.SourceIP)
+	assert.Nil(t, created.Before)
+	assert.Empty(t, created.Diff)
+	assert.JSONEq(t, `{"name":"Lakers"}`, string(created.After))
+
+	voided := repo.entries[1]
+	assert.NotEqual(t, created.ID, voided.ID)
+	assert.Equal(t, []domain.FieldDiff{{Path: "status", From: json.RawMessage(`"final"`), To: json.RawMessage(`"voided"`)}}, voided.Diff)
+}
+
+func TestUseCase_List(t *testing.T) {
+	t.Run("defaults the limit", func(t *testing.T) {
+		// Setup
+		repo := &fakeRepo{}
+		useCase := NewUseCase(repo, zaptest.NewLogger(t))
+
+		// Test
+		_, err := useCase.List(context.Background(), domain.Query{Entity: domain.EntityGame})
+
+		// Assert
+		require.NoError(t, err)
+		assert.Equal(t, 100, repo.query.Limit)
+	})
+
+	t.Run("invalid query", func(t *testing.T) {
+		// Setup
+		useCase := NewUseCase(&fakeRepo{}, zaptest.NewLogger(t))
+
+		// Test
+		_, err := useCase.List(context.Background(), domain.Query{Entity: "webhook"})
+
+		// Assert
+		assert.Equal(t, apperror.KindValidation, apperror.KindOf(err))
+	})
+}
@@ -0,0 +1,151 @@
+package db
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"time"
+
+	"github.com/jmoiron/sqlx"
+	"go.uber.org/zap"
+
+	"skyhawk/backend/apperror"
+	"skyhawk/backend/auth/domain"
+)
+
+type Repository interface {
+	Create(ctx context.Context, key domain.APIKey) error
+	List(ctx context.Context) ([]domain.APIKey, error)
+	FindByPrefix(ctx context.Context, prefix string) (domain.APIKey, error)
+	Revoke(ctx context.Context, id string, at time.Time) error
+	Touch(ctx context.Context, id string, at time.Time) error
+}
+
+type Repo struct {
+	db     *sqlx.DB
+	logger *zap.Logger
+}
+
+func NewRepo(db *sqlx.DB, logger *zap.Logger) Repository {
+
+	return &Repo{db: db, logger: logger}
+}
+
+func keyNotFound() error {
+	return apperror.NotFound("api_key_not_found", "api key not found", nil)
+}
+
+const selectKeys = "SELECT id, name, prefix, key_hash, scopes, created_at, last_used_at, revoked_at FROM api_keys"
+
+func (r *Repo) Create(ctx context.Context, key domain.APIKey) error {
+	scopes, err := json.Marshal(key.Scopes)
+	if err != nil {
+		return apperror.Internal(err)
+	}
+
+	_, err = r.db.ExecContext(ctx, "INSERT INTO api_keys (id, name, prefix, key_hash, scopes, created_at) VALUES (?, ?, ?, ?, ?, ?)",
+		key.ID, key.Name, key.Prefix, key.Hash, scopes, key.CreatedAt)
+	if err != nil {
+		r.logger.Error("failed inserting api key", zap.Error(err))
+		return apperror.FromDB(err, nil)
+	}
+
+	return nil
+}
+
+// List - every key, revoked ones included so their last use stays visible
+func (r *Repo) List(ctx context.Context) ([]domain.APIKey, error) {
+	rows, err := r.db.QueryContext(ctx, selectKeys+" ORDER BY created_at, id")
+	if err != nil {
+		return nil, apperror.FromDB(err, nil)
+	}
+
+	keys, err := scanKeys(rows)
+	if err != nil {
+		return nil, err
+	}
+	if keys == nil {
+		keys = []domain.APIKey{}
+	}
+
+	return keys, nil
+}
+
+func (r *Repo) FindByPrefix(ctx context.Context, prefix string) (domain.APIKey, error) {
+	rows, err := r.db.QueryContext(ctx, selectKeys+" WHERE prefix = ?", prefix)
+	if err != nil {
+		return domain.APIKey{}, apperror.FromDB(err, nil)
+	}
+
+	keys, err := scanKeys(rows)
+	if err != nil {
+		return domain.APIKey{}, err
+	}
+	if len(keys) == 0 {
+		return domain.APIKey{}, keyNotFound()
+	}
+
+	return keys[0], nil
+}
+
+func (r *Repo) Revoke(ctx context.Context, id string, at time.Time) error {
+	res, err := r.db.ExecContext(ctx, "UPDATE api_keys SET revoked_at = ? WHERE id = ? AND revoked_at IS NULL", at, id)
+	if err != nil {
+		return apperror.FromDB(err, nil)
+	}
+	if affected, err := res.RowsAffected(); err != nil || affected == 0 {
+		return keyNotFound()
+	}
+
+	return nil
+}
+
+// Touch - records the last use of a key
+func (r *Repo) Touch(ctx context.Context, id string, at time.Time) error {
+	_, err := r.db.ExecContext(ctx, "UPDATE api_keys SET last_used_at = ? WHERE id = ?", at, id)
+
+	return apperror.FromDB(err, nil)
+}
+
+func scanKeys(rows *sql.Rows) ([]domain.APIKey, error) {
+	defer rows.Close()
+
+	var keys []domain.APIKey
+	for rows.Next() {
+		var key domain.APIKey
+		var scopes []byte
+		var createdAt string
+		var lastUsedAt, revokedAt sql.NullString
+		if err := rows.Scan(&key.ID, &key.Name, &key.Prefix, &key.Hash, &scopes, &createdAt, &lastUsedAt, &revokedAt); err != nil {
+			return nil, apperror.FromDB(err, nil)
+		}
+		if err := json.Unmarshal(scopes, &key.Scopes); err != nil {
+			return nil, apperror.Internal(err)
+		}
+		key.CreatedAt = parseTimestamp(createdAt)
+		if lastUsedAt.Valid {
+			at := parseTimestamp(lastUsedAt.String)
+			key.LastUsedAt = &at
+		}
+		if revokedAt.Valid {
+			at := parseTimestamp(revokedAt.String)
+			key.RevokedAt = &at
+		}
+		keys = append(keys, key)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, apperror.FromDB(err, nil)
+	}
+
+	return keys, nil
+}
+
+// parseTimestamp - the driver returns timestamps as "2006-01-02 15:04:05" text
+func parseTimestamp(value string) time.Time {
+	parsed, err := time.Parse(time.DateTime, value)
+	if err != nil {
+		return time.Time{}
+	}
+
+	return parsed
+}
@@ -0,0 +1,74 @@
+package db
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/jmoiron/sqlx"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap/zaptest"
+
+	"skyhawk/backend/apperror"
+	"skyhawk/backend/auth/domain"
+)
+
+func TestRepo_FindByPrefix(t *testing.T) {
+	t.Run("found", func(t *testing.T) {
+		// Setup
+		db, dbMock := createMockDB(t)
+		repo := NewRepo(db, zaptest.NewLogger(t))
+		rows := sqlmock.NewRows([]string{"id", "name", "prefix", "key_hash", "scopes", "created_at", "last_used_at", "revoked_at"}).
+			AddRow("k1", "scorer", "a1b2c3d4e5f6", "hash", []byte(`["games:write"]`), "2024-11-02 19:30:00", "2024-11-03 08:00:00", nil)
+		dbMock.ExpectQuery("FROM api_keys WHERE prefix = \\?").WithArgs("a1b2c3d4e5f6").WillReturnRows(rows)
+
+		// Test
+		key, err := repo.FindByPrefix(context.Background(), "a1b2c3d4e5f6")
+
+		// Assert
+		require.NoError(t, err)
+		assert.Equal(t, []domain.Scope{domain.ScopeGamesWrite}, key.Scopes)
+		require.NotNil(t, key.LastUsedAt)
+		assert.Equal(t, time.Date(2024, 11, 3, 8, 0, 0, 0, time.UTC), *key.LastUsedAt)
+		assert.Nil(t, key.RevokedAt)
+	})
+
+	t.Run("not found", func(t *testing.T) {
+		// Setup
+		db, dbMock := createMockDB(t)
+		repo := NewRepo(db, zaptest.NewLogger(t))
+		dbMock.ExpectQuery("FROM api_keys WHERE prefix = \\?").WillReturnRows(sqlmock.NewRows([]string{"id"}))
+
+		// Test
+		_, err := repo.FindByPrefix(context.Background(), "missing")
+
+		// Assert
+		assert.True(t, apperror.IsNotFound(err))
+	})
+}
+
+func TestRepo_Revoke(t *testing.T) {
+	// Setup
+	db, dbMock := createMockDB(t)
+	repo := NewRepo(db, zaptest.NewLogger(t))
+	dbMock.ExpectExec("UPDATE api_keys SET revoked_at = \\? WHERE id = \\? AND revoked_at IS NULL").WillReturnResult(sqlmock.NewResult(0, 0))
+
+	// Test
+	err := repo.Revoke(context.Background(), "k1", time.Now())
+
+	// Assert
+	assert.True(t, apperror.IsNotFound(err))
+}
+
+// Helper functions for creating mocks
+func createMockDB(t *testing.T) (*sqlx.DB, sqlmock.Sqlmock) {
+	db, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("Failed to create mock DB: %v", err)
+	}
+	t.Cleanup(func() { db.Close() })
+
+	return sqlx.NewDb(db, "sqlmock"), mock
+}
@@ -0,0 +1,188 @@
+package domain
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"strings"
+	"time"
+
+	"skyhawk/backend/apperror"
+)
+
+// Scope - what a credential is allowed to do
+type Scope string
+
+const (
+	ScopeStatsRead  Scope = "stats:read"
+	ScopeGamesWrite Scope = "games:write"
+	ScopeAdmin      Scope = "admin"
+)
+
+var Scopes = []Scope{ScopeStatsRead, ScopeGamesWrite, ScopeAdmin}
+
+func (s Scope) Valid() bool {
+	for _, known := range Scopes {
+		if s == known {
+			return true
+		}
+	}
+
+	return false
+}
+
+// Role - what a signed in user does in the league, granted by the identity provider and mapped to scopes
+type Role string
+
+const (
+	RoleViewer      Role = "viewer"
+	RoleScorekeeper Role = "scorekeeper"
+	RoleLeagueAdmin Role = "league_admin"
+)
+
+var roleScopes = map[Role][]Scope{
+	RoleViewer:      {ScopeStatsRead},
+	RoleScorekeeper: {ScopeStatsRead, ScopeGamesWrite},
+	RoleLeagueAdmin: {ScopeAdmin},
+}
+
+// ScopesOf - the scopes granted by roles, without duplicates
+func ScopesOf(roles ...Role) []Scope {
+	var scopes []Scope
+	seen := map[Scope]bool{}
+	for _, role := range roles {
+		for _, scope := range roleScopes[role] {
+			if !seen[scope] {
+				seen[scope] = true
+				scopes = append(scopes, scope)
+			}
+		}
+	}
+
+	return scopes
+}
+
+// Principal - who a request is authenticated as, Subject names it in the audit log
+type Principal struct {
+	Subject string
+	Name    string
+	Scopes  []Scope
+}
+
+// Allows - reports whether the principal holds scope, admin holds every scope
+func (p Principal) Allows(scope Scope) bool {
+	for _, s := range p.Scopes {
+		if s == scope || s == ScopeAdmin {
+			return true
+		}
+	}
+
+	return false
+}
+
+type principalKey struct{}
+
+func WithPrincipal(ctx context.Context, principal Principal) context.Context {
+	return context.WithValue(ctx, principalKey{}, principal)
+}
+
+// PrincipalFrom - the principal of an authenticated request
+func PrincipalFrom(ctx context.Context) (Principal, bool) {
+	principal, ok := ctx.Value(principalKey{}).(Principal)
+
+	return principal, ok
+}
+
+// APIKey - a stored key. Only the hash of the secret is kept, the prefix finds the row
+type APIKey struct {
+	ID         string     `json:"id"`
+	Name       string     `json:"name"`
+	Prefix     string     `json:"prefix"`
+	Hash       string     `json:"-"`
+	Scopes     []Scope    `json:"scopes"`
+	CreatedAt  time.Time  `json:"created_at"`
+	LastUsedAt *time.Time `json:"last_used_at"`
+	RevokedAt  *time.Time `json:"revoked_at,omitempty"`
+}
+
+// Principal - the subject is the key's prefix, unique where names are not, the name is for display only
+func (k APIKey) Principal() Principal {
+	return Principal{Subject: "apikey:" + k.Prefix, Name: k.Name, Scopes: k.Scopes}
+}
+
+// CreatedKey - a new key with its plaintext, which is shown once and cannot be recovered
+type CreatedKey struct {
+	APIKey
+	Key string `json:"key"`
+}
+
+type CreateKeyReq struct {
+	Name   string  `json:"name"`
+	Scopes []Scope `json:"scopes"`
+}
+
+const maxNameLength = 128
+
+func (r CreateKeyReq) Validate() error {
+	var fields []apperror.FieldError
+
+	if r.Name == "" || len(r.Name) > maxNameLength {
+		fields = append(fields, apperror.FieldError{Field: "name", Message: fmt.Sprintf("name is required and at most %d characters", maxNameLength)})
+	}
+	if len(r.Scopes) == 0 {
+		fields = append(fields, apperror.FieldError{Field: "scopes", Message: "at least one scope is required"})
+	}
+	for _, scope := range r.Scopes {
+		if !scope.Valid() {
+			fields = append(fields, apperror.FieldError{Field: "scopes", Message: fmt.Sprintf("unknown scope %q", scope)})
+		}
+	}
+
+	if len(fields) > 0 {
+		return apperror.Validation("invalid_api_key", "api key request is invalid", fields...)
+	}
+
+	return nil
+}
+
+// keyPrefix - marks skyhawk keys so they are easy to spot in leaked config or logs
+const keyPrefix = "sk_"
+
+// GenerateKey - a new key of the form sk_<prefix>_<secret>
+func GenerateKey() (prefix, key string, err error) {
+	id := make([]byte, 6)
+	secret := make([]byte, 32)
+	if _, err = rand.Read(id); err != nil {
+		return "", "", err
+	}
+	if _, err = rand.Read(secret); err != nil {
+		return "", "", err
+	}
+
+	prefix = hex.EncodeToString(id)
+
+	return prefix, keyPrefix + prefix + "_" + hex.EncodeToString(secret), nil
+}
+
+// ParseKey - the prefix of key, false when key is not shaped like a skyhawk key
+func ParseKey(key string) (string, bool) {
+	if !strings.HasPrefix(key, keyPrefix) {
+		return "", false
+	}
+
+	prefix, secret, ok := strings.Cut(strings.TrimPrefix(key, keyPrefix), "_")
+	if !ok || prefix == "" || secret == "" {
+		return "", false
+	}
+
+	return prefix, true
+}
+
+// HashKey - keys carry 256 bits of randomness, a plain sha256 is enough to store them
+func HashKey(key string) string {
+	sum := sha256.Sum256([]byte(key))
+
+	return hex.EncodeToString(sum[:])
+}
@@ -0,0 +1,52 @@
+package domain
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestGenerateKey(t *testing.T) {
+	// Test
+	prefix, key, err := GenerateKey()
+
+	// Assert
+	require.NoError(t, err)
+	parsed, ok := ParseKey(key)
+	assert.True(t, ok)
+	assert.Equal(t, prefix, parsed)
+	assert.Len(t, HashKey(key), 64)
+	assert.NotEqual(t, HashKey(key), HashKey(key+"x"))
+}
+
+func TestParseKey(t *testing.T) {
+	for _, key := range []string{"", "abc", "sk_", "sk_abc", "sk__secret", "pk_abc_secret"} {
+		// Test
+		_, ok := ParseKey(key)
+
+		// Assert
+		assert.False(t, ok, key)
+	}
+}
+
+func TestPrincipal_Allows(t *testing.T) {
+	// Setup
+	reader := Principal{Scopes: []Scope{ScopeStatsRead}}
+	admin := Principal{Scopes: []Scope{ScopeAdmin}}
+
+	// Assert
+	assert.True(t, reader.Allows(ScopeStatsRead))
+	assert.False(t, reader.Allows(ScopeGamesWrite))
+	assert.False(t, reader.Allows(ScopeAdmin))
+	assert.True(t, admin.Allows(ScopeGamesWrite))
+}
+
+func TestCreateKeyReq_Validate(t *testing.T) {
+	// Test
+	err := CreateKeyReq{Name: "scorer", Scopes: []Scope{ScopeGamesWrite, "games:delete"}}.Validate()
+
+	// Assert
+	assert.Error(t, err)
+	assert.NoError(t, CreateKeyReq{Name: "scorer", Scopes: []Scope{ScopeGamesWrite}}.Validate())
+}
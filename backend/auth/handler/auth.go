@@ -0,0 +1,56 @@
+package handler
+
+import (
+	"net/http"
+
+	"github.com/labstack/echo/v4"
+	"go.uber.org/zap"
+
+	"skyhawk/backend/apperror"
+	"skyhawk/backend/auth/domain"
+	"skyhawk/backend/auth/usecase"
+)
+
+type Handler struct {
+	useCase *usecase.UseCase
+	logger  *zap.Logger
+}
+
+func NewHandler(useCase *usecase.UseCase, logger *zap.Logger) *Handler {
+	return &Handler{useCase: useCase, logger: logger}
+}
+
+// CreateKeyHandler - POST /keys, the response is the only time the key is shown
+func (h *Handler) CreateKeyHandler(c echo.Context) error {
+	var req domain.CreateKeyReq
+
+	if err := c.Bind(&req); err != nil {
+		return apperror.Validation("invalid_body", "request body is not a valid api key request")
+	}
+
+	key, err := h.useCase.Create(c.Request().Context(), req)
+
+	if err != nil {
+		return err
+	}
+
+	return c.JSON(http.StatusCreated, key)
+}
+
+func (h *Handler) ListKeysHandler(c echo.Context) error {
+	keys, err := h.useCase.List(c.Request().Context())
+
+	if err != nil {
+		return err
+	}
+
+	return c.JSON(http.StatusOK, keys)
+}
+
+func (h *Handler) RevokeKeyHandler(c echo.Context) error {
+	if err := h.useCase.Revoke(c.Request().Context(), c.Param("id")); err != nil {
+		return err
+	}
+
+	return c.NoContent(http.StatusNoContent)
+}
@@ -0,0 +1,280 @@
+package jwt
+
+import (
+	"context"
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rsa"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"math/big"
+	"net/http"
+	"os"
+	"sync"
+	"time"
+
+	"go.uber.org/zap"
+	"golang.org/x/sync/singleflight"
+)
+
+// ErrUnknownKey - the token was signed by a key the set does not hold
+var ErrUnknownKey = errors.New("unknown signing key")
+
+// KeySet - the public keys tokens are verified against, by key id
+type KeySet interface {
+	Key(ctx context.Context, kid string) (crypto.PublicKey, error)
+}
+
+// jwk - the members of a JSON web key used for RSA and EC signature keys
+type jwk struct {
+	Kty string `json:"kty"`
+	Kid string `json:"kid"`
+	Use string `json:"use"`
+	Crv string `json:"crv"`
+	N   string `json:"n"`
+	E   string `json:"e"`
+	X   string `json:"x"`
+	Y   string `json:"y"`
+}
+
+// ParseJWKS - the signature keys of a JWKS document, encryption keys and unsupported key types are skipped
+func ParseJWKS(data []byte) (map[string]crypto.PublicKey, error) {
+	var doc struct {
+		Keys []jwk `json:"keys"`
+	}
+	if err := json.Unmarshal(data, &doc); err != nil {
+		return nil, fmt.Errorf("jwks: %w", err)
+	}
+
+	keys := make(map[string]crypto.PublicKey, len(doc.Keys))
+	for _, k := range doc.Keys {
+		if k.Use != "" && k.Use != "sig" {
+			continue
+		}
+		key, err := k.publicKey()
+		if err != nil {
+			return nil, fmt.Errorf("jwks: key %q: %w", k.Kid, err)
+		}
+		if key != nil {
+			keys[k.Kid] = key
+		}
+	}
+	if len(keys) == 0 {
+		return nil, errors.New("jwks: no signature keys")
+	}
+
+	return keys, nil
+}
+
+func (k jwk) publicKey() (crypto.PublicKey, error) {
+	switch k.Kty {
+	case "RSA":
+		n, err := decodeInt(k.N)
+		if err != nil {
+			return nil, err
+		}
+		e, err := decodeInt(k.E)
+		if err != nil {
+			return nil, err
+		}
+		if !e.IsInt64() || e.Int64() > 1<<31-1 {
+			return nil, errors.New("rsa exponent is too large")
+		}
+		return &rsa.PublicKey{N: n, E: int(e.Int64())}, nil
+	case "EC":
+		var curve elliptic.Curve
+		switch k.Crv {
+		case "P-256":
+			curve = elliptic.P256()
+		case "P-384":
+			curve = elliptic.P384()
+		case "P-521":
+			curve = elliptic.P521()
+		default:
+			return nil, fmt.Errorf("unsupported curve %q", k.Crv)
+		}
+		x, err := decodeInt(k.X)
+		if err != nil {
+			return nil, err
+		}
+		y, err := decodeInt(k.Y)
+		if err != nil {
+			return nil, err
+		}
+		if !curve.IsOnCurve(x, y) {
+			return nil, errors.New("point is not on the curve")
+		}
+		return &ecdsa.PublicKey{Curve: curve, X: x, Y: y}, nil
+	}
+
+	return nil, nil
+}
+
+func decodeInt(value string) (*big.Int, error) {
+	if value == "" {
+		return nil, errors.New("missing key parameter")
+	}
+	raw, err := base64.RawURLEncoding.DecodeString(value)
+	if err != nil {
+		return nil, err
+	}
+
+	return new(big.Int).SetBytes(raw), nil
+}
+
+// StaticKeySet - keys loaded once, from a file or for tests
+type StaticKeySet map[string]crypto.PublicKey
+
+// LoadKeySetFile - a StaticKeySet from a JWKS file
+func LoadKeySetFile(path string) (StaticKeySet, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+
+	keys, err := ParseJWKS(data)
+	if err != nil {
+		return nil, err
+	}
+
+	return keys, nil
+}
+
+func (s StaticKeySet) Key(ctx context.Context, kid string) (crypto.PublicKey, error) {
+	return lookup(s, kid)
+}
+
+// lookup - a token without kid is accepted only when the set holds a single key
+func lookup(keys map[string]crypto.PublicKey, kid string) (crypto.PublicKey, error) {
+	if key, ok := keys[kid]; ok {
+		return key, nil
+	}
+	if kid == "" && len(keys) == 1 {
+		for _, key := range keys {
+			return key, nil
+		}
+	}
+
+	return nil, ErrUnknownKey
+}
+
+// RemoteKeySet - keys fetched from the identity provider JWKS url. The set is refreshed after ttl,
+// and early when a token names an unknown kid so rotated keys are picked up. The provider is asked at most once
+// per minRefresh, also after a failed fetch, and concurrent callers share one fetch made outside the lock
+type RemoteKeySet struct {
+	url        string
+	client     *http.Client
+	ttl        time.Duration
+	minRefresh time.Duration
+	logger     *zap.Logger
+	fetches    singleflight.Group
+
+	mu   sync.RWMutex
+	keys map[string]crypto.PublicKey
+	// fetchedAt - when keys were fetched, triedAt and lastErr - the latest attempt, failed or not
+	fetchedAt time.Time
+	triedAt   time.Time
+	lastErr   error
+}
+
+func NewRemoteKeySet(url string, ttl time.Duration, logger *zap.Logger) *RemoteKeySet {
+	return &RemoteKeySet{
+		url:        url,
+		client:     &http.Client{Timeout: 5 * time.Second},
+		ttl:        ttl,
+		minRefresh: time.Minute,
+		logger:     logger,
+	}
+}
+
+func (s *RemoteKeySet) Key(ctx context.Context, kid string) (crypto.PublicKey, error) {
+	s.mu.RLock()
+	keys, fetchedAt, lastErr := s.keys, s.fetchedAt, s.lastErr
+	mayFetch := time.Since(s.triedAt) >= s.minRefresh
+	s.mu.RUnlock()
+
+	if keys == nil || time.Since(fetchedAt) >= s.ttl {
+		if mayFetch {
+			keys, lastErr = s.refresh(ctx)
+			mayFetch = false
+		}
+		if keys == nil {
+			return nil, lastErr
+		}
+	}
+
+	key, err := lookup(keys, kid)
+	if errors.Is(err, ErrUnknownKey) && mayFetch {
+		if keys, err = s.refresh(ctx); err != nil {
+			return nil, ErrUnknownKey
+		}
+		return lookup(keys, kid)
+	}
+
+	return key, err
+}
+
+// refresh - fetches the set once for every caller waiting on it and returns the keys held afterwards,
+// the previous ones when the provider cannot be reached. A caller leaving does not cancel the fetch of the others
+func (s *RemoteKeySet) refresh(ctx context.Context) (map[string]crypto.PublicKey, error) {
+	result, err, _ := s.fetches.Do("jwks", func() (interface{}, error) {
+		s.mu.RLock()
+		keys, lastErr := s.keys, s.lastErr
+		recent := !s.triedAt.IsZero() && time.Since(s.triedAt) < s.minRefresh
+		s.mu.RUnlock()
+		// another flight finished between the caller's look and this one
+		if recent {
+			return keys, lastErr
+		}
+
+		fetched, err := s.fetch(context.WithoutCancel(ctx))
+
+		s.mu.Lock()
+		defer s.mu.Unlock()
+		s.triedAt, s.lastErr = time.Now(), err
+		if err == nil {
+			s.keys, s.fetchedAt = fetched, s.triedAt
+		}
+
+		return s.keys, err
+	})
+	keys, _ := result.(map[string]crypto.PublicKey)
+
+	return keys, err
+}
+
+// fetch - reads and parses the set from url
+func (s *RemoteKeySet) fetch(ctx context.Context) (map[string]crypto.PublicKey, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, s.url, nil)
+	if err != nil {
+		return nil, err
+	}
+	resp, err := s.client.Do(req)
+	if err != nil {
+		s.logger.Warn("failed fetching jwks", zap.String("url", s.url), zap.Error(err))
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		err = fmt.Errorf("jwks: %s returned %d", s.url, resp.StatusCode)
+		s.logger.Warn("failed fetching jwks", zap.Error(err))
+		return nil, err
+	}
+
+	data, err := io.ReadAll(io.LimitReader(resp.Body, 1<<20))
+	if err != nil {
+		return nil, err
+	}
+	keys, err := ParseJWKS(data)
+	if err != nil {
+		s.logger.Warn("invalid jwks", zap.String("url", s.url), zap.Error(err))
+		return nil, err
+	}
+
+	return keys, nil
+}
@@ -0,0 +1,251 @@
+package jwt
+
+import (
+	"context"
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"math/big"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap/zaptest"
+
+	"skyhawk/backend/apperror"
+	"skyhawk/backend/auth/domain"
+)
+
+var now = time.Date(2024, 11, 2, 19, 30, 0, 0, time.UTC)
+
+func b64(data []byte) string {
+	return base64.RawURLEncoding.EncodeToString(data)
+}
+
+func rsaJWK(kid string, key *rsa.PrivateKey) map[string]string {
+	return map[string]string{"kty": "RSA", "kid": kid, "use": "sig", "n": b64(key.N.Bytes()), "e": b64(big.NewInt(int64(key.E)).Bytes())}
+}
+
+func ecJWK(kid string, key *ecdsa.PrivateKey) map[string]string {
+	return map[string]string{"kty": "EC", "kid": kid, "crv": "P-256", "x": b64(key.X.FillBytes(make([]byte, 32))), "y": b64(key.Y.FillBytes(make([]byte, 32)))}
+}
+
+func jwks(t *testing.T, keys ...map[string]string) []byte {
+	data, err := json.Marshal(map[string]interface{}{"keys": keys})
+	require.NoError(t, err)
+	return data
+}
+
+// sign - a compact token over header and claims, signed with an RSA or EC key
+func sign(t *testing.T, alg, kid string, key crypto.Signer, claims map[string]interface{}) string {
+	head, err := json.Marshal(map[string]string{"alg": alg, "kid": kid, "typ": "JWT"})
+	require.NoError(t, err)
+	body, err := json.Marshal(claims)
+	require.NoError(t, err)
+
+	signed := b64(head) + "." + b64(body)
+	digest := sha256.Sum256([]byte(signed))
+
+	var signature []byte
+	switch k := key.(type) {
+	case *rsa.PrivateKey:
+		signature, err = rsa.SignPKCS1v15(rand.Reader, k, crypto.SHA256, digest[:])
+		require.NoError(t, err)
+	case *ecdsa.PrivateKey:
+		r, s, err := ecdsa.Sign(rand.Reader, k, digest[:])
+		require.NoError(t, err)
+		signature = append(r.FillBytes(make([]byte, 32)), s.FillBytes(make([]byte, 32))...)
+	}
+
+	return signed + "." + b64(signature)
+}
+
+func validClaims() map[string]interface{} {
+	return map[string]interface{}{
+		"iss":   "https://id.skyhawk.test/",
+		"aud":   []string{"skyhawk-api", "other"},
+		"sub":   "user-1",
+		"email": "scorer@skyhawk.test",
+		"exp":   now.Add(time.Hour).Unix(),
+		"realm_access": map[string]interface{}{
+			"roles": []string{"scorekeeper", "offline_access"},
+		},
+	}
+}
+
+func TestVerifier_Verify(t *testing.T) {
+	rsaKey, err := rsa.GenerateKey(rand.Reader, 2048)
+	require.NoError(t, err)
+	ecKey, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	require.NoError(t, err)
+
+	path := filepath.Join(t.TempDir(), "jwks.json")
+	require.NoError(t, os.WriteFile(path, jwks(t, rsaJWK("rsa-1", rsaKey), ecJWK("ec-1", ecKey)), 0o600))
+	keys, err := LoadKeySetFile(path)
+	require.NoError(t, err)
+
+	options := DefaultOptions()
+	options.Issuer = "https://id.skyhawk.test/"
+	options.Audience = "skyhawk-api"
+	options.RolesClaim = "realm_access.roles"
+	verifier := NewVerifier(keys, options)
+	verifier.now = func() time.Time { return now }
+
+	t.Run("rsa token maps roles to scopes", func(t *testing.T) {
+		// Test
+		principal, err := verifier.Verify(context.Background(), sign(t, "RS256", "rsa-1", rsaKey, validClaims()))
+
+		// Assert
+		require.NoError(t, err)
+		assert.Equal(t, "user:user-1", principal.Subject)
+		assert.Equal(t, "scorer@skyhawk.test", principal.Name)
+		assert.Equal(t, []domain.Scope{domain.ScopeStatsRead, domain.ScopeGamesWrite}, principal.Scopes)
+	})
+
+	t.Run("ec token", func(t *testing.T) {
+		// Setup
+		claims := validClaims()
+		claims["realm_access"] = map[string]interface{}{"roles": "league_admin"}
+
+		// Test
+		principal, err := verifier.Verify(context.Background(), sign(t, "ES256", "ec-1", ecKey, claims))
+
+		// Assert
+		require.NoError(t, err)
+		assert.True(t, principal.Allows(domain.ScopeAdmin))
+	})
+
+	t.Run("rejected tokens", func(t *testing.T) {
+		expired := validClaims()
+		expired["exp"] = now.Add(-time.Minute).Unix()
+		otherIssuer := validClaims()
+		otherIssuer["iss"] = "https://evil.test/"
+		otherAudience := validClaims()
+		otherAudience["aud"] = "someone-else"
+		noExp := validClaims()
+		delete(noExp, "exp")
+
+		valid := sign(t, "RS256", "rsa-1", rsaKey, validClaims())
+		cases := map[string]string{
+			"expired":           sign(t, "RS256", "rsa-1", rsaKey, expired),
+			"issuer":            sign(t, "RS256", "rsa-1", rsaKey, otherIssuer),
+			"audience":          sign(t, "RS256", "rsa-1", rsaKey, otherAudience),
+			"no exp":            sign(t, "RS256", "rsa-1", rsaKey, noExp),
+			"unknown kid":       sign(t, "RS256", "rsa-2", rsaKey, validClaims()),
+			"key type mismatch": sign(t, "ES256", "rsa-1", ecKey, validClaims()),
+			"tampered":          valid[:len(valid)-4] + "AAAA",
+			"alg none":          b64([]byte(`{"alg":"none","kid":"rsa-1"}`)) + "." + b64([]byte(`{"sub":"admin"}`)) + ".",
+			"malformed":         "not-a-token",
+		}
+
+		for name, token := range cases {
+			// Test
+			_, err := verifier.Verify(context.Background(), token)
+
+			// Assert
+			assert.Equal(t, apperror.KindUnauthorized, apperror.KindOf(err), name)
+		}
+	})
+}
+
+func TestRemoteKeySet(t *testing.T) {
+	// Setup
+	first, err := rsa.GenerateKey(rand.Reader, 2048)
+	require.NoError(t, err)
+	rotated, err := rsa.GenerateKey(rand.Reader, 2048)
+	require.NoError(t, err)
+
+	var fetches atomic.Int32
+	var rotatedIn atomic.Bool
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		fetches.Add(1)
+		keys := []map[string]string{rsaJWK("k1", first)}
+		if rotatedIn.Load() {
+			keys = append(keys, rsaJWK("k2", rotated))
+		}
+		w.Write(jwks(t, keys...))
+	}))
+	defer server.Close()
+
+	keys := NewRemoteKeySet(server.URL, time.Hour, zaptest.NewLogger(t))
+	keys.minRefresh = 0
+
+	// Test
+	_, errFirst := keys.Key(context.Background(), "k1")
+	_, errCached := keys.Key(context.Background(), "k1")
+	rotatedIn.Store(true)
+	key, errRotated := keys.Key(context.Background(), "k2")
+
+	// Assert
+	require.NoError(t, errFirst)
+	require.NoError(t, errCached)
+	require.NoError(t, errRotated)
+	assert.Equal(t, rotated.N, key.(*rsa.PublicKey).N)
+	assert.Equal(t, int32(2), fetches.Load(), "unknown kid refreshes, known kids are served from the cache")
+}
+
+func TestRemoteKeySet_SharedFetch(t *testing.T) {
+	// Setup - a slow provider, every caller arrives while the first fetch is in flight
+	key, err := rsa.GenerateKey(rand.Reader, 2048)
+	require.NoError(t, err)
+
+	var fetches atomic.Int32
+	release := make(chan struct{})
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		fetches.Add(1)
+		<-release
+		w.Write(jwks(t, rsaJWK("k1", key)))
+	}))
+	defer server.Close()
+
+	keys := NewRemoteKeySet(server.URL, time.Hour, zaptest.NewLogger(t))
+
+	// Test
+	errs := make(chan error, 8)
+	for i := 0; i < cap(errs); i++ {
+		go func() {
+			_, err := keys.Key(context.Background(), "k1")
+			errs <- err
+		}()
+	}
+	require.Eventually(t, func() bool { return fetches.Load() == 1 }, time.Second, time.Millisecond)
+	close(release)
+
+	// Assert
+	for i := 0; i < cap(errs); i++ {
+		assert.NoError(t, <-errs)
+	}
+	assert.Equal(t, int32(1), fetches.Load(), "callers share the fetch in flight")
+}
+
+func TestRemoteKeySet_Backoff(t *testing.T) {
+	// Setup - the provider is down and no key was ever fetched
+	var fetches atomic.Int32
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		fetches.Add(1)
+		w.WriteHeader(http.StatusServiceUnavailable)
+	}))
+	defer server.Close()
+
+	keys := NewRemoteKeySet(server.URL, time.Hour, zaptest.NewLogger(t))
+
+	// Test
+	_, errFirst := keys.Key(context.Background(), "k1")
+	_, errSecond := keys.Key(context.Background(), "k1")
+
+	// Assert
+	assert.ErrorContains(t, errFirst, "returned 503")
+	assert.ErrorContains(t, errSecond, "returned 503", "the failure is reported without asking again")
+	assert.Equal(t, int32(1), fetches.Load(), "no fetch until minRefresh passed")
+}
@@ -0,0 +1,250 @@
+package jwt
+
+import (
+	"context"
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/rsa"
+	_ "crypto/sha256"
+	_ "crypto/sha512"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"math/big"
+	"strings"
+	"time"
+
+	"skyhawk/backend/apperror"
+	"skyhawk/backend/auth/domain"
+)
+
+// Options - what a token must carry to be accepted and how its claims become roles
+type Options struct {
+	Issuer   string
+	Audience string
+	// RolesClaim - the claim holding the user roles, nested claims are dotted such as realm_access.roles
+	RolesClaim string
+	// Roles - the role each claim value grants, values that are not listed grant nothing
+	Roles map[string]domain.Role
+	// Leeway - clock skew tolerated on exp and nbf
+	Leeway time.Duration
+}
+
+func DefaultOptions() Options {
+	return Options{
+		RolesClaim: "roles",
+		Roles: map[string]domain.Role{
+			"viewer":       domain.RoleViewer,
+			"scorekeeper":  domain.RoleScorekeeper,
+			"league_admin": domain.RoleLeagueAdmin,
+		},
+		Leeway: 30 * time.Second,
+	}
+}
+
+// Verifier - validates bearer tokens issued by the identity provider
+type Verifier struct {
+	keys    KeySet
+	options Options
+	now     func() time.Time
+}
+
+func NewVerifier(keys KeySet, options Options) *Verifier {
+	return &Verifier{keys: keys, options: options, now: time.Now}
+}
+
+func invalidToken(reason string) error {
+	return apperror.Unauthorized("invalid_token", "bearer token is invalid: "+reason)
+}
+
+type header struct {
+	Alg string `json:"alg"`
+	Kid string `json:"kid"`
+}
+
+// Verify - the principal of a signed, unexpired token issued for us. Only asymmetric algorithms are accepted
+func (v *Verifier) Verify(ctx context.Context, token string) (domain.Principal, error) {
+	parts := strings.Split(token, ".")
+	if len(parts) != 3 {
+		return domain.Principal{}, invalidToken("malformed token")
+	}
+
+	var head header
+	if err := decodeSegment(parts[0], &head); err != nil {
+		return domain.Principal{}, invalidToken("malformed header")
+	}
+
+	key, err := v.keys.Key(ctx, head.Kid)
+	if errors.Is(err, ErrUnknownKey) {
+		return domain.Principal{}, invalidToken("unknown signing key")
+	}
+	if err != nil {
+		return domain.Principal{}, apperror.Unavailable("jwks_unavailable", "signing keys could not be loaded", err)
+	}
+
+	signature, err := base64.RawURLEncoding.DecodeString(parts[2])
+	if err != nil {
+		return domain.Principal{}, invalidToken("malformed signature")
+	}
+	if err = verifySignature(head.Alg, key, []byte(parts[0]+"."+parts[1]), signature); err != nil {
+		return domain.Principal{}, invalidToken(err.Error())
+	}
+
+	var claims map[string]interface{}
+	if err = decodeSegment(parts[1], &claims); err != nil {
+		return domain.Principal{}, invalidToken("malformed claims")
+	}
+	if err = v.validate(claims); err != nil {
+		return domain.Principal{}, err
+	}
+
+	return v.principal(claims), nil
+}
+
+func (v *Verifier) validate(claims map[string]interface{}) error {
+	now := v.now()
+
+	exp, ok := claims["exp"].(float64)
+	if !ok {
+		return invalidToken("exp is required")
+	}
+	if now.After(time.Unix(int64(exp), 0).Add(v.options.Leeway)) {
+		return invalidToken("token is expired")
+	}
+	if nbf, ok := claims["nbf"].(float64); ok && now.Add(v.options.Leeway).Before(time.Unix(int64(nbf), 0)) {
+		return invalidToken("token is not valid yet")
+	}
+	if v.options.Issuer != "" && claims["iss"] != v.options.Issuer {
+		return invalidToken("unexpected issuer")
+	}
+	if v.options.Audience != "" && !hasAudience(claims["aud"], v.options.Audience) {
+		return invalidToken("unexpected audience")
+	}
+	if sub, _ := claims["sub"].(string); sub == "" {
+		return invalidToken("sub is required")
+	}
+
+	return nil
+}
+
+func (v *Verifier) principal(claims map[string]interface{}) domain.Principal {
+	sub := claims["sub"].(string)
+
+	name := sub
+	for _, claim := range []string{"preferred_username", "email", "name"} {
+		if value, ok := claims[claim].(string); ok && value != "" {
+			name = value
+			break
+		}
+	}
+
+	var roles []domain.Role
+	for _, value := range stringsOf(claimAt(claims, v.options.RolesClaim)) {
+		if role, ok := v.options.Roles[value]; ok {
+			roles = append(roles, role)
+		}
+	}
+
+	return domain.Principal{Subject: "user:" + sub, Name: name, Scopes: domain.ScopesOf(roles...)}
+}
+
+// claimAt - the claim at a dotted path
+func claimAt(claims map[string]interface{}, path string) interface{} {
+	var value interface{} = claims
+	for _, name := range strings.Split(path, ".") {
+		object, ok := value.(map[string]interface{})
+		if !ok {
+			return nil
+		}
+		value = object[name]
+	}
+
+	return value
+}
+
+// stringsOf - a claim holding one string, a space separated list or an array of strings
+func stringsOf(value interface{}) []string {
+	switch v := value.(type) {
+	case string:
+		return strings.Fields(v)
+	case []interface{}:
+		values := make([]string, 0, len(v))
+		for _, item := range v {
+			if s, ok := item.(string); ok {
+				values = append(values, s)
+			}
+		}
+		return values
+	}
+
+	return nil
+}
+
+func hasAudience(aud interface{}, audience string) bool {
+	for _, value := range stringsOf(aud) {
+		if value == audience {
+			return true
+		}
+	}
+
+	return false
+}
+
+func decodeSegment(segment string, v interface{}) error {
+	raw, err := base64.RawURLEncoding.DecodeString(segment)
+	if err != nil {
+		return err
+	}
+
+	return json.Unmarshal(raw, v)
+}
+
+var hashes = map[string]crypto.Hash{
+	"RS256": crypto.SHA256, "RS384": crypto.SHA384, "RS512": crypto.SHA512,
+	"PS256": crypto.SHA256, "PS384": crypto.SHA384, "PS512": crypto.SHA512,
+	"ES256": crypto.SHA256, "ES384": crypto.SHA384, "ES512": crypto.SHA512,
+}
+
+// verifySignature - alg must match the key type, which rules out none and the HMAC algorithms
+func verifySignature(alg string, key crypto.PublicKey, signed, signature []byte) error {
+	hash, ok := hashes[alg]
+	if !ok {
+		return errors.New("unsupported algorithm " + alg)
+	}
+	h := hash.New()
+	h.Write(signed)
+	digest := h.Sum(nil)
+
+	switch alg[:2] {
+	case "RS", "PS":
+		rsaKey, ok := key.(*rsa.PublicKey)
+		if !ok {
+			return errors.New("algorithm does not match the signing key")
+		}
+		if alg[0] == 'P' {
+			if rsa.VerifyPSS(rsaKey, hash, digest, signature, nil) != nil {
+				return errors.New("signature does not match")
+			}
+			return nil
+		}
+		if rsa.VerifyPKCS1v15(rsaKey, hash, digest, signature) != nil {
+			return errors.New("signature does not match")
+		}
+	case "ES":
+		ecKey, ok := key.(*ecdsa.PublicKey)
+		if !ok {
+			return errors.New("algorithm does not match the signing key")
+		}
+		size := (ecKey.Curve.Params().BitSize + 7) / 8
+		if len(signature) != 2*size {
+			return errors.New("signature does not match")
+		}
+		r := new(big.Int).SetBytes(signature[:size])
+		s := new(big.Int).SetBytes(signature[size:])
+		if !ecdsa.Verify(ecKey, digest, r, s) {
+			return errors.New("signature does not match")
+		}
+	}
+
+	return nil
+}
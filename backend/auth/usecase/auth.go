@@ -0,0 +1,121 @@
+package usecase
+
+import (
+	"context"
+	"crypto/subtle"
+	"time"
+
+	"github.com/google/uuid"
+	"go.uber.org/zap"
+
+	"skyhawk/backend/apperror"
+	"skyhawk/backend/auth/domain"
+	"skyhawk/backend/logging"
+)
+
+// touchInterval - last use is written at most this often per key, so busy keys do not write on every request
+const touchInterval = time.Minute
+
+type Repository interface {
+	Create(ctx context.Context, key domain.APIKey) error
+	List(ctx context.Context) ([]domain.APIKey, error)
+	FindByPrefix(ctx context.Context, prefix string) (domain.APIKey, error)
+	Revoke(ctx context.Context, id string, at time.Time) error
+	Touch(ctx context.Context, id string, at time.Time) error
+}
+
+// TokenVerifier - validates bearer tokens from the identity provider
+type TokenVerifier interface {
+	Verify(ctx context.Context, token string) (domain.Principal, error)
+}
+
+type UseCase struct {
+	repo   Repository
+	tokens TokenVerifier
+	logger *zap.Logger
+}
+
+// NewUseCase - tokens may be nil, then only api keys are accepted
+func NewUseCase(repo Repository, tokens TokenVerifier, logger *zap.Logger) *UseCase {
+
+	return &UseCase{repo: repo, tokens: tokens, logger: logger}
+}
+
+func invalidKey() error {
+	return apperror.Unauthorized("invalid_api_key", "api key is invalid or revoked")
+}
+
+// Create - issues a key, the plaintext is returned only here
+func (s *UseCase) Create(ctx context.Context, req domain.CreateKeyReq) (domain.CreatedKey, error) {
+	if err := req.Validate(); err != nil {
+		return domain.CreatedKey{}, err
+	}
+
+	prefix, plaintext, err := domain.GenerateKey()
+	if err != nil {
+		return domain.CreatedKey{}, apperror.Internal(err)
+	}
+
+	key := domain.APIKey{
+		ID:        uuid.New().String(),
+		Name:      req.Name,
+		Prefix:    prefix,
+		Hash:      domain.HashKey(plaintext),
+		Scopes:    req.Scopes,
+		CreatedAt: time.Now().UTC().Truncate(time.Second),
+	}
+	if err = s.repo.Create(ctx, key); err != nil {
+		logging.From(ctx, s.logger).Error("UseCase.Create failed saving api key", zap.Error(err))
+		return domain.CreatedKey{}, err
+	}
+
+	return domain.CreatedKey{APIKey: key, Key: plaintext}, nil
+}
+
+func (s *UseCase) List(ctx context.Context) ([]domain.APIKey, error) {
+
+	return s.repo.List(ctx)
+}
+
+// Revoke - the key stops authenticating immediately, its row is kept for the audit trail
+func (s *UseCase) Revoke(ctx context.Context, id string) error {
+
+	return s.repo.Revoke(ctx, id, time.Now().UTC())
+}
+
+// Authenticate - the principal of an api key or of a bearer token from the identity provider
+func (s *UseCase) Authenticate(ctx context.Context, credential string) (domain.Principal, error) {
+	prefix, ok := domain.ParseKey(credential)
+	if ok {
+		return s.authenticateKey(ctx, prefix, credential)
+	}
+	if s.tokens != nil {
+		return s.tokens.Verify(ctx, credential)
+	}
+
+	return domain.Principal{}, invalidKey()
+}
+
+// authenticateKey - unknown and revoked keys are the same 401
+func (s *UseCase) authenticateKey(ctx context.Context, prefix, plaintext string) (domain.Principal, error) {
+	key, err := s.repo.FindByPrefix(ctx, prefix)
+	if apperror.IsNotFound(err) {
+		return domain.Principal{}, invalidKey()
+	}
+	if err != nil {
+		return domain.Principal{}, err
+	}
+
+	if subtle.ConstantTimeCompare([]byte(key.Hash), []byte(domain.HashKey(plaintext))) != 1 || key.RevokedAt != nil {
+		return domain.Principal{}, invalidKey()
+	}
+
+	now := time.Now().UTC()
+	if key.LastUsedAt == nil || now.Sub(*key.LastUsedAt) >= touchInterval {
+		if err = s.repo.Touch(ctx, key.ID, now); err != nil {
+			logging.From(ctx, s.logger).Warn("failed recording api key use", zap.String("key", key.ID), zap.Error(err))
+		}
+	}
+
+	return key.Principal(), nil
+}
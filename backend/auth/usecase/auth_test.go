@@ -0,0 +1,135 @@
+package usecase
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap/zaptest"
+
+	"skyhawk/backend/apperror"
+	"skyhawk/backend/auth/domain"
+)
+
+type fakeRepo struct {
+	keys    map[string]domain.APIKey
+	touched []string
+}
+
+func newFakeRepo() *fakeRepo {
+	return &fakeRepo{keys: map[string]domain.APIKey{}}
+}
+
+func (f *fakeRepo) Create(ctx context.Context, key domain.APIKey) error {
+	f.keys[key.Prefix] = key
+	return nil
+}
+
+func (f *fakeRepo) List(ctx context.Context) ([]domain.APIKey, error) {
+	keys := make([]domain.APIKey, 0, len(f.keys))
+	for _, key := range f.keys {
+		keys = append(keys, key)
+	}
+	return keys, nil
+}
+
+func (f *fakeRepo) FindByPrefix(ctx context.Context, prefix string) (domain.APIKey, error) {
+	key, ok := f.keys[prefix]
+	if !ok {
+		return domain.APIKey{}, apperror.NotFound("api_key_not_found", "api key not found", nil)
+	}
+	return key, nil
+}
+
+func (f *fakeRepo) Revoke(ctx context.Context, id string, at time.Time) error {
+	for prefix, key := range f.keys {
+		if key.ID == id {
+			key.RevokedAt = &at
+			f.keys[prefix] = key
+			return nil
+		}
+	}
+	return apperror.NotFound("api_key_not_found", "api key not found", nil)
+}
+
+func (f *fakeRepo) Touch(ctx context.Context, id string, at time.Time) error {
+	f.touched = append(f.touched, id)
+	for prefix, key := range f.keys {
+		if key.ID == id {
+			key.LastUsedAt = &at
+			f.keys[prefix] = key
+		}
+	}
+	return nil
+}
+
+func TestUseCase_Authenticate(t *testing.T) {
+	setup := func(t *testing.T) (*UseCase, *fakeRepo, domain.CreatedKey) {
+		repo := newFakeRepo()
+		useCase := NewUseCase(repo, nil, zaptest.NewLogger(t))
+		created, err := useCase.Create(context.Background(), domain.CreateKeyReq{Name: "scorer", Scopes: []domain.Scope{domain.ScopeGamesWrite}})
+		require.NoError(t, err)
+
+		return useCase, repo, created
+	}
+
+	t.Run("valid key", func(t *testing.T) {
+		// Setup
+		useCase, repo, created := setup(t)
+
+		// Test
+		principal, err := useCase.Authenticate(context.Background(), created.Key)
+		_, errAgain := useCase.Authenticate(context.Background(), created.Key)
+
+		// Assert
+		require.NoError(t, err)
+		require.NoError(t, errAgain)
+		assert.Equal(t, "apikey:"+created.Prefix, principal.Subject)
+		assert.Equal(t, "scorer", principal.Name)
+		assert.True(t, principal.Allows(domain.ScopeGamesWrite))
+		assert.Equal(t, []string{created.ID}, repo.touched, "last use is written once per interval")
+		assert.NotEqual(t, created.Key, repo.keys[created.Prefix].Hash)
+	})
+
+	t.Run("wrong secret, unknown and revoked keys", func(t *testing.T) {
+		// Setup
+		useCase, _, created := setup(t)
+		require.NoError(t, useCase.Revoke(context.Background(), created.ID))
+
+		for _, key := range []string{created.Key + "0", "sk_000000000000_secret", "garbage", created.Key} {
+			// Test
+			_, err := useCase.Authenticate(context.Background(), key)
+
+			// Assert
+			assert.Equal(t, apperror.KindUnauthorized, apperror.KindOf(err), key)
+		}
+	})
+}
+
+type fakeVerifier struct {
+	tokens []string
+}
+
+func (f *fakeVerifier) Verify(ctx context.Context, token string) (domain.Principal, error) {
+	f.tokens = append(f.tokens, token)
+	return domain.Principal{Subject: "user:u1", Scopes: domain.ScopesOf(domain.RoleViewer)}, nil
+}
+
+func TestUseCase_Authenticate_Token(t *testing.T) {
+	// Setup
+	repo := newFakeRepo()
+	verifier := &fakeVerifier{}
+	useCase := NewUseCase(repo, verifier, zaptest.NewLogger(t))
+
+	// Test
+	principal, err := useCase.Authenticate(context.Background(), "eyJhbGciOiJSUzI1NiJ9.e30.c2ln")
+	_, errKey := useCase.Authenticate(context.Background(), "sk_000000000000_secret")
+
+	// Assert
+	require.NoError(t, err)
+	assert.Equal(t, "user:u1", principal.Subject)
+	assert.Equal(t, apperror.KindUnauthorized, apperror.KindOf(errKey))
+	assert.Len(t, verifier.tokens, 1, "api keys never reach the token verifier")
+}
@@ -0,0 +1,272 @@
+package config
+
+import (
+	"net"
+	"strings"
+	"time"
+)
+
+// Config - every setting of the service. Values come from the defaults below, then an optional YAML or JSON file,
+// then the environment, then command line flags, each overriding the one before.
+// Fields are named by their file path (db.host), which is also their flag name, and by the env variables listed in env
+type Config struct {
+	Server     Server     `yaml:"server"`
+	Timeouts   Timeouts   `yaml:"timeouts"`
+	DB         DB         `yaml:"db"`
+	Redis      Redis      `yaml:"redis"`
+	Migrations Migrations `yaml:"migrations"`
+	Retry      Retry      `yaml:"retry"`
+	Batch      Batch      `yaml:"batch"`
+	Stream     Stream     `yaml:"stream"`
+	Webhook    Webhook    `yaml:"webhook"`
+	Outbox     Outbox     `yaml:"outbox"`
+	JWT        JWT        `yaml:"jwt"`
+	RateLimit  RateLimit  `yaml:"rate_limit"`
+	Health     Health     `yaml:"health"`
+	Tracing    Tracing    `yaml:"tracing"`
+	Log        Log        `yaml:"log"`
+}
+
+type Server struct {
+	Addr              string        `yaml:"addr" env:"SERVER_ADDR" usage:"address the api listens on"`
+	ReadHeaderTimeout time.Duration `yaml:"read_header_timeout" env:"SERVER_READ_HEADER_TIMEOUT" usage:"time allowed to read request headers"`
+	// ReadTimeout and WriteTimeout stay off by default: imports upload large bodies and live feeds and exports stream for minutes
+	ReadTimeout  time.Duration `yaml:"read_timeout" env:"SERVER_READ_TIMEOUT" usage:"time allowed to read a whole request, 0 is none"`
+	WriteTimeout time.Duration `yaml:"write_timeout" env:"SERVER_WRITE_TIMEOUT" usage:"time allowed to write a response, 0 is none"`
+	IdleTimeout  time.Duration `yaml:"idle_timeout" env:"SERVER_IDLE_TIMEOUT" usage:"how long idle keep-alive connections stay open"`
+	// ShutdownTimeout - how long in-flight requests may finish after SIGTERM, keep it below the orchestrator grace period
+	ShutdownTimeout time.Duration `yaml:"shutdown_timeout" env:"SERVER_SHUTDOWN_TIMEOUT" usage:"time in-flight requests get to finish on shutdown"`
+	// body limits are sizes such as 512K or 16M
+	BodyLimit       string `yaml:"body_limit" env:"SERVER_BODY_LIMIT" usage:"largest body of a game write or admin request"`
+	BatchBodyLimit  string `yaml:"batch_body_limit" env:"SERVER_BATCH_BODY_LIMIT" usage:"largest body of a batch"`
+	ImportBodyLimit string `yaml:"import_body_limit" env:"SERVER_IMPORT_BODY_LIMIT" usage:"largest import upload"`
+	// TrustedProxies - comma separated CIDRs, X-Forwarded-For is only believed from them, without any the peer address is the client
+	TrustedProxies string `yaml:"trusted_proxies" env:"SERVER_TRUSTED_PROXIES" usage:"comma separated CIDRs of the proxies whose X-Forwarded-For is trusted"`
+}
+
+// Proxies - the trusted proxies as networks
+func (s Server) Proxies() ([]*net.IPNet, error) {
+	var proxies []*net.IPNet
+	for _, cidr := range strings.Split(s.TrustedProxies, ",") {
+		if cidr = strings.TrimSpace(cidr); cidr == "" {
+			continue
+		}
+		_, network, err := net.ParseCIDR(cidr)
+		if err != nil {
+			return nil, err
+		}
+		proxies = append(proxies, network)
+	}
+
+	return proxies, nil
+}
+
+// Timeouts - request deadlines per kind of endpoint
+type Timeouts struct {
+	LogGame time.Duration `yaml:"log_game" env:"LOG_GAME_TIMEOUT" usage:"deadline of game writes"`
+	Batch   time.Duration `yaml:"batch" env:"BATCH_TIMEOUT" usage:"deadline of batch logging"`
+	Import  time.Duration `yaml:"import" env:"IMPORT_TIMEOUT" usage:"deadline of imports"`
+	Export  time.Duration `yaml:"export" env:"EXPORT_TIMEOUT" usage:"deadline of exports"`
+	Stats   time.Duration `yaml:"stats" env:"STATS_TIMEOUT" usage:"deadline of reads"`
+}
+
+type DB struct {
+	Host     string `yaml:"host" env:"MYSQL_HOST" required:"true" usage:"mysql host:port"`
+	User     string `yaml:"user" env:"MYSQL_USER" required:"true" usage:"mysql user"`
+	Password string `yaml:"password" env:"MYSQL_PASSWORD,MYSQL_ROOT_PASSWORD" secret:"true" usage:"mysql password"`
+	Name     string `yaml:"name" env:"MYSQL_DATABASE" required:"true" usage:"mysql database"`
+	// TLS - false, true, skip-verify or preferred. With a CA file the server certificate is checked against it
+	TLS             string        `yaml:"tls" env:"MYSQL_TLS" usage:"false, true, skip-verify or preferred"`
+	TLSCAFile       string        `yaml:"tls_ca_file" env:"MYSQL_TLS_CA_FILE" usage:"PEM file of the CA that signed the mysql server certificate"`
+	MaxOpenConns    int           `yaml:"max_open_conns" env:"MYSQL_MAX_OPEN_CONNS" usage:"connection pool size"`
+	MaxIdleConns    int           `yaml:"max_idle_conns" env:"MYSQL_MAX_IDLE_CONNS" usage:"idle connections kept in the pool"`
+	ConnMaxLifetime time.Duration `yaml:"conn_max_lifetime" env:"MYSQL_CONN_MAX_LIFETIME" usage:"connections are replaced after this long"`
+	ConnMaxIdleTime time.Duration `yaml:"conn_max_idle_time" env:"MYSQL_CONN_MAX_IDLE_TIME" usage:"idle connections are closed after this long, 0 is never"`
+	ConnectTimeout  time.Duration `yaml:"connect_timeout" env:"MYSQL_CONNECT_TIMEOUT" usage:"dial timeout"`
+}
+
+type Redis struct {
+	Addr     string `yaml:"addr" env:"REDIS_HOST" required:"true" usage:"redis host:port"`
+	Username string `yaml:"username" env:"REDIS_USERNAME" usage:"redis ACL user"`
+	Password string `yaml:"password" env:"REDIS_PASSWORD" secret:"true" usage:"redis password"`
+	DB       int    `yaml:"db" env:"REDIS_DB" usage:"redis database number"`
+	TLS      bool   `yaml:"tls" env:"REDIS_TLS" usage:"connect to redis over tls"`
+	PoolSize int    `yaml:"pool_size" env:"REDIS_POOL_SIZE" usage:"redis connection pool size, 0 is 10 per cpu"`
+}
+
+type Migrations struct {
+	// OnStart - up applies pending migrations when the server starts, check refuses to start while the schema is behind
+	OnStart     string        `yaml:"on_start" env:"MIGRATIONS_ON_START" usage:"up or check"`
+	LockTimeout time.Duration `yaml:"lock_timeout" env:"MIGRATIONS_LOCK_TIMEOUT" usage:"how long to wait for another instance that is migrating"`
+	// Dir - where migrate create writes new migrations, the binary runs the ones embedded at build time
+	Dir string `yaml:"dir" env:"MIGRATIONS_DIR" usage:"source directory migrate create adds migrations to"`
+}
+
+type Retry struct {
+	MaxAttempts int           `yaml:"max_attempts" env:"RETRY_MAX_ATTEMPTS" usage:"attempts of a transaction that hit a deadlock or lock timeout"`
+	BaseDelay   time.Duration `yaml:"base_delay" env:"RETRY_BASE_DELAY" usage:"first transaction retry backoff"`
+	MaxDelay    time.Duration `yaml:"max_delay" env:"RETRY_MAX_DELAY" usage:"longest transaction retry backoff"`
+}
+
+type Batch struct {
+	MaxGames    int `yaml:"max_games" env:"BATCH_MAX_GAMES" usage:"games accepted in one batch"`
+	Parallelism int `yaml:"parallelism" env:"BATCH_PARALLELISM" usage:"games of a best effort batch written at once"`
+}
+
+type Stream struct {
+	Buffer    int           `yaml:"buffer" env:"STREAM_BUFFER" usage:"updates buffered per live feed subscriber"`
+	Backplane string        `yaml:"backplane" env:"STREAM_BACKPLANE" usage:"local or redis"`
+	Heartbeat time.Duration `yaml:"heartbeat" env:"STREAM_HEARTBEAT" usage:"live feed keep-alive interval"`
+	// AllowedOrigins - comma separated, pages served from the api's own origin are always allowed
+	AllowedOrigins string `yaml:"allowed_origins" env:"STREAM_ALLOWED_ORIGINS" usage:"comma separated origins whose pages may open websocket feeds, * allows any"`
+}
+
+// Origins - the allowed origins as a list
+func (s Stream) Origins() []string {
+	var origins []string
+	for _, origin := range strings.Split(s.AllowedOrigins, ",") {
+		if origin = strings.TrimSpace(origin); origin != "" {
+			origins = append(origins, origin)
+		}
+	}
+
+	return origins
+}
+
+type Webhook struct {
+	MaxAttempts  int           `yaml:"max_attempts" env:"WEBHOOK_MAX_ATTEMPTS" usage:"deliveries tried before dead lettering"`
+	BaseDelay    time.Duration `yaml:"base_delay" env:"WEBHOOK_BASE_DELAY" usage:"first redelivery backoff"`
+	MaxDelay     time.Duration `yaml:"max_delay" env:"WEBHOOK_MAX_DELAY" usage:"longest redelivery backoff"`
+	PollInterval time.Duration `yaml:"poll_interval" env:"WEBHOOK_POLL_INTERVAL" usage:"how often the dispatcher looks for deliveries"`
+	Timeout      time.Duration `yaml:"timeout" env:"WEBHOOK_TIMEOUT" usage:"deadline of a delivery"`
+}
+
+type Outbox struct {
+	Publisher    string        `yaml:"publisher" env:"OUTBOX_PUBLISHER" usage:"redis or file"`
+	File         string        `yaml:"file" env:"OUTBOX_FILE" usage:"ndjson file of the file publisher"`
+	StreamMaxLen int64         `yaml:"stream_max_len" env:"OUTBOX_STREAM_MAXLEN" usage:"approximate length the redis stream is capped at"`
+	PollInterval time.Duration `yaml:"poll_interval" env:"OUTBOX_POLL_INTERVAL" usage:"how often the relay looks for events"`
+	Retention    time.Duration `yaml:"retention" env:"OUTBOX_RETENTION" usage:"how long published events stay in the table"`
+}
+
+type JWT struct {
+	JWKSFile string        `yaml:"jwks_file" env:"JWT_JWKS_FILE" usage:"JWKS file bearer tokens are verified against"`
+	JWKSURL  string        `yaml:"jwks_url" env:"JWT_JWKS_URL" usage:"JWKS url bearer tokens are verified against"`
+	JWKSTTL  time.Duration `yaml:"jwks_ttl" env:"JWT_JWKS_TTL" usage:"how long keys fetched from the JWKS url are cached"`
+	Issuer   string        `yaml:"issuer" env:"JWT_ISSUER" usage:"required iss of bearer tokens"`
+	Audience string        `yaml:"audience" env:"JWT_AUDIENCE" usage:"required aud of bearer tokens"`
+	// RolesClaim - dotted path of the roles claim, and the claim values of each role
+	RolesClaim      string `yaml:"roles_claim" env:"JWT_ROLES_CLAIM" usage:"claim holding the user roles, nested claims are dotted"`
+	RoleViewer      string `yaml:"role_viewer" env:"JWT_ROLE_VIEWER" usage:"claim value of the viewer role"`
+	RoleScorekeeper string `yaml:"role_scorekeeper" env:"JWT_ROLE_SCOREKEEPER" usage:"claim value of the scorekeeper role"`
+	RoleLeagueAdmin string `yaml:"role_league_admin" env:"JWT_ROLE_LEAGUE_ADMIN" usage:"claim value of the league admin role"`
+}
+
+// Enabled - bearer tokens are accepted only with a key set
+func (j JWT) Enabled() bool {
+	return j.JWKSFile != "" || j.JWKSURL != ""
+}
+
+type RateLimit struct {
+	ReadRPS    float64 `yaml:"read_rps" env:"RATE_LIMIT_READ_RPS" usage:"reads per second per client, 0 disables"`
+	ReadBurst  int     `yaml:"read_burst" env:"RATE_LIMIT_READ_BURST" usage:"reads a client may burst"`
+	WriteRPS   float64 `yaml:"write_rps" env:"RATE_LIMIT_WRITE_RPS" usage:"writes per second per client, 0 disables"`
+	WriteBurst int     `yaml:"write_burst" env:"RATE_LIMIT_WRITE_BURST" usage:"writes a client may burst"`
+	IPRPS      float64 `yaml:"ip_rps" env:"RATE_LIMIT_IP_RPS" usage:"requests per second per ip, counted before authentication, 0 disables"`
+	IPBurst    int     `yaml:"ip_burst" env:"RATE_LIMIT_IP_BURST" usage:"requests an ip may burst"`
+	DailyQuota int64   `yaml:"daily_quota" env:"DAILY_QUOTA" usage:"requests per client per UTC day, 0 is unlimited"`
+	Store      string  `yaml:"store" env:"RATE_LIMIT_STORE" usage:"memory or redis"`
+}
+
+type Health struct {
+	Timeout time.Duration `yaml:"timeout" env:"HEALTH_TIMEOUT" usage:"deadline of each readiness check"`
+	// RedisCritical - redis down fails readiness instead of degrading it. Off by default, reads fall back to mysql without it
+	RedisCritical bool `yaml:"redis_critical" env:"HEALTH_REDIS_CRITICAL" usage:"fail readiness when redis is down instead of degrading"`
+}
+
+type Log struct {
+	// Level - the level at startup, PUT /api/v1/log/level changes it while running
+	Level  string `yaml:"level" env:"LOG_LEVEL" usage:"debug, info, warn or error"`
+	Format string `yaml:"format" env:"LOG_FORMAT" usage:"json or console"`
+	// Sampling - per second and message, the first SamplingInitial entries are logged then every SamplingThereafter-th
+	SamplingInitial    int `yaml:"sampling_initial" env:"LOG_SAMPLING_INITIAL" usage:"entries of a message logged per second before sampling, 0 disables sampling"`
+	SamplingThereafter int `yaml:"sampling_thereafter" env:"LOG_SAMPLING_THEREAFTER" usage:"then every nth entry of the message is logged"`
+}
+
+type Tracing struct {
+	// Exporter - none, stdout (works without a collector) or otlp
+	Exporter string `yaml:"exporter" env:"TRACING_EXPORTER" usage:"none, stdout or otlp"`
+	// Endpoint - the OTLP/HTTP collector url, OTEL_EXPORTER_OTLP_ENDPOINT is used when empty
+	Endpoint    string  `yaml:"endpoint" env:"TRACING_ENDPOINT" usage:"otlp collector url such as http://collector:4318"`
+	ServiceName string  `yaml:"service_name" env:"OTEL_SERVICE_NAME" usage:"service name on the spans"`
+	SampleRatio float64 `yaml:"sample_ratio" env:"TRACING_SAMPLE_RATIO" usage:"share of new traces sampled, 0 to 1"`
+}
+
+// Default - the settings used when nothing overrides them
+func Default() Config {
+	return Config{
+		Server: Server{
+			Addr:              ":8080",
+			ReadHeaderTimeout: 10 * time.Second,
+			IdleTimeout:       2 * time.Minute,
+			ShutdownTimeout:   25 * time.Second,
+			BodyLimit:         "1M",
+			BatchBodyLimit:    "16M",
+			ImportBodyLimit:   "256M",
+		},
+		Timeouts: Timeouts{
+			LogGame: 10 * time.Second,
+			Batch:   60 * time.Second,
+			Import:  10 * time.Minute,
+			Export:  5 * time.Minute,
+			Stats:   3 * time.Second,
+		},
+		DB: DB{
+			User:            "root",
+			Name:            "games_db",
+			TLS:             "false",
+			MaxOpenConns:    1000,
+			MaxIdleConns:    500,
+			ConnMaxLifetime: time.Hour,
+			ConnectTimeout:  10 * time.Second,
+		},
+		Migrations: Migrations{OnStart: "up", LockTimeout: time.Minute, Dir: "goose/migrations"},
+		Retry:      Retry{MaxAttempts: 5, BaseDelay: 50 * time.Millisecond, MaxDelay: 2 * time.Second},
+		Batch:      Batch{MaxGames: 50, Parallelism: 4},
+		Stream:     Stream{Buffer: 16, Backplane: "local", Heartbeat: 15 * time.Second},
+		Webhook: Webhook{
+			MaxAttempts:  8,
+			BaseDelay:    30 * time.Second,
+			MaxDelay:     time.Hour,
+			PollInterval: 2 * time.Second,
+			Timeout:      10 * time.Second,
+		},
+		Outbox: Outbox{
+			Publisher:    "redis",
+			File:         "outbox.ndjson",
+			StreamMaxLen: 100000,
+			PollInterval: time.Second,
+			Retention:    24 * time.Hour,
+		},
+		JWT: JWT{
+			JWKSTTL:         time.Hour,
+			RolesClaim:      "roles",
+			RoleViewer:      "viewer",
+			RoleScorekeeper: "scorekeeper",
+			RoleLeagueAdmin: "league_admin",
+		},
+		RateLimit: RateLimit{
+			ReadRPS:    20,
+			ReadBurst:  40,
+			WriteRPS:   5,
+			WriteBurst: 10,
+			IPRPS:      50,
+			IPBurst:    100,
+			DailyQuota: 100000,
+			Store:      "memory",
+		},
+		Health:  Health{Timeout: 2 * time.Second},
+		Tracing: Tracing{Exporter: "none", ServiceName: "skyhawk", SampleRatio: 1},
+		Log:     Log{Level: "info", Format: "json", SamplingInitial: 100, SamplingThereafter: 100},
+	}
+}
@@ -0,0 +1,149 @@
+package config
+
+import (
+	"io"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func env(values map[string]string) func(string) (string, bool) {
+	return func(key string) (string, bool) {
+		value, ok := values[key]
+		return value, ok
+	}
+}
+
+func writeFile(t *testing.T, name, content string) string {
+	path := filepath.Join(t.TempDir(), name)
+	require.NoError(t, os.WriteFile(path, []byte(content), 0o600))
+	return path
+}
+
+var required = map[string]string{"MYSQL_HOST": "db:3306", "REDIS_HOST": "redis:6379"}
+
+func TestLoad(t *testing.T) {
+	t.Run("defaults", func(t *testing.T) {
+		// Test
+		cfg, err := load(nil, env(required), io.Discard)
+
+		// Assert
+		require.NoError(t, err)
+		assert.Equal(t, ":8080", cfg.Server.Addr)
+		assert.Equal(t, "root", cfg.DB.User)
+		assert.Equal(t, "games_db", cfg.DB.Name)
+		assert.Equal(t, 1000, cfg.DB.MaxOpenConns)
+		assert.Equal(t, 10*time.Second, cfg.Timeouts.LogGame)
+	})
+
+	t.Run("file, then env, then flags", func(t *testing.T) {
+		// Setup
+		file := writeFile(t, "skyhawk.yaml", `
+server:
+  addr: ":9000"
+db:
+  host: file-db:3306
+  user: stats
+  max_open_conns: 200
+  max_idle_conns: 100
+timeouts:
+  stats: 5s
+rate_limit:
+  read_rps: 2.5
+`)
+		values := map[string]string{
+			"CONFIG_FILE":         file,
+			"REDIS_HOST":          "redis:6379",
+			"MYSQL_USER":          "stats_env",
+			"MYSQL_ROOT_PASSWORD": "root-secret",
+			"STATS_TIMEOUT":       "",
+		}
+
+		// Test
+		cfg, err := load([]string{"-db.user", "stats_flag", "-redis.tls"}, env(values), io.Discard)
+
+		// Assert
+		require.NoError(t, err)
+		assert.Equal(t, ":9000", cfg.Server.Addr, "from the file")
+		assert.Equal(t, "file-db:3306", cfg.DB.Host, "from the file")
+		assert.Equal(t, 200, cfg.DB.MaxOpenConns)
+		assert.Equal(t, 5*time.Second, cfg.Timeouts.Stats, "empty env values do not override")
+		assert.Equal(t, 2.5, cfg.RateLimit.ReadRPS)
+		assert.Equal(t, "stats_flag", cfg.DB.User, "flags win over env")
+		assert.Equal(t, "root-secret", cfg.DB.Password, "MYSQL_ROOT_PASSWORD still works")
+		assert.True(t, cfg.Redis.TLS)
+	})
+
+	t.Run("json file", func(t *testing.T) {
+		// Setup
+		file := writeFile(t, "skyhawk.json", `{"db": {"host": "json-db:3306"}, "redis": {"addr": "redis:6379"}, "retry": {"base_delay": "100ms"}}`)
+
+		// Test
+		cfg, err := load([]string{"-config", file}, env(nil), io.Discard)
+
+		// Assert
+		require.NoError(t, err)
+		assert.Equal(t, "json-db:3306", cfg.DB.Host)
+		assert.Equal(t, 100*time.Millisecond, cfg.Retry.BaseDelay)
+	})
+
+	t.Run("unknown file keys are rejected", func(t *testing.T) {
+		// Setup
+		file := writeFile(t, "skyhawk.yaml", "db:\n  hots: db:3306\n")
+
+		// Test
+		_, err := load([]string{"-config", file}, env(required), io.Discard)
+
+		// Assert
+		assert.ErrorContains(t, err, "hots")
+	})
+
+	t.Run("every problem is reported", func(t *testing.T) {
+		// Setup
+		values := map[string]string{
+			"RETRY_BASE_DELAY":  "fast",
+			"MYSQL_TLS":         "maybe",
+			"JWT_JWKS_URL":      "https://id.skyhawk.test/jwks",
+			"SERVER_BODY_LIMIT": "lots",
+			"TRACING_EXPORTER":  "jaeger",
+			"LOG_LEVEL":         "loud",
+		}
+
+		// Test
+		_, err := load(nil, env(values), io.Discard)
+
+		// Assert
+		require.Error(t, err)
+		assert.ErrorContains(t, err, "RETRY_BASE_DELAY")
+		assert.NotContains(t, err.Error(), "db.tls", "parse errors are reported before validation")
+
+		values["RETRY_BASE_DELAY"] = "1s"
+		_, err = load(nil, env(values), io.Discard)
+		assert.ErrorContains(t, err, "db.host: is required, set MYSQL_HOST")
+		assert.ErrorContains(t, err, "redis.addr: is required, set REDIS_HOST")
+		assert.ErrorContains(t, err, "db.tls: must be false, true, skip-verify or preferred")
+		assert.ErrorContains(t, err, "jwt.issuer: is required")
+		assert.ErrorContains(t, err, "server.body_limit: must be a size")
+		assert.ErrorContains(t, err, "tracing.exporter: must be none, stdout or otlp")
+		assert.ErrorContains(t, err, "log.level: must be debug, info, warn or error")
+	})
+}
+
+func TestConfig_Redacted(t *testing.T) {
+	// Setup
+	cfg := Default()
+	cfg.DB.Password = "hunter2"
+
+	// Test
+	redacted := cfg.Redacted()
+
+	// Assert
+	assert.Equal(t, "****", redacted["db.password"])
+	assert.Equal(t, "", redacted["redis.password"], "unset secrets stay empty")
+	assert.Equal(t, "root", redacted["db.user"])
+	assert.Equal(t, "10s", redacted["timeouts.log_game"])
+}
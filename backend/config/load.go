@@ -0,0 +1,219 @@
+package config
+
+import (
+	"bytes"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"reflect"
+	"strconv"
+	"strings"
+	"time"
+
+	"gopkg.in/yaml.v3"
+)
+
+// FileEnv - names the config file when no -config flag is given
+const FileEnv = "CONFIG_FILE"
+
+// field - one setting of Config
+type field struct {
+	path     string
+	env      []string
+	usage    string
+	secret   bool
+	required bool
+	value    reflect.Value
+}
+
+// fields - the settings of cfg in declaration order, their values point into cfg
+func fields(cfg *Config) []field {
+	var out []field
+
+	var walk func(prefix string, v reflect.Value)
+	walk = func(prefix string, v reflect.Value) {
+		t := v.Type()
+		for i := 0; i < t.NumField(); i++ {
+			sf := t.Field(i)
+			name, _, _ := strings.Cut(sf.Tag.Get("yaml"), ",")
+			path := prefix + name
+
+			if sf.Type.Kind() == reflect.Struct && sf.Type != reflect.TypeOf(time.Duration(0)) {
+				walk(path+".", v.Field(i))
+				continue
+			}
+
+			f := field{
+				path:     path,
+				usage:    sf.Tag.Get("usage"),
+				secret:   sf.Tag.Get("secret") == "true",
+				required: sf.Tag.Get("required") == "true",
+				value:    v.Field(i),
+			}
+			if env := sf.Tag.Get("env"); env != "" {
+				f.env = strings.Split(env, ",")
+			}
+			out = append(out, f)
+		}
+	}
+	walk("", reflect.ValueOf(cfg).Elem())
+
+	return out
+}
+
+// set - parses raw into the field by its type
+func (f field) set(raw string) error {
+	switch f.value.Interface().(type) {
+	case time.Duration:
+		d, err := time.ParseDuration(raw)
+		if err != nil {
+			return fmt.Errorf("%q is not a duration such as 5s or 1m30s", raw)
+		}
+		f.value.SetInt(int64(d))
+	case string:
+		f.value.SetString(raw)
+	case bool:
+		b, err := strconv.ParseBool(raw)
+		if err != nil {
+			return fmt.Errorf("%q is not true or false", raw)
+		}
+		f.value.SetBool(b)
+	case int, int64:
+		i, err := strconv.ParseInt(raw, 10, 64)
+		if err != nil {
+			return fmt.Errorf("%q is not an integer", raw)
+		}
+		f.value.SetInt(i)
+	case float64:
+		n, err := strconv.ParseFloat(raw, 64)
+		if err != nil {
+			return fmt.Errorf("%q is not a number", raw)
+		}
+		f.value.SetFloat(n)
+	default:
+		return fmt.Errorf("unsupported setting type %s", f.value.Type())
+	}
+
+	return nil
+}
+
+// flagValue - collects a flag so flags are applied after the file and the environment
+type flagValue struct {
+	raw    map[string]string
+	path   string
+	isBool bool
+}
+
+func (v *flagValue) String() string { return "" }
+
+func (v *flagValue) Set(raw string) error {
+	v.raw[v.path] = raw
+	return nil
+}
+
+func (v *flagValue) IsBoolFlag() bool { return v.isBool }
+
+// Load - the configuration from the defaults, the file named by -config or CONFIG_FILE, the environment and args, validated
+func Load(args []string) (Config, error) {
+	return load(args, os.LookupEnv, os.Stderr)
+}
+
+func load(args []string, lookupEnv func(string) (string, bool), output io.Writer) (Config, error) {
+	cfg := Default()
+	settings := fields(&cfg)
+
+	flags := flag.NewFlagSet("skyhawk", flag.ContinueOnError)
+	flags.SetOutput(output)
+	file := flags.String("config", "", "YAML or JSON config file, overrides "+FileEnv)
+	raw := map[string]string{}
+	for _, f := range settings {
+		usage := f.usage
+		if len(f.env) > 0 {
+			usage += " (" + strings.Join(f.env, ", ") + ")"
+		}
+		flags.Var(&flagValue{raw: raw, path: f.path, isBool: f.value.Kind() == reflect.Bool}, f.path, usage)
+	}
+	if err := flags.Parse(args); err != nil {
+		return Config{}, err
+	}
+	if flags.NArg() > 0 {
+		return Config{}, fmt.Errorf("config: unexpected argument %q", flags.Arg(0))
+	}
+
+	if *file == "" {
+		*file, _ = lookupEnv(FileEnv)
+	}
+	if *file != "" {
+		if err := loadFile(&cfg, *file); err != nil {
+			return Config{}, err
+		}
+	}
+
+	var problems []string
+	for _, f := range settings {
+		for _, name := range f.env {
+			value, ok := lookupEnv(name)
+			if !ok || value == "" {
+				continue
+			}
+			if err := f.set(value); err != nil {
+				problems = append(problems, fmt.Sprintf("%s: %v", name, err))
+			}
+			break
+		}
+	}
+	for _, f := range settings {
+		value, ok := raw[f.path]
+		if !ok {
+			continue
+		}
+		if err := f.set(value); err != nil {
+			problems = append(problems, fmt.Sprintf("-%s: %v", f.path, err))
+		}
+	}
+	if len(problems) > 0 {
+		return Config{}, invalid(problems)
+	}
+
+	if err := cfg.Validate(); err != nil {
+		return Config{}, err
+	}
+
+	return cfg, nil
+}
+
+// loadFile - unknown keys are rejected so a typo does not silently leave the default in place
+func loadFile(cfg *Config, path string) error {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return fmt.Errorf("config: %w", err)
+	}
+
+	decoder := yaml.NewDecoder(bytes.NewReader(data))
+	decoder.KnownFields(true)
+	if err = decoder.Decode(cfg); err != nil && !errors.Is(err, io.EOF) {
+		return fmt.Errorf("config: %s: %w", path, err)
+	}
+
+	return nil
+}
+
+func invalid(problems []string) error {
+	return errors.New("config: invalid configuration:\n  " + strings.Join(problems, "\n  "))
+}
+
+// Redacted - every setting by path with secrets masked, safe to log
+func (c Config) Redacted() map[string]string {
+	out := map[string]string{}
+	for _, f := range fields(&c) {
+		value := fmt.Sprint(f.value.Interface())
+		if f.secret && value != "" {
+			value = "****"
+		}
+		out[f.path] = value
+	}
+
+	return out
+}
@@ -0,0 +1,152 @@
+package config
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/labstack/gommon/bytes"
+	"go.uber.org/zap/zapcore"
+)
+
+// Validate - every problem of the configuration at once, so a bad deploy is fixed in one go
+func (c *Config) Validate() error {
+	var problems []string
+	problem := func(path, format string, args ...interface{}) {
+		problems = append(problems, path+": "+fmt.Sprintf(format, args...))
+	}
+
+	for _, f := range fields(c) {
+		if f.required && f.value.IsZero() {
+			if len(f.env) > 0 {
+				problem(f.path, "is required, set %s", strings.Join(f.env, " or "))
+			} else {
+				problem(f.path, "is required")
+			}
+		}
+		if d, ok := f.value.Interface().(interface{ Nanoseconds() int64 }); ok && d.Nanoseconds() < 0 {
+			problem(f.path, "must not be negative")
+		}
+	}
+
+	if c.Server.Addr == "" {
+		problem("server.addr", "is required")
+	}
+	for path, limit := range map[string]string{
+		"server.body_limit":        c.Server.BodyLimit,
+		"server.batch_body_limit":  c.Server.BatchBodyLimit,
+		"server.import_body_limit": c.Server.ImportBodyLimit,
+	} {
+		if size, err := bytes.Parse(limit); err != nil || size <= 0 {
+			problem(path, "must be a size such as 512K or 16M, got %q", limit)
+		}
+	}
+
+	if _, err := c.Server.Proxies(); err != nil {
+		problem("server.trusted_proxies", "must be comma separated CIDRs such as 10.0.0.0/8, %v", err)
+	}
+
+	switch c.DB.TLS {
+	case "false", "true", "skip-verify", "preferred":
+	default:
+		problem("db.tls", "must be false, true, skip-verify or preferred, got %q", c.DB.TLS)
+	}
+	if c.DB.TLSCAFile != "" && c.DB.TLS != "true" {
+		problem("db.tls_ca_file", "needs db.tls true")
+	}
+	if c.DB.MaxOpenConns < 1 {
+		problem("db.max_open_conns", "must be at least 1")
+	}
+	if c.DB.MaxIdleConns < 0 || c.DB.MaxIdleConns > c.DB.MaxOpenConns {
+		problem("db.max_idle_conns", "must be between 0 and db.max_open_conns (%d)", c.DB.MaxOpenConns)
+	}
+	if c.Redis.DB < 0 {
+		problem("redis.db", "must not be negative")
+	}
+
+	if c.Migrations.OnStart != "up" && c.Migrations.OnStart != "check" {
+		problem("migrations.on_start", "must be up or check, got %q", c.Migrations.OnStart)
+	}
+	if c.Migrations.LockTimeout < time.Second {
+		problem("migrations.lock_timeout", "must be at least 1s")
+	}
+
+	if c.Retry.MaxAttempts < 1 {
+		problem("retry.max_attempts", "must be at least 1")
+	}
+	if c.Batch.MaxGames < 1 {
+		problem("batch.max_games", "must be at least 1")
+	}
+	if c.Batch.Parallelism < 1 {
+		problem("batch.parallelism", "must be at least 1")
+	}
+	if c.Stream.Backplane != "local" && c.Stream.Backplane != "redis" {
+		problem("stream.backplane", "must be local or redis, got %q", c.Stream.Backplane)
+	}
+	if c.Webhook.MaxAttempts < 1 {
+		problem("webhook.max_attempts", "must be at least 1")
+	}
+
+	switch c.Outbox.Publisher {
+	case "redis":
+	case "file":
+		if c.Outbox.File == "" {
+			problem("outbox.file", "is required with the file publisher")
+		}
+	default:
+		problem("outbox.publisher", "must be redis or file, got %q", c.Outbox.Publisher)
+	}
+
+	if c.JWT.JWKSFile != "" && c.JWT.JWKSURL != "" {
+		problem("jwt.jwks_url", "set either jwt.jwks_file or jwt.jwks_url, not both")
+	}
+	if c.JWT.Enabled() {
+		if c.JWT.Issuer == "" {
+			problem("jwt.issuer", "is required with a JWKS, set JWT_ISSUER")
+		}
+		if c.JWT.Audience == "" {
+			problem("jwt.audience", "is required with a JWKS, set JWT_AUDIENCE")
+		}
+	}
+
+	if c.RateLimit.ReadRPS < 0 || c.RateLimit.WriteRPS < 0 || c.RateLimit.IPRPS < 0 || c.RateLimit.DailyQuota < 0 {
+		problem("rate_limit", "rates and quota must not be negative")
+	}
+	if c.RateLimit.Store != "memory" && c.RateLimit.Store != "redis" {
+		problem("rate_limit.store", "must be memory or redis, got %q", c.RateLimit.Store)
+	}
+
+	if c.Health.Timeout <= 0 {
+		problem("health.timeout", "must be positive")
+	}
+
+	switch c.Tracing.Exporter {
+	case "none", "stdout", "otlp":
+	default:
+		problem("tracing.exporter", "must be none, stdout or otlp, got %q", c.Tracing.Exporter)
+	}
+	if c.Tracing.SampleRatio < 0 || c.Tracing.SampleRatio > 1 {
+		problem("tracing.sample_ratio", "must be between 0 and 1")
+	}
+
+	if _, err := zapcore.ParseLevel(c.Log.Level); err != nil {
+		problem("log.level", "must be debug, info, warn or error, got %q", c.Log.Level)
+	}
+	if c.Log.Format != "json" && c.Log.Format != "console" {
+		problem("log.format", "must be json or console, got %q", c.Log.Format)
+	}
+	if c.Log.SamplingInitial < 0 {
+		problem("log.sampling_initial", "must not be negative")
+	}
+	if c.Log.SamplingInitial > 0 && c.Log.SamplingThereafter < 1 {
+		problem("log.sampling_thereafter", "must be at least 1 with sampling on")
+	}
+
+	if len(problems) > 0 {
+		sort.Strings(problems)
+		return invalid(problems)
+	}
+
+	return nil
+}
@@ -0,0 +1,13 @@
+package db
+
+import "time"
+
+// parseTimestamp - the driver returns timestamps as "2006-01-02 15:04:05" text
+func parseTimestamp(value string) time.Time {
+	parsed, err := time.Parse(time.DateTime, value)
+	if err != nil {
+		return time.Time{}
+	}
+
+	return parsed
+}
@@ -0,0 +1,134 @@
+package db
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"fmt"
+	"strings"
+
+	"github.com/jmoiron/sqlx"
+	"go.uber.org/zap"
+
+	"skyhawk/backend/apperror"
+	"skyhawk/backend/event/domain"
+	"skyhawk/backend/logging"
+)
+
+type Repository interface {
+	NextSeq(ctx context.Context, tx *sql.Tx, gameID string) (int, error)
+	Append(ctx context.Context, tx *sql.Tx, gameID string, events []domain.Event) error
+	List(ctx context.Context, tx *sql.Tx, gameID string) ([]domain.Event, error)
+	Find(ctx context.Context, gameID string) ([]domain.Event, error)
+	Games(ctx context.Context) ([]string, error)
+}
+
+type Repo struct {
+	db     *sqlx.DB
+	logger *zap.Logger
+}
+
+// queryer - satisfied by both *sqlx.DB and *sql.Tx
+type queryer interface {
+	QueryContext(ctx context.Context, query string, args ...interface{}) (*sql.Rows, error)
+}
+
+func NewRepo(db *sqlx.DB, logger *zap.Logger) Repository {
+
+	return &Repo{db: db, logger: logger}
+}
+
+// NextSeq - the sequence number the next appended event gets, callers hold the game row lock
+func (r *Repo) NextSeq(ctx context.Context, tx *sql.Tx, gameID string) (int, error) {
+	var last int
+
+	if err := tx.QueryRowContext(ctx, "SELECT COALESCE(MAX(seq), 0) FROM game_events WHERE game_id = ?", gameID).Scan(&last); err != nil {
+		return 0, apperror.FromDB(err, nil)
+	}
+
+	return last + 1, nil
+}
+
+// Append - inserts events as given, their Seq must already be assigned
+func (r *Repo) Append(ctx context.Context, tx *sql.Tx, gameID string, events []domain.Event) error {
+	if len(events) == 0 {
+		return nil
+	}
+
+	placeHolders := make([]string, 0, len(events))
+	values := make([]interface{}, 0, len(events)*7)
+
+	for _, event := range events {
+		payload, err := json.Marshal(event)
+		if err != nil {
+			return apperror.Internal(err)
+		}
+
+		var playerID interface{}
+		if event.PlayerID != "" {
+			playerID = event.PlayerID
+		}
+
+		placeHolders = append(placeHolders, "(?, ?, ?, ?, ?, ?, ?)")
+		values = append(values, gameID, event.Seq, event.Type, event.Period, event.ClockSeconds(), playerID, payload)
+	}
+
+	q := fmt.Sprintf("INSERT INTO game_events (game_id, seq, type, period, clock_seconds, player_id, payload) VALUES %s", strings.Join(placeHolders, ","))
+	if _, err := tx.ExecContext(ctx, q, values...); err != nil {
+		logging.From(ctx, r.logger).Error("failed inserting events", zap.Error(err))
+		return apperror.FromDB(err, nil)
+	}
+
+	return nil
+}
+
+func (r *Repo) List(ctx context.Context, tx *sql.Tx, gameID string) ([]domain.Event, error) {
+	return r.list(ctx, tx, gameID)
+}
+
+func (r *Repo) Find(ctx context.Context, gameID string) ([]domain.Event, error) {
+	return r.list(ctx, r.db, gameID)
+}
+
+func (r *Repo) list(ctx context.Context, q queryer, gameID string) ([]domain.Event, error) {
+	rows, err := q.QueryContext(ctx, "SELECT seq, payload, created_at FROM game_events WHERE game_id = ? ORDER BY seq", gameID)
+	if err != nil {
+		return nil, apperror.FromDB(err, nil)
+	}
+	defer rows.Close()
+
+	var events []domain.Event
+	for rows.Next() {
+		var seq int
+		var payload []byte
+		var createdAt string
+		if err = rows.Scan(&seq, &payload, &createdAt); err != nil {
+			return nil, apperror.FromDB(err, nil)
+		}
+
+		var event domain.Event
+		if err = json.Unmarshal(payload, &event); err != nil {
+			return nil, apperror.Internal(fmt.Errorf("event %d of game %s: %w", seq, gameID, err))
+		}
+		event.Seq = seq
+		event.CreatedAt = parseTimestamp(createdAt)
+		events = append(events, event)
+	}
+	if err = rows.Err(); err != nil {
+		return nil, apperror.FromDB(err, nil)
+	}
+
+	return events, nil
+}
+
+// Games - the ids of every play by play game, oldest first
+func (r *Repo) Games(ctx context.Context) ([]string, error) {
+	var ids []string
+
+	if err := r.db.SelectContext(ctx, &ids, "SELECT id FROM games WHERE source = 'events' ORDER BY date, id"); err != nil {
+		logging.From(ctx, r.logger).Error("failed listing play by play games", zap.Error(err))
+		return nil, apperror.FromDB(err, nil)
+	}
+
+	return ids, nil
+}
@@ -0,0 +1,168 @@
+package db
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/go-sql-driver/mysql"
+	"github.com/jmoiron/sqlx"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap/zaptest"
+
+	"skyhawk/backend/apperror"
+	"skyhawk/backend/event/domain"
+)
+
+func TestRepo_NextSeq(t *testing.T) {
+	// Setup
+	db, dbMock := createMockDB(t)
+	repo := NewRepo(db, zaptest.NewLogger(t))
+
+	dbMock.ExpectBegin()
+	dbMock.ExpectQuery("SELECT COALESCE\\(MAX\\(seq\\), 0\\) FROM game_events WHERE game_id = \\?").
+		WithArgs("g1").
+		WillReturnRows(sqlmock.NewRows([]string{"seq"}).AddRow(7))
+
+	tx, err := db.Begin()
+	require.NoError(t, err)
+
+	// Test
+	seq, err := repo.NextSeq(context.Background(), tx, "g1")
+
+	// Assert
+	require.NoError(t, err)
+	assert.Equal(t, 8, seq)
+	assert.NoError(t, dbMock.ExpectationsWereMet())
+}
+
+func TestRepo_Append(t *testing.T) {
+	t.Run("insert with payload", func(t *testing.T) {
+		// Setup
+		db, dbMock := createMockDB(t)
+		repo := NewRepo(db, zaptest.NewLogger(t))
+
+		events := []domain.Event{
+			{Seq: 1, Type: domain.TypePeriodStart, Period: 1, Clock: "12:00", Lineup: []string{"p1"}},
+			{Seq: 2, Type: domain.TypeShotMade, Period: 1, Clock: "11:42", PlayerID: "p1", ShotType: domain.ShotThree},
+		}
+
+		dbMock.ExpectBegin()
+		dbMock.ExpectExec("INSERT INTO game_events \\(game_id, seq, type, period, clock_seconds, player_id, payload\\) VALUES \\(\\?, \\?, \\?, \\?, \\?, \\?, \\?\\),\\(\\?, \\?, \\?, \\?, \\?, \\?, \\?\\)").
+			WithArgs("g1", 1, domain.TypePeriodStart, 1, 720, nil, sqlmock.AnyArg(), "g1", 2, domain.TypeShotMade, 1, 702, "p1", sqlmock.AnyArg()).
+			WillReturnResult(sqlmock.NewResult(0, 2))
+
+		tx, err := db.Begin()
+		require.NoError(t, err)
+
+		// Test
+		err = repo.Append(context.Background(), tx, "g1", events)
+
+		// Assert
+		require.NoError(t, err)
+		assert.NoError(t, dbMock.ExpectationsWereMet())
+	})
+
+	t.Run("duplicate seq", func(t *testing.T) {
+		// Setup
+		db, dbMock := createMockDB(t)
+		repo := NewRepo(db, zaptest.NewLogger(t))
+
+		dbMock.ExpectBegin()
+		dbMock.ExpectExec("INSERT INTO game_events").WillReturnError(&mysql.MySQLError{Number: 1062, Message: "Duplicate entry"})
+
+		tx, err := db.Begin()
+		require.NoError(t, err)
+
+		// Test
+		err = repo.Append(context.Background(), tx, "g1", []domain.Event{{Seq: 1, Type: domain.TypePeriodEnd, Period: 1, Clock: "00:00"}})
+
+		// Assert
+		assert.Equal(t, apperror.KindConflict, apperror.KindOf(err))
+	})
+}
+
+func TestRepo_Find(t *testing.T) {
+	t.Run("events in order", func(t *testing.T) {
+		// Setup
+		db, dbMock := createMockDB(t)
+		repo := NewRepo(db, zaptest.NewLogger(t))
+
+		rows := sqlmock.NewRows([]string{"seq", "payload", "created_at"}).
+			AddRow(1, []byte(`{"seq":1,"type":"period_start","period":1,"clock":"12:00","lineup":["p1"]}`), "2024-11-02 19:30:00").
+			AddRow(2, []byte(`{"seq":2,"type":"shot_made","period":1,"clock":"11:42","player_id":"p1","shot_type":"three","location":{"x":22,"y":3}}`), "2024-11-02 19:30:18")
+		dbMock.ExpectQuery("SELECT seq, payload, created_at FROM game_events WHERE game_id = \\? ORDER BY seq").
+			WithArgs("g1").
+			WillReturnRows(rows)
+
+		// Test
+		events, err := repo.Find(context.Background(), "g1")
+
+		// Assert
+		require.NoError(t, err)
+		require.Len(t, events, 2)
+		assert.Equal(t, []string{"p1"}, events[0].Lineup)
+		assert.Equal(t, domain.ShotThree, events[1].ShotType)
+		assert.Equal(t, &domain.Location{X: 22, Y: 3}, events[1].Location)
+		assert.Equal(t, 2024, events[1].CreatedAt.Year())
+		assert.NoError(t, dbMock.ExpectationsWereMet())
+	})
+
+	t.Run("corrupt payload", func(t *testing.T) {
+		// Setup
+		db, dbMock := createMockDB(t)
+		repo := NewRepo(db, zaptest.NewLogger(t))
+
+		rows := sqlmock.NewRows([]string{"seq", "payload", "created_at"}).AddRow(1, []byte(`{`), "2024-11-02 19:30:00")
+		dbMock.ExpectQuery("FROM game_events").WillReturnRows(rows)
+
+		// Test
+		events, err := repo.Find(context.Background(), "g1")
+
+		// Assert
+		assert.Nil(t, events)
+		assert.Equal(t, apperror.KindInternal, apperror.KindOf(err))
+	})
+
+	t.Run("query error", func(t *testing.T) {
+		// Setup
+		db, dbMock := createMockDB(t)
+		repo := NewRepo(db, zaptest.NewLogger(t))
+		dbMock.ExpectQuery("FROM game_events").WillReturnError(errors.New("boom"))
+
+		// Test
+		events, err := repo.Find(context.Background(), "g1")
+
+		// Assert
+		assert.Nil(t, events)
+		assert.Error(t, err)
+	})
+}
+
+func createMockDB(t *testing.T) (*sqlx.DB, sqlmock.Sqlmock) {
+	db, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("Failed to create mock DB: %v", err)
+	}
+	t.Cleanup(func() { db.Close() })
+
+	return sqlx.NewDb(db, "sqlmock"), mock
+}
+
+func TestRepo_Games(t *testing.T) {
+	// Setup
+	db, dbMock := createMockDB(t)
+	repo := NewRepo(db, zaptest.NewLogger(t))
+	dbMock.ExpectQuery("SELECT id FROM games WHERE source = 'events' ORDER BY date, id").
+		WillReturnRows(sqlmock.NewRows([]string{"id"}).AddRow("g1").AddRow("g2"))
+
+	// Test
+	ids, err := repo.Games(context.Background())
+
+	// Assert
+	require.NoError(t, err)
+	assert.Equal(t, []string{"g1", "g2"}, ids)
+	assert.NoError(t, dbMock.ExpectationsWereMet())
+}
@@ -0,0 +1,184 @@
+package domain
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+
+	"skyhawk/backend/apperror"
+)
+
+// Type - what happened on the court
+type Type string
+
+const (
+	TypePeriodStart  Type = "period_start"
+	TypePeriodEnd    Type = "period_end"
+	TypeShotMade     Type = "shot_made"
+	TypeShotMissed   Type = "shot_missed"
+	TypeRebound      Type = "rebound"
+	TypeAssist       Type = "assist"
+	TypeSteal        Type = "steal"
+	TypeBlock        Type = "block"
+	TypeTurnover     Type = "turnover"
+	TypeFoul         Type = "foul"
+	TypeSubstitution Type = "substitution"
+)
+
+// ShotType - the value of a field goal or free throw attempt
+type ShotType string
+
+const (
+	ShotTwo       ShotType = "two"
+	ShotThree     ShotType = "three"
+	ShotFreeThrow ShotType = "free_throw"
+)
+
+func (s ShotType) Points() int {
+	switch s {
+	case ShotTwo:
+		return 2
+	case ShotThree:
+		return 3
+	case ShotFreeThrow:
+		return 1
+	}
+
+	return 0
+}
+
+// Location - where a shot was taken, in feet from the center of the basket
+type Location struct {
+	X float64 `json:"x"`
+	Y float64 `json:"y"`
+}
+
+// Event - a single play. Clock is the time left in the period as "MM:SS".
+// Lineup lists the players on the court when a period starts, substitutions change it from there.
+type Event struct {
+	Seq         int       `json:"seq"`
+	Type        Type      `json:"type"`
+	Period      int       `json:"period"`
+	Clock       string    `json:"clock"`
+	TeamID      string    `json:"team_id,omitempty"`
+	PlayerID    string    `json:"player_id,omitempty"`
+	ShotType    ShotType  `json:"shot_type,omitempty"`
+	Location    *Location `json:"location,omitempty"`
+	Offensive   bool      `json:"offensive,omitempty"`
+	PlayerInID  string    `json:"player_in_id,omitempty"`
+	PlayerOutID string    `json:"player_out_id,omitempty"`
+	Lineup      []string  `json:"lineup,omitempty"`
+	CreatedAt   time.Time `json:"created_at,omitempty"`
+}
+
+const (
+	regulationPeriods = 4
+	periodSeconds     = 12 * 60
+	overtimeSeconds   = 5 * 60
+)
+
+// PeriodLength - regulation quarters are 12 minutes, overtimes 5
+func PeriodLength(period int) int {
+	if period <= regulationPeriods {
+		return periodSeconds
+	}
+
+	return overtimeSeconds
+}
+
+// ParseClock - parses "MM:SS" into seconds
+func ParseClock(clock string) (int, error) {
+	minutes, seconds, ok := strings.Cut(clock, ":")
+	if !ok {
+		return 0, fmt.Errorf("clock %q must be MM:SS", clock)
+	}
+
+	m, err := strconv.Atoi(minutes)
+	if err != nil || m < 0 {
+		return 0, fmt.Errorf("clock %q must be MM:SS", clock)
+	}
+	s, err := strconv.Atoi(seconds)
+	if err != nil || s < 0 || s >= 60 {
+		return 0, fmt.Errorf("clock %q must be MM:SS", clock)
+	}
+
+	return m*60 + s, nil
+}
+
+// FormatClock - formats seconds as "MM:SS"
+func FormatClock(seconds int) string {
+	return fmt.Sprintf("%02d:%02d", seconds/60, seconds%60)
+}
+
+// ClockSeconds - the seconds left in the period, only valid on a validated event
+func (e Event) ClockSeconds() int {
+	seconds, _ := ParseClock(e.Clock)
+
+	return seconds
+}
+
+// Validate - checks the shape of a single event, ordering is checked by the reducer
+func (e Event) Validate(field string) []apperror.FieldError {
+	var fields []apperror.FieldError
+	add := func(name, message string) {
+		fields = append(fields, apperror.FieldError{Field: field + "." + name, Message: message})
+	}
+
+	if e.Period < 1 {
+		add("period", "period must be 1 or more")
+	}
+	if seconds, err := ParseClock(e.Clock); err != nil {
+		add("clock", err.Error())
+	} else if e.Period >= 1 && seconds > PeriodLength(e.Period) {
+		add("clock", fmt.Sprintf("clock cannot exceed %s in period %d", FormatClock(PeriodLength(e.Period)), e.Period))
+	}
+
+	switch e.Type {
+	case TypePeriodStart:
+		if len(e.Lineup) == 0 {
+			add("lineup", "period start needs the players on the court")
+		}
+	case TypePeriodEnd:
+	case TypeShotMade, TypeShotMissed:
+		if e.PlayerID == "" {
+			add("player_id", "shot needs a player")
+		}
+		if e.ShotType.Points() == 0 {
+			add("shot_type", "shot type must be two, three or free_throw")
+		}
+	case TypeRebound, TypeAssist, TypeSteal, TypeBlock, TypeTurnover, TypeFoul:
+		if e.PlayerID == "" {
+			add("player_id", fmt.Sprintf("%s needs a player", e.Type))
+		}
+	case TypeSubstitution:
+		if e.PlayerInID == "" || e.PlayerOutID == "" {
+			add("player_in_id", "substitution needs the players coming in and going out")
+		}
+	default:
+		add("type", fmt.Sprintf("unknown event type %q", e.Type))
+	}
+
+	return fields
+}
+
+// AppendReq - events to append to a game, Date is only used when the first events create the game
+type AppendReq struct {
+	Date   time.Time `json:"date"`
+	Events []Event   `json:"events"`
+}
+
+// AppendRes - the game's last sequence number and the box score derived from all of its events
+type AppendRes struct {
+	GameID  string       `json:"game_id"`
+	LastSeq int          `json:"last_seq"`
+	Lines   []PlayerLine `json:"lines"`
+}
+
+// RecomputeResult - the box score of one play by play game rederived from its events, Error is set when that failed
+type RecomputeResult struct {
+	GameID  string `json:"game_id"`
+	LastSeq int    `json:"last_seq"`
+	Lines   int    `json:"lines"`
+	Error   string `json:"error,omitempty"`
+}
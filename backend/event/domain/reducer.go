@@ -0,0 +1,159 @@
+package domain
+
+import (
+	"fmt"
+	"math"
+	"sort"
+
+	"skyhawk/backend/apperror"
+)
+
+// PlayerLine - a player's box score line derived from the events of a game
+type PlayerLine struct {
+	PlayerID      string  `json:"player_id"`
+	Points        int     `json:"points"`
+	Rebounds      int     `json:"rebounds"`
+	Assists       int     `json:"assists"`
+	Steals        int     `json:"steals"`
+	Blocks        int     `json:"blocks"`
+	Fouls         int     `json:"fouls"`
+	Turnovers     int     `json:"turnovers"`
+	SecondsPlayed int     `json:"seconds_played"`
+	MinutesPlayed float64 `json:"minutes_played"`
+}
+
+// reducer - replays events in order, tracking who is on the court to credit playing time
+type reducer struct {
+	lines    map[string]*PlayerLine
+	order    []string
+	onCourt  map[string]int
+	period   int
+	elapsed  int
+	inPeriod bool
+}
+
+// Reduce - derives the box score of a game from its events, the same events always produce the same lines.
+// Players still on the court after the last event are credited up to that event.
+func Reduce(events []Event) ([]PlayerLine, error) {
+	r := &reducer{lines: map[string]*PlayerLine{}, onCourt: map[string]int{}}
+
+	for i, event := range events {
+		if err := r.apply(event); err != nil {
+			return nil, apperror.Validation("invalid_event_sequence", fmt.Sprintf("event %d (%s): %s", event.Seq, event.Type, err.Error()),
+				apperror.FieldError{Field: fmt.Sprintf("events[%d]", i), Message: err.Error()})
+		}
+	}
+	r.closeStints()
+
+	lines := make([]PlayerLine, 0, len(r.order))
+	for _, id := range r.order {
+		line := *r.lines[id]
+		line.MinutesPlayed = math.Round(float64(line.SecondsPlayed)/60*100) / 100
+		lines = append(lines, line)
+	}
+
+	return lines, nil
+}
+
+func (r *reducer) line(playerID string) *PlayerLine {
+	line, ok := r.lines[playerID]
+	if !ok {
+		line = &PlayerLine{PlayerID: playerID}
+		r.lines[playerID] = line
+		r.order = append(r.order, playerID)
+	}
+
+	return line
+}
+
+// gameTime - seconds since tip off at the event's clock
+func gameTime(period, clock int) int {
+	elapsed := 0
+	for p := 1; p < period; p++ {
+		elapsed += PeriodLength(p)
+	}
+
+	return elapsed + PeriodLength(period) - clock
+}
+
+func (r *reducer) apply(e Event) error {
+	now := gameTime(e.Period, e.ClockSeconds())
+
+	if e.Type == TypePeriodStart {
+		if r.inPeriod {
+			return fmt.Errorf("period %d has not ended", r.period)
+		}
+		if e.Period != r.period+1 {
+			return fmt.Errorf("expected period %d to start", r.period+1)
+		}
+		r.period = e.Period
+		r.inPeriod = true
+		r.elapsed = now
+		for _, playerID := range e.Lineup {
+			if _, ok := r.onCourt[playerID]; ok {
+				return fmt.Errorf("player %s is listed twice in the lineup", playerID)
+			}
+			r.line(playerID)
+			r.onCourt[playerID] = now
+		}
+		return nil
+	}
+
+	if !r.inPeriod || e.Period != r.period {
+		return fmt.Errorf("period %d is not in progress", e.Period)
+	}
+	if now < r.elapsed {
+		return fmt.Errorf("clock went backwards to %s", e.Clock)
+	}
+	r.elapsed = now
+
+	switch e.Type {
+	case TypePeriodEnd:
+		r.closeStints()
+		r.inPeriod = false
+	case TypeShotMade:
+		r.line(e.PlayerID).Points += e.ShotType.Points()
+	case TypeShotMissed:
+		r.line(e.PlayerID)
+	case TypeRebound:
+		r.line(e.PlayerID).Rebounds++
+	case TypeAssist:
+		r.line(e.PlayerID).Assists++
+	case TypeSteal:
+		r.line(e.PlayerID).Steals++
+	case TypeBlock:
+		r.line(e.PlayerID).Blocks++
+	case TypeTurnover:
+		r.line(e.PlayerID).Turnovers++
+	case TypeFoul:
+		r.line(e.PlayerID).Fouls++
+	case TypeSubstitution:
+		since, ok := r.onCourt[e.PlayerOutID]
+		if !ok {
+			return fmt.Errorf("player %s is not on the court", e.PlayerOutID)
+		}
+		if _, ok := r.onCourt[e.PlayerInID]; ok {
+			return fmt.Errorf("player %s is already on the court", e.PlayerInID)
+		}
+		r.line(e.PlayerOutID).SecondsPlayed += now - since
+		delete(r.onCourt, e.PlayerOutID)
+		r.line(e.PlayerInID)
+		r.onCourt[e.PlayerInID] = now
+	}
+
+	return nil
+}
+
+// closeStints - credits everyone on the court up to the current game time and clears the court
+func (r *reducer) closeStints() {
+	players := make([]string, 0, len(r.onCourt))
+	for playerID := range r.onCourt {
+		players = append(players, playerID)
+	}
+	sort.Strings(players)
+
+	for _, playerID := range players {
+		r.line(playerID).SecondsPlayed += r.elapsed - r.onCourt[playerID]
+		delete(r.onCourt, playerID)
+	}
+}
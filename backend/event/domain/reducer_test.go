@@ -0,0 +1,139 @@
+package domain
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"skyhawk/backend/apperror"
+)
+
+func TestReduce(t *testing.T) {
+	t.Run("stats and minutes from substitutions", func(t *testing.T) {
+		// Setup
+		events := []Event{
+			{Seq: 1, Type: TypePeriodStart, Period: 1, Clock: "12:00", Lineup: []string{"p1", "p2"}},
+			{Seq: 2, Type: TypeShotMade, Period: 1, Clock: "11:30", PlayerID: "p1", ShotType: ShotThree},
+			{Seq: 3, Type: TypeAssist, Period: 1, Clock: "11:30", PlayerID: "p2"},
+			{Seq: 4, Type: TypeShotMissed, Period: 1, Clock: "10:00", PlayerID: "p2", ShotType: ShotTwo},
+			{Seq: 5, Type: TypeRebound, Period: 1, Clock: "09:58", PlayerID: "p1", Offensive: true},
+			{Seq: 6, Type: TypeShotMade, Period: 1, Clock: "09:55", PlayerID: "p1", ShotType: ShotTwo},
+			{Seq: 7, Type: TypeFoul, Period: 1, Clock: "06:00", PlayerID: "p2"},
+			{Seq: 8, Type: TypeSubstitution, Period: 1, Clock: "06:00", PlayerOutID: "p2", PlayerInID: "p3"},
+			{Seq: 9, Type: TypeShotMade, Period: 1, Clock: "03:00", PlayerID: "p3", ShotType: ShotFreeThrow},
+			{Seq: 10, Type: TypeTurnover, Period: 1, Clock: "01:00", PlayerID: "p3"},
+			{Seq: 11, Type: TypePeriodEnd, Period: 1, Clock: "00:00"},
+		}
+
+		// Test
+		lines, err := Reduce(events)
+
+		// Assert
+		require.NoError(t, err)
+		require.Len(t, lines, 3)
+		assert.Equal(t, PlayerLine{PlayerID: "p1", Points: 5, Rebounds: 1, SecondsPlayed: 720, MinutesPlayed: 12}, lines[0])
+		assert.Equal(t, PlayerLine{PlayerID: "p2", Assists: 1, Fouls: 1, SecondsPlayed: 360, MinutesPlayed: 6}, lines[1])
+		assert.Equal(t, PlayerLine{PlayerID: "p3", Points: 1, Turnovers: 1, SecondsPlayed: 360, MinutesPlayed: 6}, lines[2])
+	})
+
+	t.Run("open period credits up to the last event", func(t *testing.T) {
+		// Setup
+		events := []Event{
+			{Seq: 1, Type: TypePeriodStart, Period: 1, Clock: "12:00", Lineup: []string{"p1"}},
+			{Seq: 2, Type: TypeSteal, Period: 1, Clock: "11:00", PlayerID: "p1"},
+			{Seq: 3, Type: TypeBlock, Period: 1, Clock: "10:40", PlayerID: "p1"},
+		}
+
+		// Test
+		lines, err := Reduce(events)
+
+		// Assert
+		require.NoError(t, err)
+		assert.Equal(t, []PlayerLine{{PlayerID: "p1", Steals: 1, Blocks: 1, SecondsPlayed: 80, MinutesPlayed: 1.33}}, lines)
+	})
+
+	t.Run("overtime is five minutes", func(t *testing.T) {
+		// Setup
+		var events []Event
+		for period := 1; period <= 5; period++ {
+			events = append(events,
+				Event{Type: TypePeriodStart, Period: period, Clock: FormatClock(PeriodLength(period)), Lineup: []string{"p1"}},
+				Event{Type: TypePeriodEnd, Period: period, Clock: "00:00"})
+		}
+
+		// Test
+		lines, err := Reduce(events)
+
+		// Assert
+		require.NoError(t, err)
+		assert.Equal(t, 53.0, lines[0].MinutesPlayed)
+	})
+
+	t.Run("invalid sequences", func(t *testing.T) {
+		start := Event{Seq: 1, Type: TypePeriodStart, Period: 1, Clock: "12:00", Lineup: []string{"p1", "p2"}}
+		tests := map[string][]Event{
+			"event before period start": {{Seq: 1, Type: TypeSteal, Period: 1, Clock: "11:00", PlayerID: "p1"}},
+			"skipped period":            {{Seq: 1, Type: TypePeriodStart, Period: 2, Clock: "12:00", Lineup: []string{"p1"}}},
+			"clock went backwards": {start,
+				{Seq: 2, Type: TypeSteal, Period: 1, Clock: "10:00", PlayerID: "p1"},
+				{Seq: 3, Type: TypeSteal, Period: 1, Clock: "11:00", PlayerID: "p1"}},
+			"sub out player not on court": {start,
+				{Seq: 2, Type: TypeSubstitution, Period: 1, Clock: "10:00", PlayerOutID: "p3", PlayerInID: "p4"}},
+			"sub in player already on court": {start,
+				{Seq: 2, Type: TypeSubstitution, Period: 1, Clock: "10:00", PlayerOutID: "p1", PlayerInID: "p2"}},
+		}
+
+		for name, events := range tests {
+			t.Run(name, func(t *testing.T) {
+				// Test
+				lines, err := Reduce(events)
+
+				// Assert
+				assert.Nil(t, lines)
+				assert.Equal(t, apperror.KindValidation, apperror.KindOf(err))
+			})
+		}
+	})
+}
+
+func TestEvent_Validate(t *testing.T) {
+	t.Run("valid shot", func(t *testing.T) {
+		// Setup
+		event := Event{Type: TypeShotMade, Period: 1, Clock: "05:12", PlayerID: "p1", ShotType: ShotThree, Location: &Location{X: 22, Y: 3}}
+
+		// Test
+		fields := event.Validate("events[0]")
+
+		// Assert
+		assert.Empty(t, fields)
+	})
+
+	t.Run("reports every problem", func(t *testing.T) {
+		// Setup
+		event := Event{Type: TypeShotMade, Period: 5, Clock: "07:00"}
+
+		// Test
+		fields := event.Validate("events[3]")
+
+		// Assert
+		assert.Equal(t, []apperror.FieldError{
+			{Field: "events[3].clock", Message: "clock cannot exceed 05:00 in period 5"},
+			{Field: "events[3].player_id", Message: "shot needs a player"},
+			{Field: "events[3].shot_type", Message: "shot type must be two, three or free_throw"},
+		}, fields)
+	})
+
+	t.Run("unknown type and bad clock", func(t *testing.T) {
+		// Setup
+		event := Event{Type: "dunk", Period: 1, Clock: "5:75"}
+
+		// Test
+		fields := event.Validate("events[0]")
+
+		// Assert
+		require.Len(t, fields, 2)
+		assert.Equal(t, "events[0].clock", fields[0].Field)
+		assert.Equal(t, "events[0].type", fields[1].Field)
+	})
+}
@@ -0,0 +1,47 @@
+package handler
+
+import (
+	"net/http"
+
+	"github.com/labstack/echo/v4"
+	"go.uber.org/zap"
+
+	"skyhawk/backend/apperror"
+	"skyhawk/backend/event/domain"
+	"skyhawk/backend/event/usecase"
+)
+
+type Handler struct {
+	useCase usecase.EventUseCase
+	logger  *zap.Logger
+}
+
+func NewHandler(useCase usecase.EventUseCase, logger *zap.Logger) *Handler {
+	return &Handler{useCase: useCase, logger: logger}
+}
+
+func (h *Handler) AppendEventsHandler(c echo.Context) error {
+	var req domain.AppendReq
+
+	if err := c.Bind(&req); err != nil {
+		return apperror.Validation("invalid_body", "request body is not a valid list of events")
+	}
+
+	res, err := h.useCase.AppendEvents(c.Request().Context(), c.Param("id"), req)
+
+	if err != nil {
+		return err
+	}
+
+	return c.JSON(http.StatusOK, res)
+}
+
+func (h *Handler) ListEventsHandler(c echo.Context) error {
+	events, err := h.useCase.ListEvents(c.Request().Context(), c.Param("id"))
+
+	if err != nil {
+		return err
+	}
+
+	return c.JSON(http.StatusOK, events)
+}
@@ -0,0 +1,336 @@
+package usecase
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"time"
+
+	"go.uber.org/zap"
+
+	"skyhawk/backend/apperror"
+	audit_domain "skyhawk/backend/audit/domain"
+	"skyhawk/backend/event/domain"
+	game_domain "skyhawk/backend/game/domain"
+	"skyhawk/backend/logging"
+	"skyhawk/backend/retry"
+)
+
+const maxGameIDLength = 36
+
+type GameRepository interface {
+	Begin(ctx context.Context) (*sql.Tx, error)
+	CreateGame(ctx context.Context, tx *sql.Tx, game game_domain.Game) error
+	LockGame(ctx context.Context, tx *sql.Tx, id string) (game_domain.Game, error)
+	Lines(ctx context.Context, tx *sql.Tx, gameID string) ([]game_domain.GameStats, error)
+	ReplaceLines(ctx context.Context, tx *sql.Tx, gameID string, date time.Time, players []game_domain.Player) error
+}
+
+type EventRepository interface {
+	NextSeq(ctx context.Context, tx *sql.Tx, gameID string) (int, error)
+	Append(ctx context.Context, tx *sql.Tx, gameID string, events []domain.Event) error
+	List(ctx context.Context, tx *sql.Tx, gameID string) ([]domain.Event, error)
+	Find(ctx context.Context, gameID string) ([]domain.Event, error)
+	Games(ctx context.Context) ([]string, error)
+}
+
+// Notifier - told about every game whose box score was rederived
+type Notifier interface {
+	GameUpdated(ctx context.Context, gameID string)
+}
+
+// Auditor - records who changed what inside the transaction of the change
+type Auditor interface {
+	Record(ctx context.Context, tx *sql.Tx, changes ...audit_domain.Change) error
+}
+
+type EventUseCase interface {
+	AppendEvents(ctx context.Context, gameID string, req domain.AppendReq) (domain.AppendRes, error)
+	ListEvents(ctx context.Context, gameID string) ([]domain.Event, error)
+	Recompute(ctx context.Context, gameIDs []string) ([]domain.RecomputeResult, error)
+}
+
+type UseCase struct {
+	gameRepo  GameRepository
+	eventRepo EventRepository
+	retrier   *retry.Retrier
+	notifier  Notifier
+	auditor   Auditor
+	logger    *zap.Logger
+}
+
+// NewUseCase - notifier and auditor may be nil when nobody follows games
+func NewUseCase(logger *zap.Logger, gameRepo GameRepository, eventRepo EventRepository, retrier *retry.Retrier, notifier Notifier, auditor Auditor) *UseCase {
+
+	return &UseCase{
+		gameRepo:  gameRepo,
+		eventRepo: eventRepo,
+		retrier:   retrier,
+		notifier:  notifier,
+		auditor:   auditor,
+		logger:    logger,
+	}
+}
+
+// AppendEvents - appends events to a game, creating it on the first call, and rederives its box score.
+// The whole log is replayed on every append so the stored lines are always the reducer's output.
+func (s *UseCase) AppendEvents(ctx context.Context, gameID string, req domain.AppendReq) (domain.AppendRes, error) {
+	var res domain.AppendRes
+
+	if err := validate(gameID, req); err != nil {
+		return domain.AppendRes{}, err
+	}
+
+	attempts, err := s.retrier.Do(ctx, "AppendEvents", func(ctx context.Context) error {
+		var err error
+		res, err = s.attemptAppend(ctx, gameID, req)
+		return err
+	})
+	if err != nil {
+		logging.From(ctx, s.logger).Error("UseCase.AppendEvents failed", zap.String("game_id", gameID), zap.Int("attempts", attempts), zap.Error(err))
+		return domain.AppendRes{}, err
+	}
+	if s.notifier != nil {
+		s.notifier.GameUpdated(ctx, gameID)
+	}
+
+	return res, nil
+}
+
+func (s *UseCase) attemptAppend(ctx context.Context, gameID string, req domain.AppendReq) (domain.AppendRes, error) {
+	// sequence numbers are assigned per attempt, keep the caller's events untouched
+	events := append([]domain.Event(nil), req.Events...)
+
+	tx, err := s.gameRepo.Begin(ctx)
+	if err != nil {
+		logging.From(ctx, s.logger).Error("UseCase.AppendEvents failed initiating transaction", zap.Error(err))
+		return domain.AppendRes{}, apperror.FromDB(err, nil)
+	}
+	defer tx.Rollback()
+
+	// the game row lock serializes appends to the same game
+	created := false
+	game, err := s.gameRepo.LockGame(ctx, tx, gameID)
+	switch {
+	case apperror.IsNotFound(err):
+		if req.Date.IsZero() {
+			return domain.AppendRes{}, apperror.Validation("invalid_events", "date is required for the first events of a game",
+				apperror.FieldError{Field: "date", Message: "date is required"})
+		}
+		game = game_domain.Game{ID: gameID, Date: req.Date, Source: game_domain.SourceEvents, Status: game_domain.StatusLive}
+		if err = s.gameRepo.CreateGame(ctx, tx, game); err != nil {
+			return domain.AppendRes{}, err
+		}
+		created = true
+	case err != nil:
+		return domain.AppendRes{}, err
+	}
+
+	if game.Source != game_domain.SourceEvents {
+		return domain.AppendRes{}, apperror.Conflict("game_not_event_sourced", "game was logged as a box score and does not accept events", nil)
+	}
+	switch game.Status {
+	case game_domain.StatusFinal:
+		return domain.AppendRes{}, apperror.Conflict("game_final", "game is final and cannot change", nil)
+	case game_domain.StatusVoided:
+		return domain.AppendRes{}, apperror.Conflict("game_voided", "game is voided and cannot change", nil)
+	case game_domain.StatusScheduled:
+		return domain.AppendRes{}, apperror.Conflict("game_not_live", "game is scheduled, events are accepted once it is live", nil)
+	}
+
+	// the audit entry of the first append has no before state
+	var before interface{}
+	if !created {
+		current, err := s.gameRepo.Lines(ctx, tx, gameID)
+		if err != nil {
+			return domain.AppendRes{}, err
+		}
+		before = game_domain.NewState(game, game_domain.Players(current))
+	}
+
+	seq, err := s.eventRepo.NextSeq(ctx, tx, gameID)
+	if err != nil {
+		return domain.AppendRes{}, err
+	}
+
+	// a client supplied seq must continue the log, so a replayed or racing request cannot append twice
+	for i := range events {
+		if events[i].Seq != 0 && events[i].Seq != seq {
+			return domain.AppendRes{}, apperror.Conflict("seq_conflict", fmt.Sprintf("events[%d] has seq %d, next seq is %d", i, events[i].Seq, seq), nil)
+		}
+		events[i].Seq = seq
+		seq++
+	}
+
+	if err = s.eventRepo.Append(ctx, tx, gameID, events); err != nil {
+		return domain.AppendRes{}, err
+	}
+
+	all, err := s.eventRepo.List(ctx, tx, gameID)
+	if err != nil {
+		return domain.AppendRes{}, err
+	}
+
+	lines, err := domain.Reduce(all)
+	if err != nil {
+		return domain.AppendRes{}, err
+	}
+
+	players := toPlayers(lines)
+	if err = s.gameRepo.ReplaceLines(ctx, tx, gameID, game.Date, players); err != nil {
+		return domain.AppendRes{}, err
+	}
+	if s.auditor != nil {
+		change := audit_domain.Change{Entity: audit_domain.EntityGame, EntityID: gameID, Action: audit_domain.ActionAppendEvents, Before: before, After: game_domain.NewState(game, players)}
+		if err = s.auditor.Record(ctx, tx, change); err != nil {
+			return domain.AppendRes{}, err
+		}
+	}
+
+	if err = tx.Commit(); err != nil {
+		logging.From(ctx, s.logger).Error("failed committing events", zap.Error(err))
+		return domain.AppendRes{}, retry.Permanent(apperror.FromDB(err, nil))
+	}
+
+	return domain.AppendRes{GameID: gameID, LastSeq: seq - 1, Lines: lines}, nil
+}
+
+func (s *UseCase) ListEvents(ctx context.Context, gameID string) ([]domain.Event, error) {
+	events, err := s.eventRepo.Find(ctx, gameID)
+	if err != nil {
+		logging.From(ctx, s.logger).Error("UseCase.ListEvents failed fetching events", zap.Error(err))
+		return nil, err
+	}
+
+	if len(events) == 0 {
+		return nil, apperror.NotFound("game_not_found", "game has no events", nil)
+	}
+
+	return events, nil
+}
+
+// Recompute - replays the events of play by play games and rewrites their lines, after a fix to the reducer
+// or a hand edit of the stored lines. No ids recomputes every play by play game, a failed game does not stop the others
+func (s *UseCase) Recompute(ctx context.Context, gameIDs []string) ([]domain.RecomputeResult, error) {
+	if len(gameIDs) == 0 {
+		var err error
+		if gameIDs, err = s.eventRepo.Games(ctx); err != nil {
+			return nil, err
+		}
+	}
+
+	results := make([]domain.RecomputeResult, 0, len(gameIDs))
+	for _, gameID := range gameIDs {
+		if err := ctx.Err(); err != nil {
+			return results, err
+		}
+
+		result := domain.RecomputeResult{GameID: gameID}
+		_, err := s.retrier.Do(ctx, "Recompute", func(ctx context.Context) error {
+			var err error
+			result.LastSeq, result.Lines, err = s.attemptRecompute(ctx, gameID)
+			return err
+		})
+		if err != nil {
+			logging.From(ctx, s.logger).Error("UseCase.Recompute failed", zap.String("game_id", gameID), zap.Error(err))
+			result.Error = apperror.From(err).Message
+		} else if s.notifier != nil {
+			s.notifier.GameUpdated(ctx, gameID)
+		}
+		results = append(results, result)
+	}
+
+	return results, nil
+}
+
+func (s *UseCase) attemptRecompute(ctx context.Context, gameID string) (int, int, error) {
+	tx, err := s.gameRepo.Begin(ctx)
+	if err != nil {
+		return 0, 0, apperror.FromDB(err, nil)
+	}
+	defer tx.Rollback()
+
+	game, err := s.gameRepo.LockGame(ctx, tx, gameID)
+	if err != nil {
+		return 0, 0, err
+	}
+	if game.Source != game_domain.SourceEvents {
+		return 0, 0, apperror.Conflict("game_not_event_sourced", "game was logged as a box score and has no events to replay", nil)
+	}
+
+	current, err := s.gameRepo.Lines(ctx, tx, gameID)
+	if err != nil {
+		return 0, 0, err
+	}
+	events, err := s.eventRepo.List(ctx, tx, gameID)
+	if err != nil {
+		return 0, 0, err
+	}
+	lines, err := domain.Reduce(events)
+	if err != nil {
+		return 0, 0, err
+	}
+
+	players := toPlayers(lines)
+	if err = s.gameRepo.ReplaceLines(ctx, tx, gameID, game.Date, players); err != nil {
+		return 0, 0, err
+	}
+	if s.auditor != nil {
+		change := audit_domain.Change{Entity: audit_domain.EntityGame, EntityID: gameID, Action: audit_domain.ActionRecompute,
+			Before: game_domain.NewState(game, game_domain.Players(current)), After: game_domain.NewState(game, players)}
+		if err = s.auditor.Record(ctx, tx, change); err != nil {
+			return 0, 0, err
+		}
+	}
+
+	if err = tx.Commit(); err != nil {
+		return 0, 0, retry.Permanent(apperror.FromDB(err, nil))
+	}
+
+	lastSeq := 0
+	if len(events) > 0 {
+		lastSeq = events[len(events)-1].Seq
+	}
+
+	return lastSeq, len(lines), nil
+}
+
+func validate(gameID string, req domain.AppendReq) error {
+	if gameID == "" || len(gameID) > maxGameIDLength {
+		return apperror.Validation("invalid_game_id", fmt.Sprintf("game id must be 1 to %d characters", maxGameIDLength))
+	}
+
+	if len(req.Events) == 0 {
+		return apperror.Validation("invalid_events", "at least one event is required",
+			apperror.FieldError{Field: "events", Message: "at least one event is required"})
+	}
+
+	var fields []apperror.FieldError
+	for i, event := range req.Events {
+		fields = append(fields, event.Validate(fmt.Sprintf("events[%d]", i))...)
+	}
+	if len(fields) > 0 {
+		return apperror.Validation("invalid_events", "events failed validation", fields...)
+	}
+
+	return nil
+}
+
+func toPlayers(lines []domain.PlayerLine) []game_domain.Player {
+	players := make([]game_domain.Player, 0, len(lines))
+	for _, line := range lines {
+		players = append(players, game_domain.Player{
+			ID:            line.PlayerID,
+			Points:        line.Points,
+			Rebounds:      line.Rebounds,
+			Assists:       line.Assists,
+			Steals:        line.Steals,
+			Blocks:        line.Blocks,
+			Fouls:         line.Fouls,
+			Turnovers:     line.Turnovers,
+			MinutesPlayed: line.MinutesPlayed,
+		})
+	}
+
+	return players
+}
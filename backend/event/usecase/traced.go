@@ -0,0 +1,38 @@
+package usecase
+
+import (
+	"context"
+
+	"skyhawk/backend/event/domain"
+	"skyhawk/backend/tracing"
+)
+
+// Traced - an EventUseCase with a span around every method
+type Traced struct {
+	next EventUseCase
+}
+
+func NewTraced(next EventUseCase) EventUseCase {
+	return &Traced{next: next}
+}
+
+func (t *Traced) AppendEvents(ctx context.Context, gameID string, req domain.AppendReq) (res domain.AppendRes, err error) {
+	ctx, span := tracing.Start(ctx, "event_usecase.AppendEvents")
+	defer func() { tracing.End(span, err) }()
+
+	return t.next.AppendEvents(ctx, gameID, req)
+}
+
+func (t *Traced) ListEvents(ctx context.Context, gameID string) (events []domain.Event, err error) {
+	ctx, span := tracing.Start(ctx, "event_usecase.ListEvents")
+	defer func() { tracing.End(span, err) }()
+
+	return t.next.ListEvents(ctx, gameID)
+}
+
+func (t *Traced) Recompute(ctx context.Context, gameIDs []string) (results []domain.RecomputeResult, err error) {
+	ctx, span := tracing.Start(ctx, "event_usecase.Recompute")
+	defer func() { tracing.End(span, err) }()
+
+	return t.next.Recompute(ctx, gameIDs)
+}
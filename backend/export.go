@@ -0,0 +1,85 @@
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"os/signal"
+	"syscall"
+	"time"
+
+	"go.uber.org/zap"
+
+	"skyhawk/backend/config"
+	"skyhawk/backend/export"
+	"skyhawk/backend/export/domain"
+)
+
+// runExport - the "export" subcommand, writes a dataset like the export endpoints do, to a file or stdout
+func runExport(cfg config.Config, logger *zap.Logger, args []string) error {
+	flags := flag.NewFlagSet("export", flag.ContinueOnError)
+	dataset := flags.String("dataset", string(export.DatasetGames), "games, players or teams")
+	format := flags.String("format", string(export.FormatCSV), "csv or ndjson")
+	season := flags.Int("season", 0, "the year the season starts, e.g. 2024")
+	teamID := flags.String("team", "", "id of the team to export")
+	from := flags.String("from", "", "first date, RFC3339 or YYYY-MM-DD")
+	to := flags.String("to", "", "date to stop before, RFC3339 or YYYY-MM-DD")
+	out := flags.String("out", "-", "path of the file to write, - writes stdout")
+	if err := flags.Parse(args); err != nil {
+		return err
+	}
+
+	parsedFormat, err := export.ParseFormat(*format, "")
+	if err != nil {
+		return err
+	}
+	filter := domain.Filter{Season: *season, TeamID: *teamID}
+	if filter.From, err = parseDate("from", *from); err != nil {
+		return err
+	}
+	if filter.To, err = parseDate("to", *to); err != nil {
+		return err
+	}
+
+	var w io.Writer = os.Stdout
+	if *out != "-" {
+		f, err := os.Create(*out)
+		if err != nil {
+			return err
+		}
+		defer f.Close()
+		w = f
+	}
+
+	app, err := newApp(cfg, logger)
+	if err != nil {
+		return err
+	}
+	defer app.Close()
+
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+
+	rows, err := app.exporter.Export(ctx, export.Dataset(*dataset), parsedFormat, filter, w, nil)
+	if err != nil {
+		return err
+	}
+	logger.Info("exported", zap.String("dataset", *dataset), zap.Int("rows", rows))
+
+	return nil
+}
+
+func parseDate(name, raw string) (time.Time, error) {
+	if raw == "" {
+		return time.Time{}, nil
+	}
+	for _, layout := range []string{time.RFC3339, time.DateOnly} {
+		if parsed, err := time.Parse(layout, raw); err == nil {
+			return parsed, nil
+		}
+	}
+
+	return time.Time{}, fmt.Errorf("export: -%s must be RFC3339 or YYYY-MM-DD", name)
+}
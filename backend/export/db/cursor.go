@@ -0,0 +1,46 @@
+package db
+
+import (
+	"database/sql"
+
+	"skyhawk/backend/apperror"
+)
+
+// Cursor - walks a result set one row at a time so exports never hold a whole table in memory
+type Cursor[T any] struct {
+	rows    *sql.Rows
+	scan    func(*sql.Rows) (T, error)
+	current T
+	err     error
+}
+
+func newCursor[T any](rows *sql.Rows, scan func(*sql.Rows) (T, error)) *Cursor[T] {
+	return &Cursor[T]{rows: rows, scan: scan}
+}
+
+// Next - advances to the next row, false once the rows are exhausted or a row failed to scan
+func (c *Cursor[T]) Next() bool {
+	if c.err != nil || !c.rows.Next() {
+		return false
+	}
+
+	c.current, c.err = c.scan(c.rows)
+
+	return c.err == nil
+}
+
+func (c *Cursor[T]) Value() T {
+	return c.current
+}
+
+func (c *Cursor[T]) Err() error {
+	if c.err != nil {
+		return apperror.FromDB(c.err, nil)
+	}
+
+	return apperror.FromDB(c.rows.Err(), nil)
+}
+
+func (c *Cursor[T]) Close() error {
+	return c.rows.Close()
+}
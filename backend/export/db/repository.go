@@ -0,0 +1,122 @@
+package db
+
+import (
+	"context"
+	"database/sql"
+	"strings"
+	"time"
+
+	"github.com/jmoiron/sqlx"
+	"go.uber.org/zap"
+
+	"skyhawk/backend/apperror"
+	"skyhawk/backend/export/domain"
+	game_domain "skyhawk/backend/game/domain"
+)
+
+type Repository interface {
+	StatLines(ctx context.Context, filter domain.Filter) (*Cursor[domain.StatLine], error)
+	PlayerSeasons(ctx context.Context, filter domain.Filter) (*Cursor[domain.PlayerSeason], error)
+	TeamSeasons(ctx context.Context, filter domain.Filter) (*Cursor[domain.TeamSeason], error)
+}
+
+type Repo struct {
+	db     *sqlx.DB
+	logger *zap.Logger
+}
+
+func NewRepo(db *sqlx.DB, logger *zap.Logger) Repository {
+
+	return &Repo{db: db, logger: logger}
+}
+
+// where - the conditions shared by every export, teamColumn is the team id column of the query.
+// Only final games are exported, live games are still changing
+func where(filter domain.Filter, teamColumn string) (string, []interface{}) {
+	conditions := []string{"g.status = ?"}
+	args := []interface{}{game_domain.StatusFinal}
+
+	from, to := filter.Range()
+	if !from.IsZero() {
+		conditions = append(conditions, "gs.date >= ?")
+		args = append(args, from.UTC().Format(time.DateTime))
+	}
+	if !to.IsZero() {
+		conditions = append(conditions, "gs.date < ?")
+		args = append(args, to.UTC().Format(time.DateTime))
+	}
+	if filter.TeamID != "" {
+		conditions = append(conditions, teamColumn+" = ?")
+		args = append(args, filter.TeamID)
+	}
+
+	return " where " + strings.Join(conditions, " and "), args
+}
+
+func (r *Repo) StatLines(ctx context.Context, filter domain.Filter) (*Cursor[domain.StatLine], error) {
+	conditions, args := where(filter, "p.team_id")
+	q := "select gs.game_id, gs.date, p.team_id, t.name, gs.player_id, p.name, gs.points, gs.rebounds, gs.assists, gs.steals, gs.blocks, gs.fouls, gs.turnovers, gs.minutes_played " +
+		"from game_stats gs join games g on gs.game_id = g.id join players p on gs.player_id = p.id join teams t on p.team_id = t.id" +
+		conditions + " order by gs.date, gs.game_id, p.team_id, p.name"
+
+	rows, err := r.db.QueryContext(ctx, q, args...)
+	if err != nil {
+		r.logger.Error("failed querying stat lines", zap.Error(err))
+		return nil, apperror.FromDB(err, nil)
+	}
+
+	return newCursor(rows, func(rows *sql.Rows) (domain.StatLine, error) {
+		var line domain.StatLine
+		var date string
+		if err := rows.Scan(&line.GameID, &date, &line.TeamID, &line.TeamName, &line.PlayerID, &line.PlayerName, &line.Points, &line.Rebounds, &line.Assists, &line.Steals, &line.Blocks, &line.Fouls, &line.Turnovers, &line.MinutesPlayed); err != nil {
+			return domain.StatLine{}, err
+		}
+		parsedDate, err := time.Parse(time.DateTime, date)
+		if err != nil {
+			return domain.StatLine{}, err
+		}
+		line.Date = parsedDate
+		return line, nil
+	}), nil
+}
+
+func (r *Repo) PlayerSeasons(ctx context.Context, filter domain.Filter) (*Cursor[domain.PlayerSeason], error) {
+	conditions, args := where(filter, "p.team_id")
+	q := "select p.id, p.name, p.team_id, t.name, count(distinct gs.game_id), avg(gs.points), avg(gs.rebounds), avg(gs.assists), avg(gs.steals), avg(gs.blocks), avg(gs.fouls), avg(gs.turnovers), avg(gs.minutes_played) " +
+		"from game_stats gs join games g on gs.game_id = g.id join players p on gs.player_id = p.id join teams t on p.team_id = t.id" +
+		conditions + " group by p.id, p.name, p.team_id, t.name order by t.name, p.name"
+
+	rows, err := r.db.QueryContext(ctx, q, args...)
+	if err != nil {
+		r.logger.Error("failed querying player seasons", zap.Error(err))
+		return nil, apperror.FromDB(err, nil)
+	}
+
+	return newCursor(rows, func(rows *sql.Rows) (domain.PlayerSeason, error) {
+		var season domain.PlayerSeason
+		err := rows.Scan(&season.PlayerID, &season.PlayerName, &season.TeamID, &season.TeamName, &season.GamesPlayed, &season.AvgPoints, &season.AvgRebounds, &season.AvgAssists, &season.AvgSteals, &season.AvgBlocks, &season.AvgFouls, &season.AvgTurnovers, &season.AvgMinutesPlayed)
+		return season, err
+	}), nil
+}
+
+func (r *Repo) TeamSeasons(ctx context.Context, filter domain.Filter) (*Cursor[domain.TeamSeason], error) {
+	conditions, args := where(filter, "p.team_id")
+	// sum the players of a team per game first, then average those game totals
+	q := "select tg.team_id, t.name, count(*), avg(tg.points), avg(tg.rebounds), avg(tg.assists), avg(tg.steals), avg(tg.blocks), avg(tg.fouls), avg(tg.turnovers), avg(tg.minutes_played) from (" +
+		"select p.team_id, gs.game_id, sum(gs.points) points, sum(gs.rebounds) rebounds, sum(gs.assists) assists, sum(gs.steals) steals, sum(gs.blocks) blocks, sum(gs.fouls) fouls, sum(gs.turnovers) turnovers, sum(gs.minutes_played) minutes_played " +
+		"from game_stats gs join games g on gs.game_id = g.id join players p on gs.player_id = p.id" +
+		conditions + " group by p.team_id, gs.game_id" +
+		") tg join teams t on tg.team_id = t.id group by tg.team_id, t.name order by t.name"
+
+	rows, err := r.db.QueryContext(ctx, q, args...)
+	if err != nil {
+		r.logger.Error("failed querying team seasons", zap.Error(err))
+		return nil, apperror.FromDB(err, nil)
+	}
+
+	return newCursor(rows, func(rows *sql.Rows) (domain.TeamSeason, error) {
+		var season domain.TeamSeason
+		err := rows.Scan(&season.TeamID, &season.TeamName, &season.GamesPlayed, &season.AvgPoints, &season.AvgRebounds, &season.AvgAssists, &season.AvgSteals, &season.AvgBlocks, &season.AvgFouls, &season.AvgTurnovers, &season.AvgMinutesPlayed)
+		return season, err
+	}), nil
+}
@@ -0,0 +1,121 @@
+package db
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/jmoiron/sqlx"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap/zaptest"
+
+	"skyhawk/backend/apperror"
+	"skyhawk/backend/export/domain"
+	game_domain "skyhawk/backend/game/domain"
+)
+
+func TestRepo_StatLines(t *testing.T) {
+	t.Run("season and team filters", func(t *testing.T) {
+		// Setup
+		db, dbMock := createMockDB(t)
+		repo := NewRepo(db, zaptest.NewLogger(t))
+
+		rows := sqlmock.NewRows([]string{"game_id", "date", "team_id", "name", "player_id", "name", "points", "rebounds", "assists", "steals", "blocks", "fouls", "turnovers", "minutes_played"}).
+			AddRow("g1", "2024-11-02 19:30:00", "t1", "Lakers", "p1", "LeBron James", 30, 12, 8, 2, 1, 2, 3, 38.5).
+			AddRow("g1", "2024-11-02 19:30:00", "t1", "Lakers", "p2", "Anthony Davis", 28, 10, 3, 1, 3, 2, 1, 36)
+
+		dbMock.ExpectQuery("from game_stats gs join games g on gs.game_id = g.id join players p on gs.player_id = p.id join teams t on p.team_id = t.id where g.status = \\? and gs.date >= \\? and gs.date < \\? and p.team_id = \\?").
+			WithArgs(game_domain.StatusFinal, "2024-10-01 00:00:00", "2025-10-01 00:00:00", "t1").
+			WillReturnRows(rows)
+
+		// Test
+		cursor, err := repo.StatLines(context.Background(), domain.Filter{Season: 2024, TeamID: "t1"})
+		require.NoError(t, err)
+		defer cursor.Close()
+
+		var lines []domain.StatLine
+		for cursor.Next() {
+			lines = append(lines, cursor.Value())
+		}
+
+		// Assert
+		require.NoError(t, cursor.Err())
+		require.Len(t, lines, 2)
+		assert.Equal(t, "LeBron James", lines[0].PlayerName)
+		assert.Equal(t, time.Date(2024, 11, 2, 19, 30, 0, 0, time.UTC), lines[0].Date)
+		assert.Equal(t, 38.5, lines[0].MinutesPlayed)
+		assert.NoError(t, dbMock.ExpectationsWereMet())
+	})
+
+	t.Run("bad row stops the cursor", func(t *testing.T) {
+		// Setup
+		db, dbMock := createMockDB(t)
+		repo := NewRepo(db, zaptest.NewLogger(t))
+
+		rows := sqlmock.NewRows([]string{"game_id", "date", "team_id", "name", "player_id", "name", "points", "rebounds", "assists", "steals", "blocks", "fouls", "turnovers", "minutes_played"}).
+			AddRow("g1", "not a date", "t1", "Lakers", "p1", "LeBron James", 30, 12, 8, 2, 1, 2, 3, 38.5)
+		dbMock.ExpectQuery("from game_stats gs").WillReturnRows(rows)
+
+		// Test
+		cursor, err := repo.StatLines(context.Background(), domain.Filter{})
+		require.NoError(t, err)
+		defer cursor.Close()
+
+		// Assert
+		assert.False(t, cursor.Next())
+		assert.Error(t, cursor.Err())
+	})
+
+	t.Run("query error", func(t *testing.T) {
+		// Setup
+		db, dbMock := createMockDB(t)
+		repo := NewRepo(db, zaptest.NewLogger(t))
+		dbMock.ExpectQuery("from game_stats gs").WillReturnError(errors.New("boom"))
+
+		// Test
+		cursor, err := repo.StatLines(context.Background(), domain.Filter{})
+
+		// Assert
+		assert.Nil(t, cursor)
+		assert.Equal(t, apperror.KindInternal, apperror.KindOf(err))
+	})
+}
+
+func TestRepo_TeamSeasons(t *testing.T) {
+	// Setup
+	db, dbMock := createMockDB(t)
+	repo := NewRepo(db, zaptest.NewLogger(t))
+	from := time.Date(2025, 1, 1, 0, 0, 0, 0, time.UTC)
+
+	rows := sqlmock.NewRows([]string{"team_id", "name", "games", "points", "rebounds", "assists", "steals", "blocks", "fouls", "turnovers", "minutes_played"}).
+		AddRow("t1", "Lakers", 41, 112.5, 44.1, 26.3, 7.9, 5.2, 18.4, 13.1, 240.0)
+
+	dbMock.ExpectQuery("group by p.team_id, gs.game_id\\) tg join teams t").
+		WithArgs(game_domain.StatusFinal, "2025-01-01 00:00:00").
+		WillReturnRows(rows)
+
+	// Test
+	cursor, err := repo.TeamSeasons(context.Background(), domain.Filter{From: from})
+	require.NoError(t, err)
+	defer cursor.Close()
+
+	// Assert
+	require.True(t, cursor.Next())
+	assert.Equal(t, domain.TeamSeason{TeamID: "t1", TeamName: "Lakers", GamesPlayed: 41, AvgPoints: 112.5, AvgRebounds: 44.1, AvgAssists: 26.3, AvgSteals: 7.9, AvgBlocks: 5.2, AvgFouls: 18.4, AvgTurnovers: 13.1, AvgMinutesPlayed: 240}, cursor.Value())
+	assert.False(t, cursor.Next())
+	assert.NoError(t, cursor.Err())
+}
+
+// Helper functions for creating mocks
+func createMockDB(t *testing.T) (*sqlx.DB, sqlmock.Sqlmock) {
+	db, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("Failed to create mock DB: %v", err)
+	}
+	t.Cleanup(func() { db.Close() })
+
+	return sqlx.NewDb(db, "sqlmock"), mock
+}
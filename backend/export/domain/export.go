@@ -0,0 +1,89 @@
+package domain
+
+import (
+	"time"
+)
+
+// Filter - narrows an export, zero values mean no restriction
+type Filter struct {
+	Season int
+	TeamID string
+	From   time.Time
+	To     time.Time
+}
+
+// seasonStartMonth - a season is named after the year it tips off in October and runs until the next one starts
+const seasonStartMonth = time.October
+
+// SeasonRange - the half open date range [start, end) of a season
+func SeasonRange(season int) (time.Time, time.Time) {
+	start := time.Date(season, seasonStartMonth, 1, 0, 0, 0, 0, time.UTC)
+
+	return start, start.AddDate(1, 0, 0)
+}
+
+// Range - the effective half open date range of the filter, combining season and explicit dates
+func (f Filter) Range() (time.Time, time.Time) {
+	from, to := f.From, f.To
+	if f.Season != 0 {
+		start, end := SeasonRange(f.Season)
+		if from.IsZero() || from.Before(start) {
+			from = start
+		}
+		if to.IsZero() || to.After(end) {
+			to = end
+		}
+	}
+
+	return from, to
+}
+
+// StatLine - one player's line in one game
+type StatLine struct {
+	GameID        string    `json:"game_id"`
+	Date          time.Time `json:"date"`
+	TeamID        string    `json:"team_id"`
+	TeamName      string    `json:"team_name"`
+	PlayerID      string    `json:"player_id"`
+	PlayerName    string    `json:"player_name"`
+	Points        int       `json:"points"`
+	Rebounds      int       `json:"rebounds"`
+	Assists       int       `json:"assists"`
+	Steals        int       `json:"steals"`
+	Blocks        int       `json:"blocks"`
+	Fouls         int       `json:"fouls"`
+	Turnovers     int       `json:"turnovers"`
+	MinutesPlayed float64   `json:"minutes_played"`
+}
+
+// PlayerSeason - a player's per game averages over the filtered games
+type PlayerSeason struct {
+	PlayerID         string  `json:"player_id"`
+	PlayerName       string  `json:"player_name"`
+	TeamID           string  `json:"team_id"`
+	TeamName         string  `json:"team_name"`
+	GamesPlayed      int     `json:"games_played"`
+	AvgPoints        float64 `json:"avg_points"`
+	AvgRebounds      float64 `json:"avg_rebounds"`
+	AvgAssists       float64 `json:"avg_assists"`
+	AvgSteals        float64 `json:"avg_steals"`
+	AvgBlocks        float64 `json:"avg_blocks"`
+	AvgFouls         float64 `json:"avg_fouls"`
+	AvgTurnovers     float64 `json:"avg_turnovers"`
+	AvgMinutesPlayed float64 `json:"avg_minutes_played"`
+}
+
+// TeamSeason - a team's per game averages over the filtered games, summed over its players
+type TeamSeason struct {
+	TeamID           string  `json:"team_id"`
+	TeamName         string  `json:"team_name"`
+	GamesPlayed      int     `json:"games_played"`
+	AvgPoints        float64 `json:"avg_points"`
+	AvgRebounds      float64 `json:"avg_rebounds"`
+	AvgAssists       float64 `json:"avg_assists"`
+	AvgSteals        float64 `json:"avg_steals"`
+	AvgBlocks        float64 `json:"avg_blocks"`
+	AvgFouls         float64 `json:"avg_fouls"`
+	AvgTurnovers     float64 `json:"avg_turnovers"`
+	AvgMinutesPlayed float64 `json:"avg_minutes_played"`
+}
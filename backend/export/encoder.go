@@ -0,0 +1,165 @@
+package export
+
+import (
+	"bufio"
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"io"
+	"reflect"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Format - the wire format of an export
+type Format string
+
+const (
+	FormatCSV    Format = "csv"
+	FormatNDJSON Format = "ndjson"
+)
+
+func (f Format) ContentType() string {
+	if f == FormatNDJSON {
+		return "application/x-ndjson"
+	}
+
+	return "text/csv; charset=utf-8"
+}
+
+// ParseFormat - resolves the format from an explicit value first, then from an Accept header
+func ParseFormat(explicit, accept string) (Format, error) {
+	switch strings.ToLower(explicit) {
+	case "csv":
+		return FormatCSV, nil
+	case "ndjson", "jsonl":
+		return FormatNDJSON, nil
+	case "":
+	default:
+		return "", fmt.Errorf("unsupported format %q, use csv or ndjson", explicit)
+	}
+
+	for _, mediaRange := range strings.Split(accept, ",") {
+		mediaType, _, _ := strings.Cut(strings.TrimSpace(mediaRange), ";")
+		switch strings.ToLower(mediaType) {
+		case "text/csv":
+			return FormatCSV, nil
+		case "application/x-ndjson", "application/ndjson", "application/jsonl":
+			return FormatNDJSON, nil
+		}
+	}
+
+	return FormatCSV, nil
+}
+
+// encoder - writes records of one type, flushing is left to the caller so rows reach the client as they are read
+type encoder interface {
+	Encode(record interface{}) error
+	Flush() error
+}
+
+func newEncoder(format Format, w io.Writer, sample interface{}) encoder {
+	if format == FormatNDJSON {
+		buffered := bufio.NewWriter(w)
+		return &ndjsonEncoder{buffered: buffered, encoder: json.NewEncoder(buffered)}
+	}
+
+	return &csvEncoder{writer: csv.NewWriter(w), columns: columnsOf(reflect.TypeOf(sample))}
+}
+
+type ndjsonEncoder struct {
+	buffered *bufio.Writer
+	encoder  *json.Encoder
+}
+
+func (n *ndjsonEncoder) Encode(record interface{}) error {
+	return n.encoder.Encode(record)
+}
+
+func (n *ndjsonEncoder) Flush() error {
+	return n.buffered.Flush()
+}
+
+type column struct {
+	name  string
+	index int
+}
+
+type csvEncoder struct {
+	writer        *csv.Writer
+	columns       []column
+	headerWritten bool
+	record        []string
+}
+
+// columnsOf - the csv columns of a record type are its json field names, in declaration order
+func columnsOf(t reflect.Type) []column {
+	var columns []column
+	for i := 0; i < t.NumField(); i++ {
+		name, _, _ := strings.Cut(t.Field(i).Tag.Get("json"), ",")
+		if name == "" || name == "-" {
+			continue
+		}
+		columns = append(columns, column{name: name, index: i})
+	}
+
+	return columns
+}
+
+func (c *csvEncoder) writeHeader() error {
+	if c.headerWritten {
+		return nil
+	}
+	c.headerWritten = true
+
+	header := make([]string, len(c.columns))
+	for i, col := range c.columns {
+		header[i] = col.name
+	}
+
+	return c.writer.Write(header)
+}
+
+func (c *csvEncoder) Encode(record interface{}) error {
+	if err := c.writeHeader(); err != nil {
+		return err
+	}
+
+	value := reflect.ValueOf(record)
+	c.record = c.record[:0]
+	for _, col := range c.columns {
+		c.record = append(c.record, formatValue(value.Field(col.index)))
+	}
+
+	return c.writer.Write(c.record)
+}
+
+func (c *csvEncoder) Flush() error {
+	// an empty export still carries its header
+	if err := c.writeHeader(); err != nil {
+		return err
+	}
+	c.writer.Flush()
+
+	return c.writer.Error()
+}
+
+func formatValue(v reflect.Value) string {
+	switch v.Kind() {
+	case reflect.String:
+		return v.String()
+	case reflect.Int, reflect.Int64, reflect.Int32:
+		return strconv.FormatInt(v.Int(), 10)
+	case reflect.Float64, reflect.Float32:
+		return strconv.FormatFloat(v.Float(), 'f', -1, 64)
+	case reflect.Bool:
+		return strconv.FormatBool(v.Bool())
+	}
+
+	if t, ok := v.Interface().(time.Time); ok {
+		return t.UTC().Format(time.RFC3339)
+	}
+
+	return fmt.Sprint(v.Interface())
+}
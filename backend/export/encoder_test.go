@@ -0,0 +1,89 @@
+package export
+
+import (
+	"bytes"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"skyhawk/backend/export/domain"
+)
+
+func TestParseFormat(t *testing.T) {
+	cases := []struct {
+		explicit, accept string
+		want             Format
+	}{
+		{"", "", FormatCSV},
+		{"ndjson", "text/csv", FormatNDJSON},
+		{"", "application/x-ndjson", FormatNDJSON},
+		{"", "application/json, text/csv;q=0.9", FormatCSV},
+		{"CSV", "", FormatCSV},
+	}
+
+	for _, c := range cases {
+		got, err := ParseFormat(c.explicit, c.accept)
+		require.NoError(t, err)
+		assert.Equal(t, c.want, got, "format=%q accept=%q", c.explicit, c.accept)
+	}
+
+	_, err := ParseFormat("parquet", "")
+	assert.Error(t, err)
+}
+
+func TestEncoders(t *testing.T) {
+	line := domain.StatLine{
+		GameID:        "g1",
+		Date:          time.Date(2025, 3, 8, 19, 30, 0, 0, time.UTC),
+		TeamID:        "t1",
+		TeamName:      "Lakers",
+		PlayerID:      "p1",
+		PlayerName:    "James, LeBron",
+		Points:        30,
+		MinutesPlayed: 38.5,
+	}
+
+	t.Run("csv", func(t *testing.T) {
+		// Setup
+		var buf bytes.Buffer
+		enc := newEncoder(FormatCSV, &buf, domain.StatLine{})
+
+		// Test
+		require.NoError(t, enc.Encode(line))
+		require.NoError(t, enc.Flush())
+
+		// Assert
+		assert.Equal(t, "game_id,date,team_id,team_name,player_id,player_name,points,rebounds,assists,steals,blocks,fouls,turnovers,minutes_played\n"+
+			"g1,2025-03-08T19:30:00Z,t1,Lakers,p1,\"James, LeBron\",30,0,0,0,0,0,0,38.5\n", buf.String())
+	})
+
+	t.Run("csv without rows keeps the header", func(t *testing.T) {
+		// Setup
+		var buf bytes.Buffer
+		enc := newEncoder(FormatCSV, &buf, domain.TeamSeason{})
+
+		// Test
+		require.NoError(t, enc.Flush())
+
+		// Assert
+		assert.Equal(t, "team_id,team_name,games_played,avg_points,avg_rebounds,avg_assists,avg_steals,avg_blocks,avg_fouls,avg_turnovers,avg_minutes_played\n", buf.String())
+	})
+
+	t.Run("ndjson", func(t *testing.T) {
+		// Setup
+		var buf bytes.Buffer
+		enc := newEncoder(FormatNDJSON, &buf, domain.StatLine{})
+
+		// Test
+		require.NoError(t, enc.Encode(line))
+		require.NoError(t, enc.Encode(line))
+		require.NoError(t, enc.Flush())
+
+		// Assert
+		lines := bytes.Split(bytes.TrimSpace(buf.Bytes()), []byte("\n"))
+		assert.Len(t, lines, 2)
+		assert.Contains(t, string(lines[0]), `"player_name":"James, LeBron"`)
+	})
+}
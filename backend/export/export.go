@@ -0,0 +1,109 @@
+package export
+
+import (
+	"context"
+	"fmt"
+	"io"
+
+	"go.uber.org/zap"
+
+	"skyhawk/backend/apperror"
+	"skyhawk/backend/export/db"
+	"skyhawk/backend/export/domain"
+)
+
+// Dataset - what is being exported
+type Dataset string
+
+const (
+	DatasetGames   Dataset = "games"
+	DatasetPlayers Dataset = "players"
+	DatasetTeams   Dataset = "teams"
+)
+
+// flushEvery - how many rows are buffered before they are pushed to the client
+const flushEvery = 500
+
+// Flusher - implemented by writers that can push buffered bytes to the client, like http.ResponseWriter
+type Flusher interface {
+	Flush()
+}
+
+type Exporter struct {
+	repo   db.Repository
+	logger *zap.Logger
+}
+
+func New(repo db.Repository, logger *zap.Logger) *Exporter {
+
+	return &Exporter{repo: repo, logger: logger}
+}
+
+// Export - streams a dataset to w. start is called once the query succeeded and before the first byte is written,
+// so callers can still fail cleanly on a bad query and commit headers only when data is on its way.
+func (e *Exporter) Export(ctx context.Context, dataset Dataset, format Format, filter domain.Filter, w io.Writer, start func() error) (int, error) {
+	switch dataset {
+	case DatasetGames:
+		cursor, err := e.repo.StatLines(ctx, filter)
+		if err != nil {
+			return 0, err
+		}
+		return stream(cursor, format, w, start, domain.StatLine{})
+	case DatasetPlayers:
+		cursor, err := e.repo.PlayerSeasons(ctx, filter)
+		if err != nil {
+			return 0, err
+		}
+		return stream(cursor, format, w, start, domain.PlayerSeason{})
+	case DatasetTeams:
+		cursor, err := e.repo.TeamSeasons(ctx, filter)
+		if err != nil {
+			return 0, err
+		}
+		return stream(cursor, format, w, start, domain.TeamSeason{})
+	}
+
+	return 0, apperror.Validation("invalid_dataset", fmt.Sprintf("unknown dataset %q", dataset))
+}
+
+func stream[T any](cursor *db.Cursor[T], format Format, w io.Writer, start func() error, sample T) (int, error) {
+	defer cursor.Close()
+
+	if start != nil {
+		if err := start(); err != nil {
+			return 0, err
+		}
+	}
+
+	enc := newEncoder(format, w, sample)
+	flusher, _ := w.(Flusher)
+	rows := 0
+
+	for cursor.Next() {
+		if err := enc.Encode(cursor.Value()); err != nil {
+			return rows, err
+		}
+		rows++
+
+		if rows%flushEvery == 0 {
+			if err := enc.Flush(); err != nil {
+				return rows, err
+			}
+			if flusher != nil {
+				flusher.Flush()
+			}
+		}
+	}
+	if err := cursor.Err(); err != nil {
+		return rows, err
+	}
+
+	if err := enc.Flush(); err != nil {
+		return rows, err
+	}
+	if flusher != nil {
+		flusher.Flush()
+	}
+
+	return rows, nil
+}
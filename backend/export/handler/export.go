@@ -0,0 +1,111 @@
+package handler
+
+import (
+	"fmt"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/labstack/echo/v4"
+	"go.uber.org/zap"
+
+	"skyhawk/backend/apperror"
+	"skyhawk/backend/export"
+	"skyhawk/backend/export/domain"
+)
+
+type Handler struct {
+	exporter *export.Exporter
+	logger   *zap.Logger
+}
+
+func NewHandler(exporter *export.Exporter, logger *zap.Logger) *Handler {
+	return &Handler{exporter: exporter, logger: logger}
+}
+
+func (h *Handler) GamesExportHandler(c echo.Context) error {
+	return h.export(c, export.DatasetGames)
+}
+
+func (h *Handler) PlayersExportHandler(c echo.Context) error {
+	return h.export(c, export.DatasetPlayers)
+}
+
+func (h *Handler) TeamsExportHandler(c echo.Context) error {
+	return h.export(c, export.DatasetTeams)
+}
+
+func (h *Handler) export(c echo.Context, dataset export.Dataset) error {
+	format, err := export.ParseFormat(c.QueryParam("format"), c.Request().Header.Get(echo.HeaderAccept))
+	if err != nil {
+		return apperror.Validation("invalid_format", err.Error())
+	}
+
+	filter, err := parseFilter(c)
+	if err != nil {
+		return err
+	}
+
+	res := c.Response()
+	start := func() error {
+		res.Header().Set(echo.HeaderContentType, format.ContentType())
+		res.Header().Set(echo.HeaderContentDisposition, fmt.Sprintf("attachment; filename=%q", fileName(dataset, format, filter)))
+		res.WriteHeader(http.StatusOK)
+		return nil
+	}
+
+	rows, err := h.exporter.Export(c.Request().Context(), dataset, format, filter, res, start)
+	if err != nil && res.Committed {
+		// the status line is already out, all that is left is to cut the stream short
+		h.logger.Error("export interrupted", zap.String("dataset", string(dataset)), zap.Int("rows", rows), zap.Error(err))
+		return nil
+	}
+
+	return err
+}
+
+func parseFilter(c echo.Context) (domain.Filter, error) {
+	var filter domain.Filter
+	var fields []apperror.FieldError
+
+	if season := c.QueryParam("season"); season != "" {
+		parsed, err := strconv.Atoi(season)
+		if err != nil || parsed < 1900 || parsed > 3000 {
+			fields = append(fields, apperror.FieldError{Field: "season", Message: "season must be the year it starts, e.g. 2024"})
+		}
+		filter.Season = parsed
+	}
+
+	filter.TeamID = c.QueryParam("team_id")
+
+	parseDate := func(name string) time.Time {
+		raw := c.QueryParam(name)
+		if raw == "" {
+			return time.Time{}
+		}
+		for _, layout := range []string{time.RFC3339, time.DateOnly} {
+			if parsed, err := time.Parse(layout, raw); err == nil {
+				return parsed
+			}
+		}
+		fields = append(fields, apperror.FieldError{Field: name, Message: "date must be RFC3339 or YYYY-MM-DD"})
+		return time.Time{}
+	}
+	filter.From = parseDate("from")
+	filter.To = parseDate("to")
+
+	if len(fields) > 0 {
+		return domain.Filter{}, apperror.Validation("invalid_filter", "export filter is invalid", fields...)
+	}
+
+	return filter, nil
+}
+
+func fileName(dataset export.Dataset, format export.Format, filter domain.Filter) string {
+	name := string(dataset)
+	if filter.Season != 0 {
+		name = fmt.Sprintf("%s-%d", name, filter.Season)
+	}
+
+	return name + "." + string(format)
+}
@@ -0,0 +1,73 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"flag"
+	"os"
+	"time"
+
+	"go.uber.org/zap"
+
+	audit_domain "skyhawk/backend/audit/domain"
+	"skyhawk/backend/config"
+	"skyhawk/backend/stream"
+	stream_domain "skyhawk/backend/stream/domain"
+)
+
+// runGame - the "game" subcommand: void takes a game out of every season stat, like POST /games/:id/void
+func runGame(cfg config.Config, logger *zap.Logger, args []string) error {
+	if len(args) == 0 || args[0] != "void" {
+		return errors.New("game: expected void")
+	}
+
+	flags := flag.NewFlagSet("game void", flag.ContinueOnError)
+	id := flags.String("id", "", "id of the game to void")
+	if err := flags.Parse(args[1:]); err != nil {
+		return err
+	}
+	if *id == "" {
+		return errors.New("game void: -id is required")
+	}
+
+	app, err := newApp(cfg, logger)
+	if err != nil {
+		return err
+	}
+	defer app.Close()
+
+	ctx := audit_domain.WithMeta(context.Background(), audit_domain.Meta{Actor: "cli:game"})
+	game, err := app.service.VoidGame(ctx, *id)
+	if err != nil {
+		return err
+	}
+	announceVoid(ctx, cfg, app, logger, game.ID)
+
+	encoder := json.NewEncoder(os.Stdout)
+	encoder.SetIndent("", "  ")
+
+	return encoder.Encode(game)
+}
+
+// announceVoid - publishes the voided game on the backplane, so the servers end its live feeds.
+// The void is committed by now, a failure here only leaves the feeds open until their clients leave
+func announceVoid(ctx context.Context, cfg config.Config, app *app, logger *zap.Logger, gameID string) {
+	if cfg.Stream.Backplane != "redis" {
+		logger.Warn("live feeds of the game stay open, servers only hear of a void from the command with STREAM_BACKPLANE=redis", zap.String("game_id", gameID))
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, 5*time.Second)
+	defer cancel()
+
+	box, err := app.service.GetBoxScore(ctx, gameID)
+	if err != nil {
+		logger.Warn("failed loading the voided game for live feeds", zap.String("game_id", gameID), zap.Error(err))
+		return
+	}
+	backplane := stream.NewRedisBackplane(app.redis, gameUpdatesChannel, logger)
+	if err = backplane.Publish(ctx, stream_domain.Update{GameID: gameID, BoxScore: box, At: time.Now().UTC()}); err != nil {
+		logger.Warn("failed publishing the voided game to live feeds", zap.String("game_id", gameID), zap.Error(err))
+	}
+}
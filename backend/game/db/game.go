@@ -4,6 +4,7 @@ type GameStatsDB struct {
 	ID            string  `db:"id"`
 	Name          string  `db:"name"`
 	PlayerID      string  `db:"player_id"`
+	TeamID        string  `db:"team_id"`
 	Date          string  `db:"date"`
 	Points        int     `db:"points"`
 	Rebounds      int     `db:"rebounds"`
@@ -13,4 +14,5 @@ type GameStatsDB struct {
 	Fouls         int     `db:"fouls"`
 	Turnovers     int     `db:"turnovers"`
 	MinutesPlayed float64 `db:"minutes_played"`
+	Status        string  `db:"status"`
 }
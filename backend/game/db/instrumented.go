@@ -0,0 +1,95 @@
+package db
+
+import (
+	"context"
+	"database/sql"
+	"time"
+
+	"skyhawk/backend/game/domain"
+	"skyhawk/backend/metrics"
+	"skyhawk/backend/tracing"
+)
+
+// Instrumented - a Repo tracing every method and recording its latency and outcome
+type Instrumented struct {
+	next    Repo
+	metrics *metrics.Metrics
+}
+
+func NewInstrumented(next Repo, metrics *metrics.Metrics) Repo {
+	return &Instrumented{next: next, metrics: metrics}
+}
+
+// observe - a span and a latency observation around method, done ends both
+func (i *Instrumented) observe(ctx context.Context, method string) (context.Context, func(err error)) {
+	ctx, span := tracing.Start(ctx, "game_db."+method)
+	ctx, done := i.metrics.Query(ctx, "game", method)
+
+	return ctx, func(err error) {
+		done(err)
+		tracing.End(span, err)
+	}
+}
+
+func (i *Instrumented) Save(ctx context.Context, tx *sql.Tx, game domain.GameStatsReq) (id string, err error) {
+	ctx, done := i.observe(ctx, "Save")
+	defer func() { done(err) }()
+
+	return i.next.Save(ctx, tx, game)
+}
+
+func (i *Instrumented) Find(ctx context.Context, gameId string) (lines []domain.GameStats, err error) {
+	ctx, done := i.observe(ctx, "Find")
+	defer func() { done(err) }()
+
+	return i.next.Find(ctx, gameId)
+}
+
+func (i *Instrumented) Begin(ctx context.Context) (tx *sql.Tx, err error) {
+	ctx, done := i.observe(ctx, "Begin")
+	defer func() { done(err) }()
+
+	return i.next.Begin(ctx)
+}
+
+func (i *Instrumented) CreateGame(ctx context.Context, tx *sql.Tx, game domain.Game) (err error) {
+	ctx, done := i.observe(ctx, "CreateGame")
+	defer func() { done(err) }()
+
+	return i.next.CreateGame(ctx, tx, game)
+}
+
+func (i *Instrumented) FindGame(ctx context.Context, id string) (game domain.Game, err error) {
+	ctx, done := i.observe(ctx, "FindGame")
+	defer func() { done(err) }()
+
+	return i.next.FindGame(ctx, id)
+}
+
+func (i *Instrumented) LockGame(ctx context.Context, tx *sql.Tx, id string) (game domain.Game, err error) {
+	ctx, done := i.observe(ctx, "LockGame")
+	defer func() { done(err) }()
+
+	return i.next.LockGame(ctx, tx, id)
+}
+
+func (i *Instrumented) SetStatus(ctx context.Context, tx *sql.Tx, id string, status domain.Status) (err error) {
+	ctx, done := i.observe(ctx, "SetStatus")
+	defer func() { done(err) }()
+
+	return i.next.SetStatus(ctx, tx, id, status)
+}
+
+func (i *Instrumented) Lines(ctx context.Context, tx *sql.Tx, gameID string) (lines []domain.GameStats, err error) {
+	ctx, done := i.observe(ctx, "Lines")
+	defer func() { done(err) }()
+
+	return i.next.Lines(ctx, tx, gameID)
+}
+
+func (i *Instrumented) ReplaceLines(ctx context.Context, tx *sql.Tx, gameID string, date time.Time, players []domain.Player) (err error) {
+	ctx, done := i.observe(ctx, "ReplaceLines")
+	defer func() { done(err) }()
+
+	return i.next.ReplaceLines(ctx, tx, gameID, date, players)
+}
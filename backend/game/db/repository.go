@@ -1,6 +1,7 @@
 package db
 
 import (
+	"context"
 	"database/sql"
 	"fmt"
 	"strings"
@@ -10,7 +11,9 @@ import (
 	"github.com/jmoiron/sqlx"
 	"go.uber.org/zap"
 
+	"skyhawk/backend/apperror"
 	"skyhawk/backend/game/domain"
+	"skyhawk/backend/logging"
 )
 
 type Repository struct {
@@ -19,9 +22,15 @@ type Repository struct {
 }
 
 type Repo interface {
-	Save(tx *sql.Tx, game domain.GameStatsReq) (string, error)
-	Find(gameId string) ([]domain.GameStats, error)
-	Begin() (*sql.Tx, error)
+	Save(ctx context.Context, tx *sql.Tx, game domain.GameStatsReq) (string, error)
+	Find(ctx context.Context, gameId string) ([]domain.GameStats, error)
+	Begin(ctx context.Context) (*sql.Tx, error)
+	CreateGame(ctx context.Context, tx *sql.Tx, game domain.Game) error
+	FindGame(ctx context.Context, id string) (domain.Game, error)
+	LockGame(ctx context.Context, tx *sql.Tx, id string) (domain.Game, error)
+	SetStatus(ctx context.Context, tx *sql.Tx, id string, status domain.Status) error
+	Lines(ctx context.Context, tx *sql.Tx, gameID string) ([]domain.GameStats, error)
+	ReplaceLines(ctx context.Context, tx *sql.Tx, gameID string, date time.Time, players []domain.Player) error
 }
 
 func NewRepo(db *sqlx.DB, logger *zap.Logger) Repo {
@@ -29,56 +38,163 @@ func NewRepo(db *sqlx.DB, logger *zap.Logger) Repo {
 	return &Repository{db: db, logger: logger}
 }
 
-func (g *Repository) Begin() (*sql.Tx, error) {
+func (g *Repository) Begin(ctx context.Context) (*sql.Tx, error) {
 
-	return g.db.Begin()
+	return g.db.BeginTx(ctx, nil)
 }
 
-func (g *Repository) Save(tx *sql.Tx, game domain.GameStatsReq) (string, error) {
-	var placeHolders []string
-	var values []interface{}
+func (g *Repository) Save(ctx context.Context, tx *sql.Tx, game domain.GameStatsReq) (string, error) {
 	gameId := uuid.New().String()
 
-	// Check if we have any players to insert before proceeding
-	playerCount := 0
+	var players []domain.Player
 	for _, team := range game.Teams {
-		playerCount += len(team.Players)
+		players = append(players, team.Players...)
 	}
 
-	if playerCount == 0 {
+	// the games row is inserted even without lines, the returned id always names a game that exists
+	// only games of an origin are keyed, the id of a game logged on its own is the client's business
+	var origin, key string
+	if game.Origin != "" && game.ID != "" {
+		origin, key = game.Origin, game.ID
+	}
+	if err := g.insertGame(ctx, tx, domain.Game{ID: gameId, Date: game.Date, Source: domain.SourceBoxScore, Status: domain.StatusFinal}, origin, key); err != nil {
+		if key != "" && apperror.From(err).Code == "duplicate_entry" {
+			return "", apperror.Conflict("game_already_logged", fmt.Sprintf("%s already holds a game with key %q", origin, key), err)
+		}
+		return "", err
+	}
+
+	if len(players) == 0 {
 		return gameId, nil
 	}
+	if err := g.insertLines(ctx, tx, gameId, game.Date, players); err != nil {
+		return "", err
+	}
+	return gameId, nil
+}
 
-	for _, team := range game.Teams {
-		for _, player := range team.Players {
-			id := uuid.New().String()
-			placeHolders = append(placeHolders, "(?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?)")
-			values = append(values, id, gameId, player.ID, game.Date, player.Points, player.Rebounds, player.Assists, player.Steals, player.Blocks, player.Fouls, player.Turnovers, player.MinutesPlayed)
-		}
+// CreateGame - inserts the games row, stat lines reference it by game id
+func (g *Repository) CreateGame(ctx context.Context, tx *sql.Tx, game domain.Game) error {
+	return g.insertGame(ctx, tx, game, "", "")
+}
+
+// insertGame - key is the game's id in origin, the bulk source it came from. Empty stores NULL so any number of games may lack one
+func (g *Repository) insertGame(ctx context.Context, tx *sql.Tx, game domain.Game, origin, key string) error {
+	_, err := tx.ExecContext(ctx, "INSERT INTO games (id, date, source, status, origin, external_key) VALUES (?, ?, ?, ?, ?, ?)",
+		game.ID, game.Date, game.Source, game.Status, sql.NullString{String: origin, Valid: origin != ""}, sql.NullString{String: key, Valid: key != ""})
+	if err != nil {
+		logging.From(ctx, g.logger).Error("failed inserting game", zap.Error(err))
+		return apperror.FromDB(err, nil)
+	}
+
+	return nil
+}
+
+func (g *Repository) FindGame(ctx context.Context, id string) (domain.Game, error) {
+	row := g.db.QueryRowContext(ctx, "SELECT id, date, source, status FROM games WHERE id = ?", id)
+
+	return scanGame(row)
+}
+
+// LockGame - reads a game and holds its row lock until tx ends, serializing writers of the same game
+func (g *Repository) LockGame(ctx context.Context, tx *sql.Tx, id string) (domain.Game, error) {
+	row := tx.QueryRowContext(ctx, "SELECT id, date, source, status FROM games WHERE id = ? FOR UPDATE", id)
+
+	return scanGame(row)
+}
+
+func scanGame(row *sql.Row) (domain.Game, error) {
+	var game domain.Game
+	var date string
+
+	if err := row.Scan(&game.ID, &date, &game.Source, &game.Status); err != nil {
+		return domain.Game{}, apperror.FromDB(err, apperror.NotFound("game_not_found", "game not found", nil))
+	}
+
+	parsedDate, err := time.Parse(time.DateTime, date)
+	if err != nil {
+		return domain.Game{}, apperror.Internal(err)
+	}
+	game.Date = parsedDate
+
+	return game, nil
+}
+
+// SetStatus - a voided game gives up its origin key, so the game can be imported again
+func (g *Repository) SetStatus(ctx context.Context, tx *sql.Tx, id string, status domain.Status) error {
+	q := "UPDATE games SET status = ? WHERE id = ?"
+	if status == domain.StatusVoided {
+		q = "UPDATE games SET status = ?, origin = NULL, external_key = NULL WHERE id = ?"
+	}
+	if _, err := tx.ExecContext(ctx, q, status, id); err != nil {
+		logging.From(ctx, g.logger).Error("failed updating game status", zap.Error(err))
+		return apperror.FromDB(err, nil)
+	}
+
+	return nil
+}
+
+// Lines - the current stat lines of a game, read inside tx so they match the locked game
+func (g *Repository) Lines(ctx context.Context, tx *sql.Tx, gameID string) ([]domain.GameStats, error) {
+	return g.find(ctx, tx, gameID)
+}
+
+// ReplaceLines - swaps every stat line of a game for players, keeping the game id
+func (g *Repository) ReplaceLines(ctx context.Context, tx *sql.Tx, gameID string, date time.Time, players []domain.Player) error {
+	if _, err := tx.ExecContext(ctx, "DELETE FROM game_stats WHERE game_id = ?", gameID); err != nil {
+		logging.From(ctx, g.logger).Error("failed deleting stats", zap.Error(err))
+		return apperror.FromDB(err, nil)
+	}
+
+	if len(players) == 0 {
+		return nil
+	}
+
+	return g.insertLines(ctx, tx, gameID, date, players)
+}
+
+func (g *Repository) insertLines(ctx context.Context, tx *sql.Tx, gameID string, date time.Time, players []domain.Player) error {
+	placeHolders := make([]string, 0, len(players))
+	values := make([]interface{}, 0, len(players)*12)
+
+	for _, player := range players {
+		id := uuid.New().String()
+		placeHolders = append(placeHolders, "(?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?)")
+		values = append(values, id, gameID, player.ID, date, player.Points, player.Rebounds, player.Assists, player.Steals, player.Blocks, player.Fouls, player.Turnovers, player.MinutesPlayed)
 	}
 
 	q := fmt.Sprintf("INSERT INTO game_stats (id, game_id, player_id, date, points, rebounds, assists, steals, blocks, fouls, turnovers, minutes_played) values %s", strings.Join(placeHolders, ","))
-	_, err := tx.Exec(q, values...)
+	_, err := tx.ExecContext(ctx, q, values...)
 	if err != nil {
-		g.logger.Error("failed inserting stats", zap.Error(err))
-		return "", err
+		logging.From(ctx, g.logger).Error("failed inserting stats", zap.Error(err))
+		return apperror.FromDB(err, nil)
 	}
-	return gameId, nil
+
+	return nil
 }
 
-func (g *Repository) Find(id string) ([]domain.GameStats, error) {
+func (g *Repository) Find(ctx context.Context, id string) ([]domain.GameStats, error) {
+	return g.find(ctx, g.db, id)
+}
+
+// queryer - satisfied by both *sqlx.DB and *sql.Tx
+type queryer interface {
+	QueryContext(ctx context.Context, query string, args ...interface{}) (*sql.Rows, error)
+}
+
+func (g *Repository) find(ctx context.Context, q queryer, id string) ([]domain.GameStats, error) {
 	var result []domain.GameStats
 	// Fix: Changed game*id to game_id
-	row, err := g.db.Query("select g.game_id, g.player_id, p.name, g.date, g.points, g.rebounds, g.assists, g.steals, g.blocks, g.fouls, g.turnovers, g.minutes_played from game_stats g join players p on player_id = p.id where game_id =? ", id)
+	row, err := q.QueryContext(ctx, "select g.game_id, g.player_id, p.name, p.team_id, g.date, g.points, g.rebounds, g.assists, g.steals, g.blocks, g.fouls, g.turnovers, g.minutes_played, gm.status from game_stats g join players p on player_id = p.id join games gm on g.game_id = gm.id where game_id =? ", id)
 	if err != nil {
-		return nil, err
+		return nil, apperror.FromDB(err, nil)
 	}
 	defer row.Close() // Add this to ensure resources are properly released
 
 	for row.Next() {
 		gameDB := GameStatsDB{}
-		if err = row.Scan(&gameDB.ID, &gameDB.PlayerID, &gameDB.Name, &gameDB.Date, &gameDB.Points, &gameDB.Rebounds, &gameDB.Assists, &gameDB.Steals, &gameDB.Blocks, &gameDB.Fouls, &gameDB.Turnovers, &gameDB.MinutesPlayed); err != nil {
-			return nil, err
+		if err = row.Scan(&gameDB.ID, &gameDB.PlayerID, &gameDB.Name, &gameDB.TeamID, &gameDB.Date, &gameDB.Points, &gameDB.Rebounds, &gameDB.Assists, &gameDB.Steals, &gameDB.Blocks, &gameDB.Fouls, &gameDB.Turnovers, &gameDB.MinutesPlayed, &gameDB.Status); err != nil {
+			return nil, apperror.FromDB(err, nil)
 		}
 		game, err := toDomain(gameDB)
 		if err != nil {
@@ -86,6 +202,9 @@ func (g *Repository) Find(id string) ([]domain.GameStats, error) {
 		}
 		result = append(result, game)
 	}
+	if err = row.Err(); err != nil {
+		return nil, apperror.FromDB(err, nil)
+	}
 	return result, nil
 }
 
@@ -101,6 +220,7 @@ func toDomain(db GameStatsDB) (domain.GameStats, error) {
 		Date:          parsedDate,
 		Name:          db.Name,
 		PlayerID:      db.PlayerID,
+		TeamID:        db.TeamID,
 		Points:        db.Points,
 		Steals:        db.Steals,
 		Fouls:         db.Fouls,
@@ -109,5 +229,6 @@ func toDomain(db GameStatsDB) (domain.GameStats, error) {
 		Assists:       db.Assists,
 		MinutesPlayed: db.MinutesPlayed,
 		Rebounds:      db.Rebounds,
+		Status:        domain.Status(db.Status),
 	}, nil
 }
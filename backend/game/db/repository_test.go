@@ -1,6 +1,7 @@
 package db
 
 import (
+	"context"
 	"database/sql"
 	"github.com/google/uuid"
 	"github.com/stretchr/testify/require"
@@ -8,10 +9,12 @@ import (
 	"time"
 
 	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/go-sql-driver/mysql"
 	"github.com/jmoiron/sqlx"
 	"github.com/stretchr/testify/assert"
 	"go.uber.org/zap/zaptest"
 
+	"skyhawk/backend/apperror"
 	"skyhawk/backend/game/domain"
 )
 
@@ -25,7 +28,7 @@ func TestRepository_Begin(t *testing.T) {
 	dbMock.ExpectBegin()
 
 	// Test
-	tx, err := repo.Begin()
+	tx, err := repo.Begin(context.Background())
 
 	// Assert
 	assert.NoError(t, err)
@@ -47,7 +50,9 @@ func TestRepository_Save(t *testing.T) {
 		// Prepare test data
 		gameDate := time.Now()
 		gameReq := domain.GameStatsReq{
-			Date: gameDate,
+			ID:     "s1-0001",
+			Origin: "generate",
+			Date:   gameDate,
 			Teams: []domain.Team{
 				{
 					ID: "team1",
@@ -95,13 +100,18 @@ func TestRepository_Save(t *testing.T) {
 			},
 		}
 
+		// The games row is written first, with the key the game has in its origin
+		dbMock.ExpectExec("INSERT INTO games").
+			WithArgs(sqlmock.AnyArg(), gameDate, domain.SourceBoxScore, domain.StatusFinal, "generate", "s1-0001").
+			WillReturnResult(sqlmock.NewResult(1, 1))
+
 		// Set up expectations for the INSERT statement
 		// Using a generic regex for the INSERT statement to avoid strict matching
 		dbMock.ExpectExec("INSERT INTO game_stats").
 			WillReturnResult(sqlmock.NewResult(1, 3)) // 3 rows affected (3 players)
 
 		// Test
-		gameID, err := repo.Save(tx, gameReq)
+		gameID, err := repo.Save(context.Background(), tx, gameReq)
 
 		// Assert
 		assert.NoError(t, err)
@@ -143,18 +153,82 @@ func TestRepository_Save(t *testing.T) {
 			},
 		}
 
+		dbMock.ExpectExec("INSERT INTO games").
+			WillReturnResult(sqlmock.NewResult(1, 1))
+
 		// Set up expectations for the INSERT statement to fail
 		dbMock.ExpectExec("INSERT INTO game_stats").
 			WillReturnError(sql.ErrConnDone)
 
 		// Test
-		gameID, err := repo.Save(tx, gameReq)
+		gameID, err := repo.Save(context.Background(), tx, gameReq)
 
 		// Assert
 		assert.Error(t, err)
 		assert.Empty(t, gameID)
 	})
 
+	t.Run("key already logged", func(t *testing.T) {
+		// Setup
+		db, dbMock, _ := createMockDB(t)
+		repo := NewRepo(db, zaptest.NewLogger(t))
+
+		dbMock.ExpectBegin()
+		tx, err := db.Begin()
+		require.NoError(t, err)
+
+		gameReq := domain.GameStatsReq{
+			ID:     "s1-0001",
+			Origin: "import",
+			Date:   time.Now(),
+			Teams:  []domain.Team{{ID: "team1", Players: []domain.Player{{ID: "player1", Points: 20}}}},
+		}
+
+		dbMock.ExpectExec("INSERT INTO games").
+			WillReturnError(&mysql.MySQLError{Number: 1062, Message: "Duplicate entry 'import-s1-0001' for key 'games_origin_key'"})
+
+		// Test
+		gameID, err := repo.Save(context.Background(), tx, gameReq)
+
+		// Assert
+		require.Error(t, err)
+		assert.Empty(t, gameID)
+		assert.Equal(t, apperror.KindConflict, apperror.KindOf(err))
+		assert.Equal(t, "game_already_logged", apperror.From(err).Code)
+		assert.NoError(t, dbMock.ExpectationsWereMet())
+	})
+
+	t.Run("id without origin is not keyed", func(t *testing.T) {
+		// Setup
+		db, dbMock, _ := createMockDB(t)
+		repo := NewRepo(db, zaptest.NewLogger(t))
+
+		dbMock.ExpectBegin()
+		tx, err := db.Begin()
+		require.NoError(t, err)
+
+		gameDate := time.Now()
+		gameReq := domain.GameStatsReq{
+			ID:    "Warriors vs lakers",
+			Date:  gameDate,
+			Teams: []domain.Team{{ID: "team1", Players: []domain.Player{{ID: "player1", Points: 20}}}},
+		}
+
+		dbMock.ExpectExec("INSERT INTO games").
+			WithArgs(sqlmock.AnyArg(), gameDate, domain.SourceBoxScore, domain.StatusFinal, nil, nil).
+			WillReturnResult(sqlmock.NewResult(1, 1))
+		dbMock.ExpectExec("INSERT INTO game_stats").
+			WillReturnResult(sqlmock.NewResult(1, 1))
+
+		// Test
+		gameID, err := repo.Save(context.Background(), tx, gameReq)
+
+		// Assert
+		assert.NoError(t, err)
+		assert.NotEmpty(t, gameID)
+		assert.NoError(t, dbMock.ExpectationsWereMet())
+	})
+
 	t.Run("empty teams", func(t *testing.T) {
 		// Setup
 		db, dbMock, _ := createMockDB(t)
@@ -173,12 +247,18 @@ func TestRepository_Save(t *testing.T) {
 			Teams: []domain.Team{},
 		}
 
+		// the games row is still written, there are no lines to insert
+		dbMock.ExpectExec("INSERT INTO games").
+			WithArgs(sqlmock.AnyArg(), gameDate, domain.SourceBoxScore, domain.StatusFinal, nil, nil).
+			WillReturnResult(sqlmock.NewResult(1, 1))
+
 		// Test
-		gameID, err := repo.Save(tx, gameReq)
+		gameID, err := repo.Save(context.Background(), tx, gameReq)
 
 		// Assert
 		assert.NoError(t, err)
 		assert.NotEmpty(t, gameID)
+		assert.NoError(t, dbMock.ExpectationsWereMet())
 	})
 }
 
@@ -194,20 +274,20 @@ func TestRepository_Find(t *testing.T) {
 
 		// Create mock data
 		rows := sqlmock.NewRows([]string{
-			"game_id", "player_id", "name", "date", "points", "rebounds", "assists",
-			"steals", "blocks", "fouls", "turnovers", "minutes_played",
+			"game_id", "player_id", "name", "team_id", "date", "points", "rebounds", "assists",
+			"steals", "blocks", "fouls", "turnovers", "minutes_played", "status",
 		}).
-			AddRow(gameID, "player1", "LeBron James", gameDate, 24, 10, 8, 2, 1, 2, 3, 36).
-			AddRow(gameID, "player2", "Anthony Davis", gameDate, 28, 12, 3, 1, 3, 2, 1, 34).
-			AddRow(gameID, "player3", "Russell Westbrook", gameDate, 18, 7, 10, 3, 0, 3, 4, 32)
+			AddRow(gameID, "player1", "LeBron James", "team1", gameDate, 24, 10, 8, 2, 1, 2, 3, 36, "final").
+			AddRow(gameID, "player2", "Anthony Davis", "team2", gameDate, 28, 12, 3, 1, 3, 2, 1, 34, "final").
+			AddRow(gameID, "player3", "Russell Westbrook", "team3", gameDate, 18, 7, 10, 3, 0, 3, 4, 32, "final")
 
 		// Set up expectations for the SELECT query
-		dbMock.ExpectQuery("select g.game_id, g.player_id, p.name, g.date, g.points, g.rebounds, g.assists, g.steals, g.blocks, g.fouls, g.turnovers, g.minutes_played from game_stats g join players p").
+		dbMock.ExpectQuery("select g.game_id, g.player_id, p.name, p.team_id, g.date, g.points, g.rebounds, g.assists, g.steals, g.blocks, g.fouls, g.turnovers, g.minutes_played, gm.status from game_stats g join players p").
 			WithArgs(gameID).
 			WillReturnRows(rows)
 
 		// Test
-		results, err := repo.Find(gameID)
+		results, err := repo.Find(context.Background(), gameID)
 
 		// Assert
 		assert.NoError(t, err)
@@ -236,17 +316,17 @@ func TestRepository_Find(t *testing.T) {
 
 		// Create empty result set
 		rows := sqlmock.NewRows([]string{
-			"game_id", "player_id", "name", "date", "points", "rebounds", "assists",
-			"steals", "blocks", "fouls", "turnovers", "minutes_played",
+			"game_id", "player_id", "name", "team_id", "date", "points", "rebounds", "assists",
+			"steals", "blocks", "fouls", "turnovers", "minutes_played", "status",
 		})
 
 		// Set up expectations for the SELECT query
-		dbMock.ExpectQuery("select g.game_id, g.player_id, p.name, g.date, g.points, g.rebounds, g.assists, g.steals, g.blocks, g.fouls, g.turnovers, g.minutes_played from game_stats g join players p").
+		dbMock.ExpectQuery("select g.game_id, g.player_id, p.name, p.team_id, g.date, g.points, g.rebounds, g.assists, g.steals, g.blocks, g.fouls, g.turnovers, g.minutes_played, gm.status from game_stats g join players p").
 			WithArgs(gameID).
 			WillReturnRows(rows)
 
 		// Test
-		results, err := repo.Find(gameID)
+		results, err := repo.Find(context.Background(), gameID)
 
 		// Assert
 		assert.NoError(t, err)
@@ -262,12 +342,12 @@ func TestRepository_Find(t *testing.T) {
 		gameID := uuid.New().String()
 
 		// Set up expectations for the SELECT query to fail
-		dbMock.ExpectQuery("select g.game_id, g.player_id, p.name, g.date, g.points, g.rebounds, g.assists, g.steals, g.blocks, g.fouls, g.turnovers, g.minutes_played from game_stats g join players p").
+		dbMock.ExpectQuery("select g.game_id, g.player_id, p.name, p.team_id, g.date, g.points, g.rebounds, g.assists, g.steals, g.blocks, g.fouls, g.turnovers, g.minutes_played, gm.status from game_stats g join players p").
 			WithArgs(gameID).
 			WillReturnError(sql.ErrConnDone)
 
 		// Test
-		results, err := repo.Find(gameID)
+		results, err := repo.Find(context.Background(), gameID)
 
 		// Assert
 		assert.Error(t, err)
@@ -285,18 +365,18 @@ func TestRepository_Find(t *testing.T) {
 
 		// Create mock data with invalid date format
 		rows := sqlmock.NewRows([]string{
-			"game_id", "player_id", "name", "date", "points", "rebounds", "assists",
-			"steals", "blocks", "fouls", "turnovers", "minutes_played",
+			"game_id", "player_id", "name", "team_id", "date", "points", "rebounds", "assists",
+			"steals", "blocks", "fouls", "turnovers", "minutes_played", "status",
 		}).
-			AddRow(gameID, "player1", "LeBron James", gameDate, 24, 10, 8, 2, 1, 2, 3, 36)
+			AddRow(gameID, "player1", "LeBron James", "team1", gameDate, 24, 10, 8, 2, 1, 2, 3, 36, "final")
 
 		// Set up expectations for the SELECT query
-		dbMock.ExpectQuery("select g.game_id, g.player_id, p.name, g.date, g.points, g.rebounds, g.assists, g.steals, g.blocks, g.fouls, g.turnovers, g.minutes_played from game_stats g join players p").
+		dbMock.ExpectQuery("select g.game_id, g.player_id, p.name, p.team_id, g.date, g.points, g.rebounds, g.assists, g.steals, g.blocks, g.fouls, g.turnovers, g.minutes_played, gm.status from game_stats g join players p").
 			WithArgs(gameID).
 			WillReturnRows(rows)
 
 		// Test
-		results, err := repo.Find(gameID)
+		results, err := repo.Find(context.Background(), gameID)
 
 		// Assert
 		assert.Error(t, err)
@@ -304,6 +384,109 @@ func TestRepository_Find(t *testing.T) {
 	})
 }
 
+func TestRepository_LockGame(t *testing.T) {
+	t.Run("found", func(t *testing.T) {
+		// Setup
+		db, dbMock, _ := createMockDB(t)
+		repo := NewRepo(db, zaptest.NewLogger(t))
+
+		dbMock.ExpectBegin()
+		dbMock.ExpectQuery("SELECT id, date, source, status FROM games WHERE id = \\? FOR UPDATE").
+			WithArgs("g1").
+			WillReturnRows(sqlmock.NewRows([]string{"id", "date", "source", "status"}).AddRow("g1", "2024-11-02 19:30:00", "events", "live"))
+		tx, err := db.Begin()
+		require.NoError(t, err)
+
+		// Test
+		game, err := repo.LockGame(context.Background(), tx, "g1")
+
+		// Assert
+		require.NoError(t, err)
+		assert.Equal(t, domain.Game{ID: "g1", Date: time.Date(2024, 11, 2, 19, 30, 0, 0, time.UTC), Source: domain.SourceEvents, Status: domain.StatusLive}, game)
+	})
+
+	t.Run("not found", func(t *testing.T) {
+		// Setup
+		db, dbMock, _ := createMockDB(t)
+		repo := NewRepo(db, zaptest.NewLogger(t))
+
+		dbMock.ExpectBegin()
+		dbMock.ExpectQuery("FROM games").WithArgs("g1").WillReturnError(sql.ErrNoRows)
+		tx, err := db.Begin()
+		require.NoError(t, err)
+
+		// Test
+		_, err = repo.LockGame(context.Background(), tx, "g1")
+
+		// Assert
+		assert.True(t, apperror.IsNotFound(err))
+	})
+}
+
+func TestRepository_SetStatus(t *testing.T) {
+	t.Run("voided frees the origin key", func(t *testing.T) {
+		// Setup
+		db, dbMock, _ := createMockDB(t)
+		repo := NewRepo(db, zaptest.NewLogger(t))
+
+		dbMock.ExpectBegin()
+		dbMock.ExpectExec("UPDATE games SET status = \\?, origin = NULL, external_key = NULL WHERE id = \\?").
+			WithArgs(domain.StatusVoided, "g1").
+			WillReturnResult(sqlmock.NewResult(0, 1))
+		tx, err := db.Begin()
+		require.NoError(t, err)
+
+		// Test
+		err = repo.SetStatus(context.Background(), tx, "g1", domain.StatusVoided)
+
+		// Assert
+		require.NoError(t, err)
+		assert.NoError(t, dbMock.ExpectationsWereMet())
+	})
+
+	t.Run("other statuses keep it", func(t *testing.T) {
+		// Setup
+		db, dbMock, _ := createMockDB(t)
+		repo := NewRepo(db, zaptest.NewLogger(t))
+
+		dbMock.ExpectBegin()
+		dbMock.ExpectExec("UPDATE games SET status = \\? WHERE id = \\?").
+			WithArgs(domain.StatusFinal, "g1").
+			WillReturnResult(sqlmock.NewResult(0, 1))
+		tx, err := db.Begin()
+		require.NoError(t, err)
+
+		// Test
+		err = repo.SetStatus(context.Background(), tx, "g1", domain.StatusFinal)
+
+		// Assert
+		require.NoError(t, err)
+		assert.NoError(t, dbMock.ExpectationsWereMet())
+	})
+}
+
+func TestRepository_ReplaceLines(t *testing.T) {
+	// Setup
+	db, dbMock, _ := createMockDB(t)
+	repo := NewRepo(db, zaptest.NewLogger(t))
+	gameDate := time.Date(2024, 11, 2, 19, 30, 0, 0, time.UTC)
+
+	dbMock.ExpectBegin()
+	dbMock.ExpectExec("DELETE FROM game_stats WHERE game_id = \\?").WithArgs("g1").WillReturnResult(sqlmock.NewResult(0, 3))
+	dbMock.ExpectExec("INSERT INTO game_stats").
+		WithArgs(sqlmock.AnyArg(), "g1", "p1", gameDate, 5, 1, 0, 0, 0, 0, 0, 12.0).
+		WillReturnResult(sqlmock.NewResult(1, 1))
+	tx, err := db.Begin()
+	require.NoError(t, err)
+
+	// Test
+	err = repo.ReplaceLines(context.Background(), tx, "g1", gameDate, []domain.Player{{ID: "p1", Points: 5, Rebounds: 1, MinutesPlayed: 12}})
+
+	// Assert
+	require.NoError(t, err)
+	assert.NoError(t, dbMock.ExpectationsWereMet())
+}
+
 // Helper functions for creating mocks
 func createMockDB(t *testing.T) (*sqlx.DB, sqlmock.Sqlmock, error) {
 	db, mock, err := sqlmock.New()
@@ -1,12 +1,27 @@
 package domain
 
-import "time"
+import (
+	"fmt"
+	"time"
 
+	"skyhawk/backend/apperror"
+)
+
+// the longest id and origin a logged game may carry
+const (
+	MaxKeyLength    = 128
+	MaxOriginLength = 64
+)
+
+// GameStatsReq - a complete box score. ID is the game's key in the client's source, optional.
+// Origin is set by bulk loads only (the importer, generated seasons), never by clients: a game of an origin
+// is stored with its key and a key its origin already holds is refused with 409 game_already_logged
 type GameStatsReq struct {
 	ID     string    `json:"id"`
 	GameID string    `json:"gameID"`
 	Date   time.Time `json:"date"`
 	Teams  []Team    `json:"teams"`
+	Origin string    `json:"-"`
 }
 
 type Team struct {
@@ -33,6 +48,7 @@ type GameStats struct {
 	Date          time.Time `json:"date"`
 	Name          string    `json:"name"`
 	PlayerID      string    `json:"player_id"`
+	TeamID        string    `json:"team_id"`
 	Points        int       `json:"points"`
 	Rebounds      int       `json:"rebounds"`
 	Assists       int       `json:"assists"`
@@ -41,4 +57,314 @@ type GameStats struct {
 	Fouls         int       `json:"fouls"`
 	Turnovers     int       `json:"turnovers"`
 	MinutesPlayed float64   `json:"minutes_played"`
+	Status        Status    `json:"status"`
+}
+
+const (
+	maxFouls         = 6
+	maxMinutesPlayed = 48.0
+)
+
+// Validate - checks a game before it is logged, every problem is reported at once
+func (g GameStatsReq) Validate() error {
+	var fields []apperror.FieldError
+
+	if len(g.ID) > MaxKeyLength {
+		fields = append(fields, apperror.FieldError{Field: "id", Message: fmt.Sprintf("id is longer than %d characters", MaxKeyLength)})
+	}
+	if len(g.Origin) > MaxOriginLength {
+		fields = append(fields, apperror.FieldError{Field: "origin", Message: fmt.Sprintf("origin is longer than %d characters", MaxOriginLength)})
+	}
+	if len(g.Teams) == 0 {
+		fields = append(fields, apperror.FieldError{Field: "teams", Message: "at least one team is required"})
+	}
+
+	lines := 0
+	for i, team := range g.Teams {
+		if team.Name == "" {
+			fields = append(fields, apperror.FieldError{Field: fmt.Sprintf("teams[%d].name", i), Message: "team name is required"})
+		}
+
+		lines += len(team.Players)
+		for j, player := range team.Players {
+			fields = append(fields, player.Validate(fmt.Sprintf("teams[%d].players[%d]", i, j))...)
+		}
+	}
+	// a box score without lines is no game, voiding is how a game is taken back
+	if len(g.Teams) > 0 && lines == 0 {
+		fields = append(fields, apperror.FieldError{Field: "teams", Message: "at least one player is required"})
+	}
+
+	if len(fields) > 0 {
+		return apperror.Validation("invalid_game", "game stats are invalid", fields...)
+	}
+
+	return nil
+}
+
+// Validate - checks a single stat line, prefix is the line's field path in the request
+func (p Player) Validate(prefix string) []apperror.FieldError {
+	var fields []apperror.FieldError
+
+	if p.Name == "" {
+		fields = append(fields, apperror.FieldError{Field: prefix + ".player_name", Message: "player name is required"})
+	}
+	if p.Points < 0 || p.Rebounds < 0 || p.Assists < 0 || p.Steals < 0 || p.Blocks < 0 || p.Turnovers < 0 {
+		fields = append(fields, apperror.FieldError{Field: prefix, Message: "stats cannot be negative"})
+	}
+	if p.Fouls < 0 || p.Fouls > maxFouls {
+		fields = append(fields, apperror.FieldError{Field: prefix + ".fouls", Message: fmt.Sprintf("fouls must be between 0 and %d", maxFouls)})
+	}
+	if p.MinutesPlayed < 0 || p.MinutesPlayed > maxMinutesPlayed {
+		fields = append(fields, apperror.FieldError{Field: prefix + ".minutes_played", Message: fmt.Sprintf("minutes played must be between 0 and %.0f", maxMinutesPlayed)})
+	}
+
+	return fields
+}
+
+// BatchMode - how a batch reacts to a failing game
+type BatchMode string
+
+const (
+	// BatchModeAtomic - every game is logged or none is
+	BatchModeAtomic BatchMode = "all_or_nothing"
+	// BatchModeBestEffort - every game is logged on its own, failures are reported per game
+	BatchModeBestEffort BatchMode = "best_effort"
+)
+
+func (m BatchMode) Valid() bool {
+	return m == BatchModeAtomic || m == BatchModeBestEffort
+}
+
+type BatchError struct {
+	Code    string `json:"code"`
+	Message string `json:"message"`
+}
+
+// BatchResult - the outcome of one game of a batch, Index is its position in the request
+type BatchResult struct {
+	Index int         `json:"index"`
+	ID    string      `json:"id,omitempty"`
+	Error *BatchError `json:"error,omitempty"`
+}
+
+type BatchRes struct {
+	Mode      BatchMode     `json:"mode"`
+	Succeeded int           `json:"succeeded"`
+	Failed    int           `json:"failed"`
+	Results   []BatchResult `json:"results"`
+}
+
+// Source - how the stat lines of a game are recorded
+type Source string
+
+const (
+	// SourceBoxScore - stat lines are posted directly
+	SourceBoxScore Source = "box_score"
+	// SourceEvents - stat lines are derived from play by play events
+	SourceEvents Source = "events"
+)
+
+// Status - where a game is in its lifecycle, only final games count towards season stats
+type Status string
+
+const (
+	StatusScheduled Status = "scheduled"
+	StatusLive      Status = "live"
+	StatusFinal     Status = "final"
+	// StatusVoided - the game did not count, reached from any other status
+	StatusVoided Status = "voided"
+)
+
+// CanMoveTo - games only move forward, scheduled to live to final, and any of them may be voided
+func (s Status) CanMoveTo(next Status) bool {
+	if next == StatusVoided {
+		return s != StatusVoided
+	}
+
+	return (s == StatusScheduled && next == StatusLive) || (s == StatusLive && next == StatusFinal)
+}
+
+// Game - a row of the games table
+type Game struct {
+	ID     string    `json:"id"`
+	Date   time.Time `json:"date"`
+	Source Source    `json:"source"`
+	Status Status    `json:"status"`
+}
+
+// ScheduleReq - creates a game ahead of tip off
+type ScheduleReq struct {
+	Date   time.Time `json:"date"`
+	Source Source    `json:"source"`
+}
+
+func (r ScheduleReq) Validate() error {
+	var fields []apperror.FieldError
+
+	if r.Date.IsZero() {
+		fields = append(fields, apperror.FieldError{Field: "date", Message: "date is required"})
+	}
+	if r.Source != "" && r.Source != SourceBoxScore && r.Source != SourceEvents {
+		fields = append(fields, apperror.FieldError{Field: "source", Message: "source must be box_score or events"})
+	}
+
+	if len(fields) > 0 {
+		return apperror.Validation("invalid_game", "game is invalid", fields...)
+	}
+
+	return nil
+}
+
+// UpdateMode - how the lines of a live update combine with the current box score
+type UpdateMode string
+
+const (
+	// UpdateModeDelta - the posted numbers are added to the player's running totals, negative numbers correct them
+	UpdateModeDelta UpdateMode = "delta"
+	// UpdateModeSnapshot - the posted numbers replace the player's running totals
+	UpdateModeSnapshot UpdateMode = "snapshot"
+)
+
+// LiveUpdate - running totals pushed during a live game, players left out keep their current line
+type LiveUpdate struct {
+	Mode  UpdateMode `json:"mode"`
+	Teams []Team     `json:"teams"`
+}
+
+// Validate - checks the shape of an update, the resulting totals are checked once merged
+func (u LiveUpdate) Validate() error {
+	var fields []apperror.FieldError
+
+	if u.Mode != UpdateModeDelta && u.Mode != UpdateModeSnapshot {
+		fields = append(fields, apperror.FieldError{Field: "mode", Message: "mode must be delta or snapshot"})
+	}
+	if len(u.Teams) == 0 {
+		fields = append(fields, apperror.FieldError{Field: "teams", Message: "at least one team is required"})
+	}
+
+	for i, team := range u.Teams {
+		if team.Name == "" {
+			fields = append(fields, apperror.FieldError{Field: fmt.Sprintf("teams[%d].name", i), Message: "team name is required"})
+		}
+		for j, player := range team.Players {
+			if player.Name == "" {
+				fields = append(fields, apperror.FieldError{Field: fmt.Sprintf("teams[%d].players[%d].player_name", i, j), Message: "player name is required"})
+			}
+		}
+	}
+
+	if len(fields) > 0 {
+		return apperror.Validation("invalid_update", "live update is invalid", fields...)
+	}
+
+	return nil
+}
+
+// Player - the line as posted in a game request
+func (g GameStats) Player() Player {
+	return Player{
+		ID:            g.PlayerID,
+		Name:          g.Name,
+		Points:        g.Points,
+		Rebounds:      g.Rebounds,
+		Assists:       g.Assists,
+		Steals:        g.Steals,
+		Blocks:        g.Blocks,
+		Fouls:         g.Fouls,
+		Turnovers:     g.Turnovers,
+		MinutesPlayed: g.MinutesPlayed,
+	}
+}
+
+// BoxScore - the box score of a game request whose team and player ids are resolved
+func (g GameStatsReq) BoxScore(id string, status Status) BoxScore {
+	box := BoxScore{Game: Game{ID: id, Date: g.Date, Source: SourceBoxScore, Status: status}, Lines: []GameStats{}}
+	for _, team := range g.Teams {
+		for _, player := range team.Players {
+			box.Lines = append(box.Lines, GameStats{
+				ID:            id,
+				Date:          g.Date,
+				Name:          player.Name,
+				PlayerID:      player.ID,
+				TeamID:        team.ID,
+				Points:        player.Points,
+				Rebounds:      player.Rebounds,
+				Assists:       player.Assists,
+				Steals:        player.Steals,
+				Blocks:        player.Blocks,
+				Fouls:         player.Fouls,
+				Turnovers:     player.Turnovers,
+				MinutesPlayed: player.MinutesPlayed,
+				Status:        status,
+			})
+		}
+	}
+
+	return box
+}
+
+// Add - adds delta to the line's totals
+func (p Player) Add(delta Player) Player {
+	p.Points += delta.Points
+	p.Rebounds += delta.Rebounds
+	p.Assists += delta.Assists
+	p.Steals += delta.Steals
+	p.Blocks += delta.Blocks
+	p.Fouls += delta.Fouls
+	p.Turnovers += delta.Turnovers
+	p.MinutesPlayed += delta.MinutesPlayed
+
+	return p
+}
+
+// BoxScore - a game with its current stat lines
+type BoxScore struct {
+	Game
+	Lines []GameStats `json:"lines"`
+}
+
+// Score - points per team id
+func (b BoxScore) Score() map[string]int {
+	score := make(map[string]int)
+	for _, line := range b.Lines {
+		score[line.TeamID] += line.Points
+	}
+
+	return score
+}
+
+// State - a game with its lines keyed by player id, the shape written to the audit log so a diff names the player whose line changed
+type State struct {
+	Game
+	Lines map[string]Player `json:"lines"`
+}
+
+func NewState(game Game, players []Player) State {
+	lines := make(map[string]Player, len(players))
+	for _, player := range players {
+		lines[player.ID] = player
+	}
+
+	return State{Game: game, Lines: lines}
+}
+
+// Players - the posted form of stored lines
+func Players(lines []GameStats) []Player {
+	players := make([]Player, 0, len(lines))
+	for _, line := range lines {
+		players = append(players, line.Player())
+	}
+
+	return players
+}
+
+// Players - every line of the request, resolved ids included
+func (g GameStatsReq) Players() []Player {
+	var players []Player
+	for _, team := range g.Teams {
+		players = append(players, team.Players...)
+	}
+
+	return players
 }
@@ -6,16 +6,17 @@ import (
 	"github.com/labstack/echo/v4"
 	"go.uber.org/zap"
 
+	"skyhawk/backend/apperror"
 	"skyhawk/backend/game/domain"
 	"skyhawk/backend/game/usecase"
 )
 
 type Handler struct {
-	useCase *usecase.UseCase
+	useCase usecase.GameUseCase
 	logger  *zap.Logger
 }
 
-func NewHandler(useCase *usecase.UseCase, logger *zap.Logger) *Handler {
+func NewHandler(useCase usecase.GameUseCase, logger *zap.Logger) *Handler {
 	return &Handler{useCase: useCase, logger: logger}
 }
 
@@ -23,26 +24,124 @@ func (h *Handler) GameLogHandler(c echo.Context) error {
 	var req domain.GameStatsReq
 
 	if err := c.Bind(&req); err != nil {
-		return c.JSON(http.StatusBadRequest, err.Error())
+		return apperror.Validation("invalid_body", "request body is not a valid game")
 	}
 
-	id, err := h.useCase.LogGame(req)
+	id, err := h.useCase.LogGame(c.Request().Context(), req)
 
 	if err != nil {
-		return c.JSON(http.StatusInternalServerError, err.Error())
-
+		return err
 	}
 
 	return c.JSON(http.StatusOK, map[string]interface{}{"id": id})
 }
 
+func (h *Handler) GameBatchLogHandler(c echo.Context) error {
+	var games []domain.GameStatsReq
+
+	if err := c.Bind(&games); err != nil {
+		return apperror.Validation("invalid_body", "request body is not a valid array of games")
+	}
+
+	mode := domain.BatchMode(c.QueryParam("mode"))
+	if mode == "" {
+		mode = domain.BatchModeBestEffort
+	}
+
+	res, err := h.useCase.LogGames(c.Request().Context(), games, mode)
+
+	if err != nil {
+		return err
+	}
+
+	return c.JSON(http.StatusOK, res)
+}
+
+func (h *Handler) ScheduleGameHandler(c echo.Context) error {
+	var req domain.ScheduleReq
+
+	if err := c.Bind(&req); err != nil {
+		return apperror.Validation("invalid_body", "request body is not a valid game")
+	}
+
+	game, err := h.useCase.ScheduleGame(c.Request().Context(), req)
+
+	if err != nil {
+		return err
+	}
+
+	return c.JSON(http.StatusCreated, game)
+}
+
+func (h *Handler) StartGameHandler(c echo.Context) error {
+	game, err := h.useCase.StartGame(c.Request().Context(), c.Param("id"))
+
+	if err != nil {
+		return err
+	}
+
+	return c.JSON(http.StatusOK, game)
+}
+
+func (h *Handler) LiveUpdateHandler(c echo.Context) error {
+	var update domain.LiveUpdate
+
+	if err := c.Bind(&update); err != nil {
+		return apperror.Validation("invalid_body", "request body is not a valid live update")
+	}
+
+	box, err := h.useCase.UpdateLive(c.Request().Context(), c.Param("id"), update)
+
+	if err != nil {
+		return err
+	}
+
+	return c.JSON(http.StatusOK, box)
+}
+
+func (h *Handler) FinalizeGameHandler(c echo.Context) error {
+	game, err := h.useCase.FinalizeGame(c.Request().Context(), c.Param("id"))
+
+	if err != nil {
+		return err
+	}
+
+	return c.JSON(http.StatusOK, game)
+}
+
+func (h *Handler) CorrectGameHandler(c echo.Context) error {
+	var req domain.GameStatsReq
+
+	if err := c.Bind(&req); err != nil {
+		return apperror.Validation("invalid_body", "request body is not a valid game")
+	}
+
+	box, err := h.useCase.CorrectGame(c.Request().Context(), c.Param("id"), req)
+
+	if err != nil {
+		return err
+	}
+
+	return c.JSON(http.StatusOK, box)
+}
+
+func (h *Handler) VoidGameHandler(c echo.Context) error {
+	game, err := h.useCase.VoidGame(c.Request().Context(), c.Param("id"))
+
+	if err != nil {
+		return err
+	}
+
+	return c.JSON(http.StatusOK, game)
+}
+
 func (h *Handler) TeamSeasonStatsHandler(c echo.Context) error {
-	id := c.Param("id")
+	id := c.Param("team_id")
 
-	stats, err := h.useCase.GetTeamSeasonStats(id)
+	stats, err := h.useCase.GetTeamSeasonStats(c.Request().Context(), id)
 
 	if err != nil {
-		return c.JSON(http.StatusInternalServerError, err.Error())
+		return err
 	}
 
 	return c.JSON(http.StatusOK, stats)
@@ -51,11 +150,10 @@ func (h *Handler) TeamSeasonStatsHandler(c echo.Context) error {
 func (h *Handler) GameStatsHandler(c echo.Context) error {
 	id := c.Param("id")
 
-	res, err := h.useCase.GetGameStats(id)
+	res, err := h.useCase.GetGameStats(c.Request().Context(), id)
 
 	if err != nil {
-		return c.JSON(http.StatusInternalServerError, err.Error())
-
+		return err
 	}
 
 	return c.JSON(http.StatusOK, res)
@@ -64,11 +162,10 @@ func (h *Handler) GameStatsHandler(c echo.Context) error {
 func (h *Handler) PlayerSeasonStatsHandler(c echo.Context) error {
 	playerId := c.Param("player_id")
 
-	result, err := h.useCase.GetPlayerSeasonStats(playerId)
+	result, err := h.useCase.GetPlayerSeasonStats(c.Request().Context(), playerId)
 
 	if err != nil {
-		return c.JSON(http.StatusInternalServerError, err.Error())
-
+		return err
 	}
 
 	return c.JSON(http.StatusOK, result)
@@ -0,0 +1,206 @@
+package usecase
+
+import (
+	"context"
+	"fmt"
+	"sync"
+
+	"go.uber.org/zap"
+
+	"skyhawk/backend/apperror"
+	game_domain "skyhawk/backend/game/domain"
+	"skyhawk/backend/logging"
+	outbox_domain "skyhawk/backend/outbox/domain"
+	"skyhawk/backend/retry"
+)
+
+// BatchOptions - limits applied to LogGames
+type BatchOptions struct {
+	MaxGames    int
+	Parallelism int
+}
+
+func DefaultBatchOptions() BatchOptions {
+	return BatchOptions{
+		MaxGames:    50,
+		Parallelism: 4,
+	}
+}
+
+// LogGames - logs a slate of games.
+// In best effort mode games are inserted concurrently, each with its roster in its own transaction.
+// In all or nothing mode the roster, resolved once for the whole batch, and every game are written in a single transaction.
+func (s *UseCase) LogGames(ctx context.Context, games []game_domain.GameStatsReq, mode game_domain.BatchMode) (game_domain.BatchRes, error) {
+	if len(games) == 0 {
+		return game_domain.BatchRes{}, apperror.Validation("empty_batch", "batch contains no games")
+	}
+	if len(games) > s.batch.MaxGames {
+		return game_domain.BatchRes{}, apperror.Validation("batch_too_large", fmt.Sprintf("batch may contain at most %d games", s.batch.MaxGames))
+	}
+	if !mode.Valid() {
+		return game_domain.BatchRes{}, apperror.Validation("invalid_batch_mode", fmt.Sprintf("mode must be %q or %q", game_domain.BatchModeAtomic, game_domain.BatchModeBestEffort))
+	}
+
+	results := make([]game_domain.BatchResult, len(games))
+	var valid []int
+	var fields []apperror.FieldError
+
+	for i := range games {
+		results[i].Index = i
+		if err := games[i].Validate(); err != nil {
+			results[i].Error = toBatchError(err)
+			for _, field := range apperror.From(err).Fields {
+				fields = append(fields, apperror.FieldError{Field: fmt.Sprintf("[%d].%s", i, field.Field), Message: field.Message})
+			}
+			continue
+		}
+		valid = append(valid, i)
+	}
+
+	if mode == game_domain.BatchModeAtomic && len(fields) > 0 {
+		return game_domain.BatchRes{}, apperror.Validation("invalid_batch", "one or more games are invalid", fields...)
+	}
+
+	// resolved ids are written into the games, work on copies so a rolled back attempt leaves nothing behind
+	pending := make([]game_domain.GameStatsReq, len(valid))
+	for i, index := range valid {
+		pending[i] = games[index]
+		pending[i].Teams = cloneTeams(games[index].Teams)
+	}
+
+	var err error
+	if mode == game_domain.BatchModeAtomic {
+		err = s.logAtomic(ctx, pending, valid, results)
+	} else {
+		err = s.logBestEffort(ctx, pending, valid, results)
+	}
+	if err != nil {
+		return game_domain.BatchRes{}, err
+	}
+
+	res := game_domain.BatchRes{Mode: mode, Results: results}
+	for _, result := range results {
+		if result.Error != nil {
+			res.Failed++
+		} else {
+			res.Succeeded++
+			s.notify(ctx, result.ID)
+		}
+	}
+
+	return res, nil
+}
+
+func (s *UseCase) logAtomic(ctx context.Context, games []game_domain.GameStatsReq, indexes []int, results []game_domain.BatchResult) error {
+	var ids []string
+
+	attempts, err := s.retrier.Do(ctx, "LogGames", func(ctx context.Context) error {
+		attempt := make([]game_domain.GameStatsReq, len(games))
+		for i := range games {
+			attempt[i] = games[i]
+			attempt[i].Teams = cloneTeams(games[i].Teams)
+		}
+
+		tx, err := s.gameRepo.Begin(ctx)
+		if err != nil {
+			return apperror.FromDB(err, nil)
+		}
+		defer tx.Rollback()
+
+		resolved, err := s.resolveRoster(ctx, tx, attempt)
+		if err != nil {
+			return err
+		}
+
+		ids = make([]string, len(attempt))
+		for i := range attempt {
+			if ids[i], err = s.gameRepo.Save(ctx, tx, attempt[i]); err != nil {
+				return err
+			}
+			if err = s.announce(ctx, tx, outbox_domain.GameLogged, ids[i], attempt[i].BoxScore(ids[i], game_domain.StatusFinal)); err != nil {
+				return err
+			}
+			if err = s.audit(ctx, tx, logged(ids[i], attempt[i])); err != nil {
+				return err
+			}
+		}
+
+		if err = tx.Commit(); err != nil {
+			return retry.Permanent(apperror.FromDB(err, nil))
+		}
+		s.remember(ctx, resolved)
+		return nil
+	})
+	if err != nil {
+		logging.From(ctx, s.logger).Error("UseCase.LogGames failed logging batch", zap.Int("games", len(games)), zap.Int("attempts", attempts), zap.Error(err))
+		return err
+	}
+
+	for i, index := range indexes {
+		results[index].ID = ids[i]
+	}
+
+	return nil
+}
+
+func (s *UseCase) logBestEffort(ctx context.Context, games []game_domain.GameStatsReq, indexes []int, results []game_domain.BatchResult) error {
+	parallelism := s.batch.Parallelism
+	if parallelism < 1 {
+		parallelism = 1
+	}
+	sem := make(chan struct{}, parallelism)
+	var wg sync.WaitGroup
+
+	for i := range games {
+		// a cancelled batch stops handing out games, the ones already running finish or roll back on their own
+		if ctx.Err() != nil {
+			wg.Wait()
+			return ctx.Err()
+		}
+		select {
+		case sem <- struct{}{}:
+		case <-ctx.Done():
+			wg.Wait()
+			return ctx.Err()
+		}
+
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			defer func() { <-sem }()
+
+			id, err := s.saveGame(ctx, games[i])
+			if err != nil {
+				results[indexes[i]].Error = toBatchError(err)
+				return
+			}
+			results[indexes[i]].ID = id
+		}(i)
+	}
+	wg.Wait()
+
+	return nil
+}
+
+// saveGame - logs one game of the batch in its own transaction, roster included, so a team or player that cannot be written fails that game only
+func (s *UseCase) saveGame(ctx context.Context, game game_domain.GameStatsReq) (string, error) {
+	var id string
+
+	attempts, err := s.retrier.Do(ctx, "LogGames.game", func(ctx context.Context) error {
+		var err error
+		id, err = s.attemptTransaction(ctx, game)
+		return err
+	})
+	if err != nil {
+		logging.From(ctx, s.logger).Error("UseCase.LogGames failed logging game", zap.Int("attempts", attempts), zap.Error(err))
+		return "", err
+	}
+
+	return id, nil
+}
+
+func toBatchError(err error) *game_domain.BatchError {
+	appErr := apperror.From(err)
+
+	return &game_domain.BatchError{Code: appErr.Code, Message: appErr.Message}
+}
@@ -0,0 +1,482 @@
+package usecase
+
+import (
+	"context"
+	"database/sql"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/go-sql-driver/mysql"
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap/zaptest"
+
+	"skyhawk/backend/apperror"
+	game_domain "skyhawk/backend/game/domain"
+	outbox_domain "skyhawk/backend/outbox/domain"
+	player_domain "skyhawk/backend/player/domain"
+	"skyhawk/backend/retry"
+	"skyhawk/backend/team/domain"
+)
+
+// fakeTeamRepo - answers from its cache first, like the redis backed repository
+type fakeTeamRepo struct {
+	mu    sync.Mutex
+	saves map[string]int
+	cache map[string]string
+	// fail - errors returned for a team name
+	fail map[string]error
+}
+
+func (f *fakeTeamRepo) Save(_ context.Context, _ *sql.Tx, team domain.Team) (string, bool, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	if err := f.fail[team.Name]; err != nil {
+		return "", false, err
+	}
+	if id, ok := f.cache[team.Name]; ok {
+		return id, false, nil
+	}
+	f.saves[team.Name]++
+	return "team-" + team.Name, f.saves[team.Name] == 1, nil
+}
+
+func (f *fakeTeamRepo) Remember(_ context.Context, teams []domain.Team) error {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	for _, team := range teams {
+		f.cache[team.Name] = team.ID
+	}
+	return nil
+}
+
+func (f *fakeTeamRepo) GetStats(context.Context, string) (domain.SeasonStats, error) {
+	return domain.SeasonStats{}, nil
+}
+
+// fakePlayerRepo - answers from its cache first, like the redis backed repository
+type fakePlayerRepo struct {
+	mu        sync.Mutex
+	saves     map[string]int
+	cache     map[string]string
+	players   map[string]player_domain.Player
+	lines     map[string]int
+	forgotten []player_domain.Player
+}
+
+func (f *fakePlayerRepo) Save(_ context.Context, _ *sql.Tx, players []player_domain.Player) (map[string]string, []player_domain.Player, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	ids := make(map[string]string, len(players))
+	var created []player_domain.Player
+	for _, player := range players {
+		key := player.Team + "/" + player.Name
+		if id, ok := f.cache[key]; ok {
+			ids[player.Name] = id
+			continue
+		}
+		f.saves[key]++
+		ids[player.Name] = key
+		if f.saves[key] == 1 {
+			player.ID = key
+			created = append(created, player)
+		}
+	}
+	return ids, created, nil
+}
+
+func (f *fakePlayerRepo) Remember(_ context.Context, players []player_domain.Player) error {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	for _, player := range players {
+		f.cache[player.Team+"/"+player.Name] = player.ID
+	}
+	return nil
+}
+
+func (f *fakePlayerRepo) Lock(_ context.Context, _ *sql.Tx, id string) (player_domain.Player, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	player, ok := f.players[id]
+	if !ok {
+		return player_domain.Player{}, apperror.NotFound("player_not_found", "player not found", nil)
+	}
+	return player, nil
+}
+
+func (f *fakePlayerRepo) Merge(_ context.Context, _ *sql.Tx, from, into player_domain.Player) (int, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	moved := f.lines[from.ID]
+	f.lines[into.ID] += moved
+	delete(f.lines, from.ID)
+	delete(f.players, from.ID)
+	return moved, nil
+}
+
+func (f *fakePlayerRepo) Forget(_ context.Context, player player_domain.Player) error {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.forgotten = append(f.forgotten, player)
+	return nil
+}
+
+func (f *fakePlayerRepo) SeasonStats(context.Context, string) (player_domain.PlayerSeasonStats, error) {
+	return player_domain.PlayerSeasonStats{}, nil
+}
+
+type fakeGameRepo struct {
+	db    *sql.DB
+	mu    sync.Mutex
+	saved []game_domain.GameStatsReq
+	fail  map[string]error
+	// failOnce - errors returned by the first Save of a game only
+	failOnce map[string]error
+	games    map[string]game_domain.Game
+	lines    map[string][]game_domain.Player
+}
+
+func (f *fakeGameRepo) Begin(ctx context.Context) (*sql.Tx, error) {
+	return f.db.BeginTx(ctx, nil)
+}
+
+func (f *fakeGameRepo) Save(_ context.Context, _ *sql.Tx, game game_domain.GameStatsReq) (string, error) {
+	if err := f.fail[game.ID]; err != nil {
+		return "", err
+	}
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	if err := f.failOnce[game.ID]; err != nil {
+		delete(f.failOnce, game.ID)
+		return "", err
+	}
+	f.saved = append(f.saved, game)
+	return uuid.New().String(), nil
+}
+
+func (f *fakeGameRepo) Find(_ context.Context, id string) ([]game_domain.GameStats, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	var stats []game_domain.GameStats
+	for _, line := range f.lines[id] {
+		stats = append(stats, game_domain.GameStats{ID: id, PlayerID: line.ID, Name: line.Name, Points: line.Points, Rebounds: line.Rebounds, Assists: line.Assists,
+			Steals: line.Steals, Blocks: line.Blocks, Fouls: line.Fouls, Turnovers: line.Turnovers, MinutesPlayed: line.MinutesPlayed, Status: f.games[id].Status})
+	}
+	return stats, nil
+}
+
+func (f *fakeGameRepo) FindGame(_ context.Context, id string) (game_domain.Game, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	game, ok := f.games[id]
+	if !ok {
+		return game_domain.Game{}, apperror.NotFound("game_not_found", "game not found", nil)
+	}
+	return game, nil
+}
+
+func (f *fakeGameRepo) CreateGame(_ context.Context, _ *sql.Tx, game game_domain.Game) error {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.games[game.ID] = game
+	return nil
+}
+
+func (f *fakeGameRepo) LockGame(ctx context.Context, _ *sql.Tx, id string) (game_domain.Game, error) {
+	return f.FindGame(ctx, id)
+}
+
+func (f *fakeGameRepo) SetStatus(_ context.Context, _ *sql.Tx, id string, status game_domain.Status) error {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	game := f.games[id]
+	game.Status = status
+	f.games[id] = game
+	return nil
+}
+
+func (f *fakeGameRepo) Lines(ctx context.Context, _ *sql.Tx, id string) ([]game_domain.GameStats, error) {
+	return f.Find(ctx, id)
+}
+
+func (f *fakeGameRepo) ReplaceLines(_ context.Context, _ *sql.Tx, id string, _ time.Time, players []game_domain.Player) error {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.lines[id] = players
+	return nil
+}
+
+// fakeOutbox - records appended events in order
+type fakeOutbox struct {
+	mu     sync.Mutex
+	events []outbox_domain.Event
+}
+
+func (f *fakeOutbox) Append(_ context.Context, _ *sql.Tx, events []outbox_domain.Event) error {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.events = append(f.events, events...)
+	return nil
+}
+
+func newBatchUseCase(t *testing.T, transactions int) (*UseCase, *fakeGameRepo, *fakeTeamRepo, *fakePlayerRepo) {
+	db, mock, err := sqlmock.New()
+	require.NoError(t, err)
+	t.Cleanup(func() { db.Close() })
+
+	mock.MatchExpectationsInOrder(false)
+	for i := 0; i < transactions; i++ {
+		mock.ExpectBegin()
+		mock.ExpectCommit().WillReturnError(nil)
+		mock.ExpectRollback()
+	}
+
+	logger := zaptest.NewLogger(t)
+	gameRepo := &fakeGameRepo{db: db, fail: map[string]error{}, failOnce: map[string]error{}, games: map[string]game_domain.Game{}, lines: map[string][]game_domain.Player{}}
+	teamRepo := &fakeTeamRepo{saves: map[string]int{}, cache: map[string]string{}, fail: map[string]error{}}
+	playerRepo := &fakePlayerRepo{saves: map[string]int{}, cache: map[string]string{}, players: map[string]player_domain.Player{}, lines: map[string]int{}}
+	retrier := retry.New(retry.Policy{MaxAttempts: 1, BaseDelay: time.Millisecond, MaxDelay: time.Millisecond}, logger, nil)
+
+	return NewUseCase(logger, gameRepo, teamRepo, playerRepo, retrier, DefaultBatchOptions(), nil, nil, nil, nil), gameRepo, teamRepo, playerRepo
+}
+
+func slate() []game_domain.GameStatsReq {
+	game := func(id, home, away string) game_domain.GameStatsReq {
+		return game_domain.GameStatsReq{
+			ID:   id,
+			Date: time.Now(),
+			Teams: []game_domain.Team{
+				{Name: home, Players: []game_domain.Player{{Name: home + " guard", Points: 20, MinutesPlayed: 30}}},
+				{Name: away, Players: []game_domain.Player{{Name: away + " guard", Points: 18, MinutesPlayed: 32}}},
+			},
+		}
+	}
+
+	return []game_domain.GameStatsReq{
+		game("g1", "Lakers", "Warriors"),
+		game("g2", "Lakers", "Celtics"),
+		game("g3", "Warriors", "Celtics"),
+	}
+}
+
+func TestUseCase_LogGames(t *testing.T) {
+	t.Run("best effort resolves the roster of every game", func(t *testing.T) {
+		// Setup - one transaction per game
+		uc, gameRepo, teamRepo, _ := newBatchUseCase(t, 3)
+
+		// Test
+		res, err := uc.LogGames(context.Background(), slate(), game_domain.BatchModeBestEffort)
+
+		// Assert
+		require.NoError(t, err)
+		assert.Equal(t, 3, res.Succeeded)
+		assert.Equal(t, 0, res.Failed)
+		assert.Len(t, gameRepo.saved, 3)
+		assert.Len(t, teamRepo.cache, 3, "committed rosters are cached")
+		for _, game := range gameRepo.saved {
+			for _, team := range game.Teams {
+				assert.Equal(t, "team-"+team.Name, team.ID)
+				for _, player := range team.Players {
+					assert.Equal(t, team.ID+"/"+player.Name, player.ID)
+				}
+			}
+		}
+	})
+
+	t.Run("best effort fails only the game whose roster cannot be written", func(t *testing.T) {
+		// Setup
+		uc, gameRepo, teamRepo, _ := newBatchUseCase(t, 3)
+		games := slate()
+		games[2].Teams[1].Name = "Knicks"
+		teamRepo.fail["Knicks"] = apperror.FromDB(&mysql.MySQLError{Number: 1406, Message: "Data too long for column 'name'"}, nil)
+
+		// Test
+		res, err := uc.LogGames(context.Background(), games, game_domain.BatchModeBestEffort)
+
+		// Assert
+		require.NoError(t, err)
+		assert.Equal(t, 2, res.Succeeded)
+		assert.Equal(t, 1, res.Failed)
+		assert.NotEmpty(t, res.Results[0].ID)
+		assert.NotEmpty(t, res.Results[1].ID)
+		require.NotNil(t, res.Results[2].Error)
+		assert.Len(t, gameRepo.saved, 2)
+	})
+
+	t.Run("best effort stops handing out games once cancelled", func(t *testing.T) {
+		// Setup
+		uc, gameRepo, _, _ := newBatchUseCase(t, 3)
+		ctx, cancel := context.WithCancel(context.Background())
+		cancel()
+
+		// Test
+		_, err := uc.LogGames(ctx, slate(), game_domain.BatchModeBestEffort)
+
+		// Assert
+		assert.ErrorIs(t, err, context.Canceled)
+		assert.Empty(t, gameRepo.saved)
+	})
+
+	t.Run("best effort reports failures per game", func(t *testing.T) {
+		// Setup
+		uc, gameRepo, _, _ := newBatchUseCase(t, 3)
+		gameRepo.fail["g2"] = apperror.Conflict("duplicate_entry", "resource already exists", nil)
+		games := slate()
+		games[0].Teams[0].Players[0].Fouls = 9
+
+		// Test
+		res, err := uc.LogGames(context.Background(), games, game_domain.BatchModeBestEffort)
+
+		// Assert
+		require.NoError(t, err)
+		assert.Equal(t, 1, res.Succeeded)
+		assert.Equal(t, 2, res.Failed)
+		assert.Equal(t, "invalid_game", res.Results[0].Error.Code)
+		assert.Equal(t, "duplicate_entry", res.Results[1].Error.Code)
+		assert.NotEmpty(t, res.Results[2].ID)
+	})
+
+	t.Run("all or nothing rejects the batch on an invalid game", func(t *testing.T) {
+		// Setup
+		uc, gameRepo, _, _ := newBatchUseCase(t, 0)
+		games := slate()
+		games[2].Teams[1].Players[0].MinutesPlayed = 60
+
+		// Test
+		_, err := uc.LogGames(context.Background(), games, game_domain.BatchModeAtomic)
+
+		// Assert
+		assert.Equal(t, apperror.KindValidation, apperror.KindOf(err))
+		assert.Equal(t, "[2].teams[1].players[0].minutes_played", apperror.From(err).Fields[0].Field)
+		assert.Empty(t, gameRepo.saved)
+	})
+
+	t.Run("all or nothing fails as a whole", func(t *testing.T) {
+		// Setup
+		uc, gameRepo, _, _ := newBatchUseCase(t, 1)
+		gameRepo.fail["g3"] = apperror.Conflict("duplicate_entry", "resource already exists", nil)
+
+		// Test
+		_, err := uc.LogGames(context.Background(), slate(), game_domain.BatchModeAtomic)
+
+		// Assert
+		assert.Equal(t, apperror.KindConflict, apperror.KindOf(err))
+	})
+
+	t.Run("all or nothing logs every game", func(t *testing.T) {
+		// Setup
+		uc, gameRepo, _, _ := newBatchUseCase(t, 1)
+
+		// Test
+		res, err := uc.LogGames(context.Background(), slate(), game_domain.BatchModeAtomic)
+
+		// Assert
+		require.NoError(t, err)
+		assert.Equal(t, 3, res.Succeeded)
+		assert.Len(t, gameRepo.saved, 3)
+	})
+
+	t.Run("rejects unknown modes and empty batches", func(t *testing.T) {
+		// Setup
+		uc, _, _, _ := newBatchUseCase(t, 0)
+
+		// Test
+		_, modeErr := uc.LogGames(context.Background(), slate(), "sometimes")
+		_, emptyErr := uc.LogGames(context.Background(), nil, game_domain.BatchModeBestEffort)
+
+		// Assert
+		assert.Equal(t, "invalid_batch_mode", apperror.From(modeErr).Code)
+		assert.Equal(t, "empty_batch", apperror.From(emptyErr).Code)
+	})
+}
+
+func TestUseCase_LogGame_Outbox(t *testing.T) {
+	// Setup
+	uc, _, _, _ := newBatchUseCase(t, 2)
+	outbox := &fakeOutbox{}
+	uc.outbox = outbox
+	game := slate()[0]
+
+	// Test
+	id, err := uc.LogGame(context.Background(), game)
+	require.NoError(t, err)
+	_, err = uc.LogGame(context.Background(), game)
+	require.NoError(t, err)
+
+	// Assert
+	var types []outbox_domain.Type
+	for _, event := range outbox.events {
+		types = append(types, event.Type)
+	}
+	// teams and players are announced only when first created
+	assert.Equal(t, []outbox_domain.Type{
+		outbox_domain.TeamCreated, outbox_domain.TeamCreated,
+		outbox_domain.PlayerCreated, outbox_domain.PlayerCreated,
+		outbox_domain.GameLogged,
+		outbox_domain.GameLogged,
+	}, types)
+	assert.Equal(t, "team-Lakers", outbox.events[0].Key)
+	assert.JSONEq(t, `{"id":"team-Lakers/Lakers guard","name":"Lakers guard","team_id":"team-Lakers"}`, string(outbox.events[2].Payload))
+	assert.Equal(t, id, outbox.events[4].Key)
+}
+
+func TestUseCase_LogGame_Retry(t *testing.T) {
+	// Setup - the first attempt deadlocks and rolls back, the second commits
+	uc, gameRepo, teamRepo, playerRepo := newBatchUseCase(t, 2)
+	uc.retrier = retry.New(retry.Policy{MaxAttempts: 2, BaseDelay: time.Millisecond, MaxDelay: time.Millisecond}, zaptest.NewLogger(t), nil)
+	game := slate()[0]
+	gameRepo.failOnce[game.ID] = apperror.FromDB(&mysql.MySQLError{Number: 1213, Message: "Deadlock found when trying to get lock"}, nil)
+
+	// Test
+	id, err := uc.LogGame(context.Background(), game)
+
+	// Assert
+	require.NoError(t, err)
+	assert.NotEmpty(t, id)
+	// nothing of the rolled back attempt was cached, the retry resolved the roster again
+	assert.Equal(t, map[string]int{"Lakers": 2, "Warriors": 2}, teamRepo.saves)
+	assert.Equal(t, map[string]int{"team-Lakers/Lakers guard": 2, "team-Warriors/Warriors guard": 2}, playerRepo.saves)
+	// only the ids of the committed attempt are cached
+	assert.Equal(t, map[string]string{"Lakers": "team-Lakers", "Warriors": "team-Warriors"}, teamRepo.cache)
+	assert.Equal(t, map[string]string{
+		"team-Lakers/Lakers guard":     "team-Lakers/Lakers guard",
+		"team-Warriors/Warriors guard": "team-Warriors/Warriors guard",
+	}, playerRepo.cache)
+	require.Len(t, gameRepo.saved, 1)
+}
+
+func TestUseCase_LogGame_RolledBack(t *testing.T) {
+	// Setup
+	uc, gameRepo, teamRepo, playerRepo := newBatchUseCase(t, 1)
+	game := slate()[0]
+	gameRepo.fail[game.ID] = apperror.FromDB(&mysql.MySQLError{Number: 3819, Message: "Check constraint is violated"}, nil)
+
+	// Test
+	_, err := uc.LogGame(context.Background(), game)
+
+	// Assert
+	assert.Equal(t, apperror.KindValidation, apperror.KindOf(err))
+	assert.Empty(t, teamRepo.cache, "ids of a rolled back transaction are never cached")
+	assert.Empty(t, playerRepo.cache)
+}
+
+func TestUseCase_LogGame_NoPlayers(t *testing.T) {
+	// Setup
+	uc, gameRepo, teamRepo, _ := newBatchUseCase(t, 1)
+	outbox := &fakeOutbox{}
+	uc.outbox = outbox
+	game := game_domain.GameStatsReq{Date: time.Now(), Teams: []game_domain.Team{{Name: "Lakers", Players: []game_domain.Player{}}}}
+
+	// Test
+	_, err := uc.LogGame(context.Background(), game)
+
+	// Assert
+	require.Equal(t, apperror.KindValidation, apperror.KindOf(err))
+	assert.Equal(t, "teams", apperror.From(err).Fields[0].Field)
+	assert.Empty(t, gameRepo.saved)
+	assert.Empty(t, teamRepo.saves)
+	assert.Empty(t, outbox.events, "nothing is announced for a game that was never stored")
+}
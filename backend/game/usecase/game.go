@@ -3,183 +3,439 @@ package usecase
 import (
 	"context"
 	"database/sql"
-	"go.uber.org/zap"
-	"strings"
 	"time"
 
+	"go.uber.org/zap"
+
+	"skyhawk/backend/apperror"
+	audit_domain "skyhawk/backend/audit/domain"
 	game_domain "skyhawk/backend/game/domain"
+	"skyhawk/backend/logging"
+	outbox_domain "skyhawk/backend/outbox/domain"
 	player_domain "skyhawk/backend/player/domain"
+	"skyhawk/backend/retry"
 	"skyhawk/backend/team/domain"
+	webhook_domain "skyhawk/backend/webhook/domain"
 )
 
 type PlayerRepository interface {
-	SeasonStats(id string) (player_domain.PlayerSeasonStats, error)
-	Save(ctx context.Context, tx *sql.Tx, player []player_domain.Player) (map[string]string, error)
+	SeasonStats(ctx context.Context, id string) (player_domain.PlayerSeasonStats, error)
+	Save(ctx context.Context, tx *sql.Tx, player []player_domain.Player) (map[string]string, []player_domain.Player, error)
+	Remember(ctx context.Context, players []player_domain.Player) error
+	Lock(ctx context.Context, tx *sql.Tx, id string) (player_domain.Player, error)
+	Merge(ctx context.Context, tx *sql.Tx, from, into player_domain.Player) (int, error)
+	Forget(ctx context.Context, player player_domain.Player) error
 }
 
 type TeamRepository interface {
-	Save(context context.Context, tx *sql.Tx, team domain.Team) (string, error)
-	GetStats(id string) (domain.SeasonStats, error)
+	Save(context context.Context, tx *sql.Tx, team domain.Team) (string, bool, error)
+	Remember(ctx context.Context, teams []domain.Team) error
+	GetStats(ctx context.Context, id string) (domain.SeasonStats, error)
 }
 
 type GameRepository interface {
-	Begin() (tx *sql.Tx, err error)
-	Save(tx *sql.Tx, game game_domain.GameStatsReq) (string, error)
-	Find(id string) ([]game_domain.GameStats, error)
+	Begin(ctx context.Context) (tx *sql.Tx, err error)
+	Save(ctx context.Context, tx *sql.Tx, game game_domain.GameStatsReq) (string, error)
+	Find(ctx context.Context, id string) ([]game_domain.GameStats, error)
+	FindGame(ctx context.Context, id string) (game_domain.Game, error)
+	CreateGame(ctx context.Context, tx *sql.Tx, game game_domain.Game) error
+	LockGame(ctx context.Context, tx *sql.Tx, id string) (game_domain.Game, error)
+	SetStatus(ctx context.Context, tx *sql.Tx, id string, status game_domain.Status) error
+	Lines(ctx context.Context, tx *sql.Tx, gameID string) ([]game_domain.GameStats, error)
+	ReplaceLines(ctx context.Context, tx *sql.Tx, gameID string, date time.Time, players []game_domain.Player) error
+}
+
+// Notifier - told about every committed change to the stats or status of a game
+type Notifier interface {
+	GameUpdated(ctx context.Context, gameID string)
+}
+
+// Webhooks - queues a webhook event inside the transaction of the change it announces
+type Webhooks interface {
+	Enqueue(ctx context.Context, tx *sql.Tx, event webhook_domain.Event) error
+}
+
+// Outbox - records domain events inside the transaction of the change they describe
+type Outbox interface {
+	Append(ctx context.Context, tx *sql.Tx, events []outbox_domain.Event) error
+}
+
+// Auditor - records who changed what inside the transaction of the change
+type Auditor interface {
+	Record(ctx context.Context, tx *sql.Tx, changes ...audit_domain.Change) error
+}
+
+// webhookEvents - the domain events webhooks can subscribe to
+var webhookEvents = map[outbox_domain.Type]webhook_domain.EventType{
+	outbox_domain.GameLogged:    webhook_domain.EventGameLogged,
+	outbox_domain.GameCorrected: webhook_domain.EventGameCorrected,
+	outbox_domain.GameVoided:    webhook_domain.EventGameVoided,
 }
 
 type GameUseCase interface {
-	GetGameStats(id string) ([]game_domain.GameStats, error)
-	GetPlayerSeasonStats(id string) (player_domain.PlayerSeasonStats, error)
-	GetTeamSeasonStats(id string) (domain.SeasonStats, error)
-	LogGame(stats game_domain.GameStatsReq) (string, error)
+	GetGameStats(ctx context.Context, id string) ([]game_domain.GameStats, error)
+	GetPlayerSeasonStats(ctx context.Context, id string) (player_domain.PlayerSeasonStats, error)
+	GetTeamSeasonStats(ctx context.Context, id string) (domain.SeasonStats, error)
+	LogGame(ctx context.Context, stats game_domain.GameStatsReq) (string, error)
+	LogGames(ctx context.Context, games []game_domain.GameStatsReq, mode game_domain.BatchMode) (game_domain.BatchRes, error)
+	ScheduleGame(ctx context.Context, req game_domain.ScheduleReq) (game_domain.Game, error)
+	StartGame(ctx context.Context, id string) (game_domain.Game, error)
+	UpdateLive(ctx context.Context, id string, update game_domain.LiveUpdate) (game_domain.BoxScore, error)
+	FinalizeGame(ctx context.Context, id string) (game_domain.Game, error)
+	GetBoxScore(ctx context.Context, id string) (game_domain.BoxScore, error)
+	CorrectGame(ctx context.Context, id string, stats game_domain.GameStatsReq) (game_domain.BoxScore, error)
+	VoidGame(ctx context.Context, id string) (game_domain.Game, error)
+	MergePlayers(ctx context.Context, from, into string) (player_domain.MergeRes, error)
 }
 
 type UseCase struct {
 	gameRepo   GameRepository
 	teamRepo   TeamRepository
 	playerRepo PlayerRepository
+	retrier    *retry.Retrier
+	batch      BatchOptions
+	notifier   Notifier
+	webhooks   Webhooks
+	outbox     Outbox
+	auditor    Auditor
 	logger     *zap.Logger
 }
 
-const maxRetries = 3
-
-func NewUseCase(logger *zap.Logger, gameRepo GameRepository, teamRepo TeamRepository, playerRepo PlayerRepository) *UseCase {
+// NewUseCase - notifier, webhooks, outbox and auditor may be nil when nobody follows games
+func NewUseCase(logger *zap.Logger, gameRepo GameRepository, teamRepo TeamRepository, playerRepo PlayerRepository, retrier *retry.Retrier, batch BatchOptions, notifier Notifier, webhooks Webhooks, outbox Outbox, auditor Auditor) *UseCase {
 
 	return &UseCase{
 		gameRepo:   gameRepo,
 		teamRepo:   teamRepo,
 		playerRepo: playerRepo,
+		retrier:    retrier,
+		batch:      batch,
+		notifier:   notifier,
+		webhooks:   webhooks,
+		outbox:     outbox,
+		auditor:    auditor,
 		logger:     logger,
 	}
 }
 
-func (s *UseCase) LogGame(stats game_domain.GameStatsReq) (string, error) {
+func (s *UseCase) LogGame(ctx context.Context, stats game_domain.GameStatsReq) (string, error) {
 	var id string
 
-	for retryCount := 0; retryCount < maxRetries; retryCount++ {
-		id, err := s.attemptTransaction(stats)
-
-		if err == nil {
-			// Success
-			return id, nil
-		}
-
-		// Check if it's a deadlock error
-		if strings.Contains(err.Error(), "Deadlock found") {
-			s.logger.Warn("Deadlock detected, retrying transaction",
-				zap.Int("attempt", retryCount+1),
-				zap.Int("maxRetries", maxRetries))
-
-			// Add exponential backoff
-			time.Sleep(time.Millisecond * 100 * time.Duration(retryCount+1))
-			continue
-		}
+	if err := stats.Validate(); err != nil {
+		return "", err
+	}
 
-		// Not a deadlock error, return it
+	// deadlocks, lock wait timeouts and dropped connections roll the whole transaction back, so it is safe to replay
+	attempts, err := s.retrier.Do(ctx, "LogGame", func(ctx context.Context) error {
+		var err error
+		id, err = s.attemptTransaction(ctx, stats)
+		return err
+	})
+	if err != nil {
+		logging.From(ctx, s.logger).Error("UseCase.LogGame failed", zap.Int("attempts", attempts), zap.Error(err))
 		return "", err
 	}
+	s.notify(ctx, id)
 
 	return id, nil
 }
 
-func (s *UseCase) attemptTransaction(stats game_domain.GameStatsReq) (string, error) {
-	// Start transaction
-	tx, err := s.gameRepo.Begin()
+func (s *UseCase) notify(ctx context.Context, gameID string) {
+	if s.notifier != nil {
+		s.notifier.GameUpdated(ctx, gameID)
+	}
+}
+
+// announce - records a game event in tx for the outbox relay and queues it for the webhooks subscribed to it
+func (s *UseCase) announce(ctx context.Context, tx *sql.Tx, eventType outbox_domain.Type, gameID string, data interface{}) error {
+	event, err := outbox_domain.NewEvent(eventType, gameID, data)
 	if err != nil {
-		s.logger.Error("UseCase.LogGame failed initiating transaction", zap.Error(err))
-		return "", err
+		return apperror.Internal(err)
+	}
+	if err = s.record(ctx, tx, event); err != nil {
+		return err
 	}
-	defer tx.Rollback()
 
-	// Save all teams and update their IDs in stats
-	for i := range stats.Teams {
-		id, err := s.teamRepo.Save(context.Background(), tx, domain.Team{
-			ID:   stats.Teams[i].ID,
-			Name: stats.Teams[i].Name,
-		})
-		if err != nil {
-			s.logger.Error("UseCase.LogGame failed logging teams", zap.Error(err))
-			return "", err
-		}
-		stats.Teams[i].ID = id
+	webhookType, ok := webhookEvents[eventType]
+	if s.webhooks == nil || !ok {
+		return nil
 	}
 
-	// Prepare players
-	var players []player_domain.Player
-	for _, team := range stats.Teams {
-		for i := range team.Players {
-			players = append(players, player_domain.Player{
-				Team: team.ID, // Now using the updated team ID
-				Name: team.Players[i].Name,
-			})
-		}
+	return s.webhooks.Enqueue(ctx, tx, webhook_domain.Event{ID: event.ID, Type: webhookType, GameID: gameID, OccurredAt: event.OccurredAt, Data: data})
+}
+
+func (s *UseCase) record(ctx context.Context, tx *sql.Tx, events ...outbox_domain.Event) error {
+	if s.outbox == nil || len(events) == 0 {
+		return nil
 	}
 
-	// Insert players
-	playerIdsMap, err := s.playerRepo.Save(context.Background(), tx, players)
+	return s.outbox.Append(ctx, tx, events)
+}
+
+// audit - writes changes to the audit log in tx
+func (s *UseCase) audit(ctx context.Context, tx *sql.Tx, changes ...audit_domain.Change) error {
+	if s.auditor == nil || len(changes) == 0 {
+		return nil
+	}
+
+	return s.auditor.Record(ctx, tx, changes...)
+}
+
+// logged - the audit change of a game logged as a final box score
+func logged(id string, stats game_domain.GameStatsReq) audit_domain.Change {
+	game := game_domain.Game{ID: id, Date: stats.Date, Source: game_domain.SourceBoxScore, Status: game_domain.StatusFinal}
+
+	return audit_domain.Change{Entity: audit_domain.EntityGame, EntityID: id, Action: audit_domain.ActionCreate, After: game_domain.NewState(game, stats.Players())}
+}
+
+func (s *UseCase) attemptTransaction(ctx context.Context, stats game_domain.GameStatsReq) (string, error) {
+	// resolved ids are written into the request, work on a copy so a rolled back attempt leaves nothing behind
+	stats.Teams = cloneTeams(stats.Teams)
+
+	// Start transaction
+	tx, err := s.gameRepo.Begin(ctx)
 	if err != nil {
-		s.logger.Error("UseCase.LogGame failed processing players", zap.Error(err))
-		return "", err
+		logging.From(ctx, s.logger).Error("UseCase.LogGame failed initiating transaction", zap.Error(err))
+		return "", apperror.FromDB(err, nil)
 	}
+	defer tx.Rollback()
 
-	// Update player IDs in stats
-	for i := range stats.Teams {
-		for j := range stats.Teams[i].Players {
-			playerName := stats.Teams[i].Players[j].Name
-			if id, exists := playerIdsMap[playerName]; exists {
-				stats.Teams[i].Players[j].ID = id
-			}
-		}
+	// Save teams and players and update their IDs in stats
+	games := []game_domain.GameStatsReq{stats}
+	resolved, err := s.resolveRoster(ctx, tx, games)
+	if err != nil {
+		return "", err
 	}
+	stats = games[0]
 
 	// insert game stats
-	id, err := s.gameRepo.Save(tx, stats)
+	id, err := s.gameRepo.Save(ctx, tx, stats)
 
 	if err != nil {
-		s.logger.Error("failed saving game stats", zap.Error(err))
+		logging.From(ctx, s.logger).Error("failed saving game stats", zap.Error(err))
 		return "", err
 
 	}
 
+	if err = s.announce(ctx, tx, outbox_domain.GameLogged, id, stats.BoxScore(id, game_domain.StatusFinal)); err != nil {
+		return "", err
+	}
+	if err = s.audit(ctx, tx, logged(id, stats)); err != nil {
+		return "", err
+	}
+
 	// Commit transaction
 	if err = tx.Commit(); err != nil {
-		s.logger.Error("failed committing changes", zap.Error(err))
-		return "", err
+		logging.From(ctx, s.logger).Error("failed committing changes", zap.Error(err))
+		// the outcome of a failed commit is unknown, replaying it could log the game twice
+		return "", retry.Permanent(apperror.FromDB(err, nil))
 	}
+	s.remember(ctx, resolved)
 
 	return id, nil
 }
 
-func (s *UseCase) GetPlayerSeasonStats(id string) (player_domain.PlayerSeasonStats, error) {
+// roster - the teams and players a transaction resolved, their ids are cached only once it commits
+// since a rolled back attempt would otherwise leave ids of rows that never existed in the cache
+type roster struct {
+	teams   []domain.Team
+	players []player_domain.Player
+}
+
+// remember - caches the ids of a committed roster, a failure only costs a lookup next time
+func (s *UseCase) remember(ctx context.Context, resolved roster) {
+	if len(resolved.teams) > 0 {
+		if err := s.teamRepo.Remember(ctx, resolved.teams); err != nil {
+			logging.From(ctx, s.logger).Warn("failed caching team ids", zap.Error(err))
+		}
+	}
+	if len(resolved.players) > 0 {
+		if err := s.playerRepo.Remember(ctx, resolved.players); err != nil {
+			logging.From(ctx, s.logger).Warn("failed caching player ids", zap.Error(err))
+		}
+	}
+}
+
+// resolveRoster - saves every distinct team and player of games once and writes the resolved ids back into games,
+// players are keyed by team so two players sharing a name on different teams stay apart
+func (s *UseCase) resolveRoster(ctx context.Context, tx *sql.Tx, games []game_domain.GameStatsReq) (roster, error) {
+	var resolved roster
+	teamIDs := make(map[string]string)
+	var teamOrder []string
+
+	for _, game := range games {
+		for _, team := range game.Teams {
+			if _, seen := teamIDs[team.Name]; !seen {
+				teamIDs[team.Name] = team.ID
+				teamOrder = append(teamOrder, team.Name)
+			}
+		}
+	}
+
+	// Save all teams, announcing and auditing the ones seen for the first time
+	var created []outbox_domain.Event
+	var changes []audit_domain.Change
+	for _, name := range teamOrder {
+		id, isNew, err := s.teamRepo.Save(ctx, tx, domain.Team{
+			ID:   teamIDs[name],
+			Name: name,
+		})
+		if err != nil {
+			logging.From(ctx, s.logger).Error("UseCase.LogGame failed logging teams", zap.Error(err))
+			return roster{}, err
+		}
+		teamIDs[name] = id
+		resolved.teams = append(resolved.teams, domain.Team{ID: id, Name: name})
+
+		if isNew {
+			data := outbox_domain.TeamCreatedData{ID: id, Name: name}
+			event, err := outbox_domain.NewEvent(outbox_domain.TeamCreated, id, data)
+			if err != nil {
+				return roster{}, apperror.Internal(err)
+			}
+			created = append(created, event)
+			changes = append(changes, audit_domain.Change{Entity: audit_domain.EntityTeam, EntityID: id, Action: audit_domain.ActionCreate, After: data})
+		}
+	}
+
+	// Prepare players per team, each player once
+	playersByTeam := make(map[string][]player_domain.Player)
+	seenPlayers := make(map[string]bool)
+	for _, game := range games {
+		for _, team := range game.Teams {
+			teamID := teamIDs[team.Name]
+			for i := range team.Players {
+				key := teamID + "/" + team.Players[i].Name
+				if seenPlayers[key] {
+					continue
+				}
+				seenPlayers[key] = true
+				playersByTeam[teamID] = append(playersByTeam[teamID], player_domain.Player{
+					Team: teamID,
+					Name: team.Players[i].Name,
+				})
+			}
+		}
+	}
 
-	stats, err := s.playerRepo.SeasonStats(id)
+	// Insert players
+	playerIDs := make(map[string]string, len(seenPlayers))
+	for _, name := range teamOrder {
+		teamID := teamIDs[name]
+		players, ok := playersByTeam[teamID]
+		if !ok {
+			continue
+		}
+		delete(playersByTeam, teamID)
+
+		playerIdsMap, newPlayers, err := s.playerRepo.Save(ctx, tx, players)
+		if err != nil {
+			logging.From(ctx, s.logger).Error("UseCase.LogGame failed processing players", zap.Error(err))
+			return roster{}, err
+		}
+		for playerName, id := range playerIdsMap {
+			playerIDs[teamID+"/"+playerName] = id
+			resolved.players = append(resolved.players, player_domain.Player{ID: id, Name: playerName, Team: teamID})
+		}
+		for _, player := range newPlayers {
+			data := outbox_domain.PlayerCreatedData{ID: player.ID, Name: player.Name, TeamID: player.Team}
+			event, err := outbox_domain.NewEvent(outbox_domain.PlayerCreated, player.ID, data)
+			if err != nil {
+				return roster{}, apperror.Internal(err)
+			}
+			created = append(created, event)
+			changes = append(changes, audit_domain.Change{Entity: audit_domain.EntityPlayer, EntityID: player.ID, Action: audit_domain.ActionCreate, After: data})
+		}
+	}
+	if err := s.record(ctx, tx, created...); err != nil {
+		return roster{}, err
+	}
+	if err := s.audit(ctx, tx, changes...); err != nil {
+		return roster{}, err
+	}
+
+	// Update team and player IDs in stats
+	for g := range games {
+		for i := range games[g].Teams {
+			team := &games[g].Teams[i]
+			team.ID = teamIDs[team.Name]
+			for j := range team.Players {
+				if id, exists := playerIDs[team.ID+"/"+team.Players[j].Name]; exists {
+					team.Players[j].ID = id
+				}
+			}
+		}
+	}
+
+	return resolved, nil
+}
+
+func cloneTeams(teams []game_domain.Team) []game_domain.Team {
+	cloned := make([]game_domain.Team, len(teams))
+	for i := range teams {
+		cloned[i] = teams[i]
+		cloned[i].Players = append([]game_domain.Player(nil), teams[i].Players...)
+	}
+
+	return cloned
+}
+
+func (s *UseCase) GetPlayerSeasonStats(ctx context.Context, id string) (player_domain.PlayerSeasonStats, error) {
+
+	stats, err := s.playerRepo.SeasonStats(ctx, id)
 
 	if err != nil {
-		s.logger.Error("UseCase.GetPlayerSeasonStats failed fetching stats", zap.Error(err))
+		logging.From(ctx, s.logger).Error("UseCase.GetPlayerSeasonStats failed fetching stats", zap.Error(err))
 		return player_domain.PlayerSeasonStats{}, err
 	}
 
 	return stats, nil
 }
 
-func (s *UseCase) GetGameStats(id string) ([]game_domain.GameStats, error) {
-	stats, err := s.gameRepo.Find(id)
+func (s *UseCase) GetGameStats(ctx context.Context, id string) ([]game_domain.GameStats, error) {
+	stats, err := s.gameRepo.Find(ctx, id)
 
 	if err != nil {
-		s.logger.Error("UseCase.GetGameStats failed fetching team stats", zap.Error(err))
+		logging.From(ctx, s.logger).Error("UseCase.GetGameStats failed fetching team stats", zap.Error(err))
 		return nil, err
 	}
 
+	// a scheduled or just started game has no lines yet
+	if len(stats) == 0 {
+		if _, err = s.gameRepo.FindGame(ctx, id); err != nil {
+			return nil, err
+		}
+		return []game_domain.GameStats{}, nil
+	}
+
 	return stats, nil
 }
 
-func (s *UseCase) GetTeamSeasonStats(id string) (domain.SeasonStats, error) {
+// GetBoxScore - a game with its current lines, found even before it has any
+func (s *UseCase) GetBoxScore(ctx context.Context, id string) (game_domain.BoxScore, error) {
+	game, err := s.gameRepo.FindGame(ctx, id)
+	if err != nil {
+		return game_domain.BoxScore{}, err
+	}
+
+	lines, err := s.gameRepo.Find(ctx, id)
+	if err != nil {
+		logging.From(ctx, s.logger).Error("UseCase.GetBoxScore failed fetching lines", zap.Error(err))
+		return game_domain.BoxScore{}, err
+	}
+	if lines == nil {
+		lines = []game_domain.GameStats{}
+	}
+
+	return game_domain.BoxScore{Game: game, Lines: lines}, nil
+}
+
+func (s *UseCase) GetTeamSeasonStats(ctx context.Context, id string) (domain.SeasonStats, error) {
 
-	stats, err := s.teamRepo.GetStats(id)
+	stats, err := s.teamRepo.GetStats(ctx, id)
 
 	if err != nil {
-		s.logger.Error("UseCase.GetTeamSeasonStats failed fetching team stats", zap.Error(err))
+		logging.From(ctx, s.logger).Error("UseCase.GetTeamSeasonStats failed fetching team stats", zap.Error(err))
 		return domain.SeasonStats{}, err
 	}
 
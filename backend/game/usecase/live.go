@@ -0,0 +1,341 @@
+package usecase
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/google/uuid"
+	"go.uber.org/zap"
+
+	"skyhawk/backend/apperror"
+	audit_domain "skyhawk/backend/audit/domain"
+	game_domain "skyhawk/backend/game/domain"
+	"skyhawk/backend/logging"
+	outbox_domain "skyhawk/backend/outbox/domain"
+	"skyhawk/backend/retry"
+)
+
+// ScheduleGame - creates a game ahead of tip off, its stats are pushed once it is live
+func (s *UseCase) ScheduleGame(ctx context.Context, req game_domain.ScheduleReq) (game_domain.Game, error) {
+	if err := req.Validate(); err != nil {
+		return game_domain.Game{}, err
+	}
+
+	game := game_domain.Game{ID: uuid.New().String(), Date: req.Date, Source: req.Source, Status: game_domain.StatusScheduled}
+	if game.Source == "" {
+		game.Source = game_domain.SourceBoxScore
+	}
+
+	_, err := s.retrier.Do(ctx, "ScheduleGame", func(ctx context.Context) error {
+		tx, err := s.gameRepo.Begin(ctx)
+		if err != nil {
+			return apperror.FromDB(err, nil)
+		}
+		defer tx.Rollback()
+
+		if err = s.gameRepo.CreateGame(ctx, tx, game); err != nil {
+			return err
+		}
+		if err = s.audit(ctx, tx, audit_domain.Change{Entity: audit_domain.EntityGame, EntityID: game.ID, Action: audit_domain.ActionSchedule, After: game}); err != nil {
+			return err
+		}
+
+		if err = tx.Commit(); err != nil {
+			return retry.Permanent(apperror.FromDB(err, nil))
+		}
+
+		return nil
+	})
+	if err != nil {
+		logging.From(ctx, s.logger).Error("UseCase.ScheduleGame failed", zap.Error(err))
+		return game_domain.Game{}, err
+	}
+
+	return game, nil
+}
+
+// StartGame - moves a scheduled game to live
+func (s *UseCase) StartGame(ctx context.Context, id string) (game_domain.Game, error) {
+	return s.moveTo(ctx, id, game_domain.StatusLive)
+}
+
+// FinalizeGame - moves a live game to final, from here on its lines count towards season stats and only change by correction
+func (s *UseCase) FinalizeGame(ctx context.Context, id string) (game_domain.Game, error) {
+	return s.moveTo(ctx, id, game_domain.StatusFinal)
+}
+
+// VoidGame - takes a game out of every season stat, its lines are kept
+func (s *UseCase) VoidGame(ctx context.Context, id string) (game_domain.Game, error) {
+	return s.moveTo(ctx, id, game_domain.StatusVoided)
+}
+
+// statusActions - how a status change is named in the audit log
+var statusActions = map[game_domain.Status]audit_domain.Action{
+	game_domain.StatusLive:   audit_domain.ActionStart,
+	game_domain.StatusFinal:  audit_domain.ActionFinalize,
+	game_domain.StatusVoided: audit_domain.ActionVoid,
+}
+
+func (s *UseCase) moveTo(ctx context.Context, id string, status game_domain.Status) (game_domain.Game, error) {
+	var game game_domain.Game
+
+	_, err := s.retrier.Do(ctx, "MoveGame", func(ctx context.Context) error {
+		tx, err := s.gameRepo.Begin(ctx)
+		if err != nil {
+			return apperror.FromDB(err, nil)
+		}
+		defer tx.Rollback()
+
+		game, err = s.gameRepo.LockGame(ctx, tx, id)
+		if err != nil {
+			return err
+		}
+
+		if !game.Status.CanMoveTo(status) {
+			return statusConflict(game, status)
+		}
+
+		if err = s.gameRepo.SetStatus(ctx, tx, id, status); err != nil {
+			return err
+		}
+		before := game
+		game.Status = status
+		if err = s.audit(ctx, tx, audit_domain.Change{Entity: audit_domain.EntityGame, EntityID: id, Action: statusActions[status], Before: before, After: game}); err != nil {
+			return err
+		}
+
+		switch status {
+		case game_domain.StatusFinal:
+			lines, err := s.gameRepo.Lines(ctx, tx, id)
+			if err != nil {
+				return err
+			}
+			if err = s.announce(ctx, tx, outbox_domain.GameLogged, id, boxScore(game, lines)); err != nil {
+				return err
+			}
+		case game_domain.StatusVoided:
+			if err = s.announce(ctx, tx, outbox_domain.GameVoided, id, game); err != nil {
+				return err
+			}
+		}
+
+		if err = tx.Commit(); err != nil {
+			return retry.Permanent(apperror.FromDB(err, nil))
+		}
+
+		return nil
+	})
+	if err != nil {
+		logging.From(ctx, s.logger).Error("UseCase.MoveGame failed", zap.String("game_id", id), zap.String("status", string(status)), zap.Error(err))
+		return game_domain.Game{}, err
+	}
+	s.notify(ctx, id)
+
+	return game, nil
+}
+
+// UpdateLive - merges running totals into a live game's box score and returns the new box score
+func (s *UseCase) UpdateLive(ctx context.Context, id string, update game_domain.LiveUpdate) (game_domain.BoxScore, error) {
+	if err := update.Validate(); err != nil {
+		return game_domain.BoxScore{}, err
+	}
+
+	var game game_domain.Game
+	attempts, err := s.retrier.Do(ctx, "UpdateLive", func(ctx context.Context) error {
+		var err error
+		game, err = s.attemptUpdate(ctx, id, update)
+		return err
+	})
+	if err != nil {
+		logging.From(ctx, s.logger).Error("UseCase.UpdateLive failed", zap.String("game_id", id), zap.Int("attempts", attempts), zap.Error(err))
+		return game_domain.BoxScore{}, err
+	}
+	s.notify(ctx, id)
+
+	lines, err := s.gameRepo.Find(ctx, id)
+	if err != nil {
+		return game_domain.BoxScore{}, err
+	}
+
+	return game_domain.BoxScore{Game: game, Lines: lines}, nil
+}
+
+func (s *UseCase) attemptUpdate(ctx context.Context, id string, update game_domain.LiveUpdate) (game_domain.Game, error) {
+	tx, err := s.gameRepo.Begin(ctx)
+	if err != nil {
+		return game_domain.Game{}, apperror.FromDB(err, nil)
+	}
+	defer tx.Rollback()
+
+	// the row lock orders concurrent updates of a game, so deltas are never lost
+	game, err := s.gameRepo.LockGame(ctx, tx, id)
+	if err != nil {
+		return game_domain.Game{}, err
+	}
+	if game.Status == game_domain.StatusFinal || game.Status == game_domain.StatusVoided {
+		return game_domain.Game{}, statusConflict(game, game.Status)
+	}
+	if game.Status != game_domain.StatusLive {
+		return game_domain.Game{}, apperror.Conflict("game_not_live", fmt.Sprintf("game is %s, stats are accepted once it is live", game.Status), nil)
+	}
+	if game.Source != game_domain.SourceBoxScore {
+		return game_domain.Game{}, apperror.Conflict("game_event_sourced", "game stats are derived from its events", nil)
+	}
+
+	games := []game_domain.GameStatsReq{{Teams: cloneTeams(update.Teams)}}
+	resolved, err := s.resolveRoster(ctx, tx, games)
+	if err != nil {
+		return game_domain.Game{}, err
+	}
+
+	current, err := s.gameRepo.Lines(ctx, tx, id)
+	if err != nil {
+		return game_domain.Game{}, err
+	}
+
+	lines, err := mergeLines(current, games[0].Teams, update.Mode)
+	if err != nil {
+		return game_domain.Game{}, err
+	}
+
+	if err = s.gameRepo.ReplaceLines(ctx, tx, id, game.Date, lines); err != nil {
+		return game_domain.Game{}, err
+	}
+	if err = s.audit(ctx, tx, audit_domain.Change{Entity: audit_domain.EntityGame, EntityID: id, Action: audit_domain.ActionLiveUpdate,
+		Before: game_domain.NewState(game, game_domain.Players(current)), After: game_domain.NewState(game, lines)}); err != nil {
+		return game_domain.Game{}, err
+	}
+
+	if err = tx.Commit(); err != nil {
+		return game_domain.Game{}, retry.Permanent(apperror.FromDB(err, nil))
+	}
+	s.remember(ctx, resolved)
+
+	return game, nil
+}
+
+// mergeLines - applies the resolved teams of an update on top of the current lines and validates the totals
+func mergeLines(current []game_domain.GameStats, teams []game_domain.Team, mode game_domain.UpdateMode) ([]game_domain.Player, error) {
+	index := make(map[string]int, len(current))
+	lines := make([]game_domain.Player, 0, len(current))
+	for i, line := range current {
+		index[line.PlayerID] = i
+		lines = append(lines, line.Player())
+	}
+
+	var fields []apperror.FieldError
+	for i, team := range teams {
+		for j, player := range team.Players {
+			at, exists := index[player.ID]
+			if !exists {
+				at = len(lines)
+				index[player.ID] = at
+				lines = append(lines, game_domain.Player{ID: player.ID, Name: player.Name})
+			}
+
+			if mode == game_domain.UpdateModeDelta {
+				lines[at] = lines[at].Add(player)
+			} else {
+				lines[at] = player
+			}
+			fields = append(fields, lines[at].Validate(fmt.Sprintf("teams[%d].players[%d]", i, j))...)
+		}
+	}
+
+	if len(fields) > 0 {
+		return nil, apperror.Validation("invalid_update", "update leaves invalid totals", fields...)
+	}
+
+	return lines, nil
+}
+
+// CorrectGame - replaces every line of a final box score game, announced to webhooks as a correction
+func (s *UseCase) CorrectGame(ctx context.Context, id string, stats game_domain.GameStatsReq) (game_domain.BoxScore, error) {
+	if err := stats.Validate(); err != nil {
+		return game_domain.BoxScore{}, err
+	}
+
+	attempts, err := s.retrier.Do(ctx, "CorrectGame", func(ctx context.Context) error {
+		attempt := stats
+		attempt.Teams = cloneTeams(stats.Teams)
+
+		tx, err := s.gameRepo.Begin(ctx)
+		if err != nil {
+			return apperror.FromDB(err, nil)
+		}
+		defer tx.Rollback()
+
+		game, err := s.gameRepo.LockGame(ctx, tx, id)
+		if err != nil {
+			return err
+		}
+		switch {
+		case game.Status == game_domain.StatusVoided:
+			return statusConflict(game, game.Status)
+		case game.Status != game_domain.StatusFinal:
+			return apperror.Conflict("game_not_final", fmt.Sprintf("game is %s, live games are updated with PATCH /games/:id/stats", game.Status), nil)
+		case game.Source != game_domain.SourceBoxScore:
+			return apperror.Conflict("game_event_sourced", "game stats are derived from its events", nil)
+		}
+
+		games := []game_domain.GameStatsReq{attempt}
+		resolved, err := s.resolveRoster(ctx, tx, games)
+		if err != nil {
+			return err
+		}
+		attempt = games[0]
+
+		current, err := s.gameRepo.Lines(ctx, tx, id)
+		if err != nil {
+			return err
+		}
+		players := attempt.Players()
+		if err = s.gameRepo.ReplaceLines(ctx, tx, id, game.Date, players); err != nil {
+			return err
+		}
+		if err = s.audit(ctx, tx, audit_domain.Change{Entity: audit_domain.EntityGame, EntityID: id, Action: audit_domain.ActionCorrect,
+			Before: game_domain.NewState(game, game_domain.Players(current)), After: game_domain.NewState(game, players)}); err != nil {
+			return err
+		}
+
+		attempt.Date = game.Date
+		if err = s.announce(ctx, tx, outbox_domain.GameCorrected, id, attempt.BoxScore(id, game.Status)); err != nil {
+			return err
+		}
+
+		if err = tx.Commit(); err != nil {
+			return retry.Permanent(apperror.FromDB(err, nil))
+		}
+		s.remember(ctx, resolved)
+		return nil
+	})
+	if err != nil {
+		logging.From(ctx, s.logger).Error("UseCase.CorrectGame failed", zap.String("game_id", id), zap.Int("attempts", attempts), zap.Error(err))
+		return game_domain.BoxScore{}, err
+	}
+	s.notify(ctx, id)
+
+	return s.GetBoxScore(ctx, id)
+}
+
+func boxScore(game game_domain.Game, lines []game_domain.GameStats) game_domain.BoxScore {
+	if lines == nil {
+		lines = []game_domain.GameStats{}
+	}
+	for i := range lines {
+		lines[i].Status = game.Status
+	}
+
+	return game_domain.BoxScore{Game: game, Lines: lines}
+}
+
+func statusConflict(game game_domain.Game, want game_domain.Status) error {
+	switch game.Status {
+	case game_domain.StatusFinal:
+		return apperror.Conflict("game_final", "game is final and cannot change", nil)
+	case game_domain.StatusVoided:
+		return apperror.Conflict("game_voided", "game is voided and cannot change", nil)
+	}
+
+	return apperror.Conflict("invalid_status", fmt.Sprintf("game is %s, cannot move to %s", game.Status, want), nil)
+}
@@ -0,0 +1,239 @@
+package usecase
+
+import (
+	"context"
+	"database/sql"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"skyhawk/backend/apperror"
+	game_domain "skyhawk/backend/game/domain"
+	webhook_domain "skyhawk/backend/webhook/domain"
+)
+
+// fakeWebhooks - records what would be queued for delivery
+type fakeWebhooks struct {
+	events []webhook_domain.Event
+}
+
+func (f *fakeWebhooks) Enqueue(_ context.Context, _ *sql.Tx, event webhook_domain.Event) error {
+	f.events = append(f.events, event)
+	return nil
+}
+
+func liveUpdate(mode game_domain.UpdateMode, points, fouls int) game_domain.LiveUpdate {
+	return game_domain.LiveUpdate{
+		Mode: mode,
+		Teams: []game_domain.Team{{
+			Name:    "Lakers",
+			Players: []game_domain.Player{{Name: "LeBron James", Points: points, Fouls: fouls, MinutesPlayed: 6}},
+		}},
+	}
+}
+
+func TestUseCase_GameLifecycle(t *testing.T) {
+	// Setup
+	service, gameRepo, _, _ := newBatchUseCase(t, 9)
+	ctx := context.Background()
+
+	// Test
+	game, err := service.ScheduleGame(ctx, game_domain.ScheduleReq{Date: time.Now()})
+	require.NoError(t, err)
+	assert.Equal(t, game_domain.StatusScheduled, game.Status)
+	assert.Equal(t, game_domain.SourceBoxScore, game.Source)
+
+	_, err = service.UpdateLive(ctx, game.ID, liveUpdate(game_domain.UpdateModeDelta, 2, 0))
+	assert.Equal(t, "game_not_live", apperror.From(err).Code)
+
+	game, err = service.StartGame(ctx, game.ID)
+	require.NoError(t, err)
+	assert.Equal(t, game_domain.StatusLive, game.Status)
+
+	_, err = service.UpdateLive(ctx, game.ID, liveUpdate(game_domain.UpdateModeDelta, 2, 1))
+	require.NoError(t, err)
+	box, err := service.UpdateLive(ctx, game.ID, liveUpdate(game_domain.UpdateModeDelta, 3, 0))
+	require.NoError(t, err)
+
+	// Assert
+	require.Len(t, box.Lines, 1)
+	assert.Equal(t, 5, box.Lines[0].Points)
+	assert.Equal(t, 1, box.Lines[0].Fouls)
+	assert.Equal(t, 12.0, box.Lines[0].MinutesPlayed)
+	assert.Equal(t, game_domain.StatusLive, box.Lines[0].Status)
+
+	// a snapshot replaces the running totals
+	box, err = service.UpdateLive(ctx, game.ID, liveUpdate(game_domain.UpdateModeSnapshot, 20, 2))
+	require.NoError(t, err)
+	assert.Equal(t, 20, box.Lines[0].Points)
+	assert.Equal(t, 6.0, box.Lines[0].MinutesPlayed)
+
+	game, err = service.FinalizeGame(ctx, game.ID)
+	require.NoError(t, err)
+	assert.Equal(t, game_domain.StatusFinal, game.Status)
+
+	_, err = service.UpdateLive(ctx, game.ID, liveUpdate(game_domain.UpdateModeDelta, 2, 0))
+	assert.Equal(t, "game_final", apperror.From(err).Code)
+	_, err = service.FinalizeGame(ctx, game.ID)
+	assert.Equal(t, "game_final", apperror.From(err).Code)
+	assert.Equal(t, 20, gameRepo.lines[game.ID][0].Points)
+}
+
+func TestUseCase_UpdateLive(t *testing.T) {
+	t.Run("delta leaving invalid totals", func(t *testing.T) {
+		// Setup
+		service, gameRepo, _, _ := newBatchUseCase(t, 1)
+		gameRepo.games["g1"] = game_domain.Game{ID: "g1", Source: game_domain.SourceBoxScore, Status: game_domain.StatusLive}
+		gameRepo.lines["g1"] = []game_domain.Player{{ID: "team-Lakers/LeBron James", Name: "LeBron James", Fouls: 5}}
+
+		// Test
+		_, err := service.UpdateLive(context.Background(), "g1", liveUpdate(game_domain.UpdateModeDelta, 0, 2))
+
+		// Assert
+		require.Error(t, err)
+		assert.Equal(t, apperror.KindValidation, apperror.KindOf(err))
+		assert.Equal(t, "teams[0].players[0].fouls", apperror.From(err).Fields[0].Field)
+		assert.Equal(t, 5, gameRepo.lines["g1"][0].Fouls)
+	})
+
+	t.Run("negative delta corrects a line", func(t *testing.T) {
+		// Setup
+		service, gameRepo, _, _ := newBatchUseCase(t, 1)
+		gameRepo.games["g1"] = game_domain.Game{ID: "g1", Source: game_domain.SourceBoxScore, Status: game_domain.StatusLive}
+		gameRepo.lines["g1"] = []game_domain.Player{{ID: "team-Lakers/LeBron James", Name: "LeBron James", Points: 10}}
+
+		// Test
+		box, err := service.UpdateLive(context.Background(), "g1", liveUpdate(game_domain.UpdateModeDelta, -2, 0))
+
+		// Assert
+		require.NoError(t, err)
+		assert.Equal(t, 8, box.Lines[0].Points)
+	})
+
+	t.Run("event sourced game", func(t *testing.T) {
+		// Setup
+		service, gameRepo, _, _ := newBatchUseCase(t, 1)
+		gameRepo.games["g1"] = game_domain.Game{ID: "g1", Source: game_domain.SourceEvents, Status: game_domain.StatusLive}
+
+		// Test
+		_, err := service.UpdateLive(context.Background(), "g1", liveUpdate(game_domain.UpdateModeDelta, 2, 0))
+
+		// Assert
+		assert.Equal(t, apperror.KindConflict, apperror.KindOf(err))
+	})
+
+	t.Run("unknown mode", func(t *testing.T) {
+		// Setup
+		service, _, _, _ := newBatchUseCase(t, 0)
+
+		// Test
+		_, err := service.UpdateLive(context.Background(), "g1", liveUpdate("replace", 2, 0))
+
+		// Assert
+		assert.Equal(t, apperror.KindValidation, apperror.KindOf(err))
+	})
+}
+
+func TestUseCase_GetGameStats(t *testing.T) {
+	t.Run("started game without lines", func(t *testing.T) {
+		// Setup
+		service, gameRepo, _, _ := newBatchUseCase(t, 0)
+		gameRepo.games["g1"] = game_domain.Game{ID: "g1", Status: game_domain.StatusLive}
+
+		// Test
+		stats, err := service.GetGameStats(context.Background(), "g1")
+
+		// Assert
+		require.NoError(t, err)
+		assert.NotNil(t, stats)
+		assert.Empty(t, stats)
+	})
+
+	t.Run("unknown game", func(t *testing.T) {
+		// Setup
+		service, _, _, _ := newBatchUseCase(t, 0)
+
+		// Test
+		_, err := service.GetGameStats(context.Background(), "g1")
+
+		// Assert
+		assert.True(t, apperror.IsNotFound(err))
+	})
+}
+
+func TestUseCase_CorrectGame(t *testing.T) {
+	correction := game_domain.GameStatsReq{Teams: []game_domain.Team{{
+		Name:    "Lakers",
+		Players: []game_domain.Player{{Name: "LeBron James", Points: 31, MinutesPlayed: 38}},
+	}}}
+
+	t.Run("final game", func(t *testing.T) {
+		// Setup
+		service, gameRepo, _, _ := newBatchUseCase(t, 1)
+		webhooks := &fakeWebhooks{}
+		service.webhooks = webhooks
+		date := time.Date(2024, 11, 2, 19, 30, 0, 0, time.UTC)
+		gameRepo.games["g1"] = game_domain.Game{ID: "g1", Date: date, Source: game_domain.SourceBoxScore, Status: game_domain.StatusFinal}
+		gameRepo.lines["g1"] = []game_domain.Player{{ID: "team-Lakers/LeBron James", Name: "LeBron James", Points: 13}}
+
+		// Test
+		box, err := service.CorrectGame(context.Background(), "g1", correction)
+
+		// Assert
+		require.NoError(t, err)
+		require.Len(t, box.Lines, 1)
+		assert.Equal(t, 31, box.Lines[0].Points)
+		require.Len(t, webhooks.events, 1)
+		assert.Equal(t, webhook_domain.EventGameCorrected, webhooks.events[0].Type)
+		sent := webhooks.events[0].Data.(game_domain.BoxScore)
+		assert.Equal(t, date, sent.Date)
+		assert.Equal(t, 31, sent.Lines[0].Points)
+		assert.Equal(t, "team-Lakers", sent.Lines[0].TeamID)
+	})
+
+	t.Run("live game", func(t *testing.T) {
+		// Setup
+		service, gameRepo, _, _ := newBatchUseCase(t, 1)
+		gameRepo.games["g1"] = game_domain.Game{ID: "g1", Source: game_domain.SourceBoxScore, Status: game_domain.StatusLive}
+
+		// Test
+		_, err := service.CorrectGame(context.Background(), "g1", correction)
+
+		// Assert
+		assert.Equal(t, "game_not_final", apperror.From(err).Code)
+	})
+
+	t.Run("voided game", func(t *testing.T) {
+		// Setup
+		service, gameRepo, _, _ := newBatchUseCase(t, 1)
+		gameRepo.games["g1"] = game_domain.Game{ID: "g1", Source: game_domain.SourceBoxScore, Status: game_domain.StatusVoided}
+
+		// Test
+		_, err := service.CorrectGame(context.Background(), "g1", correction)
+
+		// Assert
+		assert.Equal(t, "game_voided", apperror.From(err).Code)
+	})
+}
+
+func TestUseCase_VoidGame(t *testing.T) {
+	// Setup
+	service, gameRepo, _, _ := newBatchUseCase(t, 2)
+	webhooks := &fakeWebhooks{}
+	service.webhooks = webhooks
+	gameRepo.games["g1"] = game_domain.Game{ID: "g1", Source: game_domain.SourceBoxScore, Status: game_domain.StatusFinal}
+
+	// Test
+	game, err := service.VoidGame(context.Background(), "g1")
+	require.NoError(t, err)
+	_, err = service.VoidGame(context.Background(), "g1")
+
+	// Assert
+	assert.Equal(t, game_domain.StatusVoided, game.Status)
+	assert.Equal(t, "game_voided", apperror.From(err).Code)
+	require.Len(t, webhooks.events, 1)
+	assert.Equal(t, webhook_domain.EventGameVoided, webhooks.events[0].Type)
+	assert.Equal(t, "g1", webhooks.events[0].GameID)
+}
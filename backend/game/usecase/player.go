@@ -0,0 +1,74 @@
+package usecase
+
+import (
+	"context"
+
+	"go.uber.org/zap"
+
+	"skyhawk/backend/apperror"
+	audit_domain "skyhawk/backend/audit/domain"
+	"skyhawk/backend/logging"
+	player_domain "skyhawk/backend/player/domain"
+	"skyhawk/backend/retry"
+)
+
+// MergePlayers - folds a duplicate player, e.g. a misspelt name, into the player it duplicates.
+// Both must play for the same team, the season stats of into include the moved lines right away
+func (s *UseCase) MergePlayers(ctx context.Context, from, into string) (player_domain.MergeRes, error) {
+	if from == "" || into == "" || from == into {
+		return player_domain.MergeRes{}, apperror.Validation("invalid_merge", "two different player ids are required",
+			apperror.FieldError{Field: "from", Message: "must differ from into"})
+	}
+
+	var res player_domain.MergeRes
+	var duplicate player_domain.Player
+	_, err := s.retrier.Do(ctx, "MergePlayers", func(ctx context.Context) error {
+		tx, err := s.gameRepo.Begin(ctx)
+		if err != nil {
+			return apperror.FromDB(err, nil)
+		}
+		defer tx.Rollback()
+
+		// locked in id order so two merges of the same pair cannot deadlock
+		locked := make(map[string]player_domain.Player, 2)
+		first, second := from, into
+		if second < first {
+			first, second = second, first
+		}
+		for _, id := range []string{first, second} {
+			if locked[id], err = s.playerRepo.Lock(ctx, tx, id); err != nil {
+				return err
+			}
+		}
+		duplicate = locked[from]
+		if duplicate.Team != locked[into].Team {
+			return apperror.Conflict("players_on_different_teams", "only players of the same team can be merged", nil)
+		}
+
+		moved, err := s.playerRepo.Merge(ctx, tx, duplicate, locked[into])
+		if err != nil {
+			return err
+		}
+		if err = s.audit(ctx, tx, audit_domain.Change{Entity: audit_domain.EntityPlayer, EntityID: from, Action: audit_domain.ActionMerge, Before: duplicate, After: locked[into]}); err != nil {
+			return err
+		}
+
+		if err = tx.Commit(); err != nil {
+			return retry.Permanent(apperror.FromDB(err, nil))
+		}
+		res = player_domain.MergeRes{From: from, Into: into, Lines: moved}
+
+		return nil
+	})
+	if err != nil {
+		logging.From(ctx, s.logger).Error("UseCase.MergePlayers failed", zap.String("from", from), zap.String("into", into), zap.Error(err))
+		return player_domain.MergeRes{}, err
+	}
+
+	// the name of the duplicate would otherwise resolve to the deleted id until its cache entry expires
+	if err = s.playerRepo.Forget(ctx, duplicate); err != nil {
+		logging.From(ctx, s.logger).Warn("failed forgetting merged player", zap.String("player_id", from), zap.Error(err))
+	}
+
+	return res, nil
+}
@@ -0,0 +1,59 @@
+package usecase
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"skyhawk/backend/apperror"
+	player_domain "skyhawk/backend/player/domain"
+)
+
+func TestUseCase_MergePlayers(t *testing.T) {
+	t.Run("moves the lines and forgets the duplicate", func(t *testing.T) {
+		// Setup
+		service, _, _, playerRepo := newBatchUseCase(t, 1)
+		playerRepo.players["p1"] = player_domain.Player{ID: "p1", Name: "LeBron James", Team: "lakers"}
+		playerRepo.players["p2"] = player_domain.Player{ID: "p2", Name: "Lebron James", Team: "lakers"}
+		playerRepo.lines["p1"] = 40
+		playerRepo.lines["p2"] = 3
+
+		// Test
+		res, err := service.MergePlayers(context.Background(), "p2", "p1")
+
+		// Assert
+		require.NoError(t, err)
+		assert.Equal(t, player_domain.MergeRes{From: "p2", Into: "p1", Lines: 3}, res)
+		assert.Equal(t, 43, playerRepo.lines["p1"])
+		assert.NotContains(t, playerRepo.players, "p2")
+		assert.Equal(t, []player_domain.Player{{ID: "p2", Name: "Lebron James", Team: "lakers"}}, playerRepo.forgotten)
+	})
+
+	t.Run("players of different teams", func(t *testing.T) {
+		// Setup
+		service, _, _, playerRepo := newBatchUseCase(t, 1)
+		playerRepo.players["p1"] = player_domain.Player{ID: "p1", Name: "Anthony Davis", Team: "lakers"}
+		playerRepo.players["p2"] = player_domain.Player{ID: "p2", Name: "Anthony Davis", Team: "pelicans"}
+
+		// Test
+		_, err := service.MergePlayers(context.Background(), "p2", "p1")
+
+		// Assert
+		assert.Equal(t, "players_on_different_teams", apperror.From(err).Code)
+		assert.Contains(t, playerRepo.players, "p2")
+		assert.Empty(t, playerRepo.forgotten)
+	})
+
+	t.Run("same player", func(t *testing.T) {
+		// Setup
+		service, _, _, _ := newBatchUseCase(t, 0)
+
+		// Test
+		_, err := service.MergePlayers(context.Background(), "p1", "p1")
+
+		// Assert
+		assert.Equal(t, "invalid_merge", apperror.From(err).Code)
+	})
+}
@@ -0,0 +1,110 @@
+package usecase
+
+import (
+	"context"
+
+	game_domain "skyhawk/backend/game/domain"
+	player_domain "skyhawk/backend/player/domain"
+	"skyhawk/backend/team/domain"
+	"skyhawk/backend/tracing"
+)
+
+// Traced - a GameUseCase with a span around every method, the repository and sql spans nest under it
+type Traced struct {
+	next GameUseCase
+}
+
+func NewTraced(next GameUseCase) GameUseCase {
+	return &Traced{next: next}
+}
+
+func (t *Traced) GetGameStats(ctx context.Context, id string) (lines []game_domain.GameStats, err error) {
+	ctx, span := tracing.Start(ctx, "game_usecase.GetGameStats")
+	defer func() { tracing.End(span, err) }()
+
+	return t.next.GetGameStats(ctx, id)
+}
+
+func (t *Traced) GetPlayerSeasonStats(ctx context.Context, id string) (stats player_domain.PlayerSeasonStats, err error) {
+	ctx, span := tracing.Start(ctx, "game_usecase.GetPlayerSeasonStats")
+	defer func() { tracing.End(span, err) }()
+
+	return t.next.GetPlayerSeasonStats(ctx, id)
+}
+
+func (t *Traced) GetTeamSeasonStats(ctx context.Context, id string) (stats domain.SeasonStats, err error) {
+	ctx, span := tracing.Start(ctx, "game_usecase.GetTeamSeasonStats")
+	defer func() { tracing.End(span, err) }()
+
+	return t.next.GetTeamSeasonStats(ctx, id)
+}
+
+func (t *Traced) LogGame(ctx context.Context, stats game_domain.GameStatsReq) (id string, err error) {
+	ctx, span := tracing.Start(ctx, "game_usecase.LogGame")
+	defer func() { tracing.End(span, err) }()
+
+	return t.next.LogGame(ctx, stats)
+}
+
+func (t *Traced) LogGames(ctx context.Context, games []game_domain.GameStatsReq, mode game_domain.BatchMode) (res game_domain.BatchRes, err error) {
+	ctx, span := tracing.Start(ctx, "game_usecase.LogGames")
+	defer func() { tracing.End(span, err) }()
+
+	return t.next.LogGames(ctx, games, mode)
+}
+
+func (t *Traced) ScheduleGame(ctx context.Context, req game_domain.ScheduleReq) (game game_domain.Game, err error) {
+	ctx, span := tracing.Start(ctx, "game_usecase.ScheduleGame")
+	defer func() { tracing.End(span, err) }()
+
+	return t.next.ScheduleGame(ctx, req)
+}
+
+func (t *Traced) StartGame(ctx context.Context, id string) (game game_domain.Game, err error) {
+	ctx, span := tracing.Start(ctx, "game_usecase.StartGame")
+	defer func() { tracing.End(span, err) }()
+
+	return t.next.StartGame(ctx, id)
+}
+
+func (t *Traced) UpdateLive(ctx context.Context, id string, update game_domain.LiveUpdate) (box game_domain.BoxScore, err error) {
+	ctx, span := tracing.Start(ctx, "game_usecase.UpdateLive")
+	defer func() { tracing.End(span, err) }()
+
+	return t.next.UpdateLive(ctx, id, update)
+}
+
+func (t *Traced) FinalizeGame(ctx context.Context, id string) (game game_domain.Game, err error) {
+	ctx, span := tracing.Start(ctx, "game_usecase.FinalizeGame")
+	defer func() { tracing.End(span, err) }()
+
+	return t.next.FinalizeGame(ctx, id)
+}
+
+func (t *Traced) GetBoxScore(ctx context.Context, id string) (box game_domain.BoxScore, err error) {
+	ctx, span := tracing.Start(ctx, "game_usecase.GetBoxScore")
+	defer func() { tracing.End(span, err) }()
+
+	return t.next.GetBoxScore(ctx, id)
+}
+
+func (t *Traced) CorrectGame(ctx context.Context, id string, stats game_domain.GameStatsReq) (box game_domain.BoxScore, err error) {
+	ctx, span := tracing.Start(ctx, "game_usecase.CorrectGame")
+	defer func() { tracing.End(span, err) }()
+
+	return t.next.CorrectGame(ctx, id, stats)
+}
+
+func (t *Traced) VoidGame(ctx context.Context, id string) (game game_domain.Game, err error) {
+	ctx, span := tracing.Start(ctx, "game_usecase.VoidGame")
+	defer func() { tracing.End(span, err) }()
+
+	return t.next.VoidGame(ctx, id)
+}
+
+func (t *Traced) MergePlayers(ctx context.Context, from, into string) (res player_domain.MergeRes, err error) {
+	ctx, span := tracing.Start(ctx, "game_usecase.MergePlayers")
+	defer func() { tracing.End(span, err) }()
+
+	return t.next.MergePlayers(ctx, from, into)
+}
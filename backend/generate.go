@@ -0,0 +1,92 @@
+package main
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"os/signal"
+	"syscall"
+	"time"
+
+	"go.uber.org/zap"
+
+	audit_domain "skyhawk/backend/audit/domain"
+	"skyhawk/backend/config"
+	"skyhawk/backend/generator"
+)
+
+// runGenerate - the "generate" subcommand, a synthetic season written as ndjson, one /games/log body per line,
+// or logged straight through the game usecase with -log
+func runGenerate(cfg config.Config, logger *zap.Logger, args []string) error {
+	defaults := generator.DefaultOptions()
+	flags := flag.NewFlagSet("generate", flag.ContinueOnError)
+	teams := flags.Int("teams", defaults.Teams, "number of teams")
+	roster := flags.Int("roster", defaults.Roster, "players per team")
+	rounds := flags.Int("rounds", defaults.Rounds, "how many times every team meets every other team")
+	seed := flags.Int64("seed", defaults.Seed, "the same seed generates the same season")
+	start := flags.String("start", defaults.Start.Format(time.DateOnly), "date of the first game day, YYYY-MM-DD")
+	overtime := flags.Float64("overtime-rate", defaults.OvertimeRate, "share of games that go to overtime")
+	out := flags.String("out", "-", "path of the ndjson file to write, - writes stdout")
+	logGames := flags.Bool("log", false, "log the games instead of writing them")
+	if err := flags.Parse(args); err != nil {
+		return err
+	}
+
+	opts := generator.Options{Teams: *teams, Roster: *roster, Rounds: *rounds, Seed: *seed, OvertimeRate: *overtime}
+	var err error
+	if opts.Start, err = time.Parse(time.DateOnly, *start); err != nil {
+		return errors.New("generate: -start must be YYYY-MM-DD")
+	}
+	season, err := generator.New(opts)
+	if err != nil {
+		return fmt.Errorf("generate: %w", err)
+	}
+	games := season.Games()
+
+	if !*logGames {
+		var w io.Writer = os.Stdout
+		if *out != "-" {
+			f, err := os.Create(*out)
+			if err != nil {
+				return err
+			}
+			defer f.Close()
+			w = f
+		}
+		buffered := bufio.NewWriter(w)
+		encoder := json.NewEncoder(buffered)
+		for _, game := range games {
+			if err = encoder.Encode(game); err != nil {
+				return err
+			}
+		}
+		logger.Info("generated season", zap.Int("teams", len(season.Teams)), zap.Int("games", len(games)), zap.String("out", *out))
+		return buffered.Flush()
+	}
+
+	app, err := newApp(cfg, logger)
+	if err != nil {
+		return err
+	}
+	defer app.Close()
+
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+	ctx = audit_domain.WithMeta(ctx, audit_domain.Meta{Actor: "cli:generate"})
+
+	started := time.Now()
+	for i, game := range games {
+		game.Origin = "generate"
+		if _, err = app.service.LogGame(ctx, game); err != nil {
+			return fmt.Errorf("generate: game %s, %d of %d logged: %w", game.ID, i, len(games), err)
+		}
+	}
+	logger.Info("logged season", zap.Int("teams", len(season.Teams)), zap.Int("games", len(games)), zap.Duration("took", time.Since(started)))
+
+	return nil
+}
@@ -0,0 +1,179 @@
+package generator
+
+import (
+	"math"
+	"math/rand"
+	"sort"
+
+	game_domain "skyhawk/backend/game/domain"
+)
+
+const (
+	// regulationMinutes - five players on the floor for four 12 minute quarters
+	regulationMinutes = 240
+	// overtimeMinutes - five players for a 5 minute overtime
+	overtimeMinutes = 25
+	maxMinutes      = 48
+	maxFouls        = 6
+)
+
+// rates - per minute averages of the counting stats by position
+type rates struct {
+	rebounds, assists, steals, blocks, turnovers, fouls float64
+}
+
+var positionRates = map[Position]rates{
+	PositionGuard: {rebounds: 0.12, assists: 0.2, steals: 0.035, blocks: 0.01, turnovers: 0.07, fouls: 0.065},
+	PositionWing:  {rebounds: 0.18, assists: 0.1, steals: 0.03, blocks: 0.02, turnovers: 0.05, fouls: 0.07},
+	PositionBig:   {rebounds: 0.32, assists: 0.08, steals: 0.02, blocks: 0.06, turnovers: 0.05, fouls: 0.09},
+}
+
+func (s *Season) game(rng *rand.Rand, fixture Fixture) game_domain.GameStatsReq {
+	overtimes := 0
+	if rng.Float64() < s.opts.OvertimeRate {
+		overtimes = 1
+		for overtimes < 3 && rng.Float64() < 0.2 {
+			overtimes++
+		}
+	}
+
+	home := boxScore(rng, s.Teams[fixture.Home], overtimes)
+	away := boxScore(rng, s.Teams[fixture.Away], overtimes)
+	// games do not end tied, the home side makes the last free throw
+	if points(home) == points(away) {
+		home.Players[0].Points++
+	}
+
+	return game_domain.GameStatsReq{ID: fixture.Key, Date: fixture.Date, Teams: []game_domain.Team{home, away}}
+}
+
+func points(team game_domain.Team) int {
+	total := 0
+	for _, player := range team.Players {
+		total += player.Points
+	}
+
+	return total
+}
+
+// boxScore - the lines of the players of team who got on the floor
+func boxScore(rng *rand.Rand, team Team, overtimes int) game_domain.Team {
+	minutes := distributeMinutes(rng, team.Players, regulationMinutes+overtimes*overtimeMinutes)
+
+	box := game_domain.Team{Name: team.Name}
+	for i, player := range team.Players {
+		played := minutes[i]
+		if played == 0 {
+			continue
+		}
+		rate := positionRates[player.Position]
+		mean := player.Scoring * played
+		box.Players = append(box.Players, game_domain.Player{
+			Name:          player.Name,
+			Points:        int(math.Max(0, math.Round(mean+rng.NormFloat64()*(0.25*mean+1)))),
+			Rebounds:      poisson(rng, rate.rebounds*played),
+			Assists:       poisson(rng, rate.assists*played),
+			Steals:        poisson(rng, rate.steals*played),
+			Blocks:        poisson(rng, rate.blocks*played),
+			Turnovers:     poisson(rng, rate.turnovers*played),
+			Fouls:         min(poisson(rng, rate.fouls*played), maxFouls),
+			MinutesPlayed: played,
+		})
+	}
+
+	return box
+}
+
+// distributeMinutes - splits the team's minutes between its players in tenths, so they add up exactly.
+// Each player's share varies around their usual one, the end of the bench often does not play and nobody plays more than 48
+func distributeMinutes(rng *rand.Rand, players []Player, total int) []float64 {
+	weights := make([]float64, len(players))
+	for i, player := range players {
+		if i >= starters+4 && rng.Float64() < 0.6 {
+			continue
+		}
+		weights[i] = player.Minutes * (0.85 + rng.Float64()*0.3)
+	}
+
+	var open []int
+	for i, weight := range weights {
+		if weight > 0 {
+			open = append(open, i)
+		}
+	}
+
+	// players whose share passes the cap are fixed at it and the rest is shared again
+	tenths := make([]int, len(players))
+	remaining := total * 10
+	for capped := true; capped; {
+		capped = false
+		sum := 0.0
+		for _, i := range open {
+			sum += weights[i]
+		}
+		for j := 0; j < len(open); j++ {
+			if i := open[j]; float64(remaining)*weights[i]/sum > maxMinutes*10 {
+				tenths[i] = maxMinutes * 10
+				remaining -= maxMinutes * 10
+				open = append(open[:j], open[j+1:]...)
+				capped = true
+				break
+			}
+		}
+	}
+
+	// floor every share, then hand the tenths left over to the largest remainders
+	sum := 0.0
+	for _, i := range open {
+		sum += weights[i]
+	}
+	fractions := make(map[int]float64, len(open))
+	left := remaining
+	for _, i := range open {
+		share := float64(remaining) * weights[i] / sum
+		tenths[i] = int(share)
+		fractions[i] = share - float64(tenths[i])
+		left -= tenths[i]
+	}
+	sort.SliceStable(open, func(a, b int) bool { return fractions[open[a]] > fractions[open[b]] })
+	for j := 0; j < left; j++ {
+		tenths[open[j%len(open)]]++
+	}
+
+	minutes := make([]float64, len(players))
+	for i, t := range tenths {
+		minutes[i] = float64(t) / 10
+	}
+
+	return minutes
+}
+
+// poisson - Knuth's method, fine for the small per game means of box score stats
+func poisson(rng *rand.Rand, mean float64) int {
+	limit, product, k := math.Exp(-mean), rng.Float64(), 0
+	for product > limit {
+		product *= rng.Float64()
+		k++
+	}
+
+	return k
+}
+
+var (
+	cities = []string{
+		"Harbor City", "Red Rock", "Lakeside", "Northfield", "Pine Valley", "Iron Bay",
+		"Silver Springs", "Eastport", "Cedar Falls", "Granite Peak", "Westbrook", "Sun Coast",
+	}
+	nicknames = []string{"Comets", "Foxes", "Mariners", "Owls", "Rangers", "Thunder", "Wolves", "Kings"}
+
+	firstNames = []string{
+		"Marcus", "Jalen", "Tyrese", "Darius", "Andre", "Malik", "Isaiah", "Devin", "Cole", "Luka",
+		"Nikola", "Jordan", "Trae", "Evan", "Miles", "Zion", "Cade", "Jaren", "Keegan", "Bennett",
+		"Aaron", "Chris", "Derrick", "Franz", "Gabe", "Hunter", "Ivan", "Jonas", "Kyle", "Lonzo",
+	}
+	lastNames = []string{
+		"Walker", "Brooks", "Hayes", "Mitchell", "Carter", "Coleman", "Freeman", "Griffin", "Holmes", "Jenkins",
+		"Keller", "Lawson", "Morrison", "Nash", "Owens", "Porter", "Quinn", "Reed", "Sanders", "Turner",
+		"Vance", "Wallace", "Young", "Bridges", "Edwards", "Fields", "Grant", "Hill", "Irving", "Jackson",
+	}
+)
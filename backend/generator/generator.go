@@ -0,0 +1,198 @@
+package generator
+
+import (
+	"errors"
+	"fmt"
+	"math/rand"
+	"time"
+
+	game_domain "skyhawk/backend/game/domain"
+)
+
+type Options struct {
+	// Teams - how many teams the league has
+	Teams int
+	// Roster - players per team, the first five start and the rest come off the bench
+	Roster int
+	// Rounds - how many times every team meets every other team, home and away alternate between rounds
+	Rounds int
+	// Seed - the same seed and options always generate the same season
+	Seed int64
+	// Start - the date of the first game day, one game day follows another
+	Start time.Time
+	// OvertimeRate - the share of games that go to overtime
+	OvertimeRate float64
+}
+
+func DefaultOptions() Options {
+	return Options{
+		Teams:        8,
+		Roster:       12,
+		Rounds:       2,
+		Seed:         1,
+		Start:        time.Date(2024, time.October, 22, 0, 0, 0, 0, time.UTC),
+		OvertimeRate: 0.06,
+	}
+}
+
+// maxTeams - every team name is a city and a nickname
+var maxTeams = len(cities) * len(nicknames)
+
+const (
+	minRoster = 8
+	maxRoster = 15
+	starters  = 5
+)
+
+func (o Options) validate() error {
+	switch {
+	case o.Teams < 2 || o.Teams > maxTeams:
+		return fmt.Errorf("teams must be between 2 and %d", maxTeams)
+	case o.Roster < minRoster || o.Roster > maxRoster:
+		return fmt.Errorf("roster must be between %d and %d players", minRoster, maxRoster)
+	case o.Rounds < 1:
+		return errors.New("rounds must be at least 1")
+	case o.OvertimeRate < 0 || o.OvertimeRate > 1:
+		return errors.New("overtime rate must be between 0 and 1")
+	}
+
+	return nil
+}
+
+// Position - decides which stats a player collects besides points
+type Position string
+
+const (
+	PositionGuard Position = "guard"
+	PositionWing  Position = "wing"
+	PositionBig   Position = "big"
+)
+
+// positions - a starting five of two guards, two wings and a big, the bench repeats the pattern
+var positions = []Position{PositionGuard, PositionGuard, PositionWing, PositionWing, PositionBig}
+
+type Player struct {
+	Name     string
+	Position Position
+	// Scoring - points per minute on the floor
+	Scoring float64
+	// Minutes - the share of the team's minutes the player gets on an average night
+	Minutes float64
+}
+
+type Team struct {
+	Name    string
+	Players []Player
+}
+
+// Fixture - one game of the schedule
+type Fixture struct {
+	Key  string
+	Date time.Time
+	Home int
+	Away int
+}
+
+// Season - the teams, their rosters and the schedule, the box scores are generated from it
+type Season struct {
+	Teams    []Team
+	Fixtures []Fixture
+	opts     Options
+}
+
+// New - the league and its schedule for opts, generating the box scores is left to Games
+func New(opts Options) (*Season, error) {
+	if err := opts.validate(); err != nil {
+		return nil, err
+	}
+	rng := rand.New(rand.NewSource(opts.Seed))
+
+	season := &Season{opts: opts}
+	for _, i := range rng.Perm(maxTeams)[:opts.Teams] {
+		season.Teams = append(season.Teams, newTeam(rng, cities[i%len(cities)]+" "+nicknames[i/len(cities)], opts.Roster))
+	}
+	season.Fixtures = schedule(opts)
+
+	return season, nil
+}
+
+func newTeam(rng *rand.Rand, name string, roster int) Team {
+	team := Team{Name: name}
+	taken := make(map[string]bool, roster)
+	for i := 0; i < roster; i++ {
+		player := Player{Position: positions[i%len(positions)]}
+		for player.Name == "" || taken[player.Name] {
+			player.Name = firstNames[rng.Intn(len(firstNames))] + " " + lastNames[rng.Intn(len(lastNames))]
+		}
+		taken[player.Name] = true
+
+		// starters play most and score most, one of them is the team's star, the end of the bench rarely plays
+		switch {
+		case i == 0:
+			player.Minutes, player.Scoring = 36, 0.65+rng.Float64()*0.15
+		case i < starters:
+			player.Minutes, player.Scoring = 30+rng.Float64()*4, 0.35+rng.Float64()*0.15
+		case i < starters+4:
+			player.Minutes, player.Scoring = 14+rng.Float64()*8, 0.28+rng.Float64()*0.14
+		default:
+			player.Minutes, player.Scoring = 2+rng.Float64()*4, 0.25+rng.Float64()*0.15
+		}
+		team.Players = append(team.Players, player)
+	}
+
+	return team
+}
+
+// schedule - a round robin by the circle method, every team plays once per game day, a bye when the number of teams is odd
+func schedule(opts Options) []Fixture {
+	const bye = -1
+
+	slots := make([]int, 0, opts.Teams+1)
+	for i := 0; i < opts.Teams; i++ {
+		slots = append(slots, i)
+	}
+	if len(slots)%2 == 1 {
+		slots = append(slots, bye)
+	}
+
+	var fixtures []Fixture
+	day := 0
+	for round := 0; round < opts.Rounds; round++ {
+		rotation := append([]int(nil), slots...)
+		for matchday := 0; matchday < len(slots)-1; matchday++ {
+			date := opts.Start.AddDate(0, 0, day)
+			for i := 0; i < len(rotation)/2; i++ {
+				home, away := rotation[i], rotation[len(rotation)-1-i]
+				if home == bye || away == bye {
+					continue
+				}
+				// the fixed team would always be at home, and every other round swaps home and away
+				if (matchday%2 == 1 && i == 0) != (round%2 == 1) {
+					home, away = away, home
+				}
+				fixtures = append(fixtures, Fixture{
+					Key:  fmt.Sprintf("s%d-%04d", opts.Seed, len(fixtures)+1),
+					Date: date.Add(time.Duration(19*60+30*i) * time.Minute),
+					Home: home,
+					Away: away,
+				})
+			}
+			// keep the first slot and rotate the others
+			rotation = append([]int{rotation[0], rotation[len(rotation)-1]}, rotation[1:len(rotation)-1]...)
+			day++
+		}
+	}
+
+	return fixtures
+}
+
+// Games - a box score for every fixture, in schedule order. The same season always yields the same games
+func (s *Season) Games() []game_domain.GameStatsReq {
+	rng := rand.New(rand.NewSource(s.opts.Seed))
+	games := make([]game_domain.GameStatsReq, 0, len(s.Fixtures))
+	for _, fixture := range s.Fixtures {
+		games = append(games, s.game(rng, fixture))
+	}
+
+	return games
+}
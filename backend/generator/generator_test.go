@@ -0,0 +1,124 @@
+package generator
+
+import (
+	"math"
+	"math/rand"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestNew(t *testing.T) {
+	t.Run("every pair meets once per round, home and away", func(t *testing.T) {
+		// Setup
+		opts := DefaultOptions()
+		opts.Teams = 5
+
+		// Test
+		season, err := New(opts)
+
+		// Assert
+		require.NoError(t, err)
+		require.Len(t, season.Teams, 5)
+		assert.Len(t, season.Fixtures, 5*4)
+		meetings := map[[2]int]int{}
+		for _, fixture := range season.Fixtures {
+			assert.NotEqual(t, fixture.Home, fixture.Away)
+			meetings[[2]int{fixture.Home, fixture.Away}]++
+		}
+		assert.Len(t, meetings, 5*4, "each team hosts each other team once")
+		for _, team := range season.Teams {
+			assert.Len(t, team.Players, opts.Roster)
+		}
+	})
+
+	t.Run("invalid options", func(t *testing.T) {
+		// Setup
+		opts := DefaultOptions()
+		opts.Roster = 3
+
+		// Test
+		_, err := New(opts)
+
+		// Assert
+		assert.ErrorContains(t, err, "roster must be between")
+	})
+}
+
+func TestSeason_Games(t *testing.T) {
+	t.Run("plausible box scores", func(t *testing.T) {
+		// Setup
+		opts := DefaultOptions()
+		opts.OvertimeRate = 0.3
+		season, err := New(opts)
+		require.NoError(t, err)
+
+		// Test
+		games := season.Games()
+
+		// Assert
+		require.Len(t, games, len(season.Fixtures))
+		for _, game := range games {
+			require.NoError(t, game.Validate())
+			require.Len(t, game.Teams, 2)
+			assert.NotEqual(t, points(game.Teams[0]), points(game.Teams[1]), "no ties")
+
+			var teamMinutes []float64
+			for _, team := range game.Teams {
+				minutes := 0.0
+				for _, player := range team.Players {
+					assert.LessOrEqual(t, player.Fouls, maxFouls)
+					assert.LessOrEqual(t, player.MinutesPlayed, float64(maxMinutes))
+					assert.Greater(t, player.MinutesPlayed, 0.0, "players who did not play have no line")
+					minutes += player.MinutesPlayed
+				}
+				overtime := math.Round(minutes) - regulationMinutes
+				assert.InDelta(t, 0, math.Mod(overtime, overtimeMinutes), 0.01, "240 minutes plus 25 per overtime, got %.1f", minutes)
+				assert.GreaterOrEqual(t, overtime, 0.0)
+				teamMinutes = append(teamMinutes, minutes)
+				assert.InDelta(t, 120, points(team), 60, "points follow the minutes played")
+			}
+			assert.InDelta(t, teamMinutes[0], teamMinutes[1], 0.01, "both teams play the same overtimes")
+		}
+	})
+
+	t.Run("same seed, same season", func(t *testing.T) {
+		// Setup
+		first, err := New(DefaultOptions())
+		require.NoError(t, err)
+		second, err := New(DefaultOptions())
+		require.NoError(t, err)
+		opts := DefaultOptions()
+		opts.Seed = 2
+		other, err := New(opts)
+		require.NoError(t, err)
+
+		// Test
+		games := first.Games()
+
+		// Assert
+		assert.Equal(t, games, second.Games())
+		assert.Equal(t, games, first.Games())
+		assert.NotEqual(t, games, other.Games())
+	})
+}
+
+func TestDistributeMinutes(t *testing.T) {
+	// Setup
+	season, err := New(DefaultOptions())
+	require.NoError(t, err)
+	team := season.Teams[0]
+	team.Players[0].Minutes = 200
+
+	// Test
+	minutes := distributeMinutes(rand.New(rand.NewSource(1)), team.Players, regulationMinutes+3*overtimeMinutes)
+
+	// Assert
+	total := 0.0
+	for _, played := range minutes {
+		total += played
+	}
+	assert.InDelta(t, 315, total, 0.01)
+	assert.Equal(t, float64(maxMinutes), minutes[0], "the share above the cap goes to the others")
+}
@@ -0,0 +1,61 @@
+package goose
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+var (
+	migrationFile = regexp.MustCompile(`^(\d+)_.*\.sql$`)
+	nameSeparator = regexp.MustCompile(`[^A-Za-z0-9]+`)
+)
+
+const migrationTemplate = `-- +goose up
+
+-- +goose down
+`
+
+// Create - writes an empty migration to dir, numbered after the latest one and named like the others (08_ADD_VENUES.sql).
+// It takes effect once the binary is rebuilt, the migrations are embedded
+func Create(dir, name string) (string, error) {
+	name = strings.Trim(nameSeparator.ReplaceAllString(strings.ToUpper(name), "_"), "_")
+	if name == "" {
+		return "", errors.New("migration name must hold letters or digits")
+	}
+
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		return "", err
+	}
+	var latest int64
+	for _, entry := range entries {
+		match := migrationFile.FindStringSubmatch(entry.Name())
+		if match == nil {
+			continue
+		}
+		version, err := strconv.ParseInt(match[1], 10, 64)
+		if err != nil {
+			return "", fmt.Errorf("migration %s: %w", entry.Name(), err)
+		}
+		if version > latest {
+			latest = version
+		}
+	}
+
+	path := filepath.Join(dir, fmt.Sprintf("%02d_%s.sql", latest+1, name))
+	file, err := os.OpenFile(path, os.O_WRONLY|os.O_CREATE|os.O_EXCL, 0o644)
+	if err != nil {
+		return "", err
+	}
+	if _, err = file.WriteString(migrationTemplate); err != nil {
+		file.Close()
+		return "", err
+	}
+
+	return path, file.Close()
+}
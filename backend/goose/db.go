@@ -1,41 +1,89 @@
 package goose
 
 import (
+	"context"
+	"crypto/tls"
+	"crypto/x509"
+	"database/sql/driver"
+	"errors"
 	"fmt"
 	"os"
-	"time"
 
-	_ "github.com/go-sql-driver/mysql"
+	"github.com/XSAM/otelsql"
+	"github.com/go-sql-driver/mysql"
 	"github.com/jmoiron/sqlx"
+	semconv "go.opentelemetry.io/otel/semconv/v1.26.0"
+	"go.opentelemetry.io/otel/trace"
+
+	"skyhawk/backend/config"
 )
 
-func MustNewDB() (*sqlx.DB, error) {
-	host := os.Getenv("MYSQL_HOST")
-	password := os.Getenv("MYSQL_ROOT_PASSWORD")
-	url := fmt.Sprintf("root:%s@tcp(%s)/%s", password, host, "games_db")
-	fmt.Println(url)
-	db, err := sqlx.Connect("mysql", url)
+// tlsConfigName - the driver tls config registered when a CA file is configured
+const tlsConfigName = "skyhawk"
 
-	if err != nil {
-		return nil, err
-	}
+// DSN - the mysql data source name of cfg, it carries the password so never log it, log RedactedDSN instead
+func DSN(cfg config.DB) (string, error) {
+	mysqlConfig := mysql.NewConfig()
+	mysqlConfig.User = cfg.User
+	mysqlConfig.Passwd = cfg.Password
+	mysqlConfig.Net = "tcp"
+	mysqlConfig.Addr = cfg.Host
+	mysqlConfig.DBName = cfg.Name
+	mysqlConfig.Timeout = cfg.ConnectTimeout
+	mysqlConfig.TLSConfig = cfg.TLS
 
-	//ping
-	if err = db.Ping(); err != nil {
-		fmt.Printf(fmt.Sprintf("failed connecting to goose %v", err))
-		return nil, err
+	if cfg.TLSCAFile != "" {
+		pem, err := os.ReadFile(cfg.TLSCAFile)
+		if err != nil {
+			return "", err
+		}
+		pool := x509.NewCertPool()
+		if !pool.AppendCertsFromPEM(pem) {
+			return "", errors.New("db.tls_ca_file holds no PEM certificates")
+		}
+		if err = mysql.RegisterTLSConfig(tlsConfigName, &tls.Config{RootCAs: pool, MinVersion: tls.VersionTLS12}); err != nil {
+			return "", err
+		}
+		mysqlConfig.TLSConfig = tlsConfigName
 	}
 
-	db.SetMaxOpenConns(1000)         // Max open connections to the DB
-	db.SetMaxIdleConns(500)          // Max idle connections in the pool
-	db.SetConnMaxLifetime(time.Hour) // Max lifetime of connections
+	return mysqlConfig.FormatDSN(), nil
+}
 
-	return db, nil
+// RedactedDSN - the data source name with the password masked
+func RedactedDSN(cfg config.DB) string {
+	return fmt.Sprintf("%s:****@tcp(%s)/%s?tls=%s", cfg.User, cfg.Host, cfg.Name, cfg.TLS)
 }
 
-var migrationsDir = "/backend/goose/migrations"
+func MustNewDB(cfg config.DB) (*sqlx.DB, error) {
+	dsn, err := DSN(cfg)
+	if err != nil {
+		return nil, fmt.Errorf("mysql %s: %w", RedactedDSN(cfg), err)
+	}
+
+	//every statement inside a trace gets a span, statements outside one (migrations, background polling) do not
+	sqlDB, err := otelsql.Open("mysql", dsn,
+		otelsql.WithAttributes(semconv.DBSystemMySQL),
+		otelsql.WithSpanOptions(otelsql.SpanOptions{
+			OmitConnResetSession: true,
+			OmitRows:             true,
+			SpanFilter: func(ctx context.Context, method otelsql.Method, query string, args []driver.NamedValue) bool {
+				return trace.SpanContextFromContext(ctx).IsValid()
+			},
+		}))
+	if err != nil {
+		return nil, fmt.Errorf("failed connecting to mysql %s: %w", RedactedDSN(cfg), err)
+	}
+	db := sqlx.NewDb(sqlDB, "mysql")
+	if err = db.Ping(); err != nil {
+		db.Close()
+		return nil, fmt.Errorf("failed connecting to mysql %s: %w", RedactedDSN(cfg), err)
+	}
 
-func migrate(db *sqlx.DB) error {
+	db.SetMaxOpenConns(cfg.MaxOpenConns)
+	db.SetMaxIdleConns(cfg.MaxIdleConns)
+	db.SetConnMaxLifetime(cfg.ConnMaxLifetime)
+	db.SetConnMaxIdleTime(cfg.ConnMaxIdleTime)
 
-	return nil
+	return db, nil
 }
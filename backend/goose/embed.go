@@ -0,0 +1,8 @@
+package goose
+
+import "embed"
+
+// Migrations - the migrations built into the binary, so it migrates the same wherever it is started from
+//
+//go:embed migrations/*.sql
+var Migrations embed.FS
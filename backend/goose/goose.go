@@ -1,58 +1,131 @@
 package goose
 
 import (
+	"context"
+	"errors"
+	"fmt"
+	"io/fs"
 	"time"
 
+	"github.com/go-sql-driver/mysql"
 	"github.com/jmoiron/sqlx"
 	"github.com/pressly/goose/v3"
 	"go.uber.org/zap"
 )
 
+// mysqlNoSuchTable - the version table is missing until the first migration ran
+const mysqlNoSuchTable = 1146
+
 type Service interface {
-	migrateDB() error
 	Run() error
+	Check(ctx context.Context) error
+	Status(ctx context.Context) ([]*goose.MigrationStatus, error)
+	Up(ctx context.Context) ([]*goose.MigrationResult, error)
+	UpTo(ctx context.Context, version int64) ([]*goose.MigrationResult, error)
+	Down(ctx context.Context) (*goose.MigrationResult, error)
+	Redo(ctx context.Context) ([]*goose.MigrationResult, error)
+	Versions(ctx context.Context) (current, expected int64, err error)
 }
 
+// MigrationService - applies the embedded migrations, every change to the schema holds the migration lock
 type MigrationService struct {
-	db            *sqlx.DB
-	logger        *zap.Logger
-	migrationsDir string
+	db       *sqlx.DB
+	provider *goose.Provider
+	logger   *zap.Logger
 }
 
-func New(db *sqlx.DB, logger *zap.Logger, migrationsDir string) Service {
+// New - lockTimeout is how long to wait for another instance that is migrating
+func New(db *sqlx.DB, logger *zap.Logger, lockTimeout time.Duration) (Service, error) {
+	migrations, err := fs.Sub(Migrations, "migrations")
+	if err != nil {
+		return nil, err
+	}
 
-	return &MigrationService{
-		db:            db,
-		logger:        logger,
-		migrationsDir: migrationsDir,
+	provider, err := goose.NewProvider(goose.DialectMySQL, db.DB, migrations,
+		goose.WithSessionLocker(mysqlLocker{timeout: lockTimeout}))
+	if err != nil {
+		return nil, fmt.Errorf("failed loading migrations: %w", err)
 	}
+
+	return &MigrationService{db: db, provider: provider, logger: logger}, nil
 }
 
-// Run - this function migrates DB
+// Run - applies every pending migration, the server does this on startup unless it only checks the schema
 func (ms *MigrationService) Run() error {
+	start := time.Now()
+	ms.logger.Info("starting migration...")
 
-	if err := ms.migrateDB(); err != nil {
+	results, err := ms.Up(context.Background())
+	if err != nil {
 		ms.logger.Error("failed migrating DB", zap.Error(err))
 		return err
 	}
+	ms.logger.Info("finished migration", zap.Int("applied", len(results)), zap.Duration("took", time.Since(start)))
 
 	return nil
 }
 
-// migrateDB - this function migrates the DB
-func (ms *MigrationService) migrateDB() error {
-	if err := goose.SetDialect("mysql"); err != nil {
+// Check - fails while the DB is behind the migrations of this build, for servers that must not migrate themselves
+func (ms *MigrationService) Check(ctx context.Context) error {
+	current, expected, err := ms.Versions(ctx)
+	if err != nil {
 		return err
 	}
-
-	start := time.Now()
-	ms.logger.Info("starting migration...")
-	if err := goose.Up(ms.db.DB, ms.migrationsDir); err != nil {
-		ms.logger.Error("failed migration")
-		return err
+	if current < expected {
+		return fmt.Errorf("schema is at version %d, this build needs %d: run the migrate up command", current, expected)
 	}
-	end := time.Now()
-	ms.logger.Info("finished migration time to run: ", zap.String("dir", ms.migrationsDir), zap.Any("seconds", end.Second()-start.Second()))
 
 	return nil
 }
+
+func (ms *MigrationService) Status(ctx context.Context) ([]*goose.MigrationStatus, error) {
+	return ms.provider.Status(ctx)
+}
+
+func (ms *MigrationService) Up(ctx context.Context) ([]*goose.MigrationResult, error) {
+	return ms.provider.Up(ctx)
+}
+
+// UpTo - applies the pending migrations up to and including version
+func (ms *MigrationService) UpTo(ctx context.Context, version int64) ([]*goose.MigrationResult, error) {
+	return ms.provider.UpTo(ctx, version)
+}
+
+// Down - rolls the latest applied migration back
+func (ms *MigrationService) Down(ctx context.Context) (*goose.MigrationResult, error) {
+	return ms.provider.Down(ctx)
+}
+
+// Redo - rolls the latest applied migration back and applies it again
+func (ms *MigrationService) Redo(ctx context.Context) ([]*goose.MigrationResult, error) {
+	down, err := ms.provider.Down(ctx)
+	if err != nil {
+		return nil, err
+	}
+	up, err := ms.provider.UpByOne(ctx)
+	if err != nil {
+		return []*goose.MigrationResult{down}, err
+	}
+
+	return []*goose.MigrationResult{down, up}, nil
+}
+
+// Versions - the version the DB is migrated to and the latest migration built in.
+// The version table is read directly, the provider would wait for the migration lock and readiness checks must not
+func (ms *MigrationService) Versions(ctx context.Context) (current, expected int64, err error) {
+	err = ms.db.QueryRowContext(ctx, "SELECT COALESCE(MAX(version_id), 0) FROM goose_db_version").Scan(&current)
+	var mysqlErr *mysql.MySQLError
+	if errors.As(err, &mysqlErr) && mysqlErr.Number == mysqlNoSuchTable {
+		err = nil
+	}
+	if err != nil {
+		return 0, 0, err
+	}
+
+	sources := ms.provider.ListSources()
+	if len(sources) > 0 {
+		expected = sources[len(sources)-1].Version
+	}
+
+	return current, expected, nil
+}
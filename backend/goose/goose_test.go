@@ -5,11 +5,14 @@ import (
 	"testing"
 	"time"
 
+	"github.com/go-sql-driver/mysql"
 	"github.com/pressly/goose/v3"
 	"github.com/stretchr/testify/mock"
 	"github.com/stretchr/testify/require"
 	"go.uber.org/zap"
 	"go.uber.org/zap/zaptest"
+
+	"skyhawk/backend/config"
 )
 
 // Mock DB connection
@@ -91,3 +94,21 @@ func TestMigrateDB_Failure(t *testing.T) {
 
 	mockDB.AssertCalled(t, "MigrateUp", "migrations") // Ensure migration was attempted
 }
+
+func TestDSN(t *testing.T) {
+	// Setup
+	cfg := config.Default().DB
+	cfg.Host = "db:3306"
+	cfg.Password = "p@ss:word"
+
+	// Test
+	dsn, err := DSN(cfg)
+
+	// Assert
+	require.NoError(t, err)
+	parsed, err := mysql.ParseDSN(dsn)
+	require.NoError(t, err)
+	require.Equal(t, "p@ss:word", parsed.Passwd)
+	require.Equal(t, "games_db", parsed.DBName)
+	require.NotContains(t, RedactedDSN(cfg), "p@ss:word")
+}
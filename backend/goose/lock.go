@@ -0,0 +1,42 @@
+package goose
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"time"
+)
+
+// lockName - the mysql named lock held while migrating, named locks are server wide
+const lockName = "skyhawk_migrations"
+
+// mysqlLocker - serializes migrations between replicas starting together with GET_LOCK.
+// The lock belongs to the session, so a replica that dies while migrating releases it with its connection
+type mysqlLocker struct {
+	timeout time.Duration
+}
+
+func (l mysqlLocker) SessionLock(ctx context.Context, conn *sql.Conn) error {
+	var acquired sql.NullInt64
+	if err := conn.QueryRowContext(ctx, "SELECT GET_LOCK(?, ?)", lockName, int(l.timeout.Seconds())).Scan(&acquired); err != nil {
+		return fmt.Errorf("failed acquiring migration lock: %w", err)
+	}
+	if acquired.Int64 != 1 {
+		return fmt.Errorf("migration lock not acquired within %s, another instance is migrating", l.timeout)
+	}
+
+	return nil
+}
+
+func (l mysqlLocker) SessionUnlock(ctx context.Context, conn *sql.Conn) error {
+	var released sql.NullInt64
+	if err := conn.QueryRowContext(ctx, "SELECT RELEASE_LOCK(?)", lockName).Scan(&released); err != nil {
+		return fmt.Errorf("failed releasing migration lock: %w", err)
+	}
+	if released.Int64 != 1 {
+		return errors.New("migration lock was not held by this session")
+	}
+
+	return nil
+}
@@ -0,0 +1,30 @@
+-- +goose up
+-- Games table, one row per game whatever way its stats were recorded
+CREATE TABLE IF NOT EXISTS games (
+                                     id VARCHAR(36) PRIMARY KEY,
+                                     date timestamp NOT NULL default current_timestamp,
+                                     source VARCHAR(16) NOT NULL default 'box_score',
+                                     created_at timestamp NOT NULL default current_timestamp
+);
+
+-- games logged before this table existed
+INSERT IGNORE INTO games (id, date, source)
+SELECT game_id, MIN(date), 'box_score' FROM game_stats GROUP BY game_id;
+
+-- Play by play events, append only, seq orders the events of a game
+CREATE TABLE IF NOT EXISTS game_events (
+                                           game_id VARCHAR(36) NOT NULL,
+                                           seq INT NOT NULL,
+                                           type VARCHAR(32) NOT NULL,
+                                           period INT NOT NULL,
+                                           clock_seconds INT NOT NULL,
+                                           player_id VARCHAR(36) NULL,
+                                           payload JSON NOT NULL,
+                                           created_at timestamp NOT NULL default current_timestamp,
+                                           PRIMARY KEY (game_id, seq),
+                                           FOREIGN KEY (game_id) REFERENCES games(id)
+);
+
+-- +goose down
+DROP TABLE IF EXISTS game_events;
+DROP TABLE IF EXISTS games;
@@ -0,0 +1,106 @@
+-- +goose up
+-- Game lifecycle, scheduled -> live -> final. Existing games are complete box scores
+ALTER TABLE games ADD COLUMN status VARCHAR(16) NOT NULL DEFAULT 'final';
+
+-- Event sourced games were open ended until now, keep them accepting events
+UPDATE games SET status = 'live' WHERE source = 'events';
+
+-- Season stats only count final games
+CREATE OR REPLACE VIEW player_season_stats AS
+SELECT
+    p.id AS player_id,
+    p.name AS player_name,
+    p.team_id,
+    t.name AS team_name,
+    COUNT(DISTINCT gs.game_id) AS games_played,
+    COALESCE(AVG(gs.points), 0) AS avg_points,
+    COALESCE(AVG(gs.rebounds), 0) AS avg_rebounds,
+    COALESCE(AVG(gs.assists), 0) AS avg_assists,
+    COALESCE(AVG(gs.steals), 0) AS avg_steals,
+    COALESCE(AVG(gs.blocks), 0) AS avg_blocks,
+    COALESCE(AVG(gs.fouls), 0) AS avg_fouls,
+    COALESCE(AVG(gs.turnovers), 0) AS avg_turnovers,
+    COALESCE(AVG(gs.minutes_played), 0) AS avg_minutes_played
+FROM
+    players p
+        LEFT JOIN
+    (game_stats gs JOIN games g ON g.id = gs.game_id AND g.status = 'final') ON p.id = gs.player_id
+        JOIN
+    teams t ON p.team_id = t.id
+GROUP BY
+    p.id, p.name, p.team_id, t.name;
+
+-- Team season stats view
+CREATE OR REPLACE VIEW team_season_stats AS
+SELECT
+    t.id AS team_id,
+    t.name AS team_name,
+    COUNT(DISTINCT gs.game_id) AS games_played,
+    COALESCE(AVG(gs.points), 0) AS avg_points,
+    COALESCE(AVG(gs.rebounds), 0) AS avg_rebounds,
+    COALESCE(AVG(gs.assists), 0) AS avg_assists,
+    COALESCE(AVG(gs.steals), 0) AS avg_steals,
+    COALESCE(AVG(gs.blocks), 0) AS avg_blocks,
+    COALESCE(AVG(gs.fouls), 0) AS avg_fouls,
+    COALESCE(AVG(gs.turnovers), 0) AS avg_turnovers,
+    COALESCE(AVG(gs.minutes_played), 0) AS avg_minutes_played
+FROM
+    teams t
+
+        LEFT JOIN
+    players p ON t.id = p.team_id
+        LEFT JOIN
+    (game_stats gs JOIN games g ON g.id = gs.game_id AND g.status = 'final') ON p.id = gs.player_id
+GROUP BY
+    t.id, t.name;
+
+-- +goose down
+CREATE OR REPLACE VIEW player_season_stats AS
+SELECT
+    p.id AS player_id,
+    p.name AS player_name,
+    p.team_id,
+    t.name AS team_name,
+    COUNT(DISTINCT gs.game_id) AS games_played,
+    COALESCE(AVG(gs.points), 0) AS avg_points,
+    COALESCE(AVG(gs.rebounds), 0) AS avg_rebounds,
+    COALESCE(AVG(gs.assists), 0) AS avg_assists,
+    COALESCE(AVG(gs.steals), 0) AS avg_steals,
+    COALESCE(AVG(gs.blocks), 0) AS avg_blocks,
+    COALESCE(AVG(gs.fouls), 0) AS avg_fouls,
+    COALESCE(AVG(gs.turnovers), 0) AS avg_turnovers,
+    COALESCE(AVG(gs.minutes_played), 0) AS avg_minutes_played
+FROM
+    players p
+        LEFT JOIN
+    game_stats gs ON p.id = gs.player_id
+        JOIN
+    teams t ON p.team_id = t.id
+GROUP BY
+    p.id, p.name, p.team_id, t.name;
+
+-- Team season stats view
+CREATE OR REPLACE VIEW team_season_stats AS
+SELECT
+    t.id AS team_id,
+    t.name AS team_name,
+    COUNT(DISTINCT gs.game_id) AS games_played,
+    COALESCE(AVG(gs.points), 0) AS avg_points,
+    COALESCE(AVG(gs.rebounds), 0) AS avg_rebounds,
+    COALESCE(AVG(gs.assists), 0) AS avg_assists,
+    COALESCE(AVG(gs.steals), 0) AS avg_steals,
+    COALESCE(AVG(gs.blocks), 0) AS avg_blocks,
+    COALESCE(AVG(gs.fouls), 0) AS avg_fouls,
+    COALESCE(AVG(gs.turnovers), 0) AS avg_turnovers,
+    COALESCE(AVG(gs.minutes_played), 0) AS avg_minutes_played
+FROM
+    teams t
+
+        LEFT JOIN
+    players p ON t.id = p.team_id
+        LEFT JOIN
+    game_stats gs ON p.id = gs.player_id
+GROUP BY
+    t.id, t.name;
+
+ALTER TABLE games DROP COLUMN status;
@@ -0,0 +1,34 @@
+-- +goose up
+-- Webhook receivers, event_types is a JSON array, empty receives every event
+CREATE TABLE IF NOT EXISTS webhooks (
+                                        id VARCHAR(36) PRIMARY KEY,
+                                        url VARCHAR(2048) NOT NULL,
+                                        secret VARCHAR(128) NOT NULL,
+                                        event_types JSON NOT NULL,
+                                        active BOOLEAN NOT NULL default TRUE,
+                                        created_at timestamp NOT NULL default current_timestamp
+);
+
+-- Outbox of deliveries, written in the transaction of the change they announce
+CREATE TABLE IF NOT EXISTS webhook_deliveries (
+                                                  id VARCHAR(36) PRIMARY KEY,
+                                                  webhook_id VARCHAR(36) NOT NULL,
+                                                  event_id VARCHAR(36) NOT NULL,
+                                                  event_type VARCHAR(32) NOT NULL,
+                                                  game_id VARCHAR(36) NOT NULL,
+                                                  payload JSON NOT NULL,
+                                                  status VARCHAR(16) NOT NULL default 'pending',
+                                                  attempts INT NOT NULL default 0,
+                                                  next_attempt_at timestamp NOT NULL default current_timestamp,
+                                                  last_status_code INT NULL,
+                                                  last_error VARCHAR(1024) NULL,
+                                                  created_at timestamp NOT NULL default current_timestamp,
+                                                  delivered_at timestamp NULL,
+                                                  FOREIGN KEY (webhook_id) REFERENCES webhooks(id),
+                                                  INDEX idx_due (status, next_attempt_at),
+                                                  INDEX idx_webhook_created (webhook_id, created_at)
+);
+
+-- +goose down
+DROP TABLE IF EXISTS webhook_deliveries;
+DROP TABLE IF EXISTS webhooks;
@@ -0,0 +1,15 @@
+-- +goose up
+-- Domain events written in the transaction of the change they describe, the relay publishes them in seq order
+CREATE TABLE IF NOT EXISTS outbox_events (
+                                             seq BIGINT AUTO_INCREMENT PRIMARY KEY,
+                                             id VARCHAR(36) NOT NULL UNIQUE,
+                                             type VARCHAR(32) NOT NULL,
+                                             event_key VARCHAR(36) NOT NULL,
+                                             payload JSON NOT NULL,
+                                             occurred_at timestamp NOT NULL default current_timestamp,
+                                             published_at timestamp NULL,
+                                             INDEX idx_pending (published_at, seq)
+);
+
+-- +goose down
+DROP TABLE IF EXISTS outbox_events;
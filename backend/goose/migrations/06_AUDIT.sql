@@ -0,0 +1,21 @@
+-- +goose up
+-- Every write with who made it and the state before and after, inserted in the transaction of the write
+CREATE TABLE IF NOT EXISTS audit_log (
+                                         seq BIGINT AUTO_INCREMENT PRIMARY KEY,
+                                         id VARCHAR(36) NOT NULL UNIQUE,
+                                         entity VARCHAR(16) NOT NULL,
+                                         entity_id VARCHAR(36) NOT NULL,
+                                         action VARCHAR(32) NOT NULL,
+                                         actor VARCHAR(128) NOT NULL,
+                                         request_id VARCHAR(64) NULL,
+                                         source_ip VARCHAR(45) NULL,
+                                         at timestamp NOT NULL default current_timestamp,
+                                         before_state JSON NULL,
+                                         after_state JSON NULL,
+                                         diff JSON NULL,
+                                         INDEX idx_entity (entity, entity_id, seq),
+                                         INDEX idx_at (at)
+);
+
+-- +goose down
+DROP TABLE IF EXISTS audit_log;
@@ -0,0 +1,15 @@
+-- +goose up
+-- API keys, only the sha256 of the key is stored and the prefix finds the row
+CREATE TABLE IF NOT EXISTS api_keys (
+                                        id VARCHAR(36) PRIMARY KEY,
+                                        name VARCHAR(128) NOT NULL,
+                                        prefix VARCHAR(16) NOT NULL UNIQUE,
+                                        key_hash CHAR(64) NOT NULL,
+                                        scopes JSON NOT NULL,
+                                        created_at timestamp NOT NULL default current_timestamp,
+                                        last_used_at timestamp NULL,
+                                        revoked_at timestamp NULL
+);
+
+-- +goose down
+DROP TABLE IF EXISTS api_keys;
@@ -0,0 +1,11 @@
+-- +goose up
+-- The key a game has in the bulk source it came from (an import, a generated season), so importing it again is refused
+-- instead of counted twice. Keys are unique per origin only, and games logged one by one keep NULL, which the index does not compare
+ALTER TABLE games ADD COLUMN origin VARCHAR(64) NULL;
+ALTER TABLE games ADD COLUMN external_key VARCHAR(128) NULL;
+CREATE UNIQUE INDEX games_origin_key ON games (origin, external_key);
+
+-- +goose down
+DROP INDEX games_origin_key ON games;
+ALTER TABLE games DROP COLUMN external_key;
+ALTER TABLE games DROP COLUMN origin;
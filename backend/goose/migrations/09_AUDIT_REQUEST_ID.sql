@@ -0,0 +1,7 @@
+-- +goose up
+-- Request ids come from the caller's X-Request-ID, which may be up to 128 characters
+ALTER TABLE audit_log MODIFY request_id VARCHAR(128) NULL;
+
+-- +goose down
+UPDATE audit_log SET request_id = LEFT(request_id, 64) WHERE CHAR_LENGTH(request_id) > 64;
+ALTER TABLE audit_log MODIFY request_id VARCHAR(64) NULL;
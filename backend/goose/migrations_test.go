@@ -0,0 +1,132 @@
+package goose
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/go-sql-driver/mysql"
+	"github.com/jmoiron/sqlx"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap"
+)
+
+func newMockService(t *testing.T) (Service, sqlmock.Sqlmock) {
+	mockDB, mock, err := sqlmock.New()
+	require.NoError(t, err)
+	t.Cleanup(func() { mockDB.Close() })
+
+	service, err := New(sqlx.NewDb(mockDB, "mysql"), zap.NewNop(), time.Minute)
+	require.NoError(t, err)
+
+	return service, mock
+}
+
+func TestMigrationService_Versions(t *testing.T) {
+	t.Run("behind", func(t *testing.T) {
+		// Setup
+		service, mock := newMockService(t)
+		mock.ExpectQuery("SELECT COALESCE\\(MAX\\(version_id\\), 0\\) FROM goose_db_version").
+			WillReturnRows(sqlmock.NewRows([]string{"version"}).AddRow(5))
+
+		// Test
+		current, expected, err := service.Versions(context.Background())
+
+		// Assert
+		require.NoError(t, err)
+		assert.Equal(t, int64(5), current)
+		assert.Equal(t, int64(9), expected, "every embedded migration is listed")
+	})
+
+	t.Run("fresh db", func(t *testing.T) {
+		// Setup
+		service, mock := newMockService(t)
+		mock.ExpectQuery("SELECT COALESCE").WillReturnError(&mysql.MySQLError{Number: mysqlNoSuchTable})
+
+		// Test
+		current, _, err := service.Versions(context.Background())
+
+		// Assert
+		require.NoError(t, err)
+		assert.Equal(t, int64(0), current)
+	})
+}
+
+func TestMigrationService_Check(t *testing.T) {
+	// Setup
+	service, mock := newMockService(t)
+	mock.ExpectQuery("SELECT COALESCE").WillReturnRows(sqlmock.NewRows([]string{"version"}).AddRow(8))
+	mock.ExpectQuery("SELECT COALESCE").WillReturnRows(sqlmock.NewRows([]string{"version"}).AddRow(9))
+
+	// Test
+	behind := service.Check(context.Background())
+	current := service.Check(context.Background())
+
+	// Assert
+	assert.ErrorContains(t, behind, "schema is at version 8, this build needs 9")
+	assert.NoError(t, current)
+}
+
+func TestMysqlLocker(t *testing.T) {
+	t.Run("lock and unlock", func(t *testing.T) {
+		// Setup
+		mockDB, mock, err := sqlmock.New()
+		require.NoError(t, err)
+		defer mockDB.Close()
+		conn, err := mockDB.Conn(context.Background())
+		require.NoError(t, err)
+		locker := mysqlLocker{timeout: 30 * time.Second}
+		mock.ExpectQuery("SELECT GET_LOCK").WithArgs(lockName, 30).WillReturnRows(sqlmock.NewRows([]string{"acquired"}).AddRow(1))
+		mock.ExpectQuery("SELECT RELEASE_LOCK").WithArgs(lockName).WillReturnRows(sqlmock.NewRows([]string{"released"}).AddRow(1))
+
+		// Test
+		lockErr := locker.SessionLock(context.Background(), conn)
+		unlockErr := locker.SessionUnlock(context.Background(), conn)
+
+		// Assert
+		assert.NoError(t, lockErr)
+		assert.NoError(t, unlockErr)
+		assert.NoError(t, mock.ExpectationsWereMet())
+	})
+
+	t.Run("held by another instance", func(t *testing.T) {
+		// Setup
+		mockDB, mock, err := sqlmock.New()
+		require.NoError(t, err)
+		defer mockDB.Close()
+		conn, err := mockDB.Conn(context.Background())
+		require.NoError(t, err)
+		mock.ExpectQuery("SELECT GET_LOCK").WillReturnRows(sqlmock.NewRows([]string{"acquired"}).AddRow(0))
+
+		// Test
+		err = mysqlLocker{timeout: time.Second}.SessionLock(context.Background(), conn)
+
+		// Assert
+		assert.ErrorContains(t, err, "another instance is migrating")
+	})
+}
+
+func TestCreate(t *testing.T) {
+	// Setup
+	dir := t.TempDir()
+	require.NoError(t, os.WriteFile(filepath.Join(dir, "01_INIT_SCHEMA.sql"), nil, 0o644))
+	require.NoError(t, os.WriteFile(filepath.Join(dir, "09_PLAYOFFS.sql"), nil, 0o644))
+	require.NoError(t, os.WriteFile(filepath.Join(dir, "README.md"), nil, 0o644))
+
+	// Test
+	path, err := Create(dir, "add venues")
+	_, invalid := Create(dir, "--")
+
+	// Assert
+	require.NoError(t, err)
+	assert.Equal(t, filepath.Join(dir, "10_ADD_VENUES.sql"), path)
+	content, err := os.ReadFile(path)
+	require.NoError(t, err)
+	assert.Contains(t, string(content), "-- +goose up")
+	assert.Contains(t, string(content), "-- +goose down")
+	assert.Error(t, invalid)
+}
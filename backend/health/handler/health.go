@@ -0,0 +1,48 @@
+package handler
+
+import (
+	"net/http"
+
+	"github.com/labstack/echo/v4"
+	"go.uber.org/zap"
+
+	"skyhawk/backend/health"
+)
+
+type Handler struct {
+	readiness *health.Checker
+	startup   *health.Checker
+	logger    *zap.Logger
+}
+
+func NewHandler(readiness, startup *health.Checker, logger *zap.Logger) *Handler {
+	return &Handler{readiness: readiness, startup: startup, logger: logger}
+}
+
+// LivenessHandler - GET /healthz, the process is up and serving. Dependencies are not checked,
+// a mysql outage must not get every instance restarted
+func (h *Handler) LivenessHandler(c echo.Context) error {
+	return c.JSON(http.StatusOK, health.Report{Status: health.StatusUp, Checks: []health.Result{}})
+}
+
+// ReadinessHandler - GET /readyz, 503 while a critical dependency is down so the instance is taken out of rotation
+func (h *Handler) ReadinessHandler(c echo.Context) error {
+	return h.report(c, h.readiness)
+}
+
+// StartupHandler - GET /startupz, 503 until the DB is migrated to the version this build expects
+func (h *Handler) StartupHandler(c echo.Context) error {
+	return h.report(c, h.startup)
+}
+
+func (h *Handler) report(c echo.Context, checker *health.Checker) error {
+	report := checker.Run(c.Request().Context())
+
+	status := http.StatusOK
+	if report.Status == health.StatusDown {
+		status = http.StatusServiceUnavailable
+	}
+	c.Response().Header().Set(echo.HeaderCacheControl, "no-store")
+
+	return c.JSON(status, report)
+}
@@ -0,0 +1,135 @@
+package health
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"time"
+
+	"go.uber.org/zap"
+)
+
+// Status - the state of one dependency or of the whole service
+type Status string
+
+const (
+	StatusUp Status = "up"
+	// StatusDegraded - a non critical dependency is down, the service still answers with reduced function
+	StatusDegraded Status = "degraded"
+	StatusDown     Status = "down"
+)
+
+// Check - probes one dependency. A failing critical check takes the service out of rotation,
+// a failing non critical one only degrades it
+type Check struct {
+	Name     string
+	Critical bool
+	Probe    func(ctx context.Context) error
+}
+
+type Result struct {
+	Name      string  `json:"name"`
+	Status    Status  `json:"status"`
+	Critical  bool    `json:"critical"`
+	LatencyMS float64 `json:"latency_ms"`
+	Error     string  `json:"error,omitempty"`
+}
+
+type Report struct {
+	Status Status   `json:"status"`
+	Checks []Result `json:"checks"`
+}
+
+// Checker - runs its checks concurrently, each within timeout
+type Checker struct {
+	checks  []Check
+	timeout time.Duration
+	logger  *zap.Logger
+}
+
+func NewChecker(timeout time.Duration, logger *zap.Logger, checks ...Check) *Checker {
+	return &Checker{checks: checks, timeout: timeout, logger: logger}
+}
+
+// Run - the result of every check, in the order they were given, and the overall status
+func (c *Checker) Run(ctx context.Context) Report {
+	report := Report{Status: StatusUp, Checks: make([]Result, len(c.checks))}
+
+	wg := sync.WaitGroup{}
+	for i, check := range c.checks {
+		wg.Add(1)
+		go func(i int, check Check) {
+			defer wg.Done()
+			report.Checks[i] = c.run(ctx, check)
+		}(i, check)
+	}
+	wg.Wait()
+
+	for _, result := range report.Checks {
+		if result.Status == StatusUp {
+			continue
+		}
+		if result.Critical {
+			report.Status = StatusDown
+		} else if report.Status == StatusUp {
+			report.Status = StatusDegraded
+		}
+	}
+
+	return report
+}
+
+func (c *Checker) run(ctx context.Context, check Check) Result {
+	ctx, cancel := context.WithTimeout(ctx, c.timeout)
+	defer cancel()
+
+	start := time.Now()
+	err := probe(ctx, check.Probe)
+	result := Result{
+		Name:      check.Name,
+		Status:    StatusUp,
+		Critical:  check.Critical,
+		LatencyMS: float64(time.Since(start).Microseconds()) / 1000,
+	}
+	if err != nil {
+		result.Status = StatusDown
+		result.Error = err.Error()
+		c.logger.Warn("health check failed", zap.String("check", check.Name), zap.Error(err))
+	}
+
+	return result
+}
+
+// probe - a probe that ignores its context still gives up at the deadline
+func probe(ctx context.Context, fn func(ctx context.Context) error) error {
+	done := make(chan error, 1)
+	go func() {
+		done <- fn(ctx)
+	}()
+
+	select {
+	case err := <-done:
+		return err
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+// Migrations - up once the DB is migrated to the latest migration this build ships
+func Migrations(versions func(ctx context.Context) (current, expected int64, err error)) Check {
+	return Check{
+		Name:     "migrations",
+		Critical: true,
+		Probe: func(ctx context.Context) error {
+			current, expected, err := versions(ctx)
+			if err != nil {
+				return err
+			}
+			if current < expected {
+				return fmt.Errorf("db at version %d, expected %d", current, expected)
+			}
+
+			return nil
+		},
+	}
+}
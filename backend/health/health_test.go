@@ -0,0 +1,91 @@
+package health
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap/zaptest"
+)
+
+func up(ctx context.Context) error { return nil }
+
+func down(ctx context.Context) error { return errors.New("connection refused") }
+
+func TestChecker_Run(t *testing.T) {
+	tests := []struct {
+		name   string
+		checks []Check
+		status Status
+	}{
+		{
+			name:   "all up",
+			checks: []Check{{Name: "mysql", Critical: true, Probe: up}, {Name: "redis", Probe: up}},
+			status: StatusUp,
+		},
+		{
+			name:   "non critical down degrades",
+			checks: []Check{{Name: "mysql", Critical: true, Probe: up}, {Name: "redis", Probe: down}},
+			status: StatusDegraded,
+		},
+		{
+			name:   "critical down",
+			checks: []Check{{Name: "mysql", Critical: true, Probe: down}, {Name: "redis", Probe: down}},
+			status: StatusDown,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			// Setup
+			checker := NewChecker(time.Second, zaptest.NewLogger(t), tt.checks...)
+
+			// Test
+			report := checker.Run(context.Background())
+
+			// Assert
+			assert.Equal(t, tt.status, report.Status)
+			require.Len(t, report.Checks, len(tt.checks))
+			for i, check := range tt.checks {
+				assert.Equal(t, check.Name, report.Checks[i].Name)
+			}
+		})
+	}
+
+	t.Run("slow check times out", func(t *testing.T) {
+		// Setup
+		block := make(chan struct{})
+		defer close(block)
+		checker := NewChecker(20*time.Millisecond, zaptest.NewLogger(t), Check{Name: "mysql", Critical: true, Probe: func(ctx context.Context) error {
+			<-block
+			return nil
+		}})
+
+		// Test
+		report := checker.Run(context.Background())
+
+		// Assert
+		assert.Equal(t, StatusDown, report.Status)
+		assert.Equal(t, context.DeadlineExceeded.Error(), report.Checks[0].Error)
+		assert.GreaterOrEqual(t, report.Checks[0].LatencyMS, float64(20))
+	})
+}
+
+func TestMigrations(t *testing.T) {
+	versions := func(current int64, err error) func(ctx context.Context) (int64, int64, error) {
+		return func(ctx context.Context) (int64, int64, error) { return current, 7, err }
+	}
+
+	// Test
+	behind := Migrations(versions(6, nil)).Probe(context.Background())
+	current := Migrations(versions(7, nil)).Probe(context.Background())
+	failed := Migrations(versions(0, errors.New("no goose table"))).Probe(context.Background())
+
+	// Assert
+	assert.EqualError(t, behind, "db at version 6, expected 7")
+	assert.NoError(t, current)
+	assert.Error(t, failed)
+}
@@ -0,0 +1,94 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"flag"
+	"io"
+	"os"
+	"os/signal"
+	"path/filepath"
+	"strings"
+	"syscall"
+
+	"go.uber.org/zap"
+
+	audit_domain "skyhawk/backend/audit/domain"
+	"skyhawk/backend/config"
+	"skyhawk/backend/importer"
+)
+
+// runImport - the "import" subcommand, loads a csv or ndjson box score file and prints the report as json
+func runImport(cfg config.Config, logger *zap.Logger, args []string) error {
+	flags := flag.NewFlagSet("import", flag.ContinueOnError)
+	file := flags.String("file", "", "path of the file to import, - reads stdin")
+	format := flags.String("format", "", "csv or ndjson, defaults to the file extension")
+	mapping := flags.String("map", "", "column mapping, e.g. points=PTS,player=PLAYER_NAME")
+	dateLayout := flags.String("date-layout", "", "go time layout of the date column, defaults to RFC3339, date-time or date")
+	dryRun := flags.Bool("dry-run", false, "validate and report without writing")
+	window := flags.Int("window", importer.DefaultOptions().Window, "how many games are read at once, the rows of a game must come within this many games of each other")
+	origin := flags.String("origin", "import", "the source the game keys belong to, a key is refused only when this origin already holds it")
+	if err := flags.Parse(args); err != nil {
+		return err
+	}
+	if *file == "" {
+		return errors.New("import: -file is required")
+	}
+
+	opts := importer.DefaultOptions()
+	opts.DryRun = *dryRun
+	opts.Origin = *origin
+	opts.Window = *window
+	opts.Format = importer.Format(*format)
+	if opts.Format == "" {
+		switch strings.ToLower(filepath.Ext(*file)) {
+		case ".ndjson", ".jsonl":
+			opts.Format = importer.FormatNDJSON
+		default:
+			opts.Format = importer.FormatCSV
+		}
+	}
+	if *dateLayout != "" {
+		opts.DateLayouts = []string{*dateLayout}
+	}
+	parsed, err := importer.ParseMapping(*mapping)
+	if err != nil {
+		return err
+	}
+	opts.Mapping = parsed
+
+	var source io.Reader = os.Stdin
+	if *file != "-" {
+		f, err := os.Open(*file)
+		if err != nil {
+			return err
+		}
+		defer f.Close()
+		source = f
+	}
+
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+	ctx = audit_domain.WithMeta(ctx, audit_domain.Meta{Actor: "cli:import"})
+
+	var games importer.GameLogger
+	if !opts.DryRun {
+		app, err := newApp(cfg, logger)
+		if err != nil {
+			return err
+		}
+		defer app.Close()
+		games = app.service
+	}
+
+	report, err := importer.New(games, logger).Run(ctx, source, opts)
+	if err != nil {
+		return err
+	}
+
+	encoder := json.NewEncoder(os.Stdout)
+	encoder.SetIndent("", "  ")
+
+	return encoder.Encode(report)
+}
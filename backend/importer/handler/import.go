@@ -0,0 +1,140 @@
+package handler
+
+import (
+	"errors"
+	"io"
+	"mime"
+	"net/http"
+	"path/filepath"
+	"strconv"
+	"strings"
+
+	"github.com/labstack/echo/v4"
+	"go.uber.org/zap"
+
+	"skyhawk/backend/apperror"
+	"skyhawk/backend/importer"
+)
+
+type Handler struct {
+	importer *importer.Importer
+	logger   *zap.Logger
+}
+
+func NewHandler(importer *importer.Importer, logger *zap.Logger) *Handler {
+	return &Handler{importer: importer, logger: logger}
+}
+
+// ImportHandler - imports box scores from a multipart upload (part "file", optional part "mapping")
+// or from a raw csv / ndjson body, the source is streamed, only the games of the import window are held in memory
+func (h *Handler) ImportHandler(c echo.Context) error {
+	opts := importer.DefaultOptions()
+	opts.Format = importer.Format(c.QueryParam("format"))
+
+	if dryRun := c.QueryParam("dry_run"); dryRun != "" {
+		parsed, err := strconv.ParseBool(dryRun)
+		if err != nil {
+			return apperror.Validation("invalid_dry_run", "dry_run must be true or false")
+		}
+		opts.DryRun = parsed
+	}
+	if origin := c.QueryParam("origin"); origin != "" {
+		opts.Origin = origin
+	}
+	if layout := c.QueryParam("date_layout"); layout != "" {
+		opts.DateLayouts = []string{layout}
+	}
+	if err := setMapping(&opts, c.QueryParam("mapping")); err != nil {
+		return err
+	}
+
+	mediaType, _, _ := mime.ParseMediaType(c.Request().Header.Get(echo.HeaderContentType))
+	if mediaType != echo.MIMEMultipartForm {
+		if opts.Format == "" {
+			opts.Format = formatFromMediaType(mediaType)
+		}
+		return h.run(c, c.Request().Body, opts)
+	}
+
+	reader, err := c.Request().MultipartReader()
+	if err != nil {
+		return apperror.Validation("invalid_multipart", "request is not a valid multipart upload")
+	}
+
+	for {
+		part, err := reader.NextPart()
+		if errors.Is(err, io.EOF) {
+			return apperror.Validation("missing_file", "multipart upload has no \"file\" part")
+		}
+		if err != nil {
+			return apperror.Validation("invalid_multipart", "request is not a valid multipart upload")
+		}
+
+		switch part.FormName() {
+		case "mapping":
+			spec, err := io.ReadAll(io.LimitReader(part, 64*1024))
+			if err != nil {
+				return apperror.Validation("invalid_multipart", "failed reading mapping part")
+			}
+			if err = setMapping(&opts, string(spec)); err != nil {
+				return err
+			}
+		case "file":
+			if opts.Format == "" {
+				opts.Format = formatFromFileName(part.FileName())
+			}
+			return h.run(c, part, opts)
+		}
+	}
+}
+
+func (h *Handler) run(c echo.Context, r io.Reader, opts importer.Options) error {
+	if opts.Format != importer.FormatCSV && opts.Format != importer.FormatNDJSON {
+		return apperror.Validation("invalid_format", "format must be csv or ndjson")
+	}
+
+	report, err := h.importer.Run(c.Request().Context(), r, opts)
+	if err != nil {
+		return err
+	}
+
+	return c.JSON(http.StatusOK, report)
+}
+
+func setMapping(opts *importer.Options, spec string) error {
+	if strings.TrimSpace(spec) == "" {
+		return nil
+	}
+
+	mapping, err := importer.ParseMapping(spec)
+	if err != nil {
+		return apperror.Validation("invalid_mapping", err.Error())
+	}
+	for field, column := range mapping {
+		opts.Mapping[field] = column
+	}
+
+	return nil
+}
+
+func formatFromMediaType(mediaType string) importer.Format {
+	switch mediaType {
+	case "text/csv":
+		return importer.FormatCSV
+	case "application/x-ndjson", "application/ndjson":
+		return importer.FormatNDJSON
+	}
+
+	return ""
+}
+
+func formatFromFileName(name string) importer.Format {
+	switch strings.ToLower(filepath.Ext(name)) {
+	case ".csv":
+		return importer.FormatCSV
+	case ".ndjson", ".jsonl":
+		return importer.FormatNDJSON
+	}
+
+	return ""
+}
@@ -0,0 +1,330 @@
+package importer
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+	"time"
+
+	"go.uber.org/zap"
+
+	"skyhawk/backend/apperror"
+	"skyhawk/backend/game/domain"
+)
+
+// GameLogger - where imported games are written
+type GameLogger interface {
+	LogGame(ctx context.Context, stats domain.GameStatsReq) (string, error)
+}
+
+type Options struct {
+	Format  Format
+	Mapping Mapping
+	// DateLayouts - tried in order when parsing the date column
+	DateLayouts []string
+	// DryRun - validate and report without writing anything
+	DryRun bool
+	// MaxIssues - how many issues the report lists, the counters keep counting past it
+	MaxIssues int
+	// Origin - the source the game keys belong to, a key is refused only when the same origin already holds it
+	Origin string
+	// Window - how many games are read at once, the rows of a game must come within this many games of each other
+	Window int
+}
+
+func DefaultOptions() Options {
+	return Options{
+		Format:      FormatCSV,
+		Mapping:     Mapping{},
+		DateLayouts: []string{time.RFC3339, time.DateTime, time.DateOnly},
+		MaxIssues:   100,
+		Origin:      "import",
+		Window:      64,
+	}
+}
+
+// Issue - a problem found in the source, Line is the first source line of the row or game it concerns
+type Issue struct {
+	Line    int    `json:"line"`
+	GameKey string `json:"game_key,omitempty"`
+	Field   string `json:"field,omitempty"`
+	Message string `json:"message"`
+}
+
+// Report - the outcome of an import, on a dry run GamesImported stays zero
+type Report struct {
+	DryRun        bool              `json:"dry_run"`
+	Rows          int               `json:"rows"`
+	Games         int               `json:"games"`
+	GamesValid    int               `json:"games_valid"`
+	GamesImported int               `json:"games_imported"`
+	GamesFailed   int               `json:"games_failed"`
+	IDs           map[string]string `json:"ids,omitempty"`
+	Issues        []Issue           `json:"issues"`
+	IssueCount    int               `json:"issue_count"`
+}
+
+type Importer struct {
+	games  GameLogger
+	logger *zap.Logger
+}
+
+func New(games GameLogger, logger *zap.Logger) *Importer {
+
+	return &Importer{games: games, logger: logger}
+}
+
+// pendingGame - the rows of one game read so far
+type pendingGame struct {
+	line    int
+	req     domain.GameStatsReq
+	invalid bool
+}
+
+// Run - streams r row by row and groups the rows by game key. At most opts.Window games are held at once,
+// when another game starts the one that started first is logged, so rows of a game may interleave with the rows
+// of the games around it but not come after it was logged. A key logged before is reported as a failed game
+func (i *Importer) Run(ctx context.Context, r io.Reader, opts Options) (Report, error) {
+	report := Report{DryRun: opts.DryRun, Issues: []Issue{}}
+	if !opts.DryRun {
+		report.IDs = map[string]string{}
+	}
+	if len(opts.DateLayouts) == 0 {
+		opts.DateLayouts = DefaultOptions().DateLayouts
+	}
+	if opts.Origin == "" {
+		opts.Origin = DefaultOptions().Origin
+	}
+	if opts.Window <= 0 {
+		opts.Window = DefaultOptions().Window
+	}
+	if len(opts.Origin) > domain.MaxOriginLength {
+		return report, apperror.Validation("invalid_origin", fmt.Sprintf("origin is longer than %d characters", domain.MaxOriginLength))
+	}
+
+	reader, err := newRowReader(opts.Format, r)
+	if err != nil {
+		return report, apperror.Validation("invalid_import_source", err.Error())
+	}
+
+	addIssue := func(issue Issue) {
+		report.IssueCount++
+		if opts.MaxIssues <= 0 || len(report.Issues) < opts.MaxIssues {
+			report.Issues = append(report.Issues, issue)
+		}
+	}
+
+	open := make(map[string]*pendingGame)
+	var order []*pendingGame
+	logged := make(map[string]bool)
+
+	flush := func() error {
+		game := order[0]
+		order = order[1:]
+		delete(open, game.req.ID)
+		logged[game.req.ID] = true
+
+		return i.log(ctx, game, opts, &report, addIssue)
+	}
+
+	for {
+		if err := ctx.Err(); err != nil {
+			return report, err
+		}
+
+		row, err := reader.next()
+		if errors.Is(err, io.EOF) {
+			break
+		}
+		if err != nil {
+			// a malformed row is reported and skipped, anything else means the source itself cannot be read
+			if row.line > 0 {
+				addIssue(Issue{Line: row.line, Message: err.Error()})
+				continue
+			}
+			return report, apperror.Validation("invalid_import_source", err.Error())
+		}
+		report.Rows++
+
+		key := strings.TrimSpace(row.values[opts.Mapping.Column(FieldGameKey)])
+		if logged[key] {
+			addIssue(Issue{Line: row.line, GameKey: key, Message: fmt.Sprintf("the game was already logged, its rows must come within %d games of each other", opts.Window)})
+			continue
+		}
+		game, ok := open[key]
+		if !ok {
+			if len(order) == opts.Window {
+				if err := flush(); err != nil {
+					return report, err
+				}
+			}
+			game = &pendingGame{line: row.line, req: domain.GameStatsReq{ID: key, Origin: opts.Origin}}
+			open[key] = game
+			order = append(order, game)
+		}
+
+		if issues := game.add(row, opts); len(issues) > 0 {
+			game.invalid = true
+			for _, issue := range issues {
+				addIssue(issue)
+			}
+		}
+	}
+
+	for len(order) > 0 {
+		if err := flush(); err != nil {
+			return report, err
+		}
+	}
+
+	if !opts.DryRun {
+		i.logger.Info("import finished",
+			zap.Int("rows", report.Rows),
+			zap.Int("games", report.Games),
+			zap.Int("imported", report.GamesImported),
+			zap.Int("failed", report.GamesFailed))
+	}
+
+	return report, nil
+}
+
+// log - validates a game read in full and logs it unless this is a dry run, only a cancelled ctx is returned
+func (i *Importer) log(ctx context.Context, game *pendingGame, opts Options, report *Report, addIssue func(Issue)) error {
+	report.Games++
+
+	if game.invalid {
+		report.GamesFailed++
+		return nil
+	}
+	if err := game.req.Validate(); err != nil {
+		report.GamesFailed++
+		for _, field := range apperror.From(err).Fields {
+			addIssue(Issue{Line: game.line, GameKey: game.req.ID, Field: field.Field, Message: field.Message})
+		}
+		return nil
+	}
+	report.GamesValid++
+
+	if opts.DryRun {
+		return nil
+	}
+
+	id, err := i.games.LogGame(ctx, game.req)
+	if err != nil {
+		if ctx.Err() != nil {
+			return ctx.Err()
+		}
+		report.GamesFailed++
+		addIssue(Issue{Line: game.line, GameKey: game.req.ID, Message: apperror.From(err).Message})
+		return nil
+	}
+	report.GamesImported++
+	report.IDs[game.req.ID] = id
+
+	return nil
+}
+
+// add - parses a row into the game, returning what is wrong with it
+func (g *pendingGame) add(r row, opts Options) []Issue {
+	var issues []Issue
+	issue := func(field Field, message string) {
+		issues = append(issues, Issue{Line: r.line, GameKey: g.req.ID, Field: opts.Mapping.Column(field), Message: message})
+	}
+
+	for _, field := range requiredFields {
+		if strings.TrimSpace(r.values[opts.Mapping.Column(field)]) == "" {
+			issue(field, "value is required")
+		}
+	}
+	if len(issues) > 0 {
+		return issues
+	}
+
+	rawDate := strings.TrimSpace(r.values[opts.Mapping.Column(FieldDate)])
+	date, err := parseDate(rawDate, opts.DateLayouts)
+	if err != nil {
+		issue(FieldDate, fmt.Sprintf("cannot parse date %q", rawDate))
+	} else if g.req.Date.IsZero() {
+		g.req.Date = date
+	} else if !g.req.Date.Equal(date) {
+		issue(FieldDate, "date differs from earlier rows of the same game")
+	}
+
+	integer := func(field Field) int {
+		raw := strings.TrimSpace(r.values[opts.Mapping.Column(field)])
+		if raw == "" {
+			return 0
+		}
+		value, err := strconv.Atoi(raw)
+		if err != nil {
+			issue(field, fmt.Sprintf("%q is not a whole number", raw))
+		}
+		return value
+	}
+
+	player := domain.Player{
+		Name:      strings.TrimSpace(r.values[opts.Mapping.Column(FieldPlayer)]),
+		Points:    integer(FieldPoints),
+		Rebounds:  integer(FieldRebounds),
+		Assists:   integer(FieldAssists),
+		Steals:    integer(FieldSteals),
+		Blocks:    integer(FieldBlocks),
+		Fouls:     integer(FieldFouls),
+		Turnovers: integer(FieldTurnovers),
+	}
+
+	if raw := strings.TrimSpace(r.values[opts.Mapping.Column(FieldMinutes)]); raw != "" {
+		minutes, err := parseMinutes(raw)
+		if err != nil {
+			issue(FieldMinutes, fmt.Sprintf("%q is not a number of minutes", raw))
+		}
+		player.MinutesPlayed = minutes
+	}
+
+	if len(issues) > 0 {
+		return issues
+	}
+
+	teamName := strings.TrimSpace(r.values[opts.Mapping.Column(FieldTeam)])
+	for i := range g.req.Teams {
+		if g.req.Teams[i].Name == teamName {
+			g.req.Teams[i].Players = append(g.req.Teams[i].Players, player)
+			return nil
+		}
+	}
+	g.req.Teams = append(g.req.Teams, domain.Team{Name: teamName, Players: []domain.Player{player}})
+
+	return nil
+}
+
+func parseDate(raw string, layouts []string) (time.Time, error) {
+	var err error
+	for _, layout := range layouts {
+		var date time.Time
+		if date, err = time.Parse(layout, raw); err == nil {
+			return date, nil
+		}
+	}
+
+	return time.Time{}, err
+}
+
+// parseMinutes - accepts decimal minutes ("34.5") and clock notation ("34:30")
+func parseMinutes(raw string) (float64, error) {
+	if minutes, seconds, ok := strings.Cut(raw, ":"); ok {
+		m, err := strconv.Atoi(minutes)
+		if err != nil {
+			return 0, err
+		}
+		s, err := strconv.Atoi(seconds)
+		if err != nil || s < 0 || s >= 60 {
+			return 0, fmt.Errorf("invalid seconds in %q", raw)
+		}
+		return float64(m) + float64(s)/60, nil
+	}
+
+	return strconv.ParseFloat(raw, 64)
+}
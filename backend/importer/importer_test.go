@@ -0,0 +1,245 @@
+package importer
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap/zaptest"
+
+	"skyhawk/backend/apperror"
+	"skyhawk/backend/game/domain"
+)
+
+// fakeGameLogger - refuses a key its origin logged before, like the unique key of the games table
+type fakeGameLogger struct {
+	games []domain.GameStatsReq
+}
+
+func (f *fakeGameLogger) LogGame(_ context.Context, stats domain.GameStatsReq) (string, error) {
+	for _, game := range f.games {
+		if game.Origin == stats.Origin && game.ID == stats.ID {
+			return "", apperror.Conflict("game_already_logged", fmt.Sprintf("%s already holds a game with key %q", stats.Origin, stats.ID), nil)
+		}
+	}
+	f.games = append(f.games, stats)
+	return "id-" + stats.ID, nil
+}
+
+const boxScoreCSV = `GAME,DAY,TEAM,PLAYER_NAME,PTS,REB,AST,STL,BLK,PF,TOV,MIN
+g1,2025-03-08,Lakers,LeBron James,30,12,8,2,1,2,3,38:30
+g1,2025-03-08,Lakers,Anthony Davis,28,10,3,1,3,2,1,36
+g1,2025-03-08,Warriors,Stephen Curry,35,5,6,1,0,1,2,40
+g2,2025-03-09,Celtics,Jayson Tatum,27,8,4,1,1,3,2,37.5
+`
+
+func csvMapping(t *testing.T) Mapping {
+	mapping, err := ParseMapping("game_key=GAME,date=DAY,team=TEAM,player=PLAYER_NAME,points=PTS,rebounds=REB,assists=AST,steals=STL,blocks=BLK,fouls=PF,turnovers=TOV,minutes_played=MIN")
+	require.NoError(t, err)
+	return mapping
+}
+
+func TestImporter_Run(t *testing.T) {
+	t.Run("csv rows are grouped into games", func(t *testing.T) {
+		// Setup
+		games := &fakeGameLogger{}
+		opts := DefaultOptions()
+		opts.Mapping = csvMapping(t)
+
+		// Test
+		report, err := New(games, zaptest.NewLogger(t)).Run(context.Background(), strings.NewReader(boxScoreCSV), opts)
+
+		// Assert
+		require.NoError(t, err)
+		assert.Equal(t, 4, report.Rows)
+		assert.Equal(t, 2, report.Games)
+		assert.Equal(t, 2, report.GamesImported)
+		assert.Empty(t, report.Issues)
+		require.Len(t, games.games, 2)
+
+		first := games.games[0]
+		assert.Equal(t, "g1", first.ID)
+		require.Len(t, first.Teams, 2)
+		assert.Equal(t, "Lakers", first.Teams[0].Name)
+		assert.Len(t, first.Teams[0].Players, 2)
+		assert.Equal(t, 38.5, first.Teams[0].Players[0].MinutesPlayed)
+		assert.Equal(t, "id-g1", report.IDs["g1"])
+	})
+
+	t.Run("dry run validates without writing", func(t *testing.T) {
+		// Setup
+		games := &fakeGameLogger{}
+		opts := DefaultOptions()
+		opts.Mapping = csvMapping(t)
+		opts.DryRun = true
+		source := strings.Replace(boxScoreCSV, "Stephen Curry,35,5,6,1,0,1", "Stephen Curry,35,5,6,1,0,9", 1)
+
+		// Test
+		report, err := New(games, zaptest.NewLogger(t)).Run(context.Background(), strings.NewReader(source), opts)
+
+		// Assert
+		require.NoError(t, err)
+		assert.Empty(t, games.games)
+		assert.Equal(t, 1, report.GamesValid)
+		assert.Equal(t, 1, report.GamesFailed)
+		require.Len(t, report.Issues, 1)
+		assert.Equal(t, "g1", report.Issues[0].GameKey)
+		assert.Equal(t, "teams[1].players[0].fouls", report.Issues[0].Field)
+	})
+
+	t.Run("bad values are reported with their line", func(t *testing.T) {
+		// Setup
+		opts := DefaultOptions()
+		opts.Mapping = csvMapping(t)
+		opts.DryRun = true
+		source := strings.Replace(boxScoreCSV, "Jayson Tatum,27", "Jayson Tatum,lots", 1)
+
+		// Test
+		report, err := New(nil, zaptest.NewLogger(t)).Run(context.Background(), strings.NewReader(source), opts)
+
+		// Assert
+		require.NoError(t, err)
+		require.Len(t, report.Issues, 1)
+		assert.Equal(t, 5, report.Issues[0].Line)
+		assert.Equal(t, "PTS", report.Issues[0].Field)
+		assert.Equal(t, 1, report.GamesFailed)
+	})
+
+	t.Run("rows of a game may be apart within the window", func(t *testing.T) {
+		// Setup
+		games := &fakeGameLogger{}
+		opts := DefaultOptions()
+		opts.Mapping = csvMapping(t)
+		source := boxScoreCSV + "g1,2025-03-08,Lakers,Austin Reaves,12,3,4,1,0,2,1,30\n"
+
+		// Test
+		report, err := New(games, zaptest.NewLogger(t)).Run(context.Background(), strings.NewReader(source), opts)
+
+		// Assert
+		require.NoError(t, err)
+		assert.Empty(t, report.Issues)
+		assert.Equal(t, 2, report.GamesImported)
+		require.Len(t, games.games, 2)
+		assert.Equal(t, "g1", games.games[0].ID)
+		require.Len(t, games.games[0].Teams, 2)
+		assert.Len(t, games.games[0].Teams[0].Players, 3, "the late row joins its game")
+	})
+
+	t.Run("a game is logged once the window moves past it", func(t *testing.T) {
+		// Setup
+		games := &fakeGameLogger{}
+		opts := DefaultOptions()
+		opts.Mapping = csvMapping(t)
+		opts.Window = 1
+		source := boxScoreCSV + "g1,2025-03-08,Lakers,Austin Reaves,12,3,4,1,0,2,1,30\n"
+
+		// Test
+		report, err := New(games, zaptest.NewLogger(t)).Run(context.Background(), strings.NewReader(source), opts)
+
+		// Assert
+		require.NoError(t, err)
+		assert.Equal(t, 2, report.GamesImported)
+		require.Len(t, games.games, 2)
+		assert.Len(t, games.games[0].Teams[0].Players, 2, "the late row is not added to a logged game")
+		require.Len(t, report.Issues, 1)
+		assert.Equal(t, 6, report.Issues[0].Line)
+		assert.Equal(t, "g1", report.Issues[0].GameKey)
+	})
+
+	t.Run("a game imported before is reported", func(t *testing.T) {
+		// Setup
+		games := &fakeGameLogger{}
+		opts := DefaultOptions()
+		opts.Mapping = csvMapping(t)
+		importer := New(games, zaptest.NewLogger(t))
+		_, err := importer.Run(context.Background(), strings.NewReader(boxScoreCSV), opts)
+		require.NoError(t, err)
+
+		// Test
+		report, err := importer.Run(context.Background(), strings.NewReader(boxScoreCSV), opts)
+
+		// Assert
+		require.NoError(t, err)
+		assert.Len(t, games.games, 2, "nothing is logged twice")
+		assert.Zero(t, report.GamesImported)
+		assert.Equal(t, 2, report.GamesFailed)
+		require.Len(t, report.Issues, 2)
+		assert.Equal(t, "g1", report.Issues[0].GameKey)
+		assert.Contains(t, report.Issues[0].Message, "already holds")
+	})
+
+	t.Run("the same keys from another origin are imported", func(t *testing.T) {
+		// Setup
+		games := &fakeGameLogger{}
+		opts := DefaultOptions()
+		opts.Mapping = csvMapping(t)
+		importer := New(games, zaptest.NewLogger(t))
+		_, err := importer.Run(context.Background(), strings.NewReader(boxScoreCSV), opts)
+		require.NoError(t, err)
+		opts.Origin = "league-feed"
+
+		// Test
+		report, err := importer.Run(context.Background(), strings.NewReader(boxScoreCSV), opts)
+
+		// Assert
+		require.NoError(t, err)
+		assert.Equal(t, 2, report.GamesImported)
+		assert.Len(t, games.games, 4)
+		assert.Equal(t, "league-feed", games.games[3].Origin)
+	})
+
+	t.Run("ndjson rows", func(t *testing.T) {
+		// Setup
+		games := &fakeGameLogger{}
+		opts := DefaultOptions()
+		opts.Format = FormatNDJSON
+		source := `{"game_key": "g1", "date": "2025-03-08T19:30:00Z", "team": "Lakers", "player": "LeBron James", "points": 30, "minutes_played": 38.5}
+
+{"game_key": "g1", "date": "2025-03-08T19:30:00Z", "team": "Warriors", "player": "Stephen Curry", "points": 35, "minutes_played": 40}
+`
+
+		// Test
+		report, err := New(games, zaptest.NewLogger(t)).Run(context.Background(), strings.NewReader(source), opts)
+
+		// Assert
+		require.NoError(t, err)
+		assert.Equal(t, 2, report.Rows)
+		assert.Equal(t, 1, report.GamesImported)
+		require.Len(t, games.games, 1)
+		assert.Len(t, games.games[0].Teams, 2)
+		assert.Equal(t, 35, games.games[0].Teams[1].Players[0].Points)
+	})
+
+	t.Run("malformed ndjson line is skipped", func(t *testing.T) {
+		// Setup
+		opts := DefaultOptions()
+		opts.Format = FormatNDJSON
+		opts.DryRun = true
+		source := "{\"game_key\": \"g1\", \"date\": \"2025-03-08\", \"team\": \"Lakers\", \"player\": \"LeBron James\"}\n{not json\n"
+
+		// Test
+		report, err := New(nil, zaptest.NewLogger(t)).Run(context.Background(), strings.NewReader(source), opts)
+
+		// Assert
+		require.NoError(t, err)
+		assert.Equal(t, 1, report.GamesValid)
+		require.Len(t, report.Issues, 1)
+		assert.Equal(t, 2, report.Issues[0].Line)
+	})
+}
+
+func TestParseMapping(t *testing.T) {
+	mapping, err := ParseMapping("points=PTS, player=NAME")
+	require.NoError(t, err)
+	assert.Equal(t, "PTS", mapping.Column(FieldPoints))
+	assert.Equal(t, "team", mapping.Column(FieldTeam))
+
+	_, err = ParseMapping("pints=PTS")
+	assert.Error(t, err)
+
+	_, err = ParseMapping("points")
+	assert.Error(t, err)
+}
@@ -0,0 +1,79 @@
+package importer
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+)
+
+// Field - a box score value the importer understands
+type Field string
+
+const (
+	FieldGameKey   Field = "game_key"
+	FieldDate      Field = "date"
+	FieldTeam      Field = "team"
+	FieldPlayer    Field = "player"
+	FieldPoints    Field = "points"
+	FieldRebounds  Field = "rebounds"
+	FieldAssists   Field = "assists"
+	FieldSteals    Field = "steals"
+	FieldBlocks    Field = "blocks"
+	FieldFouls     Field = "fouls"
+	FieldTurnovers Field = "turnovers"
+	FieldMinutes   Field = "minutes_played"
+)
+
+var allFields = []Field{
+	FieldGameKey, FieldDate, FieldTeam, FieldPlayer, FieldPoints, FieldRebounds,
+	FieldAssists, FieldSteals, FieldBlocks, FieldFouls, FieldTurnovers, FieldMinutes,
+}
+
+var requiredFields = []Field{FieldGameKey, FieldDate, FieldTeam, FieldPlayer}
+
+// Mapping - which source column holds each field, unmapped fields default to a column named after the field
+type Mapping map[Field]string
+
+func (m Mapping) Column(field Field) string {
+	if column, ok := m[field]; ok && column != "" {
+		return column
+	}
+
+	return string(field)
+}
+
+// ParseMapping - parses "points=PTS,player=PLAYER_NAME" into a Mapping
+func ParseMapping(spec string) (Mapping, error) {
+	mapping := Mapping{}
+	if strings.TrimSpace(spec) == "" {
+		return mapping, nil
+	}
+
+	known := make(map[Field]bool, len(allFields))
+	for _, field := range allFields {
+		known[field] = true
+	}
+
+	for _, pair := range strings.Split(spec, ",") {
+		field, column, ok := strings.Cut(strings.TrimSpace(pair), "=")
+		if !ok || column == "" {
+			return nil, fmt.Errorf("invalid mapping %q, expected field=column", pair)
+		}
+		if !known[Field(field)] {
+			return nil, fmt.Errorf("unknown field %q, known fields are %s", field, fieldNames())
+		}
+		mapping[Field(field)] = column
+	}
+
+	return mapping, nil
+}
+
+func fieldNames() string {
+	names := make([]string, len(allFields))
+	for i, field := range allFields {
+		names[i] = string(field)
+	}
+	sort.Strings(names)
+
+	return strings.Join(names, ", ")
+}
@@ -0,0 +1,138 @@
+package importer
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/csv"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"strconv"
+)
+
+// Format - the layout of an import source
+type Format string
+
+const (
+	FormatCSV    Format = "csv"
+	FormatNDJSON Format = "ndjson"
+)
+
+// row - one player line of one game, keyed by source column
+type row struct {
+	line   int
+	values map[string]string
+}
+
+type rowReader interface {
+	next() (row, error)
+}
+
+func newRowReader(format Format, r io.Reader) (rowReader, error) {
+	switch format {
+	case FormatCSV:
+		return newCSVReader(r)
+	case FormatNDJSON:
+		return &ndjsonReader{scanner: newScanner(r)}, nil
+	}
+
+	return nil, fmt.Errorf("unsupported format %q", format)
+}
+
+type csvReader struct {
+	reader *csv.Reader
+	header []string
+}
+
+func newCSVReader(r io.Reader) (*csvReader, error) {
+	reader := csv.NewReader(r)
+	reader.ReuseRecord = true
+	reader.TrimLeadingSpace = true
+	reader.FieldsPerRecord = -1
+
+	header, err := reader.Read()
+	if err != nil {
+		if errors.Is(err, io.EOF) {
+			return nil, errors.New("csv source is empty")
+		}
+		return nil, fmt.Errorf("failed reading csv header: %w", err)
+	}
+
+	return &csvReader{reader: reader, header: append([]string(nil), header...)}, nil
+}
+
+func (c *csvReader) next() (row, error) {
+	record, err := c.reader.Read()
+	if err != nil {
+		var parseErr *csv.ParseError
+		if errors.As(err, &parseErr) {
+			return row{line: parseErr.StartLine}, err
+		}
+		return row{}, err
+	}
+
+	line, _ := c.reader.FieldPos(0)
+	values := make(map[string]string, len(c.header))
+	for i, column := range c.header {
+		if i < len(record) {
+			values[column] = record[i]
+		}
+	}
+
+	return row{line: line, values: values}, nil
+}
+
+type ndjsonReader struct {
+	scanner *bufio.Scanner
+	line    int
+}
+
+const maxLineSize = 1 << 20
+
+func newScanner(r io.Reader) *bufio.Scanner {
+	scanner := bufio.NewScanner(r)
+	scanner.Buffer(make([]byte, 64*1024), maxLineSize)
+
+	return scanner
+}
+
+func (n *ndjsonReader) next() (row, error) {
+	for n.scanner.Scan() {
+		n.line++
+		line := bytes.TrimSpace(n.scanner.Bytes())
+		if len(line) == 0 {
+			continue
+		}
+
+		var object map[string]interface{}
+		decoder := json.NewDecoder(bytes.NewReader(line))
+		decoder.UseNumber()
+		if err := decoder.Decode(&object); err != nil {
+			return row{line: n.line}, fmt.Errorf("line %d is not a json object: %w", n.line, err)
+		}
+
+		values := make(map[string]string, len(object))
+		for key, value := range object {
+			switch v := value.(type) {
+			case nil:
+			case string:
+				values[key] = v
+			case json.Number:
+				values[key] = v.String()
+			case bool:
+				values[key] = strconv.FormatBool(v)
+			default:
+				return row{line: n.line}, fmt.Errorf("line %d: field %q must be a string or a number", n.line, key)
+			}
+		}
+
+		return row{line: n.line, values: values}, nil
+	}
+
+	if err := n.scanner.Err(); err != nil {
+		return row{}, err
+	}
+
+	return row{}, io.EOF
+}
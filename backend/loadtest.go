@@ -0,0 +1,113 @@
+package main
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"os"
+	"os/signal"
+	"strconv"
+	"syscall"
+	"time"
+
+	"go.uber.org/zap"
+
+	"skyhawk/backend/config"
+	game_domain "skyhawk/backend/game/domain"
+	"skyhawk/backend/generator"
+	"skyhawk/backend/loadtest"
+)
+
+// runLoadTest - the "loadtest" subcommand, replays generated games against a running server's POST /games/log and
+// prints throughput, latency percentiles, errors by class and the server's deadlock retries over the run.
+// Few teams make the games overlap on the same team and player rows, which is what contends
+func runLoadTest(_ config.Config, logger *zap.Logger, args []string) error {
+	defaults := loadtest.DefaultOptions()
+	flags := flag.NewFlagSet("loadtest", flag.ContinueOnError)
+	target := flags.String("target", defaults.Target, "base url of the server")
+	key := flags.String("key", os.Getenv("LOADTEST_API_KEY"), "api key with games:write, an admin key adds the server's retry counters to the report, defaults to LOADTEST_API_KEY")
+	concurrency := flags.Int("concurrency", defaults.Concurrency, "games in flight at once")
+	games := flags.Int("games", defaults.Games, "games to log, the source is replayed when it runs out")
+	duration := flags.Duration("duration", 0, "stop sending after this long")
+	timeout := flags.Duration("timeout", defaults.Timeout, "deadline of each request")
+	file := flags.String("file", "", "ndjson of games written by generate, a season is generated when empty")
+	teams := flags.Int("teams", 4, "teams of the generated season, fewer teams overlap more")
+	seed := flags.Int64("seed", 1, "seed of the generated season")
+	fresh := flags.Bool("fresh", true, "rename the teams for this run, so their teams and players are created under load")
+	if err := flags.Parse(args); err != nil {
+		return err
+	}
+	if *key == "" {
+		return errors.New("loadtest: -key or LOADTEST_API_KEY is required")
+	}
+
+	var source []game_domain.GameStatsReq
+	var err error
+	if *file != "" {
+		source, err = readGames(*file)
+	} else {
+		source, err = generateGames(*teams, *seed)
+	}
+	if err != nil {
+		return err
+	}
+
+	opts := loadtest.Options{Target: *target, APIKey: *key, Concurrency: *concurrency, Games: *games, Duration: *duration, Timeout: *timeout}
+	if *fresh {
+		opts.RunID = "lt" + strconv.FormatInt(time.Now().Unix(), 36)
+	}
+
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+
+	logger.Info("starting load test", zap.String("target", opts.Target), zap.Int("concurrency", opts.Concurrency), zap.Int("games", opts.Games),
+		zap.Int("source_games", len(source)), zap.String("run_id", opts.RunID))
+	report, err := loadtest.New(opts, logger).Run(ctx, source)
+	if err != nil {
+		return err
+	}
+
+	encoder := json.NewEncoder(os.Stdout)
+	encoder.SetIndent("", "  ")
+
+	return encoder.Encode(report)
+}
+
+func readGames(path string) ([]game_domain.GameStatsReq, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	var games []game_domain.GameStatsReq
+	scanner := bufio.NewScanner(f)
+	scanner.Buffer(make([]byte, 64*1024), 1<<20)
+	for line := 1; scanner.Scan(); line++ {
+		if len(scanner.Bytes()) == 0 {
+			continue
+		}
+		var game game_domain.GameStatsReq
+		if err = json.Unmarshal(scanner.Bytes(), &game); err != nil {
+			return nil, fmt.Errorf("loadtest: line %d of %s: %w", line, path, err)
+		}
+		games = append(games, game)
+	}
+
+	return games, scanner.Err()
+}
+
+func generateGames(teams int, seed int64) ([]game_domain.GameStatsReq, error) {
+	opts := generator.DefaultOptions()
+	opts.Teams = teams
+	opts.Seed = seed
+	season, err := generator.New(opts)
+	if err != nil {
+		return nil, fmt.Errorf("loadtest: %w", err)
+	}
+
+	return season.Games(), nil
+}
@@ -0,0 +1,311 @@
+package loadtest
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"go.uber.org/zap"
+
+	game_domain "skyhawk/backend/game/domain"
+)
+
+type Options struct {
+	// Target - base url of the api, e.g. http://localhost:8080
+	Target string
+	// APIKey - a key with the games:write scope, sent in X-API-Key. With an admin key the report includes the retry counters
+	APIKey string
+	// Concurrency - how many games are in flight at once
+	Concurrency int
+	// Games - how many games to log, the source games are replayed from the start when they run out
+	Games int
+	// Duration - stops sending earlier when set
+	Duration time.Duration
+	// Timeout - the deadline of each request
+	Timeout time.Duration
+	// RunID - appended to every team name when set, so the run creates its teams and players concurrently
+	// instead of finding those of an earlier run
+	RunID string
+}
+
+func DefaultOptions() Options {
+	return Options{
+		Target:      "http://localhost:8080",
+		Concurrency: 16,
+		Games:       1000,
+		Timeout:     10 * time.Second,
+	}
+}
+
+// Latency - request latency percentiles in milliseconds
+type Latency struct {
+	Mean float64 `json:"mean"`
+	P50  float64 `json:"p50"`
+	P90  float64 `json:"p90"`
+	P95  float64 `json:"p95"`
+	P99  float64 `json:"p99"`
+	Max  float64 `json:"max"`
+}
+
+// Report - the outcome of a run. Errors are keyed by status and problem code, or by the transport failure.
+// Retries and Exhausted are the server's transaction retry counters over the run, keyed by "op.class"
+type Report struct {
+	Requests        int              `json:"requests"`
+	Succeeded       int              `json:"succeeded"`
+	Failed          int              `json:"failed"`
+	DurationSeconds float64          `json:"duration_seconds"`
+	Throughput      float64          `json:"throughput_per_second"`
+	LatencyMS       Latency          `json:"latency_ms"`
+	Errors          map[string]int   `json:"errors"`
+	Retries         map[string]int64 `json:"retries"`
+	Exhausted       map[string]int64 `json:"retries_exhausted"`
+}
+
+type Runner struct {
+	client *http.Client
+	opts   Options
+	logger *zap.Logger
+}
+
+func New(opts Options, logger *zap.Logger) *Runner {
+	transport := http.DefaultTransport.(*http.Transport).Clone()
+	transport.MaxIdleConnsPerHost = opts.Concurrency
+
+	return &Runner{client: &http.Client{Transport: transport, Timeout: opts.Timeout}, opts: opts, logger: logger}
+}
+
+// result - the outcome of one request, class is empty on success
+type result struct {
+	took  time.Duration
+	class string
+}
+
+// Run - logs opts.Games of games through POST /api/v1/games/log with opts.Concurrency workers and reports on it
+func (r *Runner) Run(ctx context.Context, games []game_domain.GameStatsReq) (Report, error) {
+	if len(games) == 0 {
+		return Report{}, errors.New("no games to send")
+	}
+	if r.opts.Concurrency < 1 {
+		return Report{}, errors.New("concurrency must be at least 1")
+	}
+	// the duration only stops sending, requests in flight when it ends are waited for
+	sending := ctx
+	if r.opts.Duration > 0 {
+		var cancel context.CancelFunc
+		sending, cancel = context.WithTimeout(ctx, r.opts.Duration)
+		defer cancel()
+	}
+
+	// the bodies are encoded up front so the workers only measure the api. Keys are dropped,
+	// a logged key is refused with 409 and the games are replayed within and across runs
+	bodies := make([][]byte, len(games))
+	for i, game := range games {
+		game.ID = ""
+		if r.opts.RunID != "" {
+			game.Teams = renameTeams(game.Teams, r.opts.RunID)
+		}
+		body, err := json.Marshal(game)
+		if err != nil {
+			return Report{}, err
+		}
+		bodies[i] = body
+	}
+
+	before, err := r.retryCounters(ctx)
+	if err != nil {
+		r.logger.Warn("server retry counters are not available, the report will not include them", zap.Error(err))
+	}
+
+	jobs := make(chan []byte)
+	results := make(chan result, r.opts.Concurrency)
+	var workers sync.WaitGroup
+	for i := 0; i < r.opts.Concurrency; i++ {
+		workers.Add(1)
+		go func() {
+			defer workers.Done()
+			for body := range jobs {
+				results <- r.send(ctx, body)
+			}
+		}()
+	}
+
+	started := time.Now()
+	go func() {
+		defer close(jobs)
+		for i := 0; i < r.opts.Games; i++ {
+			select {
+			case jobs <- bodies[i%len(bodies)]:
+			case <-sending.Done():
+				return
+			}
+		}
+	}()
+	go func() {
+		workers.Wait()
+		close(results)
+	}()
+
+	report := Report{Errors: map[string]int{}}
+	var latencies []time.Duration
+	for res := range results {
+		report.Requests++
+		latencies = append(latencies, res.took)
+		if res.class == "" {
+			report.Succeeded++
+			continue
+		}
+		report.Failed++
+		report.Errors[res.class]++
+	}
+	elapsed := time.Since(started)
+	report.DurationSeconds = elapsed.Seconds()
+	if elapsed > 0 {
+		report.Throughput = float64(report.Succeeded) / elapsed.Seconds()
+	}
+	report.LatencyMS = percentiles(latencies)
+
+	if before != nil {
+		if after, err := r.retryCounters(ctx); err != nil {
+			r.logger.Warn("failed reading server retry counters after the run", zap.Error(err))
+		} else {
+			report.Retries = diff(before.Retried, after.Retried)
+			report.Exhausted = diff(before.Exhausted, after.Exhausted)
+		}
+	}
+
+	return report, nil
+}
+
+func (r *Runner) send(ctx context.Context, body []byte) result {
+	started := time.Now()
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, strings.TrimRight(r.opts.Target, "/")+"/api/v1/games/log", bytes.NewReader(body))
+	if err != nil {
+		return result{class: "request"}
+	}
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("X-API-Key", r.opts.APIKey)
+
+	res, err := r.client.Do(req)
+	if err != nil {
+		return result{took: time.Since(started), class: transportClass(err)}
+	}
+	defer res.Body.Close()
+	payload, _ := io.ReadAll(res.Body)
+	took := time.Since(started)
+
+	if res.StatusCode < http.StatusBadRequest {
+		return result{took: took}
+	}
+	var problem struct {
+		Code string `json:"code"`
+	}
+	if json.Unmarshal(payload, &problem) != nil || problem.Code == "" {
+		problem.Code = "unknown"
+	}
+
+	return result{took: took, class: fmt.Sprintf("%d %s", res.StatusCode, problem.Code)}
+}
+
+func transportClass(err error) string {
+	var netErr net.Error
+	switch {
+	case errors.Is(err, context.Canceled):
+		return "canceled"
+	case errors.Is(err, context.DeadlineExceeded), errors.As(err, &netErr) && netErr.Timeout():
+		return "timeout"
+	default:
+		return "connection"
+	}
+}
+
+// counters - the transaction retry counters the server publishes at GET /api/v1/debug/retries
+type counters struct {
+	Retried   map[string]int64 `json:"retried"`
+	Exhausted map[string]int64 `json:"exhausted"`
+}
+
+// retryCounters - reading them takes the admin scope, with a key without it the report leaves them out
+func (r *Runner) retryCounters(ctx context.Context) (*counters, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, strings.TrimRight(r.opts.Target, "/")+"/api/v1/debug/retries", nil)
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("X-API-Key", r.opts.APIKey)
+	res, err := r.client.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer res.Body.Close()
+	if res.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("GET /api/v1/debug/retries answered %d", res.StatusCode)
+	}
+
+	var retries counters
+	if err = json.NewDecoder(res.Body).Decode(&retries); err != nil {
+		return nil, err
+	}
+
+	return &retries, nil
+}
+
+// diff - how much each counter grew, counters that did not move are left out
+func diff(before, after map[string]int64) map[string]int64 {
+	grown := map[string]int64{}
+	for key, value := range after {
+		if delta := value - before[key]; delta > 0 {
+			grown[key] = delta
+		}
+	}
+
+	return grown
+}
+
+// percentiles - nearest rank percentiles of the latencies
+func percentiles(latencies []time.Duration) Latency {
+	if len(latencies) == 0 {
+		return Latency{}
+	}
+	sorted := append([]time.Duration(nil), latencies...)
+	sort.Slice(sorted, func(i, j int) bool { return sorted[i] < sorted[j] })
+
+	ms := func(d time.Duration) float64 { return float64(d.Microseconds()) / 1000 }
+	rank := func(p float64) float64 {
+		i := int(p*float64(len(sorted))+0.999999) - 1
+		if i < 0 {
+			i = 0
+		}
+		return ms(sorted[i])
+	}
+	var total time.Duration
+	for _, latency := range sorted {
+		total += latency
+	}
+
+	return Latency{
+		Mean: ms(total / time.Duration(len(sorted))),
+		P50:  rank(0.5),
+		P90:  rank(0.9),
+		P95:  rank(0.95),
+		P99:  rank(0.99),
+		Max:  ms(sorted[len(sorted)-1]),
+	}
+}
+
+func renameTeams(teams []game_domain.Team, runID string) []game_domain.Team {
+	renamed := make([]game_domain.Team, len(teams))
+	for i, team := range teams {
+		team.Name = fmt.Sprintf("%s %s", team.Name, runID)
+		renamed[i] = team
+	}
+
+	return renamed
+}
@@ -0,0 +1,113 @@
+package loadtest
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap/zaptest"
+
+	game_domain "skyhawk/backend/game/domain"
+)
+
+// fakeAPI - logs every third game with a conflict and counts a deadlock retry per game
+func fakeAPI(t *testing.T) (*httptest.Server, *atomic.Int64, chan string) {
+	var logged atomic.Int64
+	teams := make(chan string, 100)
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/api/v1/debug/retries":
+			if r.Header.Get("X-API-Key") != "secret" {
+				w.WriteHeader(http.StatusUnauthorized)
+				return
+			}
+			retried := logged.Load()
+			_ = json.NewEncoder(w).Encode(map[string]interface{}{
+				"retried": map[string]int64{"LogGame.deadlock": retried, "AppendEvents.deadlock": 3}, "exhausted": map[string]int64{},
+			})
+		case "/api/v1/games/log":
+			assert.Equal(t, "secret", r.Header.Get("X-API-Key"))
+			var game game_domain.GameStatsReq
+			require.NoError(t, json.NewDecoder(r.Body).Decode(&game))
+			teams <- game.Teams[0].Name
+			if logged.Add(1)%3 == 0 {
+				w.Header().Set("Content-Type", "application/problem+json")
+				w.WriteHeader(http.StatusConflict)
+				_, _ = w.Write([]byte(`{"status":409,"code":"duplicate_entry"}`))
+				return
+			}
+			w.WriteHeader(http.StatusCreated)
+		default:
+			http.NotFound(w, r)
+		}
+	}))
+	t.Cleanup(server.Close)
+
+	return server, &logged, teams
+}
+
+func TestRunner_Run(t *testing.T) {
+	t.Run("replays the games and reports", func(t *testing.T) {
+		// Setup
+		server, logged, teams := fakeAPI(t)
+		opts := DefaultOptions()
+		opts.Target = server.URL
+		opts.APIKey = "secret"
+		opts.Concurrency = 4
+		opts.Games = 9
+		opts.RunID = "run-1"
+		games := []game_domain.GameStatsReq{{ID: "g1", Teams: []game_domain.Team{{Name: "Lakers"}}}, {ID: "g2", Teams: []game_domain.Team{{Name: "Celtics"}}}}
+
+		// Test
+		report, err := New(opts, zaptest.NewLogger(t)).Run(context.Background(), games)
+
+		// Assert
+		require.NoError(t, err)
+		assert.Equal(t, int64(9), logged.Load(), "the source games are replayed")
+		assert.Equal(t, 9, report.Requests)
+		assert.Equal(t, 6, report.Succeeded)
+		assert.Equal(t, map[string]int{"409 duplicate_entry": 3}, report.Errors)
+		assert.Equal(t, map[string]int64{"LogGame.deadlock": 9}, report.Retries, "counters that did not move are left out")
+		assert.Greater(t, report.LatencyMS.Max, 0.0)
+		assert.LessOrEqual(t, report.LatencyMS.P50, report.LatencyMS.P99)
+		assert.True(t, strings.HasSuffix(<-teams, " run-1"))
+		assert.Equal(t, "Lakers", games[0].Teams[0].Name, "the source games are left as they are")
+	})
+
+	t.Run("unreachable target", func(t *testing.T) {
+		// Setup
+		opts := DefaultOptions()
+		opts.Target = "http://127.0.0.1:1"
+		opts.Games = 2
+		opts.Timeout = time.Second
+
+		// Test
+		report, err := New(opts, zaptest.NewLogger(t)).Run(context.Background(), []game_domain.GameStatsReq{{ID: "g1"}})
+
+		// Assert
+		require.NoError(t, err)
+		assert.Equal(t, map[string]int{"connection": 2}, report.Errors)
+		assert.Nil(t, report.Retries)
+	})
+}
+
+func TestPercentiles(t *testing.T) {
+	// Setup
+	var latencies []time.Duration
+	for i := 100; i >= 1; i-- {
+		latencies = append(latencies, time.Duration(i)*time.Millisecond)
+	}
+
+	// Test
+	latency := percentiles(latencies)
+
+	// Assert
+	assert.Equal(t, Latency{Mean: 50.5, P50: 50, P90: 90, P95: 95, P99: 99, Max: 100}, latency)
+}
@@ -0,0 +1,56 @@
+package handler
+
+import (
+	"net/http"
+
+	"github.com/labstack/echo/v4"
+	"go.uber.org/zap"
+	"go.uber.org/zap/zapcore"
+
+	"skyhawk/backend/apperror"
+	audit_domain "skyhawk/backend/audit/domain"
+	"skyhawk/backend/logging"
+)
+
+type Handler struct {
+	level  zap.AtomicLevel
+	logger *zap.Logger
+}
+
+func NewHandler(level zap.AtomicLevel, logger *zap.Logger) *Handler {
+	return &Handler{level: level, logger: logger}
+}
+
+// LevelRes - the level the service logs at
+type LevelRes struct {
+	Level string `json:"level"`
+}
+
+// GetLevelHandler - GET /log/level
+func (h *Handler) GetLevelHandler(c echo.Context) error {
+	return c.JSON(http.StatusOK, LevelRes{Level: h.level.String()})
+}
+
+// SetLevelHandler - PUT /log/level {"level": "debug"}, takes effect at once on this instance and lasts until restart
+func (h *Handler) SetLevelHandler(c echo.Context) error {
+	var req LevelRes
+	if err := c.Bind(&req); err != nil {
+		return apperror.Validation("invalid_log_level", "body must be {\"level\": \"debug\"}")
+	}
+
+	level, err := zapcore.ParseLevel(req.Level)
+	if err != nil {
+		return apperror.Validation("invalid_log_level", "level must be debug, info, warn or error",
+			apperror.FieldError{Field: "level", Message: "must be debug, info, warn or error"})
+	}
+
+	ctx := c.Request().Context()
+	previous := h.level.Level()
+	h.level.SetLevel(level)
+	logging.From(ctx, h.logger).Warn("log level changed",
+		zap.Stringer("from", previous),
+		zap.Stringer("to", level),
+		zap.String("actor", audit_domain.MetaFrom(ctx).Actor))
+
+	return c.JSON(http.StatusOK, LevelRes{Level: level.String()})
+}
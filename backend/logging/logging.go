@@ -0,0 +1,58 @@
+package logging
+
+import (
+	"context"
+	"fmt"
+
+	"go.uber.org/zap"
+	"go.uber.org/zap/zapcore"
+
+	"skyhawk/backend/config"
+	"skyhawk/backend/tracing"
+)
+
+// New - the service logger: JSON lines in production, colored console lines with format console.
+// level can be changed while running, sampling keeps a hot error path from flooding the logs
+func New(cfg config.Log) (*zap.Logger, zap.AtomicLevel, error) {
+	level, err := zap.ParseAtomicLevel(cfg.Level)
+	if err != nil {
+		return nil, zap.AtomicLevel{}, fmt.Errorf("logging: %w", err)
+	}
+
+	zapCfg := zap.NewProductionConfig()
+	if cfg.Format == "console" {
+		zapCfg = zap.NewDevelopmentConfig()
+		zapCfg.EncoderConfig.EncodeLevel = zapcore.CapitalColorLevelEncoder
+	}
+	zapCfg.Level = level
+	zapCfg.EncoderConfig.TimeKey = "time"
+	zapCfg.EncoderConfig.EncodeTime = zapcore.ISO8601TimeEncoder
+	zapCfg.Sampling = nil
+	if cfg.SamplingInitial > 0 {
+		zapCfg.Sampling = &zap.SamplingConfig{Initial: cfg.SamplingInitial, Thereafter: cfg.SamplingThereafter}
+	}
+
+	logger, err := zapCfg.Build()
+	if err != nil {
+		return nil, zap.AtomicLevel{}, fmt.Errorf("logging: %w", err)
+	}
+
+	return logger, level, nil
+}
+
+type loggerKey struct{}
+
+// WithLogger - ctx carrying the request scoped logger
+func WithLogger(ctx context.Context, logger *zap.Logger) context.Context {
+	return context.WithValue(ctx, loggerKey{}, logger)
+}
+
+// From - the request scoped logger of ctx, carrying the request id and trace id.
+// Outside a request it is fallback with the trace id of ctx, if any
+func From(ctx context.Context, fallback *zap.Logger) *zap.Logger {
+	if logger, ok := ctx.Value(loggerKey{}).(*zap.Logger); ok {
+		return logger
+	}
+
+	return tracing.Logger(ctx, fallback)
+}
@@ -0,0 +1,51 @@
+package logging
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap"
+	"go.uber.org/zap/zapcore"
+	"go.uber.org/zap/zaptest/observer"
+
+	"skyhawk/backend/config"
+)
+
+func TestNew(t *testing.T) {
+	// Setup
+	cfg := config.Default().Log
+	cfg.Level = "warn"
+
+	// Test
+	logger, level, err := New(cfg)
+
+	// Assert
+	require.NoError(t, err)
+	assert.False(t, logger.Core().Enabled(zapcore.InfoLevel))
+
+	// the level is shared with the logger
+	level.SetLevel(zapcore.DebugLevel)
+	assert.True(t, logger.Core().Enabled(zapcore.DebugLevel))
+
+	_, _, err = New(config.Log{Level: "loud"})
+	assert.Error(t, err)
+}
+
+func TestFrom(t *testing.T) {
+	// Setup
+	core, logs := observer.New(zapcore.InfoLevel)
+	fallback := zap.New(core)
+	ctx := WithLogger(context.Background(), fallback.With(zap.String("request_id", "r1")))
+
+	// Test
+	From(ctx, fallback).Info("in a request")
+	From(context.Background(), fallback).Info("outside a request")
+
+	// Assert
+	entries := logs.All()
+	require.Len(t, entries, 2)
+	assert.Equal(t, "r1", entries[0].ContextMap()["request_id"])
+	assert.NotContains(t, entries[1].ContextMap(), "request_id")
+}
@@ -1,81 +1,96 @@
 package main
 
 import (
-	"github.com/labstack/echo/v4"
-	"go.uber.org/zap"
+	"fmt"
+	"io"
 	"log"
-	"net/http"
 	"os"
-	"path/filepath"
+	"sort"
+	"strings"
+
+	"go.uber.org/zap"
 
-	"skyhawk/backend/game/db"
-	handler2 "skyhawk/backend/game/handler"
-	"skyhawk/backend/game/usecase"
-	goose "skyhawk/backend/goose"
-	playerrepo "skyhawk/backend/player/db"
-	"skyhawk/backend/redis"
-	teamrepo "skyhawk/backend/team/db"
+	"skyhawk/backend/config"
+	"skyhawk/backend/logging"
 )
 
-var migrationsDir = "/backend/goose/migrations"
+// command - an operator command, it shares the config and the wiring of the server and takes its own flags
+type command struct {
+	usage string
+	run   func(cfg config.Config, logger *zap.Logger, args []string) error
+	// client - only talks to a running server, it runs with the default config instead of loading one
+	client bool
+}
 
-func main() {
+var commands = map[string]command{
+	"migrate":              {usage: "status, up, up-to, down, redo, version or create migrations", run: runMigrate},
+	"import":               {usage: "log the games of a csv or ndjson box score file", run: runImport},
+	"export":               {usage: "write the games, players or teams dataset as csv or ndjson", run: runExport},
+	"recompute-aggregates": {usage: "rederive the box scores of play by play games from their events", run: runRecompute},
+	"seed":                 {usage: "log a small demo season into an empty database", run: runSeed},
+	"generate":             {usage: "generate a synthetic season as ndjson or log it", run: runGenerate},
+	"loadtest":             {usage: "replay generated games against a running server and report on it", run: runLoadTest, client: true},
+	"player":               {usage: "merge a duplicate player into another", run: runPlayer},
+	"game":                 {usage: "void a game", run: runGame},
+	"apikey":               {usage: "create, list or revoke api keys", run: runAPIKey},
+}
 
-	logger, err := zap.NewDevelopment()
-	if err != nil {
-		log.Fatalf("failed initaiting service logger error %v", err)
+func usage(w io.Writer) {
+	names := make([]string, 0, len(commands))
+	for name := range commands {
+		names = append(names, name)
 	}
+	sort.Strings(names)
 
-	//prepare DBs
-	DB, err := goose.MustNewDB()
-	if err != nil {
-		log.Fatal(err)
+	fmt.Fprintln(w, "usage: backend [command] [flags]")
+	fmt.Fprintf(w, "  %-22s %s\n", "serve", "run the server, the default, takes config flags (-h lists them)")
+	for _, name := range names {
+		fmt.Fprintf(w, "  %-22s %s\n", name, commands[name].usage)
 	}
+}
 
-	//migrate database
-	pwd, err := os.Getwd()
-	if err != nil {
-		panic(err)
-	}
+func main() {
 
-	migrationsDir, err := filepath.Abs(pwd + migrationsDir)
-	if err != nil {
-		panic(err)
+	//commands read the config file and the environment, only serve takes config flags
+	name, args := "serve", os.Args[1:]
+	if len(args) > 0 && !strings.HasPrefix(args[0], "-") {
+		name, args = args[0], args[1:]
 	}
-	migrationService := goose.New(DB, logger, migrationsDir)
-	if err = migrationService.Run(); err != nil {
-		panic(err)
+	cmd, ok := commands[name]
+	switch {
+	case name == "help":
+		usage(os.Stdout)
+		return
+	case name != "serve" && !ok:
+		fmt.Fprintf(os.Stderr, "unknown command %q\n", name)
+		usage(os.Stderr)
+		os.Exit(2)
+	}
+	var configArgs []string
+	if name == "serve" {
+		configArgs = args
+	}
+	var err error
+	cfg := config.Default()
+	if !cmd.client {
+		if cfg, err = config.Load(configArgs); err != nil {
+			log.Fatal(err)
+		}
 	}
 
-	redis, err := redis.MustNewRedis()
-
+	//JSON lines at LOG_LEVEL, the level can be changed while running through /api/v1/log/level
+	logger, logLevel, err := logging.New(cfg.Log)
 	if err != nil {
 		log.Fatal(err)
 	}
-	//initiate service
-	playerRepo := playerrepo.NewRepo(logger, DB, redis)
-	teamRepo := teamrepo.New(DB, redis, logger)
-	gameRepo := db.NewRepo(DB, logger)
-	service := usecase.NewUseCase(logger, gameRepo, teamRepo, playerRepo)
-
-	//handler
-	handler := handler2.NewHandler(service, logger)
-
-	e := echo.New()
-
-	group := e.Group("api/v1")
-
-	//game handler
-	group.Add(http.MethodPost, "/games/log", handler.GameLogHandler)
-	group.Add(http.MethodGet, "/games/:id", handler.GameStatsHandler)
-
-	//player handler
-	group.Add(http.MethodGet, "/players/season/:player_id", handler.PlayerSeasonStatsHandler)
-
-	//team handler
-
-	group.Add(http.MethodGet, "/teams/stats/season/:team_id", handler.TeamSeasonStatsHandler)
-
-	log.Fatal(e.Start(":8080"))
 
+	if name == "serve" {
+		err = runServe(cfg, logger, logLevel)
+	} else {
+		err = cmd.run(cfg, logger, args)
+	}
+	_ = logger.Sync()
+	if err != nil {
+		log.Fatal(err)
+	}
 }
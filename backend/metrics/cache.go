@@ -0,0 +1,74 @@
+package metrics
+
+import (
+	"context"
+	"errors"
+	"net"
+	"strings"
+
+	"github.com/redis/go-redis/v9"
+)
+
+type repositoryKey struct{}
+
+func withRepository(ctx context.Context, repository string) context.Context {
+	return context.WithValue(ctx, repositoryKey{}, repository)
+}
+
+func repositoryFrom(ctx context.Context) (string, bool) {
+	repository, ok := ctx.Value(repositoryKey{}).(string)
+	return repository, ok
+}
+
+// RedisHook - counts the cache hits, misses and errors of the repositories on the client.
+// Commands are attributed to the repository named by Query, other redis traffic (rate limits, streams) is not counted
+func (m *Metrics) RedisHook() redis.Hook {
+	return cacheHook{metrics: m}
+}
+
+type cacheHook struct {
+	metrics *Metrics
+}
+
+func (h cacheHook) DialHook(next redis.DialHook) redis.DialHook {
+	return func(ctx context.Context, network, addr string) (net.Conn, error) {
+		return next(ctx, network, addr)
+	}
+}
+
+func (h cacheHook) ProcessHook(next redis.ProcessHook) redis.ProcessHook {
+	return func(ctx context.Context, cmd redis.Cmder) error {
+		err := next(ctx, cmd)
+		h.observe(ctx, cmd.Name(), err)
+		return err
+	}
+}
+
+func (h cacheHook) ProcessPipelineHook(next redis.ProcessPipelineHook) redis.ProcessPipelineHook {
+	return func(ctx context.Context, cmds []redis.Cmder) error {
+		err := next(ctx, cmds)
+		for _, cmd := range cmds {
+			h.observe(ctx, cmd.Name(), cmd.Err())
+		}
+		return err
+	}
+}
+
+// observe - a read is a hit or a miss, any failed command is an error.
+// Single commands report their error through next, pipelined ones through cmd.Err
+func (h cacheHook) observe(ctx context.Context, name string, err error) {
+	repository, ok := repositoryFrom(ctx)
+	if !ok {
+		return
+	}
+
+	read := strings.EqualFold(name, "get")
+	switch {
+	case errors.Is(err, redis.Nil) && read:
+		h.metrics.cache.WithLabelValues(repository, "miss").Inc()
+	case err != nil && !errors.Is(err, redis.Nil):
+		h.metrics.cache.WithLabelValues(repository, "error").Inc()
+	case err == nil && read:
+		h.metrics.cache.WithLabelValues(repository, "hit").Inc()
+	}
+}
@@ -0,0 +1,116 @@
+package metrics
+
+import (
+	"context"
+	"database/sql"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/collectors"
+	"github.com/prometheus/client_golang/prometheus/promhttp"
+)
+
+const namespace = "skyhawk"
+
+// Metrics - the prometheus collectors of the service, registered on their own registry so tests can build as many as they like
+type Metrics struct {
+	registry *prometheus.Registry
+
+	httpRequests  *prometheus.CounterVec
+	httpDuration  *prometheus.HistogramVec
+	queryDuration *prometheus.HistogramVec
+	cache         *prometheus.CounterVec
+	retries       *prometheus.CounterVec
+	exhausted     *prometheus.CounterVec
+	gamesLogged   prometheus.Counter
+	playersAdded  prometheus.Counter
+}
+
+func New() *Metrics {
+	m := &Metrics{
+		registry: prometheus.NewRegistry(),
+		httpRequests: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Namespace: namespace,
+			Name:      "http_requests_total",
+			Help:      "Requests served, by method, route template and status.",
+		}, []string{"method", "route", "status"}),
+		httpDuration: prometheus.NewHistogramVec(prometheus.HistogramOpts{
+			Namespace: namespace,
+			Name:      "http_request_duration_seconds",
+			Help:      "Time to serve a request, by method, route template and status.",
+			Buckets:   prometheus.DefBuckets,
+		}, []string{"method", "route", "status"}),
+		queryDuration: prometheus.NewHistogramVec(prometheus.HistogramOpts{
+			Namespace: namespace,
+			Name:      "db_query_duration_seconds",
+			Help:      "Time spent in a repository method, by repository, method and outcome.",
+			Buckets:   []float64{.001, .0025, .005, .01, .025, .05, .1, .25, .5, 1, 2.5, 5},
+		}, []string{"repository", "method", "outcome"}),
+		cache: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Namespace: namespace,
+			Name:      "cache_requests_total",
+			Help:      "Redis cache lookups and writes of the repositories, by result: hit, miss or error.",
+		}, []string{"repository", "result"}),
+		retries: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Namespace: namespace,
+			Name:      "tx_retries_total",
+			Help:      "Transactions retried, by operation and class such as deadlock or lock_wait_timeout.",
+		}, []string{"op", "class"}),
+		exhausted: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Namespace: namespace,
+			Name:      "tx_retries_exhausted_total",
+			Help:      "Transactions that failed after their last attempt, by operation and class.",
+		}, []string{"op", "class"}),
+		gamesLogged: prometheus.NewCounter(prometheus.CounterOpts{
+			Namespace: namespace,
+			Name:      "games_logged_total",
+			Help:      "Games logged, counted as their GameLogged events are published.",
+		}),
+		playersAdded: prometheus.NewCounter(prometheus.CounterOpts{
+			Namespace: namespace,
+			Name:      "players_created_total",
+			Help:      "Players created, counted as their PlayerCreated events are published.",
+		}),
+	}
+
+	m.registry.MustRegister(
+		collectors.NewGoCollector(),
+		collectors.NewProcessCollector(collectors.ProcessCollectorOpts{}),
+		m.httpRequests, m.httpDuration, m.queryDuration, m.cache, m.retries, m.exhausted, m.gamesLogged, m.playersAdded,
+	)
+
+	return m
+}
+
+// Handler - serves the registry in the prometheus text format
+func (m *Metrics) Handler() http.Handler {
+	return promhttp.HandlerFor(m.registry, promhttp.HandlerOpts{Registry: m.registry})
+}
+
+// RegisterDB - exports the pool stats of db: open, in use and idle connections, waits and closes
+func (m *Metrics) RegisterDB(db *sql.DB, name string) {
+	m.registry.MustRegister(collectors.NewDBStatsCollector(db, name))
+}
+
+// Request - records a served request. route is the template, such as /api/v1/games/:id, so ids do not explode the series
+func (m *Metrics) Request(method, route string, status int, took time.Duration) {
+	code := strconv.Itoa(status)
+	m.httpRequests.WithLabelValues(method, route, code).Inc()
+	m.httpDuration.WithLabelValues(method, route, code).Observe(took.Seconds())
+}
+
+// Query - starts timing a repository method. The returned context names the repository for the redis hook,
+// done records the duration and whether the method failed
+func (m *Metrics) Query(ctx context.Context, repository, method string) (context.Context, func(err error)) {
+	start := time.Now()
+
+	return withRepository(ctx, repository), func(err error) {
+		outcome := "ok"
+		if err != nil {
+			outcome = "error"
+		}
+		m.queryDuration.WithLabelValues(repository, method, outcome).Observe(time.Since(start).Seconds())
+	}
+}
@@ -0,0 +1,117 @@
+package metrics
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/alicebob/miniredis/v2"
+	"github.com/prometheus/client_golang/prometheus/testutil"
+	"github.com/redis/go-redis/v9"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"skyhawk/backend/outbox/domain"
+	"skyhawk/backend/retry"
+)
+
+type fakePublisher struct {
+	err error
+}
+
+func (f *fakePublisher) Publish(ctx context.Context, event domain.Event) error { return f.err }
+
+func (f *fakePublisher) Close() error { return nil }
+
+type fakeObserver struct {
+	retried int
+}
+
+func (f *fakeObserver) Retried(op string, class retry.Class) { f.retried++ }
+
+func (f *fakeObserver) Exhausted(op string, class retry.Class) {}
+
+func TestMetrics_RedisHook(t *testing.T) {
+	// Setup
+	m := New()
+	server := miniredis.RunT(t)
+	client := redis.NewClient(&redis.Options{Addr: server.Addr()})
+	client.AddHook(m.RedisHook())
+	require.NoError(t, server.Set("team:hawks", "t1"))
+
+	// Test
+	ctx, done := m.Query(context.Background(), "team", "Save")
+	client.Get(ctx, "team:hawks")
+	client.Get(ctx, "team:eagles")
+	pipe := client.Pipeline()
+	pipe.Get(ctx, "team:hawks")
+	pipe.Set(ctx, "team:eagles", "t2", time.Minute)
+	_, _ = pipe.Exec(ctx)
+	done(nil)
+	client.Get(context.Background(), "team:hawks")
+
+	// Assert
+	assert.Equal(t, float64(2), testutil.ToFloat64(m.cache.WithLabelValues("team", "hit")))
+	assert.Equal(t, float64(1), testutil.ToFloat64(m.cache.WithLabelValues("team", "miss")))
+	assert.Equal(t, float64(0), testutil.ToFloat64(m.cache.WithLabelValues("team", "error")))
+	assert.Equal(t, 1, testutil.CollectAndCount(m.queryDuration, "skyhawk_db_query_duration_seconds"))
+
+	// errors of a down redis are counted
+	server.Close()
+	client.Get(ctx, "team:hawks")
+	assert.Equal(t, float64(1), testutil.ToFloat64(m.cache.WithLabelValues("team", "error")))
+}
+
+func TestMetrics_Publisher(t *testing.T) {
+	// Setup
+	m := New()
+	ok := m.Publisher(&fakePublisher{})
+	failing := m.Publisher(&fakePublisher{err: errors.New("redis down")})
+
+	// Test
+	require.NoError(t, ok.Publish(context.Background(), domain.Event{Type: domain.GameLogged}))
+	require.NoError(t, ok.Publish(context.Background(), domain.Event{Type: domain.PlayerCreated}))
+	require.NoError(t, ok.Publish(context.Background(), domain.Event{Type: domain.PlayerCreated}))
+	require.NoError(t, ok.Publish(context.Background(), domain.Event{Type: domain.GameVoided}))
+	require.Error(t, failing.Publish(context.Background(), domain.Event{Type: domain.GameLogged}))
+
+	// Assert
+	assert.Equal(t, float64(1), testutil.ToFloat64(m.gamesLogged))
+	assert.Equal(t, float64(2), testutil.ToFloat64(m.playersAdded))
+}
+
+func TestMetrics_RetryObserver(t *testing.T) {
+	// Setup
+	m := New()
+	next := &fakeObserver{}
+	observer := m.RetryObserver(next)
+
+	// Test
+	observer.Retried("LogGame", retry.ClassDeadlock)
+	observer.Retried("LogGame", retry.ClassDeadlock)
+	observer.Exhausted("LogGame", retry.ClassLockWaitTimeout)
+
+	// Assert
+	assert.Equal(t, 2, next.retried)
+	assert.Equal(t, float64(2), testutil.ToFloat64(m.retries.WithLabelValues("LogGame", "deadlock")))
+	assert.Equal(t, float64(1), testutil.ToFloat64(m.exhausted.WithLabelValues("LogGame", "lock_wait_timeout")))
+}
+
+func TestMetrics_Handler(t *testing.T) {
+	// Setup
+	m := New()
+	m.Request(http.MethodGet, "/api/v1/games/:id", http.StatusNotFound, 30*time.Millisecond)
+
+	// Test
+	rec := httptest.NewRecorder()
+	m.Handler().ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/metrics", nil))
+
+	// Assert
+	assert.Equal(t, http.StatusOK, rec.Code)
+	assert.Contains(t, rec.Body.String(), `skyhawk_http_requests_total{method="GET",route="/api/v1/games/:id",status="404"} 1`)
+	assert.Contains(t, rec.Body.String(), "skyhawk_http_request_duration_seconds_bucket")
+	assert.Contains(t, rec.Body.String(), "go_goroutines")
+}
@@ -0,0 +1,59 @@
+package metrics
+
+import (
+	"context"
+
+	"skyhawk/backend/outbox"
+	"skyhawk/backend/outbox/domain"
+	"skyhawk/backend/retry"
+)
+
+// RetryObserver - counts transaction retries, then passes them on to next (the expvar counters)
+func (m *Metrics) RetryObserver(next retry.Observer) retry.Observer {
+	return retryObserver{metrics: m, next: next}
+}
+
+type retryObserver struct {
+	metrics *Metrics
+	next    retry.Observer
+}
+
+func (o retryObserver) Retried(op string, class retry.Class) {
+	o.metrics.retries.WithLabelValues(op, string(class)).Inc()
+	o.next.Retried(op, class)
+}
+
+func (o retryObserver) Exhausted(op string, class retry.Class) {
+	o.metrics.exhausted.WithLabelValues(op, string(class)).Inc()
+	o.next.Exhausted(op, class)
+}
+
+// Publisher - counts the business events the relay publishes. Events are only published once their transaction
+// committed, so rolled back and retried writes are not counted
+func (m *Metrics) Publisher(next outbox.Publisher) outbox.Publisher {
+	return publisher{metrics: m, next: next}
+}
+
+type publisher struct {
+	metrics *Metrics
+	next    outbox.Publisher
+}
+
+func (p publisher) Publish(ctx context.Context, event domain.Event) error {
+	if err := p.next.Publish(ctx, event); err != nil {
+		return err
+	}
+
+	switch event.Type {
+	case domain.GameLogged:
+		p.metrics.gamesLogged.Inc()
+	case domain.PlayerCreated:
+		p.metrics.playersAdded.Inc()
+	}
+
+	return nil
+}
+
+func (p publisher) Close() error {
+	return p.next.Close()
+}
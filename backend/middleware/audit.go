@@ -0,0 +1,53 @@
+package middleware
+
+import (
+	"net"
+
+	"github.com/labstack/echo/v4"
+
+	audit_domain "skyhawk/backend/audit/domain"
+)
+
+// ActorHeader - names the caller of routes that are not authenticated, authenticated requests are attributed to their principal
+const ActorHeader = "X-Actor"
+
+// the widths of the audit_log columns, a longer value would fail the insert and with it the audited change
+const (
+	maxActorLength    = 128
+	maxAuditRequestID = 128
+)
+
+// AuditMeta - puts who is calling, the request id and the source ip on the request context,
+// so every change made while serving the request is attributed to it. Must run after the request id middleware
+func AuditMeta() echo.MiddlewareFunc {
+	return func(next echo.HandlerFunc) echo.HandlerFunc {
+		return func(c echo.Context) error {
+			actor := c.Request().Header.Get(ActorHeader)
+			if actor == "" {
+				actor = "anonymous"
+			}
+			if len(actor) > maxActorLength {
+				actor = actor[:maxActorLength]
+			}
+
+			requestID := c.Response().Header().Get(echo.HeaderXRequestID)
+			if len(requestID) > maxAuditRequestID {
+				requestID = requestID[:maxAuditRequestID]
+			}
+			// RealIP is only the peer address when the server sets no IPExtractor, a forwarded value may be anything
+			sourceIP := c.RealIP()
+			if net.ParseIP(sourceIP) == nil {
+				sourceIP = ""
+			}
+
+			ctx := audit_domain.WithMeta(c.Request().Context(), audit_domain.Meta{
+				Actor:     actor,
+				RequestID: requestID,
+				SourceIP:  sourceIP,
+			})
+			c.SetRequest(c.Request().WithContext(ctx))
+
+			return next(c)
+		}
+	}
+}
@@ -0,0 +1,74 @@
+package middleware
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/labstack/echo/v4"
+	echomiddleware "github.com/labstack/echo/v4/middleware"
+	"github.com/stretchr/testify/assert"
+
+	audit_domain "skyhawk/backend/audit/domain"
+)
+
+func TestAuditMeta(t *testing.T) {
+	serve := func(actor string, headers ...string) audit_domain.Meta {
+		var meta audit_domain.Meta
+		e := echo.New()
+		e.Use(echomiddleware.RequestID())
+		e.Use(AuditMeta())
+		e.POST("/games/log", func(c echo.Context) error {
+			meta = audit_domain.MetaFrom(c.Request().Context())
+			return c.NoContent(http.StatusOK)
+		})
+
+		req := httptest.NewRequest(http.MethodPost, "/games/log", nil)
+		req.Header.Set(echo.HeaderXRealIP, "10.0.0.1")
+		if actor != "" {
+			req.Header.Set(ActorHeader, actor)
+		}
+		for i := 0; i+1 < len(headers); i += 2 {
+			req.Header.Set(headers[i], headers[i+1])
+		}
+		e.ServeHTTP(httptest.NewRecorder(), req)
+
+		return meta
+	}
+
+	t.Run("named actor", func(t *testing.T) {
+		// Test
+		meta := serve("scorer-1")
+
+		// Assert
+		assert.Equal(t, "scorer-1", meta.Actor)
+		assert.Equal(t, "10.0.0.1", meta.SourceIP)
+		assert.NotEmpty(t, meta.RequestID)
+	})
+
+	t.Run("anonymous", func(t *testing.T) {
+		// Test
+		meta := serve("")
+
+		// Assert
+		assert.Equal(t, "anonymous", meta.Actor)
+	})
+
+	t.Run("values longer than their columns are cut", func(t *testing.T) {
+		// Test - echo's RequestID passes any incoming id through
+		meta := serve(strings.Repeat("a", 300), echo.HeaderXRequestID, strings.Repeat("r", 300))
+
+		// Assert
+		assert.Len(t, meta.Actor, maxActorLength)
+		assert.Len(t, meta.RequestID, maxAuditRequestID)
+	})
+
+	t.Run("a source ip that is not an address is dropped", func(t *testing.T) {
+		// Test
+		meta := serve("scorer-1", echo.HeaderXRealIP, strings.Repeat("9", 60))
+
+		// Assert
+		assert.Empty(t, meta.SourceIP)
+	})
+}
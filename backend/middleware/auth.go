@@ -0,0 +1,141 @@
+package middleware
+
+import (
+	"context"
+	"strings"
+
+	"github.com/labstack/echo/v4"
+
+	"skyhawk/backend/apperror"
+	audit_domain "skyhawk/backend/audit/domain"
+	auth_domain "skyhawk/backend/auth/domain"
+)
+
+// APIKeyHeader - carries an api key, Authorization: Bearer takes an api key or an identity provider token
+const APIKeyHeader = "X-API-Key"
+
+// Authenticator - turns a credential into the principal it belongs to
+type Authenticator interface {
+	Authenticate(ctx context.Context, credential string) (auth_domain.Principal, error)
+}
+
+// Authenticate - rejects requests without a valid credential with a 401 and puts the principal on the request context.
+// Changes made by the request are audited as the principal. Must run after AuditMeta
+func Authenticate(authenticator Authenticator) echo.MiddlewareFunc {
+	return func(next echo.HandlerFunc) echo.HandlerFunc {
+		return func(c echo.Context) error {
+			credential := credentialOf(c)
+			if credential == "" {
+				c.Response().Header().Set(echo.HeaderWWWAuthenticate, "Bearer")
+				return apperror.Unauthorized("missing_credentials", "an api key in the "+APIKeyHeader+" header or a bearer token is required")
+			}
+
+			principal, err := authenticate(c, authenticator, credential)
+			if err != nil {
+				return err
+			}
+
+			return next(login(c, principal))
+		}
+	}
+}
+
+const (
+	// FeedTokenParam - the query parameter a live feed takes its credential from
+	FeedTokenParam = "access_token"
+	// FeedTokenCookie - the cookie a live feed takes its credential from
+	FeedTokenCookie = "skyhawk_token"
+)
+
+// AuthenticateFeed - Authenticate for the live feeds, whose browser clients (EventSource, WebSocket) cannot set headers.
+// Besides the headers, the credential may come from the access_token query parameter or the skyhawk_token cookie.
+// Those end up in URLs, proxy logs and browser storage, so a credential passed that way must be scoped to stats:read alone
+func AuthenticateFeed(authenticator Authenticator) echo.MiddlewareFunc {
+	return func(next echo.HandlerFunc) echo.HandlerFunc {
+		return func(c echo.Context) error {
+			credential := credentialOf(c)
+			if credential != "" {
+				principal, err := authenticate(c, authenticator, credential)
+				if err != nil {
+					return err
+				}
+				return next(login(c, principal))
+			}
+
+			credential = c.QueryParam(FeedTokenParam)
+			if credential == "" {
+				if cookie, err := c.Cookie(FeedTokenCookie); err == nil {
+					credential = cookie.Value
+				}
+			}
+			if credential == "" {
+				c.Response().Header().Set(echo.HeaderWWWAuthenticate, "Bearer")
+				return apperror.Unauthorized("missing_credentials", "an api key or bearer token in the headers, the "+FeedTokenParam+" query parameter or the "+FeedTokenCookie+" cookie is required")
+			}
+
+			principal, err := authenticate(c, authenticator, credential)
+			if err != nil {
+				return err
+			}
+			for _, scope := range principal.Scopes {
+				if scope != auth_domain.ScopeStatsRead {
+					return apperror.Forbidden("credential_not_read_only", "credentials outside the headers must be scoped to "+string(auth_domain.ScopeStatsRead)+" only")
+				}
+			}
+
+			return next(login(c, principal))
+		}
+	}
+}
+
+func authenticate(c echo.Context, authenticator Authenticator, credential string) (auth_domain.Principal, error) {
+	principal, err := authenticator.Authenticate(c.Request().Context(), credential)
+	if err != nil {
+		if apperror.KindOf(err) == apperror.KindUnauthorized {
+			c.Response().Header().Set(echo.HeaderWWWAuthenticate, "Bearer")
+		}
+		return auth_domain.Principal{}, err
+	}
+
+	return principal, nil
+}
+
+// login - puts principal on the request context and audits the request as it
+func login(c echo.Context, principal auth_domain.Principal) echo.Context {
+	ctx := auth_domain.WithPrincipal(c.Request().Context(), principal)
+	meta := audit_domain.MetaFrom(ctx)
+	meta.Actor = principal.Subject
+	c.SetRequest(c.Request().WithContext(audit_domain.WithMeta(ctx, meta)))
+
+	return c
+}
+
+// RequireScope - rejects principals without scope with a 403. Must run after Authenticate
+func RequireScope(scope auth_domain.Scope) echo.MiddlewareFunc {
+	return func(next echo.HandlerFunc) echo.HandlerFunc {
+		return func(c echo.Context) error {
+			principal, ok := auth_domain.PrincipalFrom(c.Request().Context())
+			if !ok {
+				return apperror.Unauthorized("missing_credentials", "request is not authenticated")
+			}
+			if !principal.Allows(scope) {
+				return apperror.Forbidden("insufficient_scope", "this endpoint requires the "+string(scope)+" scope")
+			}
+
+			return next(c)
+		}
+	}
+}
+
+func credentialOf(c echo.Context) string {
+	if key := c.Request().Header.Get(APIKeyHeader); key != "" {
+		return key
+	}
+
+	scheme, token, ok := strings.Cut(c.Request().Header.Get(echo.HeaderAuthorization), " ")
+	if ok && strings.EqualFold(scheme, "Bearer") {
+		return strings.TrimSpace(token)
+	}
+
+	return ""
+}
@@ -0,0 +1,189 @@
+package middleware
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/labstack/echo/v4"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap/zaptest"
+
+	"skyhawk/backend/apperror"
+	audit_domain "skyhawk/backend/audit/domain"
+	auth_domain "skyhawk/backend/auth/domain"
+)
+
+type fakeAuthenticator map[string]auth_domain.Principal
+
+func (f fakeAuthenticator) Authenticate(ctx context.Context, credential string) (auth_domain.Principal, error) {
+	principal, ok := f[credential]
+	if !ok {
+		return auth_domain.Principal{}, apperror.Unauthorized("invalid_api_key", "api key is invalid or revoked")
+	}
+	return principal, nil
+}
+
+func TestAuthenticate(t *testing.T) {
+	authenticator := fakeAuthenticator{
+		"reader-key": {Subject: "apikey:reader", Scopes: []auth_domain.Scope{auth_domain.ScopeStatsRead}},
+	}
+	serve := func(t *testing.T, method, path, header, value string) (*httptest.ResponseRecorder, Problem, string) {
+		var actor string
+		e := echo.New()
+		e.HTTPErrorHandler = ErrorHandler(zaptest.NewLogger(t))
+		e.Use(AuditMeta())
+		group := e.Group("/api/v1", Authenticate(authenticator))
+		group.GET("/games/:id", func(c echo.Context) error {
+			actor = audit_domain.MetaFrom(c.Request().Context()).Actor
+			return c.NoContent(http.StatusOK)
+		}, RequireScope(auth_domain.ScopeStatsRead))
+		group.POST("/games/log", func(c echo.Context) error {
+			return c.NoContent(http.StatusOK)
+		}, RequireScope(auth_domain.ScopeGamesWrite))
+
+		req := httptest.NewRequest(method, path, nil)
+		if header != "" {
+			req.Header.Set(header, value)
+		}
+		rec := httptest.NewRecorder()
+		e.ServeHTTP(rec, req)
+
+		var problem Problem
+		if rec.Code != http.StatusOK {
+			require.NoError(t, json.Unmarshal(rec.Body.Bytes(), &problem))
+		}
+
+		return rec, problem, actor
+	}
+
+	t.Run("missing credentials", func(t *testing.T) {
+		// Test
+		rec, problem, _ := serve(t, http.MethodGet, "/api/v1/games/g1", "", "")
+
+		// Assert
+		assert.Equal(t, http.StatusUnauthorized, rec.Code)
+		assert.Equal(t, "missing_credentials", problem.Code)
+		assert.Equal(t, "Bearer", rec.Header().Get(echo.HeaderWWWAuthenticate))
+	})
+
+	t.Run("invalid key", func(t *testing.T) {
+		// Test
+		rec, problem, _ := serve(t, http.MethodGet, "/api/v1/games/g1", APIKeyHeader, "stolen")
+
+		// Assert
+		assert.Equal(t, http.StatusUnauthorized, rec.Code)
+		assert.Equal(t, "invalid_api_key", problem.Code)
+	})
+
+	t.Run("bearer key is audited as its principal", func(t *testing.T) {
+		// Test
+		rec, _, actor := serve(t, http.MethodGet, "/api/v1/games/g1", echo.HeaderAuthorization, "Bearer reader-key")
+
+		// Assert
+		assert.Equal(t, http.StatusOK, rec.Code)
+		assert.Equal(t, "apikey:reader", actor)
+	})
+
+	t.Run("missing scope", func(t *testing.T) {
+		// Test
+		rec, problem, _ := serve(t, http.MethodPost, "/api/v1/games/log", APIKeyHeader, "reader-key")
+
+		// Assert
+		assert.Equal(t, http.StatusForbidden, rec.Code)
+		assert.Equal(t, "insufficient_scope", problem.Code)
+	})
+}
+
+func TestAuthenticateFeed(t *testing.T) {
+	authenticator := fakeAuthenticator{
+		"reader-key": {Subject: "apikey:reader", Scopes: []auth_domain.Scope{auth_domain.ScopeStatsRead}},
+		"writer-key": {Subject: "apikey:writer", Scopes: []auth_domain.Scope{auth_domain.ScopeStatsRead, auth_domain.ScopeGamesWrite}},
+	}
+	serve := func(t *testing.T, req *http.Request) (*httptest.ResponseRecorder, Problem, string) {
+		var actor string
+		e := echo.New()
+		e.HTTPErrorHandler = ErrorHandler(zaptest.NewLogger(t))
+		e.Use(AuditMeta())
+		e.GET("/api/v1/games/:id/stream", func(c echo.Context) error {
+			actor = audit_domain.MetaFrom(c.Request().Context()).Actor
+			return c.NoContent(http.StatusOK)
+		}, AuthenticateFeed(authenticator), RequireScope(auth_domain.ScopeStatsRead))
+
+		rec := httptest.NewRecorder()
+		e.ServeHTTP(rec, req)
+
+		var problem Problem
+		if rec.Code != http.StatusOK {
+			require.NoError(t, json.Unmarshal(rec.Body.Bytes(), &problem))
+		}
+
+		return rec, problem, actor
+	}
+
+	t.Run("read only token in the query", func(t *testing.T) {
+		// Test
+		rec, _, actor := serve(t, httptest.NewRequest(http.MethodGet, "/api/v1/games/g1/stream?access_token=reader-key", nil))
+
+		// Assert
+		assert.Equal(t, http.StatusOK, rec.Code)
+		assert.Equal(t, "apikey:reader", actor)
+	})
+
+	t.Run("read only token in the cookie", func(t *testing.T) {
+		// Setup
+		req := httptest.NewRequest(http.MethodGet, "/api/v1/games/g1/stream", nil)
+		req.AddCookie(&http.Cookie{Name: FeedTokenCookie, Value: "reader-key"})
+
+		// Test
+		rec, _, actor := serve(t, req)
+
+		// Assert
+		assert.Equal(t, http.StatusOK, rec.Code)
+		assert.Equal(t, "apikey:reader", actor)
+	})
+
+	t.Run("broader token in the query", func(t *testing.T) {
+		// Test
+		rec, problem, _ := serve(t, httptest.NewRequest(http.MethodGet, "/api/v1/games/g1/stream?access_token=writer-key", nil))
+
+		// Assert
+		assert.Equal(t, http.StatusForbidden, rec.Code)
+		assert.Equal(t, "credential_not_read_only", problem.Code)
+	})
+
+	t.Run("broader key in the header", func(t *testing.T) {
+		// Setup
+		req := httptest.NewRequest(http.MethodGet, "/api/v1/games/g1/stream", nil)
+		req.Header.Set(APIKeyHeader, "writer-key")
+
+		// Test
+		rec, _, actor := serve(t, req)
+
+		// Assert
+		assert.Equal(t, http.StatusOK, rec.Code)
+		assert.Equal(t, "apikey:writer", actor)
+	})
+
+	t.Run("invalid token in the query", func(t *testing.T) {
+		// Test
+		rec, problem, _ := serve(t, httptest.NewRequest(http.MethodGet, "/api/v1/games/g1/stream?access_token=stolen", nil))
+
+		// Assert
+		assert.Equal(t, http.StatusUnauthorized, rec.Code)
+		assert.Equal(t, "invalid_api_key", problem.Code)
+		assert.Equal(t, "Bearer", rec.Header().Get(echo.HeaderWWWAuthenticate))
+	})
+
+	t.Run("missing credentials", func(t *testing.T) {
+		// Test
+		rec, problem, _ := serve(t, httptest.NewRequest(http.MethodGet, "/api/v1/games/g1/stream", nil))
+
+		// Assert
+		assert.Equal(t, http.StatusUnauthorized, rec.Code)
+		assert.Equal(t, "missing_credentials", problem.Code)
+	})
+}
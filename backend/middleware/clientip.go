@@ -0,0 +1,24 @@
+package middleware
+
+import (
+	"net"
+
+	"github.com/labstack/echo/v4"
+)
+
+// ClientIP - how RealIP finds the client, which the rate limits and the audit log key on.
+// Without proxies the peer address is the client, any X-Forwarded-For or X-Real-IP is ignored since a client can send one.
+// With proxies X-Forwarded-For is read back to the first address that is not one of them, and only them:
+// echo would otherwise trust every loopback and private address as a proxy
+func ClientIP(proxies []*net.IPNet) echo.IPExtractor {
+	if len(proxies) == 0 {
+		return echo.ExtractIPDirect()
+	}
+
+	options := []echo.TrustOption{echo.TrustLoopback(false), echo.TrustLinkLocal(false), echo.TrustPrivateNet(false)}
+	for _, proxy := range proxies {
+		options = append(options, echo.TrustIPRange(proxy))
+	}
+
+	return echo.ExtractIPFromXFFHeader(options...)
+}
@@ -0,0 +1,46 @@
+package middleware
+
+import (
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/labstack/echo/v4"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestClientIP(t *testing.T) {
+	_, proxy, err := net.ParseCIDR("10.0.0.0/8")
+	require.NoError(t, err)
+
+	cases := []struct {
+		name    string
+		proxies []*net.IPNet
+		remote  string
+		xff     string
+		want    string
+	}{
+		{name: "no proxies ignores forwarded headers", remote: "203.0.113.7:51000", xff: "198.51.100.1", want: "203.0.113.7"},
+		{name: "trusted proxy forwards the client", proxies: []*net.IPNet{proxy}, remote: "10.1.2.3:51000", xff: "198.51.100.1", want: "198.51.100.1"},
+		{name: "client behind the proxy cannot prepend addresses", proxies: []*net.IPNet{proxy}, remote: "10.1.2.3:51000", xff: "192.0.2.99, 198.51.100.1", want: "198.51.100.1"},
+		{name: "untrusted peer is the client", proxies: []*net.IPNet{proxy}, remote: "203.0.113.7:51000", xff: "198.51.100.1", want: "203.0.113.7"},
+		{name: "private peers are not trusted by default", proxies: []*net.IPNet{proxy}, remote: "192.168.1.5:51000", xff: "198.51.100.1", want: "192.168.1.5"},
+	}
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			// Setup
+			req := httptest.NewRequest(http.MethodGet, "/api/v1/games/g1", nil)
+			req.RemoteAddr = tc.remote
+			req.Header.Set(echo.HeaderXForwardedFor, tc.xff)
+			req.Header.Set(echo.HeaderXRealIP, "192.0.2.50")
+
+			// Test
+			ip := ClientIP(tc.proxies)(req)
+
+			// Assert
+			assert.Equal(t, tc.want, ip)
+		})
+	}
+}
@@ -0,0 +1,129 @@
+package middleware
+
+import (
+	"errors"
+	"net/http"
+
+	"github.com/labstack/echo/v4"
+	"go.uber.org/zap"
+
+	"skyhawk/backend/apperror"
+	"skyhawk/backend/logging"
+)
+
+const problemContentType = "application/problem+json"
+
+// StatusClientClosedRequest - the client went away before the response, nginx's 499, net/http has no name for it
+const StatusClientClosedRequest = 499
+
+// Problem - an RFC 7807 problem details body
+type Problem struct {
+	Type      string                `json:"type"`
+	Title     string                `json:"title"`
+	Status    int                   `json:"status"`
+	Detail    string                `json:"detail,omitempty"`
+	Instance  string                `json:"instance,omitempty"`
+	Code      string                `json:"code"`
+	RequestID string                `json:"request_id,omitempty"`
+	Errors    []apperror.FieldError `json:"errors,omitempty"`
+}
+
+var statusByKind = map[apperror.Kind]int{
+	apperror.KindNotFound:     http.StatusNotFound,
+	apperror.KindValidation:   http.StatusBadRequest,
+	apperror.KindConflict:     http.StatusConflict,
+	apperror.KindUnavailable:  http.StatusServiceUnavailable,
+	apperror.KindTimeout:      http.StatusGatewayTimeout,
+	apperror.KindCanceled:     StatusClientClosedRequest,
+	apperror.KindUnauthorized: http.StatusUnauthorized,
+	apperror.KindForbidden:    http.StatusForbidden,
+	apperror.KindRateLimited:  http.StatusTooManyRequests,
+	apperror.KindInternal:     http.StatusInternalServerError,
+}
+
+// ErrorHandler - the single place errors returned by handlers are turned into responses
+func ErrorHandler(logger *zap.Logger) echo.HTTPErrorHandler {
+	return func(err error, c echo.Context) {
+		if c.Response().Committed {
+			return
+		}
+
+		problem := toProblem(err)
+		problem.Instance = c.Request().URL.Path
+		problem.RequestID = c.Response().Header().Get(echo.HeaderXRequestID)
+
+		if problem.Status >= http.StatusInternalServerError {
+			logging.From(c.Request().Context(), logger).Error("request failed",
+				zap.Error(err),
+				zap.String("code", problem.Code),
+				zap.String("path", problem.Instance))
+		}
+
+		if c.Request().Method == http.MethodHead {
+			err = c.NoContent(problem.Status)
+		} else {
+			c.Response().Header().Set(echo.HeaderContentType, problemContentType)
+			err = c.JSON(problem.Status, problem)
+		}
+		if err != nil {
+			logger.Error("failed writing error response", zap.Error(err))
+		}
+	}
+}
+
+func toProblem(err error) Problem {
+	// errors raised by echo itself - unknown routes, bad methods, oversized bodies
+	var httpErr *echo.HTTPError
+	if errors.As(err, &httpErr) {
+		return Problem{
+			Type:   "about:blank",
+			Title:  http.StatusText(httpErr.Code),
+			Status: httpErr.Code,
+			Code:   codeForStatus(httpErr.Code),
+		}
+	}
+
+	appErr := apperror.From(err)
+	status, ok := statusByKind[appErr.Kind]
+	if !ok {
+		status = http.StatusInternalServerError
+	}
+
+	return Problem{
+		Type:   "urn:skyhawk:error:" + appErr.Code,
+		Title:  statusText(status),
+		Status: status,
+		Detail: appErr.Message,
+		Code:   appErr.Code,
+		Errors: appErr.Fields,
+	}
+}
+
+func statusText(status int) string {
+	if status == StatusClientClosedRequest {
+		return "Client Closed Request"
+	}
+
+	return http.StatusText(status)
+}
+
+func codeForStatus(status int) string {
+	switch status {
+	case http.StatusNotFound:
+		return "route_not_found"
+	case http.StatusMethodNotAllowed:
+		return "method_not_allowed"
+	case http.StatusRequestEntityTooLarge:
+		return "body_too_large"
+	case http.StatusUnauthorized:
+		return "unauthorized"
+	case http.StatusForbidden:
+		return "forbidden"
+	case http.StatusTooManyRequests:
+		return "rate_limited"
+	case http.StatusBadRequest:
+		return "bad_request"
+	}
+
+	return "http_error"
+}
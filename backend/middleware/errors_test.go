@@ -0,0 +1,115 @@
+package middleware
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/go-sql-driver/mysql"
+	"github.com/labstack/echo/v4"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap"
+	"go.uber.org/zap/zapcore"
+	"go.uber.org/zap/zaptest"
+	"go.uber.org/zap/zaptest/observer"
+
+	"skyhawk/backend/apperror"
+)
+
+func TestErrorHandler(t *testing.T) {
+	serve := func(t *testing.T, err error) (*httptest.ResponseRecorder, Problem) {
+		e := echo.New()
+		e.HTTPErrorHandler = ErrorHandler(zaptest.NewLogger(t))
+
+		req := httptest.NewRequest(http.MethodGet, "/api/v1/players/season/42", nil)
+		rec := httptest.NewRecorder()
+		c := e.NewContext(req, rec)
+		c.Response().Header().Set(echo.HeaderXRequestID, "req-1")
+
+		e.HTTPErrorHandler(err, c)
+
+		var problem Problem
+		require.NoError(t, json.Unmarshal(rec.Body.Bytes(), &problem))
+
+		return rec, problem
+	}
+
+	t.Run("not found", func(t *testing.T) {
+		// Test
+		rec, problem := serve(t, apperror.NotFound("player_not_found", "player not found", nil))
+
+		// Assert
+		assert.Equal(t, http.StatusNotFound, rec.Code)
+		assert.Equal(t, "application/problem+json", rec.Header().Get(echo.HeaderContentType))
+		assert.Equal(t, "player_not_found", problem.Code)
+		assert.Equal(t, "req-1", problem.RequestID)
+		assert.Equal(t, "/api/v1/players/season/42", problem.Instance)
+	})
+
+	t.Run("validation carries field errors", func(t *testing.T) {
+		// Test
+		rec, problem := serve(t, apperror.Validation("invalid_game", "game stats are invalid",
+			apperror.FieldError{Field: "teams", Message: "at least one team is required"}))
+
+		// Assert
+		assert.Equal(t, http.StatusBadRequest, rec.Code)
+		assert.Len(t, problem.Errors, 1)
+	})
+
+	t.Run("sql text is not leaked", func(t *testing.T) {
+		// Setup
+		cause := &mysql.MySQLError{Number: 1064, Message: "You have an error in your SQL syntax near 'select'"}
+
+		// Test
+		rec, problem := serve(t, apperror.FromDB(cause, nil))
+
+		// Assert
+		assert.Equal(t, http.StatusInternalServerError, rec.Code)
+		assert.Equal(t, "internal_error", problem.Code)
+		assert.NotContains(t, rec.Body.String(), "SQL syntax")
+	})
+
+	t.Run("unknown errors are internal", func(t *testing.T) {
+		// Test
+		rec, problem := serve(t, errors.New("boom"))
+
+		// Assert
+		assert.Equal(t, http.StatusInternalServerError, rec.Code)
+		assert.NotContains(t, rec.Body.String(), "boom")
+		assert.Equal(t, "internal_error", problem.Code)
+	})
+
+	t.Run("client closed request", func(t *testing.T) {
+		// Setup
+		core, logs := observer.New(zapcore.DebugLevel)
+		e := echo.New()
+		e.HTTPErrorHandler = ErrorHandler(zap.New(core))
+		rec := httptest.NewRecorder()
+		c := e.NewContext(httptest.NewRequest(http.MethodGet, "/api/v1/players/season/42", nil), rec)
+
+		// Test
+		e.HTTPErrorHandler(apperror.FromDB(fmt.Errorf("query season: %w", context.Canceled), nil), c)
+
+		// Assert
+		var problem Problem
+		require.NoError(t, json.Unmarshal(rec.Body.Bytes(), &problem))
+		assert.Equal(t, StatusClientClosedRequest, rec.Code)
+		assert.Equal(t, "client_closed_request", problem.Code)
+		assert.Equal(t, "Client Closed Request", problem.Title)
+		assert.Zero(t, logs.FilterLevelExact(zapcore.ErrorLevel).Len(), "a client leaving is not a server error")
+	})
+
+	t.Run("echo errors", func(t *testing.T) {
+		// Test
+		rec, problem := serve(t, echo.ErrNotFound)
+
+		// Assert
+		assert.Equal(t, http.StatusNotFound, rec.Code)
+		assert.Equal(t, "route_not_found", problem.Code)
+	})
+}
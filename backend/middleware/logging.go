@@ -0,0 +1,74 @@
+package middleware
+
+import (
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/labstack/echo/v4"
+	"go.uber.org/zap"
+	"go.uber.org/zap/zapcore"
+
+	auth_domain "skyhawk/backend/auth/domain"
+	"skyhawk/backend/logging"
+	"skyhawk/backend/tracing"
+)
+
+// quietRoutes - polled by the orchestrator and prometheus every few seconds, their access logs are debug
+var quietRoutes = map[string]bool{"/healthz": true, "/readyz": true, "/startupz": true, "/metrics": true}
+
+// RequestLogger - puts a logger carrying the request id and trace id in the request context, for the usecases and
+// repositories to log with, and writes an access log line once the request is served
+func RequestLogger(logger *zap.Logger) echo.MiddlewareFunc {
+	return func(next echo.HandlerFunc) echo.HandlerFunc {
+		return func(c echo.Context) error {
+			start := time.Now()
+			req := c.Request()
+
+			fields := append([]zap.Field{zap.String("request_id", c.Response().Header().Get(echo.HeaderXRequestID))},
+				tracing.Fields(req.Context())...)
+			requestLogger := logger.With(fields...)
+			c.SetRequest(req.WithContext(logging.WithLogger(req.Context(), requestLogger)))
+
+			err := next(c)
+
+			status := c.Response().Status
+			if err != nil && !c.Response().Committed {
+				status = toProblem(err).Status
+			}
+			route := c.Path()
+			if route == "" {
+				route = "unmatched"
+			}
+
+			level := zapcore.InfoLevel
+			switch {
+			case status >= http.StatusInternalServerError:
+				level = zapcore.ErrorLevel
+			case quietRoutes[route]:
+				level = zapcore.DebugLevel
+			}
+			if entry := requestLogger.Check(level, "request"); entry != nil {
+				bytesIn, _ := strconv.ParseInt(req.Header.Get(echo.HeaderContentLength), 10, 64)
+				//the principal is only known once the api group authenticated the request
+				client := "anonymous"
+				if principal, ok := auth_domain.PrincipalFrom(c.Request().Context()); ok {
+					client = principal.Subject
+				}
+				entry.Write(
+					zap.String("method", req.Method),
+					zap.String("route", route),
+					zap.String("path", req.URL.Path),
+					zap.Int("status", status),
+					zap.Float64("latency_ms", float64(time.Since(start).Microseconds())/1000),
+					zap.Int64("bytes_in", bytesIn),
+					zap.Int64("bytes_out", c.Response().Size),
+					zap.String("client", client),
+					zap.String("client_ip", c.RealIP()),
+					zap.String("user_agent", req.UserAgent()))
+			}
+
+			return err
+		}
+	}
+}
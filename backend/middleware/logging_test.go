@@ -0,0 +1,78 @@
+package middleware
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/labstack/echo/v4"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap"
+	"go.uber.org/zap/zapcore"
+	"go.uber.org/zap/zaptest/observer"
+
+	"skyhawk/backend/logging"
+)
+
+func TestRequestID(t *testing.T) {
+	serve := func(incoming string) string {
+		e := echo.New()
+		e.Use(RequestID())
+		e.GET("/", func(c echo.Context) error { return c.NoContent(http.StatusOK) })
+
+		req := httptest.NewRequest(http.MethodGet, "/", nil)
+		if incoming != "" {
+			req.Header.Set(echo.HeaderXRequestID, incoming)
+		}
+		rec := httptest.NewRecorder()
+		e.ServeHTTP(rec, req)
+
+		return rec.Header().Get(echo.HeaderXRequestID)
+	}
+
+	// Test & Assert
+	assert.Equal(t, "gw-7f3a:01", serve("gw-7f3a:01"), "the caller's id is kept")
+	assert.Len(t, serve(""), 36, "an id is generated")
+	assert.NotContains(t, serve("evil\"id\nlevel=error"), "evil", "unsafe ids are replaced")
+	assert.Len(t, serve(strings.Repeat("a", 200)), 36)
+}
+
+func TestRequestLogger(t *testing.T) {
+	// Setup
+	core, logs := observer.New(zapcore.DebugLevel)
+	logger := zap.New(core)
+	e := echo.New()
+	e.HTTPErrorHandler = ErrorHandler(logger)
+	e.Use(RequestID())
+	e.Use(RequestLogger(logger))
+	e.POST("/games/:id/final", func(c echo.Context) error {
+		logging.From(c.Request().Context(), zap.NewNop()).Info("finalizing")
+		return c.String(http.StatusOK, "done")
+	})
+	e.GET("/healthz", func(c echo.Context) error { return c.NoContent(http.StatusOK) })
+
+	req := httptest.NewRequest(http.MethodPost, "/games/g1/final", nil)
+	req.Header.Set(echo.HeaderXRequestID, "req-1")
+
+	// Test
+	e.ServeHTTP(httptest.NewRecorder(), req)
+	e.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, "/healthz", nil))
+
+	// Assert
+	entries := logs.All()
+	require.Len(t, entries, 3)
+	assert.Equal(t, "finalizing", entries[0].Message)
+	assert.Equal(t, "req-1", entries[0].ContextMap()["request_id"], "handlers log with the request logger")
+
+	access := entries[1].ContextMap()
+	assert.Equal(t, "request", entries[1].Message)
+	assert.Equal(t, "req-1", access["request_id"])
+	assert.Equal(t, "/games/:id/final", access["route"])
+	assert.Equal(t, int64(http.StatusOK), access["status"])
+	assert.Equal(t, int64(4), access["bytes_out"])
+	assert.Equal(t, "anonymous", access["client"])
+
+	assert.Equal(t, zapcore.DebugLevel, entries[2].Level, "probes are logged at debug")
+}
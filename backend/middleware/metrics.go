@@ -0,0 +1,35 @@
+package middleware
+
+import (
+	"time"
+
+	"github.com/labstack/echo/v4"
+)
+
+// RequestRecorder - records every served request
+type RequestRecorder interface {
+	Request(method, route string, status int, took time.Duration)
+}
+
+// Metrics - records the method, route template and status of every request with how long it took.
+// The status of a returned error is the one ErrorHandler will answer with
+func Metrics(recorder RequestRecorder) echo.MiddlewareFunc {
+	return func(next echo.HandlerFunc) echo.HandlerFunc {
+		return func(c echo.Context) error {
+			start := time.Now()
+			err := next(c)
+
+			status := c.Response().Status
+			if err != nil && !c.Response().Committed {
+				status = toProblem(err).Status
+			}
+			route := c.Path()
+			if route == "" {
+				route = "unmatched"
+			}
+			recorder.Request(c.Request().Method, route, status, time.Since(start))
+
+			return err
+		}
+	}
+}
@@ -0,0 +1,54 @@
+package middleware
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/labstack/echo/v4"
+	"github.com/stretchr/testify/assert"
+	"go.uber.org/zap/zaptest"
+
+	"skyhawk/backend/apperror"
+)
+
+type request struct {
+	method string
+	route  string
+	status int
+}
+
+type fakeRecorder struct {
+	requests []request
+}
+
+func (f *fakeRecorder) Request(method, route string, status int, took time.Duration) {
+	f.requests = append(f.requests, request{method: method, route: route, status: status})
+}
+
+func TestMetrics(t *testing.T) {
+	// Setup
+	recorder := &fakeRecorder{}
+	e := echo.New()
+	e.HTTPErrorHandler = ErrorHandler(zaptest.NewLogger(t))
+	e.Use(Metrics(recorder))
+	e.GET("/games/:id", func(c echo.Context) error {
+		if c.Param("id") == "missing" {
+			return apperror.NotFound("game_not_found", "game not found", nil)
+		}
+		return c.NoContent(http.StatusOK)
+	})
+
+	// Test
+	for _, path := range []string{"/games/g1", "/games/missing", "/nowhere"} {
+		e.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, path, nil))
+	}
+
+	// Assert
+	assert.Equal(t, []request{
+		{method: http.MethodGet, route: "/games/:id", status: http.StatusOK},
+		{method: http.MethodGet, route: "/games/:id", status: http.StatusNotFound},
+		{method: http.MethodGet, route: "unmatched", status: http.StatusNotFound},
+	}, recorder.requests)
+}
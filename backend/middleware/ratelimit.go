@@ -0,0 +1,82 @@
+package middleware
+
+import (
+	"context"
+	"math"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/labstack/echo/v4"
+
+	"skyhawk/backend/apperror"
+	"skyhawk/backend/ratelimit"
+)
+
+// RateLimiter - decides whether a client may make another request
+type RateLimiter interface {
+	Allow(ctx context.Context, client string, class ratelimit.Class) ratelimit.Decision
+}
+
+// IPRateLimiter - decides whether an ip may make another request
+type IPRateLimiter interface {
+	AllowIP(ctx context.Context, ip string) ratelimit.Decision
+}
+
+// RateLimitIP - limits each ip before Authenticate, so requests with bad or missing credentials are throttled
+// before they cost a key lookup. RateLimit then limits the authenticated client
+func RateLimitIP(limiter IPRateLimiter) echo.MiddlewareFunc {
+	return func(next echo.HandlerFunc) echo.HandlerFunc {
+		return func(c echo.Context) error {
+			decision := limiter.AllowIP(c.Request().Context(), c.RealIP())
+			if decision.Allowed {
+				return next(c)
+			}
+
+			setLimitHeaders(c, decision)
+			c.Response().Header().Set("Retry-After", strconv.Itoa(ceilSeconds(decision.RetryAfter)))
+			return apperror.RateLimited("rate_limited", "too many requests from this address, retry after the Retry-After delay")
+		}
+	}
+}
+
+// RateLimit - limits each client, reads and writes separately, and answers with the RateLimit-Limit, -Remaining and -Reset headers.
+// Runs after Authenticate so a client is its api key or user, unauthenticated requests are counted by ip
+func RateLimit(limiter RateLimiter) echo.MiddlewareFunc {
+	return func(next echo.HandlerFunc) echo.HandlerFunc {
+		return func(c echo.Context) error {
+			class := ratelimit.Write
+			switch c.Request().Method {
+			case http.MethodGet, http.MethodHead, http.MethodOptions:
+				class = ratelimit.Read
+			}
+
+			decision := limiter.Allow(c.Request().Context(), ratelimit.ClientOf(c.Request().Context(), c.RealIP()), class)
+
+			setLimitHeaders(c, decision)
+			if decision.Allowed {
+				return next(c)
+			}
+
+			c.Response().Header().Set("Retry-After", strconv.Itoa(ceilSeconds(decision.RetryAfter)))
+			if decision.QuotaExceeded {
+				return apperror.RateLimited("quota_exceeded", "daily request quota is used up, it resets at midnight UTC")
+			}
+
+			return apperror.RateLimited("rate_limited", "too many "+string(class)+" requests, retry after the Retry-After delay")
+		}
+	}
+}
+
+func setLimitHeaders(c echo.Context, decision ratelimit.Decision) {
+	if decision.Limit > 0 {
+		header := c.Response().Header()
+		header.Set("RateLimit-Limit", strconv.Itoa(decision.Limit))
+		header.Set("RateLimit-Remaining", strconv.Itoa(decision.Remaining))
+		header.Set("RateLimit-Reset", strconv.Itoa(ceilSeconds(decision.Reset)))
+	}
+}
+
+func ceilSeconds(d time.Duration) int {
+	return int(math.Ceil(d.Seconds()))
+}
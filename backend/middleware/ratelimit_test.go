@@ -0,0 +1,144 @@
+package middleware
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/labstack/echo/v4"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap/zaptest"
+
+	auth_domain "skyhawk/backend/auth/domain"
+	"skyhawk/backend/ratelimit"
+)
+
+type fakeLimiter struct {
+	decision ratelimit.Decision
+	client   string
+	class    ratelimit.Class
+}
+
+func (f *fakeLimiter) Allow(ctx context.Context, client string, class ratelimit.Class) ratelimit.Decision {
+	f.client, f.class = client, class
+	return f.decision
+}
+
+func TestRateLimit(t *testing.T) {
+	serve := func(t *testing.T, limiter *fakeLimiter, method string) (*httptest.ResponseRecorder, Problem) {
+		e := echo.New()
+		e.HTTPErrorHandler = ErrorHandler(zaptest.NewLogger(t))
+		e.IPExtractor = ClientIP(nil)
+		e.Use(RateLimit(limiter))
+		e.Any("/api/v1/games/log", func(c echo.Context) error {
+			return c.NoContent(http.StatusOK)
+		})
+
+		req := httptest.NewRequest(method, "/api/v1/games/log", nil)
+		req.RemoteAddr = "10.0.0.1:51000"
+		rec := httptest.NewRecorder()
+		e.ServeHTTP(rec, req)
+
+		var problem Problem
+		if rec.Code != http.StatusOK {
+			require.NoError(t, json.Unmarshal(rec.Body.Bytes(), &problem))
+		}
+
+		return rec, problem
+	}
+
+	t.Run("allowed", func(t *testing.T) {
+		// Setup
+		limiter := &fakeLimiter{decision: ratelimit.Decision{Result: ratelimit.Result{Allowed: true, Limit: 40, Remaining: 39, Reset: 50 * time.Millisecond}}}
+
+		// Test
+		rec, _ := serve(t, limiter, http.MethodGet)
+
+		// Assert
+		assert.Equal(t, http.StatusOK, rec.Code)
+		assert.Equal(t, "40", rec.Header().Get("RateLimit-Limit"))
+		assert.Equal(t, "39", rec.Header().Get("RateLimit-Remaining"))
+		assert.Equal(t, "1", rec.Header().Get("RateLimit-Reset"))
+		assert.Equal(t, "ip:10.0.0.1", limiter.client)
+		assert.Equal(t, ratelimit.Read, limiter.class)
+	})
+
+	t.Run("rate limited", func(t *testing.T) {
+		// Setup
+		limiter := &fakeLimiter{decision: ratelimit.Decision{Result: ratelimit.Result{Limit: 10, RetryAfter: 1500 * time.Millisecond, Reset: 2 * time.Second}}}
+
+		// Test
+		rec, problem := serve(t, limiter, http.MethodPost)
+
+		// Assert
+		assert.Equal(t, http.StatusTooManyRequests, rec.Code)
+		assert.Equal(t, "rate_limited", problem.Code)
+		assert.Equal(t, "2", rec.Header().Get("Retry-After"))
+		assert.Equal(t, "0", rec.Header().Get("RateLimit-Remaining"))
+		assert.Equal(t, ratelimit.Write, limiter.class)
+	})
+
+	t.Run("quota exceeded", func(t *testing.T) {
+		// Setup
+		limiter := &fakeLimiter{decision: ratelimit.Decision{QuotaExceeded: true, Result: ratelimit.Result{RetryAfter: time.Hour}}}
+
+		// Test
+		rec, problem := serve(t, limiter, http.MethodGet)
+
+		// Assert
+		assert.Equal(t, http.StatusTooManyRequests, rec.Code)
+		assert.Equal(t, "quota_exceeded", problem.Code)
+		assert.Equal(t, "3600", rec.Header().Get("Retry-After"))
+	})
+}
+
+// countingAuthenticator - counts the credentials it was asked to check
+type countingAuthenticator struct {
+	fakeAuthenticator
+	calls int
+}
+
+func (c *countingAuthenticator) Authenticate(ctx context.Context, credential string) (auth_domain.Principal, error) {
+	c.calls++
+	return c.fakeAuthenticator.Authenticate(ctx, credential)
+}
+
+func TestRateLimitIP(t *testing.T) {
+	// Setup - an ip guessing keys and forging a new forwarded address with every guess, its bucket holds two requests
+	authenticator := &countingAuthenticator{fakeAuthenticator: fakeAuthenticator{}}
+	limiter := ratelimit.NewLimiter(ratelimit.NewMemoryBuckets(), ratelimit.NewMemoryCounters(), ratelimit.Options{IP: ratelimit.Limit{Rate: 0.01, Burst: 2}}, zaptest.NewLogger(t))
+	e := echo.New()
+	e.HTTPErrorHandler = ErrorHandler(zaptest.NewLogger(t))
+	e.IPExtractor = ClientIP(nil)
+	group := e.Group("/api/v1", RateLimitIP(limiter), Authenticate(authenticator), RateLimit(limiter))
+	group.GET("/games/:id", func(c echo.Context) error {
+		return c.NoContent(http.StatusOK)
+	})
+
+	// Test
+	var codes []int
+	var last *httptest.ResponseRecorder
+	for i := 0; i < 3; i++ {
+		req := httptest.NewRequest(http.MethodGet, "/api/v1/games/g1", nil)
+		req.RemoteAddr = "203.0.113.7:51000"
+		req.Header.Set(echo.HeaderXForwardedFor, fmt.Sprintf("198.51.100.%d", i))
+		req.Header.Set(echo.HeaderXRealIP, fmt.Sprintf("198.51.100.%d", i))
+		req.Header.Set(APIKeyHeader, "guess")
+		last = httptest.NewRecorder()
+		e.ServeHTTP(last, req)
+		codes = append(codes, last.Code)
+	}
+
+	// Assert
+	assert.Equal(t, []int{http.StatusUnauthorized, http.StatusUnauthorized, http.StatusTooManyRequests}, codes)
+	assert.Equal(t, 2, authenticator.calls, "the throttled guess is never checked, forged addresses share the peer's bucket")
+	var problem Problem
+	require.NoError(t, json.Unmarshal(last.Body.Bytes(), &problem))
+	assert.Equal(t, "rate_limited", problem.Code)
+	assert.NotEmpty(t, last.Header().Get("Retry-After"))
+}
@@ -0,0 +1,34 @@
+package middleware
+
+import (
+	"fmt"
+	"runtime/debug"
+
+	"github.com/labstack/echo/v4"
+	"go.uber.org/zap"
+
+	"skyhawk/backend/apperror"
+	"skyhawk/backend/logging"
+)
+
+// Recover - turns a panicking handler into a 500 problem response and logs the stack, the server keeps serving
+func Recover(logger *zap.Logger) echo.MiddlewareFunc {
+	return func(next echo.HandlerFunc) echo.HandlerFunc {
+		return func(c echo.Context) (err error) {
+			defer func() {
+				recovered := recover()
+				if recovered == nil {
+					return
+				}
+
+				logging.From(c.Request().Context(), logger).Error("handler panicked",
+					zap.Any("panic", recovered),
+					zap.String("path", c.Request().URL.Path),
+					zap.ByteString("stack", debug.Stack()))
+				err = apperror.Internal(fmt.Errorf("panic: %v", recovered))
+			}()
+
+			return next(c)
+		}
+	}
+}
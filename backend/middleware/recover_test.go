@@ -0,0 +1,37 @@
+package middleware
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/labstack/echo/v4"
+	"github.com/stretchr/testify/assert"
+	"go.uber.org/zap/zaptest"
+)
+
+func TestRecover(t *testing.T) {
+	// Setup
+	logger := zaptest.NewLogger(t)
+	e := echo.New()
+	e.HTTPErrorHandler = ErrorHandler(logger)
+	e.Use(Recover(logger))
+	e.GET("/panic", func(c echo.Context) error {
+		panic("boom")
+	})
+	e.GET("/ok", func(c echo.Context) error {
+		return c.NoContent(http.StatusNoContent)
+	})
+
+	// Test
+	panicked := httptest.NewRecorder()
+	e.ServeHTTP(panicked, httptest.NewRequest(http.MethodGet, "/panic", nil))
+	ok := httptest.NewRecorder()
+	e.ServeHTTP(ok, httptest.NewRequest(http.MethodGet, "/ok", nil))
+
+	// Assert
+	assert.Equal(t, http.StatusInternalServerError, panicked.Code)
+	assert.Contains(t, panicked.Body.String(), `"code":"internal_error"`)
+	assert.NotContains(t, panicked.Body.String(), "boom", "panic values are not leaked to clients")
+	assert.Equal(t, http.StatusNoContent, ok.Code, "the server keeps serving")
+}
@@ -0,0 +1,23 @@
+package middleware
+
+import (
+	"github.com/labstack/echo/v4"
+
+	"skyhawk/backend/apperror"
+)
+
+// Starting - answers 503 starting until started is closed, so the server listens for its probes
+// while it migrates the DB without serving the api from a schema that is behind
+func Starting(started <-chan struct{}) echo.MiddlewareFunc {
+	return func(next echo.HandlerFunc) echo.HandlerFunc {
+		return func(c echo.Context) error {
+			select {
+			case <-started:
+				return next(c)
+			default:
+				c.Response().Header().Set("Retry-After", "1")
+				return apperror.Unavailable("starting", "the server is starting, retry shortly", nil)
+			}
+		}
+	}
+}
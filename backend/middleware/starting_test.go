@@ -0,0 +1,41 @@
+package middleware
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/labstack/echo/v4"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap/zaptest"
+)
+
+func TestStarting(t *testing.T) {
+	// Setup
+	started := make(chan struct{})
+	e := echo.New()
+	e.HTTPErrorHandler = ErrorHandler(zaptest.NewLogger(t))
+	e.GET("/api/v1/games/:id", func(c echo.Context) error {
+		return c.NoContent(http.StatusOK)
+	}, Starting(started))
+	serve := func() *httptest.ResponseRecorder {
+		rec := httptest.NewRecorder()
+		e.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/api/v1/games/g1", nil))
+		return rec
+	}
+
+	// Test
+	migrating := serve()
+	close(started)
+	migrated := serve()
+
+	// Assert
+	assert.Equal(t, http.StatusServiceUnavailable, migrating.Code)
+	assert.Equal(t, "1", migrating.Header().Get("Retry-After"))
+	var problem Problem
+	require.NoError(t, json.Unmarshal(migrating.Body.Bytes(), &problem))
+	assert.Equal(t, "starting", problem.Code)
+	assert.Equal(t, http.StatusOK, migrated.Code)
+}
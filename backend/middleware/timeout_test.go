@@ -0,0 +1,76 @@
+package middleware
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/labstack/echo/v4"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap/zaptest"
+)
+
+func TestTimeout(t *testing.T) {
+	serve := func(t *testing.T, timeout time.Duration, handler echo.HandlerFunc) *httptest.ResponseRecorder {
+		e := echo.New()
+		e.HTTPErrorHandler = ErrorHandler(zaptest.NewLogger(t))
+		e.GET("/api/v1/games/:id", handler, Timeout(timeout))
+
+		rec := httptest.NewRecorder()
+		e.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/api/v1/games/g1", nil))
+
+		return rec
+	}
+
+	t.Run("handler context carries the deadline", func(t *testing.T) {
+		// Setup
+		var deadline time.Time
+		var ok bool
+		before := time.Now()
+
+		// Test
+		rec := serve(t, time.Minute, func(c echo.Context) error {
+			deadline, ok = c.Request().Context().Deadline()
+			return c.NoContent(http.StatusOK)
+		})
+
+		// Assert
+		assert.Equal(t, http.StatusOK, rec.Code)
+		require.True(t, ok)
+		assert.WithinDuration(t, before.Add(time.Minute), deadline, time.Second)
+	})
+
+	t.Run("spent deadline is a 504 problem", func(t *testing.T) {
+		// Test - the handler returns what a repository returns once the budget is spent
+		rec := serve(t, 10*time.Millisecond, func(c echo.Context) error {
+			ctx := c.Request().Context()
+			<-ctx.Done()
+			return ctx.Err()
+		})
+
+		// Assert
+		assert.Equal(t, http.StatusGatewayTimeout, rec.Code)
+		assert.Equal(t, "application/problem+json", rec.Header().Get(echo.HeaderContentType))
+		var problem Problem
+		require.NoError(t, json.Unmarshal(rec.Body.Bytes(), &problem))
+		assert.Equal(t, "timeout", problem.Code)
+	})
+
+	t.Run("zero timeout leaves the context alone", func(t *testing.T) {
+		// Setup
+		var ok bool
+
+		// Test
+		rec := serve(t, 0, func(c echo.Context) error {
+			_, ok = c.Request().Context().Deadline()
+			return c.NoContent(http.StatusOK)
+		})
+
+		// Assert
+		assert.Equal(t, http.StatusOK, rec.Code)
+		assert.False(t, ok)
+	})
+}
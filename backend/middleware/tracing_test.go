@@ -0,0 +1,60 @@
+package middleware
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/labstack/echo/v4"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/propagation"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/sdk/trace/tracetest"
+	"go.opentelemetry.io/otel/trace"
+	"go.uber.org/zap/zaptest"
+
+	"skyhawk/backend/apperror"
+)
+
+func TestTracing(t *testing.T) {
+	// Setup
+	recorder := tracetest.NewSpanRecorder()
+	previous, previousPropagator := otel.GetTracerProvider(), otel.GetTextMapPropagator()
+	otel.SetTracerProvider(sdktrace.NewTracerProvider(sdktrace.WithSpanProcessor(recorder)))
+	otel.SetTextMapPropagator(propagation.TraceContext{})
+	defer func() {
+		otel.SetTracerProvider(previous)
+		otel.SetTextMapPropagator(previousPropagator)
+	}()
+
+	var handlerTrace trace.SpanContext
+	e := echo.New()
+	e.HTTPErrorHandler = ErrorHandler(zaptest.NewLogger(t))
+	e.Use(Tracing())
+	e.POST("/games/log", func(c echo.Context) error {
+		handlerTrace = trace.SpanContextFromContext(c.Request().Context())
+		return apperror.Unavailable("db_unavailable", "database unavailable", nil)
+	})
+
+	const traceID = "4bf92f3577b34da6a3ce929d0e0e4736"
+	req := httptest.NewRequest(http.MethodPost, "/games/log", nil)
+	req.Header.Set("traceparent", "00-"+traceID+"-00f067aa0ba902b7-01")
+	rec := httptest.NewRecorder()
+
+	// Test
+	e.ServeHTTP(rec, req)
+
+	// Assert
+	spans := recorder.Ended()
+	require.Len(t, spans, 1)
+	span := spans[0]
+	assert.Equal(t, "POST /games/log", span.Name())
+	assert.Equal(t, traceID, span.SpanContext().TraceID().String(), "the incoming trace is continued")
+	assert.Equal(t, "00f067aa0ba902b7", span.Parent().SpanID().String())
+	assert.Equal(t, codes.Error, span.Status().Code)
+	assert.Equal(t, span.SpanContext().SpanID(), handlerTrace.SpanID(), "handlers see the request span")
+	assert.Contains(t, rec.Header().Get("traceparent"), traceID)
+}
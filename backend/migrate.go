@@ -0,0 +1,120 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"flag"
+	"fmt"
+	"os"
+	"strconv"
+	"text/tabwriter"
+	"time"
+
+	pressly "github.com/pressly/goose/v3"
+	"go.uber.org/zap"
+
+	"skyhawk/backend/config"
+	"skyhawk/backend/goose"
+)
+
+// runMigrate - the "migrate" subcommand: status, up, up-to, down, redo, version or create.
+// It connects without starting the app, which would migrate on its own
+func runMigrate(cfg config.Config, logger *zap.Logger, args []string) error {
+	if len(args) == 0 {
+		return errors.New("migrate: expected status, up, up-to, down, redo, version or create")
+	}
+
+	flags := flag.NewFlagSet("migrate "+args[0], flag.ContinueOnError)
+	dir := flags.String("dir", cfg.Migrations.Dir, "directory create adds the migration to")
+	if err := flags.Parse(args[1:]); err != nil {
+		return err
+	}
+
+	//create only writes the source file, the migration is embedded by the next build
+	if args[0] == "create" {
+		if flags.NArg() != 1 {
+			return errors.New("migrate create: expected the name of the migration")
+		}
+		path, err := goose.Create(*dir, flags.Arg(0))
+		if err != nil {
+			return err
+		}
+		fmt.Println("created", path)
+		return nil
+	}
+
+	logger.Info("connecting to mysql", zap.String("dsn", goose.RedactedDSN(cfg.DB)))
+	DB, err := goose.MustNewDB(cfg.DB)
+	if err != nil {
+		return err
+	}
+	defer DB.Close()
+
+	migrations, err := goose.New(DB, logger, cfg.Migrations.LockTimeout)
+	if err != nil {
+		return err
+	}
+
+	ctx := context.Background()
+	switch args[0] {
+	case "status":
+		statuses, err := migrations.Status(ctx)
+		if err != nil {
+			return err
+		}
+		out := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+		fmt.Fprintln(out, "VERSION\tSTATE\tAPPLIED AT\tFILE")
+		for _, status := range statuses {
+			appliedAt := "-"
+			if !status.AppliedAt.IsZero() {
+				appliedAt = status.AppliedAt.Format(time.RFC3339)
+			}
+			fmt.Fprintf(out, "%d\t%s\t%s\t%s\n", status.Source.Version, status.State, appliedAt, status.Source.Path)
+		}
+		return out.Flush()
+	case "version":
+		current, expected, err := migrations.Versions(ctx)
+		if err != nil {
+			return err
+		}
+		fmt.Printf("current %d, expected %d\n", current, expected)
+		return nil
+	case "up":
+		results, err := migrations.Up(ctx)
+		printResults(results)
+		return err
+	case "up-to":
+		if flags.NArg() != 1 {
+			return errors.New("migrate up-to: expected the version to migrate to")
+		}
+		version, err := strconv.ParseInt(flags.Arg(0), 10, 64)
+		if err != nil {
+			return fmt.Errorf("migrate up-to: version %q is not a number", flags.Arg(0))
+		}
+		results, err := migrations.UpTo(ctx, version)
+		printResults(results)
+		return err
+	case "down":
+		result, err := migrations.Down(ctx)
+		if result != nil {
+			printResults([]*pressly.MigrationResult{result})
+		}
+		return err
+	case "redo":
+		results, err := migrations.Redo(ctx)
+		printResults(results)
+		return err
+	default:
+		return fmt.Errorf("migrate: unknown command %q, expected status, up, up-to, down, redo, version or create", args[0])
+	}
+}
+
+func printResults(results []*pressly.MigrationResult) {
+	if len(results) == 0 {
+		fmt.Println("no migrations to apply")
+		return
+	}
+	for _, result := range results {
+		fmt.Println(result)
+	}
+}
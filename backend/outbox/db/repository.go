@@ -0,0 +1,161 @@
+package db
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/jmoiron/sqlx"
+	"go.uber.org/zap"
+
+	"skyhawk/backend/apperror"
+	"skyhawk/backend/outbox/domain"
+)
+
+const relayLock = "skyhawk_outbox_relay"
+
+type Repository interface {
+	Append(ctx context.Context, tx *sql.Tx, events []domain.Event) error
+	Pending(ctx context.Context, limit int) ([]domain.Event, error)
+	MarkPublished(ctx context.Context, seqs []int64, at time.Time) error
+	Purge(ctx context.Context, before time.Time, limit int) (int64, error)
+	Lock(ctx context.Context) (domain.Lease, error)
+}
+
+type Repo struct {
+	db     *sqlx.DB
+	logger *zap.Logger
+}
+
+func NewRepo(db *sqlx.DB, logger *zap.Logger) Repository {
+
+	return &Repo{db: db, logger: logger}
+}
+
+// Append - records events inside tx, they become visible to the relay when tx commits
+func (r *Repo) Append(ctx context.Context, tx *sql.Tx, events []domain.Event) error {
+	if len(events) == 0 {
+		return nil
+	}
+
+	placeHolders := make([]string, 0, len(events))
+	values := make([]interface{}, 0, len(events)*5)
+	for _, event := range events {
+		placeHolders = append(placeHolders, "(?, ?, ?, ?, ?)")
+		values = append(values, event.ID, event.Type, event.Key, []byte(event.Payload), event.OccurredAt)
+	}
+
+	q := fmt.Sprintf("INSERT INTO outbox_events (id, type, event_key, payload, occurred_at) VALUES %s", strings.Join(placeHolders, ","))
+	if _, err := tx.ExecContext(ctx, q, values...); err != nil {
+		r.logger.Error("failed inserting outbox events", zap.Error(err))
+		return apperror.FromDB(err, nil)
+	}
+
+	return nil
+}
+
+// Pending - the oldest unpublished events, in seq order
+func (r *Repo) Pending(ctx context.Context, limit int) ([]domain.Event, error) {
+	rows, err := r.db.QueryContext(ctx, "SELECT seq, id, type, event_key, payload, occurred_at FROM outbox_events WHERE published_at IS NULL ORDER BY seq LIMIT ?", limit)
+	if err != nil {
+		return nil, apperror.FromDB(err, nil)
+	}
+	defer rows.Close()
+
+	var events []domain.Event
+	for rows.Next() {
+		var event domain.Event
+		var payload []byte
+		var occurredAt string
+		if err = rows.Scan(&event.Seq, &event.ID, &event.Type, &event.Key, &payload, &occurredAt); err != nil {
+			return nil, apperror.FromDB(err, nil)
+		}
+		event.Payload = payload
+		if event.OccurredAt, err = time.Parse(time.DateTime, occurredAt); err != nil {
+			return nil, apperror.Internal(err)
+		}
+		events = append(events, event)
+	}
+	if err = rows.Err(); err != nil {
+		return nil, apperror.FromDB(err, nil)
+	}
+
+	return events, nil
+}
+
+func (r *Repo) MarkPublished(ctx context.Context, seqs []int64, at time.Time) error {
+	if len(seqs) == 0 {
+		return nil
+	}
+
+	placeHolders := make([]string, len(seqs))
+	args := []interface{}{at}
+	for i, seq := range seqs {
+		placeHolders[i] = "?"
+		args = append(args, seq)
+	}
+
+	_, err := r.db.ExecContext(ctx, fmt.Sprintf("UPDATE outbox_events SET published_at = ? WHERE seq IN (%s)", strings.Join(placeHolders, ",")), args...)
+
+	return apperror.FromDB(err, nil)
+}
+
+// Purge - deletes up to limit events published before before, returning how many went
+func (r *Repo) Purge(ctx context.Context, before time.Time, limit int) (int64, error) {
+	res, err := r.db.ExecContext(ctx, "DELETE FROM outbox_events WHERE published_at < ? ORDER BY published_at LIMIT ?", before, limit)
+	if err != nil {
+		return 0, apperror.FromDB(err, nil)
+	}
+
+	purged, err := res.RowsAffected()
+	if err != nil {
+		return 0, apperror.FromDB(err, nil)
+	}
+
+	return purged, nil
+}
+
+// Lock - takes the relay lock on a connection of its own, a nil lease means another relay holds it
+func (r *Repo) Lock(ctx context.Context) (domain.Lease, error) {
+	conn, err := r.db.Conn(ctx)
+	if err != nil {
+		return nil, apperror.FromDB(err, nil)
+	}
+
+	var acquired sql.NullInt64
+	if err = conn.QueryRowContext(ctx, "SELECT GET_LOCK(?, 0)", relayLock).Scan(&acquired); err != nil {
+		conn.Close()
+		return nil, apperror.FromDB(err, nil)
+	}
+	if acquired.Int64 != 1 {
+		conn.Close()
+		return nil, nil
+	}
+
+	return &lease{conn: conn, logger: r.logger}, nil
+}
+
+// lease - a named MySQL lock, it lives as long as the connection that took it
+type lease struct {
+	conn   *sql.Conn
+	logger *zap.Logger
+}
+
+func (l *lease) Held(ctx context.Context) bool {
+	var held sql.NullInt64
+	if err := l.conn.QueryRowContext(ctx, "SELECT IS_USED_LOCK(?) = CONNECTION_ID()", relayLock).Scan(&held); err != nil {
+		l.logger.Warn("failed checking outbox relay lock", zap.Error(err))
+		return false
+	}
+
+	return held.Int64 == 1
+}
+
+func (l *lease) Release() {
+	if _, err := l.conn.ExecContext(context.Background(), "SELECT RELEASE_LOCK(?)", relayLock); err != nil {
+		l.logger.Warn("failed releasing outbox relay lock", zap.Error(err))
+	}
+	l.conn.Close()
+}
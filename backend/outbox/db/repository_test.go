@@ -0,0 +1,112 @@
+package db
+
+import (
+	"context"
+	"encoding/json"
+	"testing"
+	"time"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/jmoiron/sqlx"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap/zaptest"
+
+	"skyhawk/backend/outbox/domain"
+)
+
+func TestRepo_Append(t *testing.T) {
+	// Setup
+	db, dbMock := createMockDB(t)
+	repo := NewRepo(db, zaptest.NewLogger(t))
+	at := time.Date(2024, 11, 2, 19, 30, 0, 0, time.UTC)
+	events := []domain.Event{
+		{ID: "e1", Type: domain.TeamCreated, Key: "t1", OccurredAt: at, Payload: json.RawMessage(`{"id":"t1"}`)},
+		{ID: "e2", Type: domain.GameLogged, Key: "g1", OccurredAt: at, Payload: json.RawMessage(`{"id":"g1"}`)},
+	}
+
+	dbMock.ExpectBegin()
+	dbMock.ExpectExec("INSERT INTO outbox_events \\(id, type, event_key, payload, occurred_at\\) VALUES \\(\\?, \\?, \\?, \\?, \\?\\),\\(\\?, \\?, \\?, \\?, \\?\\)").
+		WithArgs("e1", domain.TeamCreated, "t1", []byte(`{"id":"t1"}`), at, "e2", domain.GameLogged, "g1", []byte(`{"id":"g1"}`), at).
+		WillReturnResult(sqlmock.NewResult(2, 2))
+	tx, err := db.Begin()
+	require.NoError(t, err)
+
+	// Test
+	err = repo.Append(context.Background(), tx, events)
+
+	// Assert
+	assert.NoError(t, err)
+	assert.NoError(t, dbMock.ExpectationsWereMet())
+}
+
+func TestRepo_Pending(t *testing.T) {
+	// Setup
+	db, dbMock := createMockDB(t)
+	repo := NewRepo(db, zaptest.NewLogger(t))
+
+	rows := sqlmock.NewRows([]string{"seq", "id", "type", "event_key", "payload", "occurred_at"}).
+		AddRow(3, "e3", "GameLogged", "g1", []byte(`{"id":"g1"}`), "2024-11-02 19:30:00").
+		AddRow(4, "e4", "GameCorrected", "g1", []byte(`{"id":"g1"}`), "2024-11-02 21:00:00")
+	dbMock.ExpectQuery("SELECT seq, id, type, event_key, payload, occurred_at FROM outbox_events WHERE published_at IS NULL ORDER BY seq LIMIT \\?").
+		WithArgs(100).
+		WillReturnRows(rows)
+
+	// Test
+	events, err := repo.Pending(context.Background(), 100)
+
+	// Assert
+	require.NoError(t, err)
+	require.Len(t, events, 2)
+	assert.Equal(t, int64(3), events[0].Seq)
+	assert.Equal(t, domain.GameCorrected, events[1].Type)
+	assert.Equal(t, time.Date(2024, 11, 2, 21, 0, 0, 0, time.UTC), events[1].OccurredAt)
+	assert.JSONEq(t, `{"id":"g1"}`, string(events[0].Payload))
+}
+
+func TestRepo_Lock(t *testing.T) {
+	t.Run("acquired", func(t *testing.T) {
+		// Setup
+		db, dbMock := createMockDB(t)
+		repo := NewRepo(db, zaptest.NewLogger(t))
+		dbMock.ExpectQuery("SELECT GET_LOCK\\(\\?, 0\\)").WithArgs(relayLock).WillReturnRows(sqlmock.NewRows([]string{"lock"}).AddRow(1))
+		dbMock.ExpectQuery("SELECT IS_USED_LOCK\\(\\?\\) = CONNECTION_ID\\(\\)").WithArgs(relayLock).WillReturnRows(sqlmock.NewRows([]string{"held"}).AddRow(1))
+		dbMock.ExpectExec("SELECT RELEASE_LOCK\\(\\?\\)").WithArgs(relayLock).WillReturnResult(sqlmock.NewResult(0, 0))
+
+		// Test
+		lease, err := repo.Lock(context.Background())
+		require.NoError(t, err)
+		require.NotNil(t, lease)
+		held := lease.Held(context.Background())
+		lease.Release()
+
+		// Assert
+		assert.True(t, held)
+		assert.NoError(t, dbMock.ExpectationsWereMet())
+	})
+
+	t.Run("held by another relay", func(t *testing.T) {
+		// Setup
+		db, dbMock := createMockDB(t)
+		repo := NewRepo(db, zaptest.NewLogger(t))
+		dbMock.ExpectQuery("SELECT GET_LOCK\\(\\?, 0\\)").WithArgs(relayLock).WillReturnRows(sqlmock.NewRows([]string{"lock"}).AddRow(0))
+
+		// Test
+		lease, err := repo.Lock(context.Background())
+
+		// Assert
+		assert.NoError(t, err)
+		assert.Nil(t, lease)
+	})
+}
+
+// Helper functions for creating mocks
+func createMockDB(t *testing.T) (*sqlx.DB, sqlmock.Sqlmock) {
+	db, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("Failed to create mock DB: %v", err)
+	}
+	t.Cleanup(func() { db.Close() })
+
+	return sqlx.NewDb(db, "sqlmock"), mock
+}
@@ -0,0 +1,67 @@
+package domain
+
+import (
+	"context"
+	"encoding/json"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// Type - what happened, consumers switch on it to decode Payload
+type Type string
+
+const (
+	GameLogged    Type = "GameLogged"
+	GameCorrected Type = "GameCorrected"
+	GameVoided    Type = "GameVoided"
+	TeamCreated   Type = "TeamCreated"
+	PlayerCreated Type = "PlayerCreated"
+)
+
+// Event - a domain event recorded in the transaction of the change it describes.
+// Seq is assigned by the outbox table, events of one Key are published in Seq order
+type Event struct {
+	Seq        int64           `json:"seq"`
+	ID         string          `json:"id"`
+	Type       Type            `json:"type"`
+	Key        string          `json:"key"`
+	OccurredAt time.Time       `json:"occurred_at"`
+	Payload    json.RawMessage `json:"payload"`
+}
+
+// NewEvent - an event of type about key, payload is stored as JSON
+func NewEvent(eventType Type, key string, payload interface{}) (Event, error) {
+	data, err := json.Marshal(payload)
+	if err != nil {
+		return Event{}, err
+	}
+
+	return Event{
+		ID:         uuid.New().String(),
+		Type:       eventType,
+		Key:        key,
+		OccurredAt: time.Now().UTC(),
+		Payload:    data,
+	}, nil
+}
+
+// TeamCreatedData - the payload of TeamCreated
+type TeamCreatedData struct {
+	ID   string `json:"id"`
+	Name string `json:"name"`
+}
+
+// PlayerCreatedData - the payload of PlayerCreated
+type PlayerCreatedData struct {
+	ID     string `json:"id"`
+	Name   string `json:"name"`
+	TeamID string `json:"team_id"`
+}
+
+// Lease - the relay lock, one relay holds it at a time so events leave in the order they were recorded
+type Lease interface {
+	// Held - false once the lock was lost, for example when its connection dropped
+	Held(ctx context.Context) bool
+	Release()
+}
@@ -0,0 +1,239 @@
+package outbox
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"errors"
+	"os"
+	"path/filepath"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/alicebob/miniredis/v2"
+	"github.com/redis/go-redis/v9"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap/zaptest"
+
+	"skyhawk/backend/outbox/domain"
+)
+
+// fakeRepo - an outbox table in memory
+type fakeRepo struct {
+	mu        sync.Mutex
+	events    []domain.Event
+	published map[int64]bool
+}
+
+func newFakeRepo(events ...domain.Event) *fakeRepo {
+	for i := range events {
+		events[i].Seq = int64(i + 1)
+	}
+
+	return &fakeRepo{events: events, published: make(map[int64]bool)}
+}
+
+func (f *fakeRepo) Pending(_ context.Context, limit int) ([]domain.Event, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	var pending []domain.Event
+	for _, event := range f.events {
+		if !f.published[event.Seq] && len(pending) < limit {
+			pending = append(pending, event)
+		}
+	}
+	return pending, nil
+}
+
+func (f *fakeRepo) MarkPublished(_ context.Context, seqs []int64, _ time.Time) error {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	for _, seq := range seqs {
+		f.published[seq] = true
+	}
+	return nil
+}
+
+func (f *fakeRepo) Purge(context.Context, time.Time, int) (int64, error) {
+	return 0, nil
+}
+
+func (f *fakeRepo) Lock(context.Context) (domain.Lease, error) {
+	return fakeLease{}, nil
+}
+
+type fakeLease struct{}
+
+func (fakeLease) Held(context.Context) bool { return true }
+func (fakeLease) Release()                  {}
+
+// flakyPublisher - fails every publish of the event with id failID
+type flakyPublisher struct {
+	*MemoryPublisher
+	failID string
+}
+
+func (p *flakyPublisher) Publish(ctx context.Context, event domain.Event) error {
+	if event.ID == p.failID {
+		return errors.New("broker unavailable")
+	}
+	return p.MemoryPublisher.Publish(ctx, event)
+}
+
+func gameEvents(t *testing.T) []domain.Event {
+	var events []domain.Event
+	for _, key := range []string{"g1", "g2", "g1", "g1", "g2"} {
+		event, err := domain.NewEvent(domain.GameLogged, key, map[string]string{"game_id": key})
+		require.NoError(t, err)
+		events = append(events, event)
+	}
+	return events
+}
+
+func seqs(events []domain.Event) []int64 {
+	out := make([]int64, len(events))
+	for i, event := range events {
+		out[i] = event.Seq
+	}
+	return out
+}
+
+func TestRelay_RelayOnce(t *testing.T) {
+	t.Run("publishes in seq order", func(t *testing.T) {
+		// Setup
+		repo := newFakeRepo(gameEvents(t)...)
+		publisher := NewMemoryPublisher()
+		options := DefaultRelayOptions()
+		options.BatchSize = 2
+		relay := NewRelay(repo, publisher, options, zaptest.NewLogger(t))
+
+		// Test
+		require.NoError(t, relay.drain(context.Background()))
+
+		// Assert
+		assert.Equal(t, []int64{1, 2, 3, 4, 5}, seqs(publisher.Events()))
+		assert.Len(t, repo.published, 5)
+	})
+
+	t.Run("a failed publish holds back every later event", func(t *testing.T) {
+		// Setup
+		events := gameEvents(t)
+		repo := newFakeRepo(events...)
+		publisher := &flakyPublisher{MemoryPublisher: NewMemoryPublisher(), failID: events[2].ID}
+		relay := NewRelay(repo, publisher, DefaultRelayOptions(), zaptest.NewLogger(t))
+
+		// Test
+		relayed, err := relay.RelayOnce(context.Background())
+		require.Error(t, err)
+		publisher.failID = ""
+		_, retryErr := relay.RelayOnce(context.Background())
+
+		// Assert
+		assert.Equal(t, 2, relayed)
+		require.NoError(t, retryErr)
+		assert.Equal(t, []int64{1, 2, 3, 4, 5}, seqs(publisher.Events()))
+	})
+
+	t.Run("unmarked events are published again", func(t *testing.T) {
+		// Setup
+		repo := newFakeRepo(gameEvents(t)[:1]...)
+		publisher := NewMemoryPublisher()
+		relay := NewRelay(repo, publisher, DefaultRelayOptions(), zaptest.NewLogger(t))
+
+		// Test
+		_, err := relay.RelayOnce(context.Background())
+		require.NoError(t, err)
+		repo.published = map[int64]bool{}
+		_, err = relay.RelayOnce(context.Background())
+		require.NoError(t, err)
+
+		// Assert
+		published := publisher.Events()
+		require.Len(t, published, 2)
+		assert.Equal(t, published[0].ID, published[1].ID, "consumers dedupe on the event id")
+	})
+}
+
+func TestRelay_Run(t *testing.T) {
+	// Setup
+	repo := newFakeRepo(gameEvents(t)...)
+	publisher := NewMemoryPublisher()
+	received := publisher.Subscribe(5)
+	options := DefaultRelayOptions()
+	options.PollInterval = time.Millisecond
+	relay := NewRelay(repo, publisher, options, zaptest.NewLogger(t))
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	// Test
+	go relay.Run(ctx)
+
+	var keys []string
+	for i := 0; i < 5; i++ {
+		select {
+		case event := <-received:
+			keys = append(keys, event.Key)
+		case <-time.After(time.Second):
+			t.Fatal("timed out waiting for events")
+		}
+	}
+
+	// Assert
+	assert.Equal(t, []string{"g1", "g2", "g1", "g1", "g2"}, keys)
+}
+
+func TestRedisPublisher_Publish(t *testing.T) {
+	// Setup
+	mr := miniredis.RunT(t)
+	client := redis.NewClient(&redis.Options{Addr: mr.Addr()})
+	publisher := NewRedisPublisher(client, "skyhawk:events", 1000)
+	event, err := domain.NewEvent(domain.TeamCreated, "t1", domain.TeamCreatedData{ID: "t1", Name: "Lakers"})
+	require.NoError(t, err)
+	event.Seq = 7
+
+	// Test
+	require.NoError(t, publisher.Publish(context.Background(), event))
+
+	// Assert
+	entries, err := client.XRange(context.Background(), "skyhawk:events", "-", "+").Result()
+	require.NoError(t, err)
+	require.Len(t, entries, 1)
+	assert.Equal(t, event.ID, entries[0].Values["id"])
+	assert.Equal(t, "7", entries[0].Values["seq"])
+	assert.Equal(t, "TeamCreated", entries[0].Values["type"])
+	assert.Equal(t, "t1", entries[0].Values["key"])
+	assert.JSONEq(t, `{"id":"t1","name":"Lakers"}`, entries[0].Values["payload"].(string))
+}
+
+func TestFilePublisher_Publish(t *testing.T) {
+	// Setup
+	path := filepath.Join(t.TempDir(), "outbox.ndjson")
+	publisher, err := NewFilePublisher(path)
+	require.NoError(t, err)
+	events := gameEvents(t)[:2]
+
+	// Test
+	for _, event := range events {
+		require.NoError(t, publisher.Publish(context.Background(), event))
+	}
+	require.NoError(t, publisher.Close())
+
+	// Assert
+	file, err := os.Open(path)
+	require.NoError(t, err)
+	defer file.Close()
+
+	var ids []string
+	scanner := bufio.NewScanner(file)
+	for scanner.Scan() {
+		var event domain.Event
+		require.NoError(t, json.Unmarshal(scanner.Bytes(), &event))
+		ids = append(ids, event.ID)
+	}
+	require.NoError(t, scanner.Err())
+	assert.Equal(t, []string{events[0].ID, events[1].ID}, ids)
+}
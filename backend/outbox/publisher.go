@@ -0,0 +1,147 @@
+package outbox
+
+import (
+	"context"
+	"encoding/json"
+	"os"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/redis/go-redis/v9"
+
+	"skyhawk/backend/outbox/domain"
+)
+
+// Publisher - where the relay sends recorded events. Publish returns once the event is durably handed over,
+// an error makes the relay retry it and everything recorded after it
+type Publisher interface {
+	Publish(ctx context.Context, event domain.Event) error
+	Close() error
+}
+
+// RedisPublisher - appends events to a redis stream, consumers read it with XREADGROUP
+type RedisPublisher struct {
+	client *redis.Client
+	stream string
+	maxLen int64
+}
+
+// NewRedisPublisher - maxLen caps the stream length approximately, 0 keeps every entry
+func NewRedisPublisher(client *redis.Client, stream string, maxLen int64) *RedisPublisher {
+	return &RedisPublisher{client: client, stream: stream, maxLen: maxLen}
+}
+
+func (p *RedisPublisher) Publish(ctx context.Context, event domain.Event) error {
+	return p.client.XAdd(ctx, &redis.XAddArgs{
+		Stream: p.stream,
+		MaxLen: p.maxLen,
+		Approx: p.maxLen > 0,
+		Values: map[string]interface{}{
+			"id":          event.ID,
+			"seq":         strconv.FormatInt(event.Seq, 10),
+			"type":        string(event.Type),
+			"key":         event.Key,
+			"occurred_at": event.OccurredAt.Format(time.RFC3339),
+			"payload":     string(event.Payload),
+		},
+	}).Err()
+}
+
+func (p *RedisPublisher) Close() error {
+	return nil
+}
+
+// FilePublisher - appends every event as one JSON line to a file
+type FilePublisher struct {
+	mu   sync.Mutex
+	file *os.File
+}
+
+func NewFilePublisher(path string) (*FilePublisher, error) {
+	file, err := os.OpenFile(path, os.O_CREATE|os.O_APPEND|os.O_WRONLY, 0o644)
+	if err != nil {
+		return nil, err
+	}
+
+	return &FilePublisher{file: file}, nil
+}
+
+func (p *FilePublisher) Publish(_ context.Context, event domain.Event) error {
+	line, err := json.Marshal(event)
+	if err != nil {
+		return err
+	}
+
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	if _, err = p.file.Write(append(line, '\n')); err != nil {
+		return err
+	}
+
+	// the relay marks the event published next, it has to be on disk by then
+	return p.file.Sync()
+}
+
+func (p *FilePublisher) Close() error {
+	return p.file.Close()
+}
+
+// MemoryPublisher - an in process bus, every subscriber receives every event
+type MemoryPublisher struct {
+	mu          sync.Mutex
+	events      []domain.Event
+	subscribers []chan domain.Event
+}
+
+func NewMemoryPublisher() *MemoryPublisher {
+	return &MemoryPublisher{}
+}
+
+// Subscribe - events published from now on, Publish waits for a subscriber whose buffer is full
+func (p *MemoryPublisher) Subscribe(buffer int) <-chan domain.Event {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	ch := make(chan domain.Event, buffer)
+	p.subscribers = append(p.subscribers, ch)
+
+	return ch
+}
+
+func (p *MemoryPublisher) Publish(ctx context.Context, event domain.Event) error {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	p.events = append(p.events, event)
+	for _, ch := range p.subscribers {
+		select {
+		case ch <- event:
+		case <-ctx.Done():
+			return ctx.Err()
+		}
+	}
+
+	return nil
+}
+
+// Events - everything published so far, in order
+func (p *MemoryPublisher) Events() []domain.Event {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	return append([]domain.Event(nil), p.events...)
+}
+
+func (p *MemoryPublisher) Close() error {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	for _, ch := range p.subscribers {
+		close(ch)
+	}
+	p.subscribers = nil
+
+	return nil
+}
@@ -0,0 +1,147 @@
+package outbox
+
+import (
+	"context"
+	"time"
+
+	"go.uber.org/zap"
+
+	"skyhawk/backend/outbox/domain"
+	"skyhawk/backend/retry"
+)
+
+type Repository interface {
+	Pending(ctx context.Context, limit int) ([]domain.Event, error)
+	MarkPublished(ctx context.Context, seqs []int64, at time.Time) error
+	Purge(ctx context.Context, before time.Time, limit int) (int64, error)
+	Lock(ctx context.Context) (domain.Lease, error)
+}
+
+// RelayOptions - how often the outbox is polled and how long published events are kept
+type RelayOptions struct {
+	// Retry - BaseDelay and MaxDelay space the attempts after a failed publish, MaxAttempts is not used, events are never dropped
+	Retry        retry.Policy
+	PollInterval time.Duration
+	BatchSize    int
+	Retention    time.Duration
+}
+
+func DefaultRelayOptions() RelayOptions {
+	return RelayOptions{
+		Retry:        retry.Policy{BaseDelay: time.Second, MaxDelay: time.Minute},
+		PollInterval: time.Second,
+		BatchSize:    100,
+		Retention:    24 * time.Hour,
+	}
+}
+
+// Relay - publishes recorded events in seq order. Events are marked published only after the publisher took them,
+// so a crash in between publishes them again: delivery is at least once and consumers dedupe on the event id
+type Relay struct {
+	repo      Repository
+	publisher Publisher
+	backoff   *retry.Retrier
+	options   RelayOptions
+	now       func() time.Time
+	logger    *zap.Logger
+}
+
+func NewRelay(repo Repository, publisher Publisher, options RelayOptions, logger *zap.Logger) *Relay {
+	return &Relay{
+		repo:      repo,
+		publisher: publisher,
+		backoff:   retry.New(options.Retry, logger, nil),
+		options:   options,
+		now:       func() time.Time { return time.Now().UTC() },
+		logger:    logger,
+	}
+}
+
+// Run - relays until ctx is done. Only the instance holding the relay lock publishes, the others wait to take over
+func (r *Relay) Run(ctx context.Context) {
+	var lease domain.Lease
+	defer func() {
+		if lease != nil {
+			lease.Release()
+		}
+	}()
+
+	failures := 0
+	for {
+		if lease != nil && !lease.Held(ctx) {
+			r.logger.Warn("lost the outbox relay lock")
+			lease.Release()
+			lease = nil
+		}
+		if lease == nil {
+			var err error
+			if lease, err = r.repo.Lock(ctx); err != nil {
+				r.logger.Warn("failed taking the outbox relay lock", zap.Error(err))
+			}
+		}
+
+		wait := r.options.PollInterval
+		if lease != nil {
+			if err := r.drain(ctx); err != nil {
+				failures++
+				wait = r.backoff.Backoff(failures)
+				r.logger.Warn("failed relaying outbox events", zap.Int("failures", failures), zap.Duration("retry_in", wait), zap.Error(err))
+			} else {
+				failures = 0
+				r.purge(ctx)
+			}
+		}
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-time.After(wait):
+		}
+	}
+}
+
+// drain - relays batches until the outbox is empty
+func (r *Relay) drain(ctx context.Context) error {
+	for {
+		relayed, err := r.RelayOnce(ctx)
+		if err != nil {
+			return err
+		}
+		if relayed < r.options.BatchSize {
+			return nil
+		}
+	}
+}
+
+// RelayOnce - publishes one batch of pending events, stopping at the first failure so none overtakes an earlier one
+func (r *Relay) RelayOnce(ctx context.Context) (int, error) {
+	events, err := r.repo.Pending(ctx, r.options.BatchSize)
+	if err != nil {
+		return 0, err
+	}
+
+	published := make([]int64, 0, len(events))
+	var publishErr error
+	for _, event := range events {
+		if publishErr = r.publisher.Publish(ctx, event); publishErr != nil {
+			break
+		}
+		published = append(published, event.Seq)
+	}
+
+	if err = r.repo.MarkPublished(ctx, published, r.now()); err != nil {
+		return 0, err
+	}
+
+	return len(published), publishErr
+}
+
+func (r *Relay) purge(ctx context.Context) {
+	if r.options.Retention <= 0 {
+		return
+	}
+
+	if _, err := r.repo.Purge(ctx, r.now().Add(-r.options.Retention), r.options.BatchSize); err != nil {
+		r.logger.Warn("failed purging published outbox events", zap.Error(err))
+	}
+}
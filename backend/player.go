@@ -0,0 +1,48 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"flag"
+	"os"
+
+	"go.uber.org/zap"
+
+	audit_domain "skyhawk/backend/audit/domain"
+	"skyhawk/backend/config"
+)
+
+// runPlayer - the "player" subcommand: merge folds a duplicate player into the one it duplicates
+func runPlayer(cfg config.Config, logger *zap.Logger, args []string) error {
+	if len(args) == 0 || args[0] != "merge" {
+		return errors.New("player: expected merge")
+	}
+
+	flags := flag.NewFlagSet("player merge", flag.ContinueOnError)
+	from := flags.String("from", "", "id of the duplicate player, it is deleted")
+	into := flags.String("into", "", "id of the player that keeps the lines")
+	if err := flags.Parse(args[1:]); err != nil {
+		return err
+	}
+	if *from == "" || *into == "" {
+		return errors.New("player merge: -from and -into are required")
+	}
+
+	app, err := newApp(cfg, logger)
+	if err != nil {
+		return err
+	}
+	defer app.Close()
+
+	ctx := audit_domain.WithMeta(context.Background(), audit_domain.Meta{Actor: "cli:player"})
+	res, err := app.service.MergePlayers(ctx, *from, *into)
+	if err != nil {
+		return err
+	}
+
+	encoder := json.NewEncoder(os.Stdout)
+	encoder.SetIndent("", "  ")
+
+	return encoder.Encode(res)
+}
@@ -0,0 +1,73 @@
+package db
+
+import (
+	"context"
+	"database/sql"
+
+	"skyhawk/backend/metrics"
+	"skyhawk/backend/player/domain"
+	"skyhawk/backend/tracing"
+)
+
+// Instrumented - a Repository tracing every method and recording its latency, outcome and cache hits
+type Instrumented struct {
+	next    Repository
+	metrics *metrics.Metrics
+}
+
+func NewInstrumented(next Repository, metrics *metrics.Metrics) Repository {
+	return &Instrumented{next: next, metrics: metrics}
+}
+
+// observe - a span and a latency observation around method, done ends both
+func (i *Instrumented) observe(ctx context.Context, method string) (context.Context, func(err error)) {
+	ctx, span := tracing.Start(ctx, "player_db."+method)
+	ctx, done := i.metrics.Query(ctx, "player", method)
+
+	return ctx, func(err error) {
+		done(err)
+		tracing.End(span, err)
+	}
+}
+
+func (i *Instrumented) SeasonStats(ctx context.Context, id string) (stats domain.PlayerSeasonStats, err error) {
+	ctx, done := i.observe(ctx, "SeasonStats")
+	defer func() { done(err) }()
+
+	return i.next.SeasonStats(ctx, id)
+}
+
+func (i *Instrumented) Save(ctx context.Context, tx *sql.Tx, players []domain.Player) (ids map[string]string, created []domain.Player, err error) {
+	ctx, done := i.observe(ctx, "Save")
+	defer func() { done(err) }()
+
+	return i.next.Save(ctx, tx, players)
+}
+
+func (i *Instrumented) Remember(ctx context.Context, players []domain.Player) (err error) {
+	ctx, done := i.observe(ctx, "Remember")
+	defer func() { done(err) }()
+
+	return i.next.Remember(ctx, players)
+}
+
+func (i *Instrumented) Lock(ctx context.Context, tx *sql.Tx, id string) (player domain.Player, err error) {
+	ctx, done := i.observe(ctx, "Lock")
+	defer func() { done(err) }()
+
+	return i.next.Lock(ctx, tx, id)
+}
+
+func (i *Instrumented) Merge(ctx context.Context, tx *sql.Tx, from, into domain.Player) (moved int, err error) {
+	ctx, done := i.observe(ctx, "Merge")
+	defer func() { done(err) }()
+
+	return i.next.Merge(ctx, tx, from, into)
+}
+
+func (i *Instrumented) Forget(ctx context.Context, player domain.Player) (err error) {
+	ctx, done := i.observe(ctx, "Forget")
+	defer func() { done(err) }()
+
+	return i.next.Forget(ctx, player)
+}
@@ -12,12 +12,18 @@ import (
 	"github.com/redis/go-redis/v9"
 	"go.uber.org/zap"
 
+	"skyhawk/backend/apperror"
+	"skyhawk/backend/logging"
 	"skyhawk/backend/player/domain"
 )
 
 type Repository interface {
-	SeasonStats(id string) (domain.PlayerSeasonStats, error)
-	Save(ctx context.Context, tx *sql.Tx, player []domain.Player) (map[string]string, error)
+	SeasonStats(ctx context.Context, id string) (domain.PlayerSeasonStats, error)
+	Save(ctx context.Context, tx *sql.Tx, player []domain.Player) (map[string]string, []domain.Player, error)
+	Remember(ctx context.Context, players []domain.Player) error
+	Lock(ctx context.Context, tx *sql.Tx, id string) (domain.Player, error)
+	Merge(ctx context.Context, tx *sql.Tx, from, into domain.Player) (int, error)
+	Forget(ctx context.Context, player domain.Player) error
 }
 
 type Repo struct {
@@ -33,22 +39,23 @@ func NewRepo(logger *zap.Logger, db *sqlx.DB, redis *redis.Client) Repository {
 	return &Repo{logger: logger, db: db, redis: redis}
 }
 
-func (r *Repo) Save(ctx context.Context, tx *sql.Tx, players []domain.Player) (map[string]string, error) {
+// Save - the ids of players by name, created are the players inserted by tx.
+// Nothing is cached here, tx may still roll back, the caller remembers the ids once it commits
+func (r *Repo) Save(ctx context.Context, tx *sql.Tx, players []domain.Player) (map[string]string, []domain.Player, error) {
 	// First check Redis for existing players in batch
 	redisPipe := r.redis.Pipeline()
 	redisResults := make(map[string]*redis.StringCmd)
 
 	// Create player keys and check Redis first
 	for i := range players {
-		// Use composite key: "player:{name}:{team_id}"
-		redisKey := fmt.Sprintf("player:%s:%s", players[i].Name, players[i].Team)
+		redisKey := cacheKey(players[i])
 		redisResults[redisKey] = redisPipe.Get(ctx, redisKey)
 	}
 
 	// Execute all Redis gets in one batch
 	_, err := redisPipe.Exec(ctx) // Fixed: removed * before err
 	if err != nil && err != redis.Nil {
-		r.logger.Warn("Redis pipeline error", zap.Error(err))
+		logging.From(ctx, r.logger).Warn("Redis pipeline error", zap.Error(err))
 		// Continue execution even if Redis fails
 	}
 
@@ -57,8 +64,7 @@ func (r *Repo) Save(ctx context.Context, tx *sql.Tx, players []domain.Player) (m
 	playerIdsMap := make(map[string]string, len(players))
 
 	for i := range players {
-		redisKey := fmt.Sprintf("player:%s:%s", players[i].Name, players[i].Team)
-		id, err := redisResults[redisKey].Result()
+		id, err := redisResults[cacheKey(players[i])].Result()
 		if err == nil && id != "" {
 			// Found in Redis
 			players[i].ID = id
@@ -67,21 +73,19 @@ func (r *Repo) Save(ctx context.Context, tx *sql.Tx, players []domain.Player) (m
 			// Not found in Redis, check DB before assigning new ID
 			// Query for this specific player
 			var playerID string
-			err := tx.QueryRow("SELECT id FROM players WHERE name = ? AND team_id = ?",
+			err := tx.QueryRowContext(ctx, "SELECT id FROM players WHERE name = ? AND team_id = ?",
 				players[i].Name, players[i].Team).Scan(&playerID)
 			if err == nil {
 				// Found in DB
 				players[i].ID = playerID
 				playerIdsMap[players[i].Name] = playerID
-				// Update Redis for this found player
-				r.redis.Set(ctx, redisKey, playerID, 24*time.Hour)
 			} else if err == sql.ErrNoRows {
 				// Not found in DB either, assign new ID
 				players[i].ID = uuid.New().String()
 				missingPlayers = append(missingPlayers, players[i])
 			} else {
 				// Actual DB error
-				return nil, fmt.Errorf("database error checking player: %w", err)
+				return nil, nil, apperror.FromDB(fmt.Errorf("database error checking player: %w", err), nil)
 			}
 		}
 	}
@@ -101,35 +105,140 @@ func (r *Repo) Save(ctx context.Context, tx *sql.Tx, players []domain.Player) (m
 		q := fmt.Sprintf("INSERT INTO players (id, name, team_id) VALUES %s ON DUPLICATE KEY UPDATE name=VALUES(name)",
 			strings.Join(placeholders, ","))
 		// Note: Ensure that the team_id values being inserted exist in the teams table to prevent foreign key errors
-		_, err := tx.Exec(q, values...) // Fixed: removed * before err
+		_, err := tx.ExecContext(ctx, q, values...) // Fixed: removed * before err
 		if err != nil {
-			return nil, err
+			return nil, nil, apperror.FromDB(err, nil)
 		}
 
-		// Update Redis with new players
-		updatePipe := r.redis.Pipeline()
+		// a concurrent request may have inserted some of them first and the upsert keeps its rows,
+		// so the stored ids are read back and only the players whose row carries the id generated here were created
+		stored, err := r.storedIDs(ctx, tx, missingPlayers)
+		if err != nil {
+			return nil, nil, err
+		}
+		var created []domain.Player
 		for i := range missingPlayers {
-			redisKey := fmt.Sprintf("player:%s:%s", missingPlayers[i].Name, missingPlayers[i].Team)
-			updatePipe.Set(ctx, redisKey, missingPlayers[i].ID, 24*time.Hour)
-			playerIdsMap[missingPlayers[i].Name] = missingPlayers[i].ID
+			id, ok := stored[cacheKey(missingPlayers[i])]
+			if !ok {
+				return nil, nil, apperror.Internal(fmt.Errorf("player %s of team %s not found after insert", missingPlayers[i].Name, missingPlayers[i].Team))
+			}
+			playerIdsMap[missingPlayers[i].Name] = id
+			if id == missingPlayers[i].ID {
+				created = append(created, missingPlayers[i])
+			}
 		}
+		missingPlayers = created
+	}
+
+	return playerIdsMap, missingPlayers, nil
+}
+
+// storedIDs - the ids of players by cache key, read with a locking read so rows committed by other transactions are seen
+func (r *Repo) storedIDs(ctx context.Context, tx *sql.Tx, players []domain.Player) (map[string]string, error) {
+	pairs := make([]string, 0, len(players))
+	args := make([]interface{}, 0, len(players)*2)
+	for i := range players {
+		pairs = append(pairs, "(?, ?)")
+		args = append(args, players[i].Name, players[i].Team)
+	}
 
-		// Execute Redis updates
-		if _, err := updatePipe.Exec(ctx); err != nil { // Fixed: removed * before err
-			r.logger.Warn("Failed to update Redis with new players", zap.Error(err))
-			// Continue even if Redis update fails
+	rows, err := tx.QueryContext(ctx, fmt.Sprintf("SELECT id, name, team_id FROM players WHERE (name, team_id) IN (%s) FOR UPDATE", strings.Join(pairs, ",")), args...)
+	if err != nil {
+		return nil, apperror.FromDB(err, nil)
+	}
+	defer rows.Close()
+
+	ids := make(map[string]string, len(players))
+	for rows.Next() {
+		var player PlayerDB
+		if err = rows.Scan(&player.ID, &player.Name, &player.Team); err != nil {
+			return nil, apperror.FromDB(err, nil)
 		}
+		ids[cacheKey(domain.Player{Name: player.Name, Team: player.Team})] = player.ID
+	}
+	if err = rows.Err(); err != nil {
+		return nil, apperror.FromDB(err, nil)
+	}
+
+	return ids, nil
+}
+
+// Remember - caches the ids of players by name and team, only ids of committed rows may be remembered
+func (r *Repo) Remember(ctx context.Context, players []domain.Player) error {
+	pipe := r.redis.Pipeline()
+	for _, player := range players {
+		pipe.Set(ctx, cacheKey(player), player.ID, playerTtl)
 	}
+	_, err := pipe.Exec(ctx)
+
+	return err
+}
+
+// cacheKey - players are cached by "player:{name}:{team_id}"
+func cacheKey(player domain.Player) string {
+	return fmt.Sprintf("player:%s:%s", player.Name, player.Team)
+}
+
+// Lock - reads a player and holds its row lock until tx ends
+func (r *Repo) Lock(ctx context.Context, tx *sql.Tx, id string) (domain.Player, error) {
+	var player PlayerDB
+
+	row := tx.QueryRowContext(ctx, "SELECT id, name, team_id FROM players WHERE id = ? FOR UPDATE", id)
+	if err := row.Scan(&player.ID, &player.Name, &player.Team); err != nil {
+		return domain.Player{}, apperror.FromDB(err, apperror.NotFound("player_not_found", fmt.Sprintf("player %s not found", id), nil))
+	}
+
+	return domain.Player{ID: player.ID, Name: player.Name, Team: player.Team}, nil
+}
+
+// Merge - moves the lines of from to into and deletes from, the number of lines moved is returned.
+// Players sharing a game cannot be merged, and neither can players of play by play games, whose lines are rederived from their events
+func (r *Repo) Merge(ctx context.Context, tx *sql.Tx, from, into domain.Player) (int, error) {
+	var shared int
+	if err := tx.QueryRowContext(ctx, "SELECT COUNT(*) FROM game_stats a JOIN game_stats b ON b.game_id = a.game_id WHERE a.player_id = ? AND b.player_id = ?",
+		from.ID, into.ID).Scan(&shared); err != nil {
+		return 0, apperror.FromDB(err, nil)
+	}
+	if shared > 0 {
+		return 0, apperror.Conflict("players_share_game", fmt.Sprintf("both players have lines in %d games", shared), nil)
+	}
+
+	var events int
+	if err := tx.QueryRowContext(ctx, "SELECT COUNT(*) FROM game_events WHERE player_id = ? OR JSON_SEARCH(payload, 'one', ?) IS NOT NULL",
+		from.ID, from.ID).Scan(&events); err != nil {
+		return 0, apperror.FromDB(err, nil)
+	}
+	if events > 0 {
+		return 0, apperror.Conflict("player_in_events", fmt.Sprintf("player %s appears in %d play by play events", from.ID, events), nil)
+	}
+
+	res, err := tx.ExecContext(ctx, "UPDATE game_stats SET player_id = ? WHERE player_id = ?", into.ID, from.ID)
+	if err != nil {
+		return 0, apperror.FromDB(err, nil)
+	}
+	moved, err := res.RowsAffected()
+	if err != nil {
+		return 0, apperror.FromDB(err, nil)
+	}
+
+	if _, err = tx.ExecContext(ctx, "DELETE FROM players WHERE id = ?", from.ID); err != nil {
+		return 0, apperror.FromDB(err, nil)
+	}
+
+	return int(moved), nil
+}
 
-	return playerIdsMap, nil
+// Forget - drops the cached id of a player that no longer exists, so its name resolves from the DB again
+func (r *Repo) Forget(ctx context.Context, player domain.Player) error {
+	return r.redis.Del(ctx, cacheKey(player)).Err()
 }
 
-func (r *Repo) SeasonStats(id string) (domain.PlayerSeasonStats, error) {
+func (r *Repo) SeasonStats(ctx context.Context, id string) (domain.PlayerSeasonStats, error) {
 	var playerSeasonStatsDB PlayerSeasonStats
-	row := r.db.QueryRow("select player_id, player_name, games_played, avg_points, avg_rebounds, avg_assists, avg_steals, avg_blocks, avg_fouls, avg_turnovers, avg_minutes_played  from player_season_stats where player_id = ?", id)
+	row := r.db.QueryRowContext(ctx, "select player_id, player_name, games_played, avg_points, avg_rebounds, avg_assists, avg_steals, avg_blocks, avg_fouls, avg_turnovers, avg_minutes_played  from player_season_stats where player_id = ?", id)
 
 	if err := row.Scan(&playerSeasonStatsDB.PlayerID, &playerSeasonStatsDB.PlayerName, &playerSeasonStatsDB.GamesPlayed, &playerSeasonStatsDB.AvgPoints, &playerSeasonStatsDB.AvgRebounds, &playerSeasonStatsDB.AvgAssists, &playerSeasonStatsDB.AvgSteals, &playerSeasonStatsDB.AvgBlocks, &playerSeasonStatsDB.AvgFouls, &playerSeasonStatsDB.AvgTurnovers, &playerSeasonStatsDB.AvgMinutesPlayed); err != nil {
-		return domain.PlayerSeasonStats{}, err
+		return domain.PlayerSeasonStats{}, apperror.FromDB(err, apperror.NotFound("player_not_found", "player not found", nil))
 	}
 
 	return toDomain(playerSeasonStatsDB), nil
@@ -0,0 +1,126 @@
+package db
+
+import (
+	"context"
+	"database/sql"
+	"database/sql/driver"
+	"testing"
+	"time"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/alicebob/miniredis/v2"
+	"github.com/jmoiron/sqlx"
+	"github.com/redis/go-redis/v9"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap/zaptest"
+
+	"skyhawk/backend/player/domain"
+)
+
+// capturedID - a sqlmock argument copying the id it matches into itself, rows added with it return that id
+type capturedID []byte
+
+func (c capturedID) Match(v driver.Value) bool {
+	id, ok := v.(string)
+	return ok && copy(c, id) == len(c)
+}
+
+// newRepo - a repository over sqlmock and miniredis, with a transaction already begun
+func newRepo(t *testing.T) (Repository, sqlmock.Sqlmock, *sql.Tx, *redis.Client) {
+	db, mock, err := sqlmock.New()
+	require.NoError(t, err)
+	mr := miniredis.RunT(t)
+	rdb := redis.NewClient(&redis.Options{Addr: mr.Addr()})
+	t.Cleanup(func() {
+		rdb.Close()
+		db.Close()
+	})
+
+	mock.ExpectBegin()
+	tx, err := db.Begin()
+	require.NoError(t, err)
+
+	return NewRepo(zaptest.NewLogger(t), sqlx.NewDb(db, "sqlmock"), rdb), mock, tx, rdb
+}
+
+const (
+	lookupQuery   = "SELECT id FROM players WHERE name = \\? AND team_id = \\?"
+	insertQuery   = "INSERT INTO players \\(id, name, team_id\\) VALUES \\(\\?, \\?, \\?\\) ON DUPLICATE KEY UPDATE"
+	readBackQuery = "SELECT id, name, team_id FROM players WHERE \\(name, team_id\\) IN \\(\\(\\?, \\?\\)\\) FOR UPDATE"
+)
+
+func TestRepo_Save(t *testing.T) {
+	t.Run("cached, stored and new players", func(t *testing.T) {
+		// Setup
+		repo, mock, tx, rdb := newRepo(t)
+		ctx := context.Background()
+		require.NoError(t, rdb.Set(ctx, "player:Cached:team-1", "cached-id", 0).Err())
+
+		mock.ExpectQuery(lookupQuery).
+			WithArgs("Stored", "team-1").
+			WillReturnRows(sqlmock.NewRows([]string{"id"}).AddRow("stored-id"))
+		mock.ExpectQuery(lookupQuery).
+			WithArgs("New", "team-1").
+			WillReturnRows(sqlmock.NewRows([]string{"id"}))
+		generated := make(capturedID, 36)
+		mock.ExpectExec(insertQuery).
+			WithArgs(generated, "New", "team-1").
+			WillReturnResult(sqlmock.NewResult(1, 1))
+		mock.ExpectQuery(readBackQuery).
+			WithArgs("New", "team-1").
+			WillReturnRows(sqlmock.NewRows([]string{"id", "name", "team_id"}).AddRow([]byte(generated), "New", "team-1"))
+
+		// Test
+		ids, created, err := repo.Save(ctx, tx, []domain.Player{
+			{Name: "Cached", Team: "team-1"},
+			{Name: "Stored", Team: "team-1"},
+			{Name: "New", Team: "team-1"},
+		})
+
+		// Assert
+		require.NoError(t, err)
+		assert.Equal(t, map[string]string{"Cached": "cached-id", "Stored": "stored-id", "New": string(generated)}, ids)
+		assert.Equal(t, []domain.Player{{ID: string(generated), Name: "New", Team: "team-1"}}, created)
+		assert.NoError(t, mock.ExpectationsWereMet())
+		assert.Equal(t, []string{"player:Cached:team-1"}, rdb.Keys(ctx, "*").Val(), "nothing is cached before the caller commits")
+	})
+
+	t.Run("player inserted concurrently", func(t *testing.T) {
+		// Setup - another transaction inserts the player between the lookup and the upsert
+		repo, mock, tx, _ := newRepo(t)
+
+		mock.ExpectQuery(lookupQuery).
+			WithArgs("New", "team-1").
+			WillReturnRows(sqlmock.NewRows([]string{"id"}))
+		mock.ExpectExec(insertQuery).
+			WithArgs(sqlmock.AnyArg(), "New", "team-1").
+			WillReturnResult(sqlmock.NewResult(0, 0))
+		mock.ExpectQuery(readBackQuery).
+			WithArgs("New", "team-1").
+			WillReturnRows(sqlmock.NewRows([]string{"id", "name", "team_id"}).AddRow("other-id", "New", "team-1"))
+
+		// Test
+		ids, created, err := repo.Save(context.Background(), tx, []domain.Player{{Name: "New", Team: "team-1"}})
+
+		// Assert
+		require.NoError(t, err)
+		assert.Equal(t, map[string]string{"New": "other-id"}, ids, "the id of the row that won")
+		assert.Empty(t, created, "the player was created by the other transaction")
+		assert.NoError(t, mock.ExpectationsWereMet())
+	})
+}
+
+func TestRepo_Remember(t *testing.T) {
+	// Setup
+	repo, _, _, rdb := newRepo(t)
+	ctx := context.Background()
+
+	// Test
+	err := repo.Remember(ctx, []domain.Player{{ID: "id-1", Name: "Guard", Team: "team-1"}})
+
+	// Assert
+	require.NoError(t, err)
+	assert.Equal(t, "id-1", rdb.Get(ctx, "player:Guard:team-1").Val())
+	assert.Equal(t, playerTtl, rdb.TTL(ctx, "player:Guard:team-1").Val().Round(time.Hour))
+}
@@ -21,3 +21,10 @@ type PlayerSeasonStats struct {
 	AvgTurnovers     float64 `json:"avg_turnovers"`
 	AvgMinutesPlayed float64 `json:"avg_minutes_played"`
 }
+
+// MergeRes - a duplicate player folded into another, the lines of From now belong to Into
+type MergeRes struct {
+	From  string `json:"from"`
+	Into  string `json:"into"`
+	Lines int    `json:"lines_moved"`
+}
@@ -0,0 +1,155 @@
+package ratelimit
+
+import (
+	"context"
+	"math"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/redis/go-redis/v9"
+)
+
+// Limit - a token bucket refilled at Rate tokens a second holding at most Burst tokens
+type Limit struct {
+	Rate  float64
+	Burst int
+}
+
+func (l Limit) Enabled() bool {
+	return l.Rate > 0 && l.Burst > 0
+}
+
+// Result - the state of a bucket after a request took from it
+type Result struct {
+	Allowed   bool
+	Limit     int
+	Remaining int
+	// Reset - until the bucket is full again
+	Reset time.Duration
+	// RetryAfter - until the next token, zero when the request was allowed
+	RetryAfter time.Duration
+}
+
+// Buckets - token buckets by key
+type Buckets interface {
+	Take(ctx context.Context, key string, limit Limit, now time.Time) (Result, error)
+}
+
+// result - the outcome of taking from a bucket holding tokens after the refill
+func result(limit Limit, tokens float64) Result {
+	res := Result{Limit: limit.Burst}
+	if tokens >= 1 {
+		tokens--
+		res.Allowed = true
+	} else {
+		res.RetryAfter = seconds((1 - tokens) / limit.Rate)
+	}
+	res.Remaining = int(math.Floor(tokens))
+	res.Reset = seconds((float64(limit.Burst) - tokens) / limit.Rate)
+
+	return res
+}
+
+func seconds(s float64) time.Duration {
+	return time.Duration(s * float64(time.Second))
+}
+
+type bucket struct {
+	tokens float64
+	at     time.Time
+}
+
+// MemoryBuckets - buckets of a single instance
+type MemoryBuckets struct {
+	mu        sync.Mutex
+	buckets   map[string]*bucket
+	lastSweep time.Time
+}
+
+func NewMemoryBuckets() *MemoryBuckets {
+	return &MemoryBuckets{buckets: map[string]*bucket{}}
+}
+
+func (m *MemoryBuckets) Take(_ context.Context, key string, limit Limit, now time.Time) (Result, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	m.sweep(now)
+
+	b, ok := m.buckets[key]
+	if !ok {
+		b = &bucket{tokens: float64(limit.Burst), at: now}
+		m.buckets[key] = b
+	}
+	b.tokens = math.Min(float64(limit.Burst), b.tokens+now.Sub(b.at).Seconds()*limit.Rate)
+	b.at = now
+
+	res := result(limit, b.tokens)
+	if res.Allowed {
+		b.tokens--
+	}
+
+	return res, nil
+}
+
+// sweep - drops buckets idle for a minute, an idle bucket is full so forgetting it changes nothing once refilled
+func (m *MemoryBuckets) sweep(now time.Time) {
+	if now.Sub(m.lastSweep) < time.Minute {
+		return
+	}
+	m.lastSweep = now
+
+	for key, b := range m.buckets {
+		if now.Sub(b.at) >= time.Minute {
+			delete(m.buckets, key)
+		}
+	}
+}
+
+// takeScript - refills and takes from the bucket in one step, so instances sharing it never race
+var takeScript = redis.NewScript(`
+local rate = tonumber(ARGV[1])
+local burst = tonumber(ARGV[2])
+local now = tonumber(ARGV[3])
+local state = redis.call('HMGET', KEYS[1], 'tokens', 'at')
+local tokens = tonumber(state[1])
+local at = tonumber(state[2])
+if tokens == nil then
+  tokens = burst
+  at = now
+end
+tokens = math.min(burst, tokens + math.max(0, now - at) / 1000 * rate)
+local refilled = tokens
+if tokens >= 1 then
+  tokens = tokens - 1
+end
+redis.call('HSET', KEYS[1], 'tokens', tostring(tokens), 'at', now)
+redis.call('PEXPIRE', KEYS[1], math.ceil(burst / rate * 1000) + 1000)
+return tostring(refilled)
+`)
+
+// RedisBuckets - buckets shared by every instance
+type RedisBuckets struct {
+	client *redis.Client
+	prefix string
+}
+
+func NewRedisBuckets(client *redis.Client, prefix string) *RedisBuckets {
+	return &RedisBuckets{client: client, prefix: prefix}
+}
+
+func (r *RedisBuckets) Take(ctx context.Context, key string, limit Limit, now time.Time) (Result, error) {
+	refilled, err := takeScript.Run(ctx, r.client, []string{r.prefix + key},
+		limit.Rate, limit.Burst, now.UnixMilli()).Text()
+	if err != nil {
+		return Result{}, err
+	}
+
+	tokens, err := strconv.ParseFloat(refilled, 64)
+	if err != nil {
+		return Result{}, err
+	}
+
+	return result(limit, tokens), nil
+}
@@ -0,0 +1,53 @@
+package handler
+
+import (
+	"net/http"
+	"time"
+
+	"github.com/labstack/echo/v4"
+	"go.uber.org/zap"
+
+	"skyhawk/backend/apperror"
+	auth_domain "skyhawk/backend/auth/domain"
+	"skyhawk/backend/ratelimit"
+)
+
+type Handler struct {
+	limiter *ratelimit.Limiter
+	logger  *zap.Logger
+}
+
+func NewHandler(limiter *ratelimit.Limiter, logger *zap.Logger) *Handler {
+	return &Handler{limiter: limiter, logger: logger}
+}
+
+// UsageHandler - GET /usage?day=YYYY-MM-DD, the requests of the caller on a UTC day.
+// Admins may pass client=apikey:<prefix> or user:<sub> to see anyone's usage
+func (h *Handler) UsageHandler(c echo.Context) error {
+	ctx := c.Request().Context()
+
+	client := ratelimit.ClientOf(ctx, c.RealIP())
+	if other := c.QueryParam("client"); other != "" && other != client {
+		if principal, ok := auth_domain.PrincipalFrom(ctx); !ok || !principal.Allows(auth_domain.ScopeAdmin) {
+			return apperror.Forbidden("insufficient_scope", "only admins can see the usage of other clients")
+		}
+		client = other
+	}
+
+	day := time.Now().UTC().Format(time.DateOnly)
+	if value := c.QueryParam("day"); value != "" {
+		if _, err := time.Parse(time.DateOnly, value); err != nil {
+			return apperror.Validation("invalid_usage_query", "day must be YYYY-MM-DD",
+				apperror.FieldError{Field: "day", Message: "day must be YYYY-MM-DD"})
+		}
+		day = value
+	}
+
+	usage, err := h.limiter.Usage(ctx, client, day)
+
+	if err != nil {
+		return apperror.Unavailable("usage_unavailable", "usage could not be read", err)
+	}
+
+	return c.JSON(http.StatusOK, usage)
+}
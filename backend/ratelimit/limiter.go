@@ -0,0 +1,146 @@
+package ratelimit
+
+import (
+	"context"
+	"time"
+
+	"go.uber.org/zap"
+
+	auth_domain "skyhawk/backend/auth/domain"
+)
+
+// Class - reads and writes are limited separately, a burst of stat lookups never blocks logging a game
+type Class string
+
+const (
+	Read  Class = "read"
+	Write Class = "write"
+)
+
+type Options struct {
+	Read  Limit
+	Write Limit
+	// IP - requests per ip over both classes, taken before authentication so bad credentials are throttled too
+	IP Limit
+	// DailyQuota - requests a client may make per UTC day, 0 is unlimited
+	DailyQuota int64
+}
+
+func DefaultOptions() Options {
+	return Options{
+		Read:       Limit{Rate: 20, Burst: 40},
+		Write:      Limit{Rate: 5, Burst: 10},
+		IP:         Limit{Rate: 50, Burst: 100},
+		DailyQuota: 100000,
+	}
+}
+
+// Decision - whether a request may go ahead, with what to tell the client about its budget
+type Decision struct {
+	Result
+	QuotaExceeded bool
+}
+
+// Limiter - token buckets per client and class in front of a daily quota per client
+type Limiter struct {
+	buckets  Buckets
+	counters Counters
+	options  Options
+	logger   *zap.Logger
+	now      func() time.Time
+}
+
+func NewLimiter(buckets Buckets, counters Counters, options Options, logger *zap.Logger) *Limiter {
+	return &Limiter{buckets: buckets, counters: counters, options: options, logger: logger, now: time.Now}
+}
+
+// ClientOf - who a request is counted against: the authenticated principal, otherwise its ip
+func ClientOf(ctx context.Context, ip string) string {
+	if principal, ok := auth_domain.PrincipalFrom(ctx); ok {
+		return principal.Subject
+	}
+
+	return "ip:" + ip
+}
+
+// Allow - takes a token from the bucket of client and class, then counts the request against the quota.
+// A store that cannot be reached lets the request through, the limiter must not take the api down with it
+func (l *Limiter) Allow(ctx context.Context, client string, class Class) Decision {
+	now := l.now().UTC()
+
+	limit := l.options.Read
+	if class == Write {
+		limit = l.options.Write
+	}
+
+	decision := Decision{Result: Result{Allowed: true}}
+	if limit.Enabled() {
+		res, err := l.buckets.Take(ctx, string(class)+":"+client, limit, now)
+		if err != nil {
+			l.logger.Warn("rate limit store failed, allowing request", zap.String("client", client), zap.Error(err))
+			return decision
+		}
+		decision.Result = res
+		if !res.Allowed {
+			return decision
+		}
+	}
+
+	if l.options.DailyQuota > 0 {
+		total, err := l.counters.Add(ctx, client, class, now.Format(time.DateOnly))
+		if err != nil {
+			l.logger.Warn("quota store failed, allowing request", zap.String("client", client), zap.Error(err))
+			return decision
+		}
+		if total > l.options.DailyQuota {
+			decision.Allowed = false
+			decision.QuotaExceeded = true
+			decision.RetryAfter = untilMidnight(now)
+		}
+	}
+
+	return decision
+}
+
+// AllowIP - takes a token from the bucket of ip, whoever the request turns out to be.
+// Not counted against a quota, and like Allow a store that cannot be reached lets the request through
+func (l *Limiter) AllowIP(ctx context.Context, ip string) Decision {
+	decision := Decision{Result: Result{Allowed: true}}
+	if !l.options.IP.Enabled() {
+		return decision
+	}
+
+	res, err := l.buckets.Take(ctx, "ip:"+ip, l.options.IP, l.now().UTC())
+	if err != nil {
+		l.logger.Warn("rate limit store failed, allowing request", zap.String("ip", ip), zap.Error(err))
+		return decision
+	}
+	decision.Result = res
+
+	return decision
+}
+
+// Usage - the requests client made on day, with what is left of the quota
+func (l *Limiter) Usage(ctx context.Context, client, day string) (Usage, error) {
+	usage, err := l.counters.Usage(ctx, client, day)
+	if err != nil {
+		return Usage{}, err
+	}
+
+	if l.options.DailyQuota > 0 {
+		remaining := l.options.DailyQuota - usage.Total
+		if remaining < 0 {
+			remaining = 0
+		}
+		usage.Quota = l.options.DailyQuota
+		usage.Remaining = &remaining
+	}
+
+	return usage, nil
+}
+
+func untilMidnight(now time.Time) time.Duration {
+	year, month, day := now.Date()
+
+	return time.Date(year, month, day+1, 0, 0, 0, 0, time.UTC).Sub(now)
+}
@@ -0,0 +1,130 @@
+package ratelimit
+
+import (
+	"context"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/redis/go-redis/v9"
+)
+
+// usageRetention - how long daily usage stays available for reporting
+const usageRetention = 8 * 24 * time.Hour
+
+// Usage - the requests of a client on a UTC day
+type Usage struct {
+	Client    string `json:"client"`
+	Day       string `json:"day"`
+	Reads     int64  `json:"reads"`
+	Writes    int64  `json:"writes"`
+	Total     int64  `json:"total"`
+	Quota     int64  `json:"quota,omitempty"`
+	Remaining *int64 `json:"remaining,omitempty"`
+}
+
+// Counters - requests counted by client and day
+type Counters interface {
+	// Add - counts a request and returns the client total of the day
+	Add(ctx context.Context, client string, class Class, day string) (int64, error)
+	Usage(ctx context.Context, client, day string) (Usage, error)
+}
+
+// MemoryCounters - counters of a single instance
+type MemoryCounters struct {
+	mu     sync.Mutex
+	counts map[string]*Usage
+}
+
+func NewMemoryCounters() *MemoryCounters {
+	return &MemoryCounters{counts: map[string]*Usage{}}
+}
+
+func (m *MemoryCounters) Add(_ context.Context, client string, class Class, day string) (int64, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	key := day + "|" + client
+	usage, ok := m.counts[key]
+	if !ok {
+		m.forget(day)
+		usage = &Usage{Client: client, Day: day}
+		m.counts[key] = usage
+	}
+	if class == Read {
+		usage.Reads++
+	} else {
+		usage.Writes++
+	}
+	usage.Total++
+
+	return usage.Total, nil
+}
+
+func (m *MemoryCounters) Usage(_ context.Context, client, day string) (Usage, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	if usage, ok := m.counts[day+"|"+client]; ok {
+		return *usage, nil
+	}
+
+	return Usage{Client: client, Day: day}, nil
+}
+
+// forget - drops days past the retention when a new client shows up
+func (m *MemoryCounters) forget(today string) {
+	day, err := time.Parse(time.DateOnly, today)
+	if err != nil {
+		return
+	}
+	oldest := day.Add(-usageRetention).Format(time.DateOnly)
+
+	for key, usage := range m.counts {
+		if usage.Day < oldest {
+			delete(m.counts, key)
+		}
+	}
+}
+
+// RedisCounters - counters shared by every instance, one hash per client and day
+type RedisCounters struct {
+	client *redis.Client
+	prefix string
+}
+
+func NewRedisCounters(client *redis.Client, prefix string) *RedisCounters {
+	return &RedisCounters{client: client, prefix: prefix}
+}
+
+func (r *RedisCounters) key(client, day string) string {
+	return r.prefix + day + ":" + client
+}
+
+func (r *RedisCounters) Add(ctx context.Context, client string, class Class, day string) (int64, error) {
+	key := r.key(client, day)
+
+	pipe := r.client.TxPipeline()
+	pipe.HIncrBy(ctx, key, string(class), 1)
+	total := pipe.HIncrBy(ctx, key, "total", 1)
+	pipe.Expire(ctx, key, usageRetention)
+	if _, err := pipe.Exec(ctx); err != nil {
+		return 0, err
+	}
+
+	return total.Val(), nil
+}
+
+func (r *RedisCounters) Usage(ctx context.Context, client, day string) (Usage, error) {
+	fields, err := r.client.HGetAll(ctx, r.key(client, day)).Result()
+	if err != nil {
+		return Usage{}, err
+	}
+
+	usage := Usage{Client: client, Day: day}
+	usage.Reads, _ = strconv.ParseInt(fields[string(Read)], 10, 64)
+	usage.Writes, _ = strconv.ParseInt(fields[string(Write)], 10, 64)
+	usage.Total, _ = strconv.ParseInt(fields["total"], 10, 64)
+
+	return usage, nil
+}
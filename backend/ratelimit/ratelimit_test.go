@@ -0,0 +1,142 @@
+package ratelimit
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/alicebob/miniredis/v2"
+	"github.com/redis/go-redis/v9"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap/zaptest"
+)
+
+var start = time.Date(2024, 11, 2, 19, 30, 0, 0, time.UTC)
+
+func testBuckets(t *testing.T, buckets Buckets) {
+	ctx := context.Background()
+	limit := Limit{Rate: 2, Burst: 3}
+
+	// Test
+	var results []Result
+	for i := 0; i < 4; i++ {
+		res, err := buckets.Take(ctx, "read:apikey:partner", limit, start)
+		require.NoError(t, err)
+		results = append(results, res)
+	}
+	refilled, err := buckets.Take(ctx, "read:apikey:partner", limit, start.Add(500*time.Millisecond))
+	require.NoError(t, err)
+	other, err := buckets.Take(ctx, "read:apikey:other", limit, start)
+	require.NoError(t, err)
+
+	// Assert
+	assert.True(t, results[0].Allowed)
+	assert.Equal(t, 2, results[0].Remaining)
+	assert.Equal(t, 3, results[0].Limit)
+	assert.True(t, results[2].Allowed)
+	assert.Equal(t, 0, results[2].Remaining)
+	assert.Equal(t, 1500*time.Millisecond, results[2].Reset)
+	assert.False(t, results[3].Allowed)
+	assert.Equal(t, 500*time.Millisecond, results[3].RetryAfter)
+	assert.True(t, refilled.Allowed, "a token is back after 1/rate")
+	assert.True(t, other.Allowed, "clients have their own buckets")
+}
+
+func TestMemoryBuckets(t *testing.T) {
+	testBuckets(t, NewMemoryBuckets())
+}
+
+func TestRedisBuckets(t *testing.T) {
+	mr := miniredis.RunT(t)
+	client := redis.NewClient(&redis.Options{Addr: mr.Addr()})
+
+	testBuckets(t, NewRedisBuckets(client, "skyhawk:ratelimit:"))
+	assert.True(t, mr.Exists("skyhawk:ratelimit:read:apikey:partner"))
+}
+
+func TestRedisCounters(t *testing.T) {
+	// Setup
+	mr := miniredis.RunT(t)
+	counters := NewRedisCounters(redis.NewClient(&redis.Options{Addr: mr.Addr()}), "skyhawk:usage:")
+	ctx := context.Background()
+
+	// Test
+	_, _ = counters.Add(ctx, "apikey:partner", Read, "2024-11-02")
+	_, _ = counters.Add(ctx, "apikey:partner", Read, "2024-11-02")
+	total, err := counters.Add(ctx, "apikey:partner", Write, "2024-11-02")
+	require.NoError(t, err)
+	usage, err := counters.Usage(ctx, "apikey:partner", "2024-11-02")
+
+	// Assert
+	require.NoError(t, err)
+	assert.Equal(t, int64(3), total)
+	assert.Equal(t, Usage{Client: "apikey:partner", Day: "2024-11-02", Reads: 2, Writes: 1, Total: 3}, usage)
+	assert.Positive(t, mr.TTL("skyhawk:usage:2024-11-02:apikey:partner"))
+}
+
+type failingBuckets struct{}
+
+func (failingBuckets) Take(context.Context, string, Limit, time.Time) (Result, error) {
+	return Result{}, errors.New("redis: connection refused")
+}
+
+func TestLimiter_Allow(t *testing.T) {
+	t.Run("daily quota", func(t *testing.T) {
+		// Setup
+		limiter := NewLimiter(NewMemoryBuckets(), NewMemoryCounters(), Options{Read: Limit{Rate: 100, Burst: 100}, DailyQuota: 2}, zaptest.NewLogger(t))
+		limiter.now = func() time.Time { return start }
+		ctx := context.Background()
+
+		// Test
+		first := limiter.Allow(ctx, "apikey:partner", Read)
+		second := limiter.Allow(ctx, "apikey:partner", Write)
+		third := limiter.Allow(ctx, "apikey:partner", Read)
+		usage, err := limiter.Usage(ctx, "apikey:partner", "2024-11-02")
+
+		// Assert
+		assert.True(t, first.Allowed)
+		assert.True(t, second.Allowed, "writes are not limited when their limit is unset")
+		assert.False(t, third.Allowed)
+		assert.True(t, third.QuotaExceeded)
+		assert.Equal(t, 4*time.Hour+30*time.Minute, third.RetryAfter)
+		require.NoError(t, err)
+		assert.Equal(t, int64(1), usage.Writes)
+		assert.Equal(t, int64(0), *usage.Remaining)
+	})
+
+	t.Run("store failures let requests through", func(t *testing.T) {
+		// Setup
+		limiter := NewLimiter(failingBuckets{}, NewMemoryCounters(), DefaultOptions(), zaptest.NewLogger(t))
+
+		// Test
+		decision := limiter.Allow(context.Background(), "apikey:partner", Write)
+
+		// Assert
+		assert.True(t, decision.Allowed)
+	})
+}
+
+func TestLimiter_AllowIP(t *testing.T) {
+	// Setup
+	limiter := NewLimiter(NewMemoryBuckets(), NewMemoryCounters(), Options{IP: Limit{Rate: 1, Burst: 2}, DailyQuota: 1}, zaptest.NewLogger(t))
+	limiter.now = func() time.Time { return start }
+	ctx := context.Background()
+
+	// Test
+	first := limiter.AllowIP(ctx, "10.0.0.1")
+	second := limiter.AllowIP(ctx, "10.0.0.1")
+	third := limiter.AllowIP(ctx, "10.0.0.1")
+	other := limiter.AllowIP(ctx, "10.0.0.2")
+	usage, err := limiter.Usage(ctx, "ip:10.0.0.1", "2024-11-02")
+
+	// Assert
+	assert.True(t, first.Allowed)
+	assert.True(t, second.Allowed, "the quota is left to the client limit")
+	assert.False(t, third.Allowed)
+	assert.Equal(t, time.Second, third.RetryAfter)
+	assert.True(t, other.Allowed, "each ip has its own bucket")
+	require.NoError(t, err)
+	assert.Zero(t, usage.Total)
+}
@@ -0,0 +1,67 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"os"
+	"os/signal"
+	"strings"
+	"syscall"
+
+	"go.uber.org/zap"
+
+	audit_domain "skyhawk/backend/audit/domain"
+	"skyhawk/backend/config"
+)
+
+// runRecompute - the "recompute-aggregates" subcommand. Season stats are views over the lines and need no rebuild,
+// the lines of play by play games are derived from their events and are rewritten by replaying them
+func runRecompute(cfg config.Config, logger *zap.Logger, args []string) error {
+	flags := flag.NewFlagSet("recompute-aggregates", flag.ContinueOnError)
+	games := flags.String("games", "", "comma separated ids of the games to recompute, every play by play game by default")
+	if err := flags.Parse(args); err != nil {
+		return err
+	}
+
+	var gameIDs []string
+	for _, id := range strings.Split(*games, ",") {
+		if id = strings.TrimSpace(id); id != "" {
+			gameIDs = append(gameIDs, id)
+		}
+	}
+
+	app, err := newApp(cfg, logger)
+	if err != nil {
+		return err
+	}
+	defer app.Close()
+
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+	ctx = audit_domain.WithMeta(ctx, audit_domain.Meta{Actor: "cli:recompute-aggregates"})
+
+	results, err := app.events.Recompute(ctx, gameIDs)
+	if err != nil {
+		return err
+	}
+
+	encoder := json.NewEncoder(os.Stdout)
+	encoder.SetIndent("", "  ")
+	if err = encoder.Encode(results); err != nil {
+		return err
+	}
+
+	failed := 0
+	for _, result := range results {
+		if result.Error != "" {
+			failed++
+		}
+	}
+	if failed > 0 {
+		return fmt.Errorf("recompute-aggregates: %d of %d games failed", failed, len(results))
+	}
+
+	return nil
+}
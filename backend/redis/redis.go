@@ -0,0 +1,31 @@
+package redis
+
+import (
+	"context"
+	"crypto/tls"
+
+	"github.com/redis/go-redis/v9"
+
+	"skyhawk/backend/config"
+)
+
+func MustNewRedis(cfg config.Redis) (*redis.Client, error) {
+	options := &redis.Options{
+		Addr:     cfg.Addr,
+		Username: cfg.Username,
+		Password: cfg.Password,
+		DB:       cfg.DB,
+		PoolSize: cfg.PoolSize,
+	}
+	if cfg.TLS {
+		options.TLSConfig = &tls.Config{MinVersion: tls.VersionTLS12}
+	}
+	client := redis.NewClient(options)
+
+	//ping
+	if err := client.Ping(context.Background()).Err(); err != nil {
+		return nil, err
+	}
+
+	return client, nil
+}
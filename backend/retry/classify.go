@@ -0,0 +1,82 @@
+package retry
+
+import (
+	"database/sql/driver"
+	"errors"
+	"io"
+	"net"
+	"syscall"
+
+	"github.com/go-sql-driver/mysql"
+)
+
+// Class - why a failed attempt may succeed when tried again
+type Class string
+
+const (
+	ClassPermanent       Class = "permanent"
+	ClassDeadlock        Class = "deadlock"
+	ClassLockWaitTimeout Class = "lock_wait_timeout"
+	ClassConnection      Class = "connection"
+)
+
+const (
+	mysqlLockWaitTimeout = 1205
+	mysqlDeadlock        = 1213
+)
+
+type permanentError struct {
+	err error
+}
+
+func (p *permanentError) Error() string { return p.err.Error() }
+func (p *permanentError) Unwrap() error { return p.err }
+
+// Permanent - marks err as not retryable even if it looks transient,
+// e.g. a connection lost during COMMIT where the outcome is unknown
+func Permanent(err error) error {
+	if err == nil {
+		return nil
+	}
+
+	return &permanentError{err: err}
+}
+
+// Classify - inspects the driver error number rather than the message text
+func Classify(err error) Class {
+	if err == nil {
+		return ClassPermanent
+	}
+
+	var permanent *permanentError
+	if errors.As(err, &permanent) {
+		return ClassPermanent
+	}
+
+	var mysqlErr *mysql.MySQLError
+	if errors.As(err, &mysqlErr) {
+		switch mysqlErr.Number {
+		case mysqlDeadlock:
+			return ClassDeadlock
+		case mysqlLockWaitTimeout:
+			return ClassLockWaitTimeout
+		}
+		return ClassPermanent
+	}
+
+	if errors.Is(err, driver.ErrBadConn) ||
+		errors.Is(err, mysql.ErrInvalidConn) ||
+		errors.Is(err, syscall.ECONNRESET) ||
+		errors.Is(err, syscall.ECONNREFUSED) ||
+		errors.Is(err, syscall.EPIPE) ||
+		errors.Is(err, io.ErrUnexpectedEOF) {
+		return ClassConnection
+	}
+
+	var netErr *net.OpError
+	if errors.As(err, &netErr) {
+		return ClassConnection
+	}
+
+	return ClassPermanent
+}
@@ -0,0 +1,24 @@
+package handler
+
+import (
+	"net/http"
+
+	"github.com/labstack/echo/v4"
+	"go.uber.org/zap"
+
+	"skyhawk/backend/retry"
+)
+
+type Handler struct {
+	stats  *retry.Stats
+	logger *zap.Logger
+}
+
+func NewHandler(stats *retry.Stats, logger *zap.Logger) *Handler {
+	return &Handler{stats: stats, logger: logger}
+}
+
+// StatsHandler - GET /debug/retries, the transaction retry counters of this instance since it started
+func (h *Handler) StatsHandler(c echo.Context) error {
+	return c.JSON(http.StatusOK, h.stats.Counters())
+}
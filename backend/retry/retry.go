@@ -0,0 +1,140 @@
+package retry
+
+import (
+	"context"
+	"math/rand"
+	"sync"
+	"time"
+
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/trace"
+	"go.uber.org/zap"
+
+	"skyhawk/backend/logging"
+)
+
+// Policy - how many times and how far apart attempts are made
+type Policy struct {
+	MaxAttempts int
+	BaseDelay   time.Duration
+	MaxDelay    time.Duration
+}
+
+func DefaultPolicy() Policy {
+	return Policy{
+		MaxAttempts: 5,
+		BaseDelay:   50 * time.Millisecond,
+		MaxDelay:    2 * time.Second,
+	}
+}
+
+// Observer - receives every retry decision, used to feed metrics
+type Observer interface {
+	Retried(op string, class Class)
+	Exhausted(op string, class Class)
+}
+
+type Retrier struct {
+	policy   Policy
+	logger   *zap.Logger
+	observer Observer
+
+	mu   sync.Mutex
+	rand *rand.Rand
+}
+
+func New(policy Policy, logger *zap.Logger, observer Observer) *Retrier {
+	if policy.MaxAttempts < 1 {
+		policy.MaxAttempts = 1
+	}
+
+	return &Retrier{
+		policy:   policy,
+		logger:   logger,
+		observer: observer,
+		rand:     rand.New(rand.NewSource(time.Now().UnixNano())),
+	}
+}
+
+// Do - runs fn until it succeeds, fails with a permanent error, the attempts run out or ctx is done,
+// it returns the number of attempts made and the error of the last attempt
+func (r *Retrier) Do(ctx context.Context, op string, fn func(ctx context.Context) error) (int, error) {
+	var err error
+	logger := logging.From(ctx, r.logger)
+
+	for attempt := 1; ; attempt++ {
+		if err = fn(ctx); err == nil {
+			if attempt > 1 {
+				logger.Info("operation succeeded after retry", zap.String("op", op), zap.Int("attempts", attempt))
+			}
+			return attempt, nil
+		}
+
+		class := Classify(err)
+		if class == ClassPermanent {
+			return attempt, err
+		}
+
+		if attempt >= r.policy.MaxAttempts {
+			logger.Error("retries exhausted",
+				zap.String("op", op),
+				zap.String("class", string(class)),
+				zap.Int("attempts", attempt),
+				zap.Error(err))
+			if r.observer != nil {
+				r.observer.Exhausted(op, class)
+			}
+			return attempt, err
+		}
+
+		delay := r.Backoff(attempt)
+		logger.Warn("transient error, retrying",
+			zap.String("op", op),
+			zap.String("class", string(class)),
+			zap.Int("attempt", attempt),
+			zap.Int("maxAttempts", r.policy.MaxAttempts),
+			zap.Duration("delay", delay),
+			zap.Error(err))
+		//the retries show on the span of the operation, next to the spans of each attempt
+		trace.SpanFromContext(ctx).AddEvent("retry", trace.WithAttributes(
+			attribute.String("retry.class", string(class)),
+			attribute.Int("retry.attempt", attempt),
+			attribute.String("retry.delay", delay.String())))
+		if r.observer != nil {
+			r.observer.Retried(op, class)
+		}
+
+		if sleepErr := sleep(ctx, delay); sleepErr != nil {
+			return attempt, sleepErr
+		}
+	}
+}
+
+// Backoff - the delay before the next attempt, exponential growth capped at MaxDelay with full jitter so competing writers spread out
+func (r *Retrier) Backoff(attempt int) time.Duration {
+	ceiling := r.policy.BaseDelay << (attempt - 1)
+	if ceiling <= 0 || ceiling > r.policy.MaxDelay {
+		ceiling = r.policy.MaxDelay
+	}
+	if ceiling <= 0 {
+		return 0
+	}
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	return time.Duration(r.rand.Int63n(int64(ceiling)) + 1)
+}
+
+// sleep - waits for d or until ctx is done, whichever comes first
+func sleep(ctx context.Context, d time.Duration) error {
+	timer := time.NewTimer(d)
+	defer timer.Stop()
+
+	select {
+	case <-ctx.Done():
+		return ctx.Err()
+	case <-timer.C:
+		return nil
+	}
+}
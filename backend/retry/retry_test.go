@@ -0,0 +1,151 @@
+package retry
+
+import (
+	"context"
+	"database/sql/driver"
+	"errors"
+	"fmt"
+	"sync"
+	"syscall"
+	"testing"
+	"time"
+
+	"github.com/go-sql-driver/mysql"
+	"github.com/stretchr/testify/assert"
+	"go.uber.org/zap/zaptest"
+)
+
+type recordingObserver struct {
+	mu        sync.Mutex
+	retried   []Class
+	exhausted []Class
+}
+
+func (o *recordingObserver) Retried(_ string, class Class) {
+	o.mu.Lock()
+	defer o.mu.Unlock()
+	o.retried = append(o.retried, class)
+}
+
+func (o *recordingObserver) Exhausted(_ string, class Class) {
+	o.mu.Lock()
+	defer o.mu.Unlock()
+	o.exhausted = append(o.exhausted, class)
+}
+
+var deadlock = &mysql.MySQLError{Number: 1213, Message: "Deadlock found when trying to get lock"}
+
+func testPolicy() Policy {
+	return Policy{MaxAttempts: 3, BaseDelay: time.Millisecond, MaxDelay: 2 * time.Millisecond}
+}
+
+func TestClassify(t *testing.T) {
+	assert.Equal(t, ClassDeadlock, Classify(fmt.Errorf("wrapped: %w", deadlock)))
+	assert.Equal(t, ClassLockWaitTimeout, Classify(&mysql.MySQLError{Number: 1205}))
+	assert.Equal(t, ClassConnection, Classify(driver.ErrBadConn))
+	assert.Equal(t, ClassConnection, Classify(mysql.ErrInvalidConn))
+	assert.Equal(t, ClassConnection, Classify(fmt.Errorf("read: %w", syscall.ECONNRESET)))
+	assert.Equal(t, ClassPermanent, Classify(&mysql.MySQLError{Number: 1062}))
+	assert.Equal(t, ClassPermanent, Classify(errors.New("Deadlock found in a message only")))
+	assert.Equal(t, ClassPermanent, Classify(Permanent(deadlock)))
+	assert.Equal(t, ClassPermanent, Classify(context.DeadlineExceeded))
+}
+
+func TestRetrier_Do(t *testing.T) {
+	t.Run("succeeds after transient errors", func(t *testing.T) {
+		// Setup
+		observer := &recordingObserver{}
+		r := New(testPolicy(), zaptest.NewLogger(t), observer)
+		calls := 0
+
+		// Test
+		attempts, err := r.Do(context.Background(), "op", func(ctx context.Context) error {
+			calls++
+			if calls < 3 {
+				return deadlock
+			}
+			return nil
+		})
+
+		// Assert
+		assert.NoError(t, err)
+		assert.Equal(t, 3, attempts)
+		assert.Equal(t, []Class{ClassDeadlock, ClassDeadlock}, observer.retried)
+		assert.Empty(t, observer.exhausted)
+	})
+
+	t.Run("returns the final error when attempts run out", func(t *testing.T) {
+		// Setup
+		observer := &recordingObserver{}
+		r := New(testPolicy(), zaptest.NewLogger(t), observer)
+
+		// Test
+		attempts, err := r.Do(context.Background(), "op", func(ctx context.Context) error {
+			return deadlock
+		})
+
+		// Assert
+		assert.ErrorIs(t, err, deadlock)
+		assert.Equal(t, 3, attempts)
+		assert.Equal(t, []Class{ClassDeadlock}, observer.exhausted)
+	})
+
+	t.Run("permanent errors are not retried", func(t *testing.T) {
+		// Setup
+		r := New(testPolicy(), zaptest.NewLogger(t), nil)
+		boom := errors.New("boom")
+
+		// Test
+		attempts, err := r.Do(context.Background(), "op", func(ctx context.Context) error {
+			return boom
+		})
+
+		// Assert
+		assert.ErrorIs(t, err, boom)
+		assert.Equal(t, 1, attempts)
+	})
+
+	t.Run("stops waiting when the context is done", func(t *testing.T) {
+		// Setup
+		r := New(Policy{MaxAttempts: 5, BaseDelay: time.Hour, MaxDelay: time.Hour}, zaptest.NewLogger(t), nil)
+		ctx, cancel := context.WithTimeout(context.Background(), 10*time.Millisecond)
+		defer cancel()
+
+		// Test
+		start := time.Now()
+		attempts, err := r.Do(ctx, "op", func(ctx context.Context) error {
+			return deadlock
+		})
+
+		// Assert
+		assert.ErrorIs(t, err, context.DeadlineExceeded)
+		assert.Equal(t, 1, attempts)
+		assert.Less(t, time.Since(start), time.Second)
+	})
+}
+
+func TestRetrier_Backoff(t *testing.T) {
+	r := New(Policy{MaxAttempts: 10, BaseDelay: 10 * time.Millisecond, MaxDelay: 50 * time.Millisecond}, zaptest.NewLogger(t), nil)
+
+	for attempt := 1; attempt < 10; attempt++ {
+		delay := r.Backoff(attempt)
+		assert.Greater(t, delay, time.Duration(0))
+		assert.LessOrEqual(t, delay, 50*time.Millisecond)
+	}
+}
+
+func TestStats_Counters(t *testing.T) {
+	// Setup
+	stats := NewStats()
+	stats.Retried("LogGame", ClassDeadlock)
+	stats.Retried("LogGame", ClassDeadlock)
+	stats.Exhausted("LogGame", ClassLockWaitTimeout)
+
+	// Test
+	counters := stats.Counters()
+
+	// Assert
+	assert.Equal(t, map[string]int64{"LogGame." + string(ClassDeadlock): 2}, counters.Retried)
+	assert.Equal(t, map[string]int64{"LogGame." + string(ClassLockWaitTimeout): 1}, counters.Exhausted)
+	assert.Empty(t, NewStats().Counters().Retried)
+}
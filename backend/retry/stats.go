@@ -0,0 +1,48 @@
+package retry
+
+import (
+	"expvar"
+)
+
+// Stats - an Observer counting retries and give ups of this instance, keyed by "op.class"
+type Stats struct {
+	retried   *expvar.Map
+	exhausted *expvar.Map
+}
+
+func NewStats() *Stats {
+	return &Stats{
+		retried:   new(expvar.Map).Init(),
+		exhausted: new(expvar.Map).Init(),
+	}
+}
+
+func (s *Stats) Retried(op string, class Class) {
+	s.retried.Add(op+"."+string(class), 1)
+}
+
+func (s *Stats) Exhausted(op string, class Class) {
+	s.exhausted.Add(op+"."+string(class), 1)
+}
+
+// Counters - the counters of Stats at one moment
+type Counters struct {
+	Retried   map[string]int64 `json:"retried"`
+	Exhausted map[string]int64 `json:"exhausted"`
+}
+
+// Counters - a snapshot of the counters since the instance started
+func (s *Stats) Counters() Counters {
+	return Counters{Retried: snapshot(s.retried), Exhausted: snapshot(s.exhausted)}
+}
+
+func snapshot(counters *expvar.Map) map[string]int64 {
+	values := map[string]int64{}
+	counters.Do(func(kv expvar.KeyValue) {
+		if counter, ok := kv.Value.(*expvar.Int); ok {
+			values[kv.Key] = counter.Value()
+		}
+	})
+
+	return values
+}
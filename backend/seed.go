@@ -0,0 +1,56 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	_ "embed"
+	"encoding/json"
+	"flag"
+	"os"
+
+	"go.uber.org/zap"
+
+	audit_domain "skyhawk/backend/audit/domain"
+	"skyhawk/backend/config"
+	"skyhawk/backend/importer"
+)
+
+// demoSeason - box scores of four teams over six games, in the import csv format
+//
+//go:embed seed/demo.csv
+var demoSeason []byte
+
+// runSeed - the "seed" subcommand, imports the demo season so a fresh database has stats to show.
+// Every run logs the games again, seed an empty database
+func runSeed(cfg config.Config, logger *zap.Logger, args []string) error {
+	flags := flag.NewFlagSet("seed", flag.ContinueOnError)
+	dryRun := flags.Bool("dry-run", false, "validate and report without writing")
+	if err := flags.Parse(args); err != nil {
+		return err
+	}
+
+	opts := importer.DefaultOptions()
+	opts.DryRun = *dryRun
+
+	ctx := audit_domain.WithMeta(context.Background(), audit_domain.Meta{Actor: "cli:seed"})
+
+	var games importer.GameLogger
+	if !opts.DryRun {
+		app, err := newApp(cfg, logger)
+		if err != nil {
+			return err
+		}
+		defer app.Close()
+		games = app.service
+	}
+
+	report, err := importer.New(games, logger).Run(ctx, bytes.NewReader(demoSeason), opts)
+	if err != nil {
+		return err
+	}
+
+	encoder := json.NewEncoder(os.Stdout)
+	encoder.SetIndent("", "  ")
+
+	return encoder.Encode(report)
+}
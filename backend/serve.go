@@ -0,0 +1,252 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"os/signal"
+	"syscall"
+	"time"
+
+	"github.com/labstack/echo/v4"
+	echomiddleware "github.com/labstack/echo/v4/middleware"
+	"go.uber.org/zap"
+
+	audithandler "skyhawk/backend/audit/handler"
+	auth_domain "skyhawk/backend/auth/domain"
+	authhandler "skyhawk/backend/auth/handler"
+	"skyhawk/backend/config"
+	eventhandler "skyhawk/backend/event/handler"
+	exporthandler "skyhawk/backend/export/handler"
+	handler2 "skyhawk/backend/game/handler"
+	"skyhawk/backend/health"
+	healthhandler "skyhawk/backend/health/handler"
+	"skyhawk/backend/importer"
+	importhandler "skyhawk/backend/importer/handler"
+	logginghandler "skyhawk/backend/logging/handler"
+	"skyhawk/backend/middleware"
+	ratelimithandler "skyhawk/backend/ratelimit/handler"
+	retryhandler "skyhawk/backend/retry/handler"
+	streamhandler "skyhawk/backend/stream/handler"
+	"skyhawk/backend/tracing"
+	webhookhandler "skyhawk/backend/webhook/handler"
+)
+
+// runServe - the server, the default command. It returns once a signal stopped it and in-flight requests finished
+func runServe(cfg config.Config, logger *zap.Logger, logLevel zap.AtomicLevel) error {
+	logger.Info("starting with config", zap.Any("config", cfg.Redacted()))
+
+	proxies, err := cfg.Server.Proxies()
+	if err != nil {
+		return err
+	}
+
+	//spans of requests, usecases, repositories, sql statements and redis commands, exported to TRACING_EXPORTER
+	shutdownTracing, err := tracing.Setup(context.Background(), cfg.Tracing)
+	if err != nil {
+		return err
+	}
+
+	app, err := connect(cfg, logger)
+	if err != nil {
+		return err
+	}
+	defer app.Close()
+
+	//live updates, webhook deliveries and the outbox relay run on the servers only, they start once the DB is migrated
+	background, err := newWorkers(cfg, app)
+	if err != nil {
+		return err
+	}
+	if err = app.wire(cfg, background.notifier); err != nil {
+		_ = background.publisher.Close()
+		return err
+	}
+
+	//handler
+	handler := handler2.NewHandler(app.service, logger)
+	eventHandler := eventhandler.NewHandler(app.events, logger)
+	importHandler := importhandler.NewHandler(importer.New(app.service, logger), logger)
+	exportHandler := exporthandler.NewHandler(app.exporter, logger)
+	streamHandler := streamhandler.NewHandler(app.service, background.hub, cfg.Stream.Heartbeat, cfg.Stream.Origins(), logger)
+	webhookHandler := webhookhandler.NewHandler(app.webhooks, logger)
+	auditHandler := audithandler.NewHandler(app.audit, logger)
+	authHandler := authhandler.NewHandler(app.keys, logger)
+	usageHandler := ratelimithandler.NewHandler(app.limiter, logger)
+	logLevelHandler := logginghandler.NewHandler(logLevel, logger)
+	retryHandler := retryhandler.NewHandler(app.retries, logger)
+
+	//readiness degrades instead of failing on redis by default, the repos fall back to mysql without it
+	migrations := health.Migrations(app.migrations.Versions)
+	readiness := health.NewChecker(cfg.Health.Timeout, logger,
+		health.Check{Name: "mysql", Critical: true, Probe: app.db.PingContext},
+		health.Check{Name: "redis", Critical: cfg.Health.RedisCritical, Probe: func(ctx context.Context) error {
+			return app.redis.Ping(ctx).Err()
+		}},
+		migrations)
+	healthHandler := healthhandler.NewHandler(readiness, health.NewChecker(cfg.Health.Timeout, logger, migrations), logger)
+
+	//the api answers 503 starting until the DB is migrated, the probes and metrics answer right away
+	migrated := make(chan struct{})
+
+	e := echo.New()
+	e.HTTPErrorHandler = middleware.ErrorHandler(logger)
+	//the client address the rate limits and the audit log use, forwarded headers only count from SERVER_TRUSTED_PROXIES
+	e.IPExtractor = middleware.ClientIP(proxies)
+	e.Use(middleware.RequestID())
+	e.Use(middleware.Tracing())
+	e.Use(middleware.RequestLogger(logger))
+	e.Use(middleware.Metrics(app.metrics))
+	e.Use(middleware.Recover(logger))
+	e.Use(middleware.AuditMeta())
+
+	//probes for the orchestrator, outside the api so they need no key and are not rate limited
+	e.GET("/healthz", healthHandler.LivenessHandler)
+	e.GET("/readyz", healthHandler.ReadinessHandler)
+	e.GET("/startupz", healthHandler.StartupHandler)
+
+	//prometheus metrics, scraped without a key like the probes
+	e.GET("/metrics", echo.WrapHandler(app.metrics.Handler()))
+
+	//every api route needs an api key or a bearer token, each route requires a scope and admin holds them all.
+	//every ip is rate limited before its credential is checked, then every client, reads and writes separately, and has a daily quota
+	group := e.Group("api/v1", middleware.Starting(migrated), middleware.RateLimitIP(app.limiter), middleware.Authenticate(app.keys), middleware.RateLimit(app.limiter))
+	read := middleware.RequireScope(auth_domain.ScopeStatsRead)
+	write := middleware.RequireScope(auth_domain.ScopeGamesWrite)
+	admin := middleware.RequireScope(auth_domain.ScopeAdmin)
+
+	//per endpoint request deadlines
+	logGameTimeout := cfg.Timeouts.LogGame
+	batchTimeout := cfg.Timeouts.Batch
+	importTimeout := cfg.Timeouts.Import
+	exportTimeout := cfg.Timeouts.Export
+	statsTimeout := cfg.Timeouts.Stats
+
+	//request body limits, a larger body is rejected with 413 before it is read
+	bodyLimit := echomiddleware.BodyLimit(cfg.Server.BodyLimit)
+	batchBodyLimit := echomiddleware.BodyLimit(cfg.Server.BatchBodyLimit)
+	importBodyLimit := echomiddleware.BodyLimit(cfg.Server.ImportBodyLimit)
+
+	//game handler
+	group.Add(http.MethodPost, "/games/log", handler.GameLogHandler, write, bodyLimit, middleware.Timeout(logGameTimeout))
+	group.Add(http.MethodPost, "/games/batch", handler.GameBatchLogHandler, write, batchBodyLimit, middleware.Timeout(batchTimeout))
+	group.Add(http.MethodGet, "/games/:id", handler.GameStatsHandler, read, middleware.Timeout(statsTimeout))
+	group.Add(http.MethodPut, "/games/:id", handler.CorrectGameHandler, write, bodyLimit, middleware.Timeout(logGameTimeout))
+	group.Add(http.MethodPost, "/games/:id/void", handler.VoidGameHandler, admin, bodyLimit, middleware.Timeout(logGameTimeout))
+
+	//live game handler
+	group.Add(http.MethodPost, "/games", handler.ScheduleGameHandler, write, bodyLimit, middleware.Timeout(logGameTimeout))
+	group.Add(http.MethodPost, "/games/:id/start", handler.StartGameHandler, write, bodyLimit, middleware.Timeout(logGameTimeout))
+	group.Add(http.MethodPatch, "/games/:id/stats", handler.LiveUpdateHandler, write, bodyLimit, middleware.Timeout(logGameTimeout))
+	group.Add(http.MethodPost, "/games/:id/final", handler.FinalizeGameHandler, write, bodyLimit, middleware.Timeout(logGameTimeout))
+
+	//live feeds stay open for the whole game, no request deadline. Browsers cannot set headers on them,
+	//so they also take a stats:read only credential from the access_token query parameter or the skyhawk_token cookie
+	feed := []echo.MiddlewareFunc{middleware.Starting(migrated), middleware.RateLimitIP(app.limiter), middleware.AuthenticateFeed(app.keys), middleware.RateLimit(app.limiter), read}
+	e.Add(http.MethodGet, "/api/v1/games/:id/stream", streamHandler.SSEHandler, feed...)
+	e.Add(http.MethodGet, "/api/v1/games/:id/ws", streamHandler.WebSocketHandler, feed...)
+
+	//play by play handler
+	group.Add(http.MethodPost, "/games/:id/events", eventHandler.AppendEventsHandler, write, bodyLimit, middleware.Timeout(logGameTimeout))
+	group.Add(http.MethodGet, "/games/:id/events", eventHandler.ListEventsHandler, read, middleware.Timeout(statsTimeout))
+
+	//webhook handler
+	group.Add(http.MethodPost, "/webhooks", webhookHandler.CreateHandler, admin, bodyLimit, middleware.Timeout(statsTimeout))
+	group.Add(http.MethodGet, "/webhooks", webhookHandler.ListHandler, admin, middleware.Timeout(statsTimeout))
+	group.Add(http.MethodGet, "/webhooks/:id", webhookHandler.GetHandler, admin, middleware.Timeout(statsTimeout))
+	group.Add(http.MethodDelete, "/webhooks/:id", webhookHandler.DeleteHandler, admin, middleware.Timeout(statsTimeout))
+	group.Add(http.MethodGet, "/webhooks/:id/deliveries", webhookHandler.DeliveriesHandler, admin, middleware.Timeout(statsTimeout))
+	group.Add(http.MethodPost, "/webhooks/:id/deliveries/:delivery_id/retry", webhookHandler.RedeliverHandler, admin, bodyLimit, middleware.Timeout(statsTimeout))
+
+	//api key handler
+	group.Add(http.MethodPost, "/keys", authHandler.CreateKeyHandler, admin, bodyLimit, middleware.Timeout(statsTimeout))
+	group.Add(http.MethodGet, "/keys", authHandler.ListKeysHandler, admin, middleware.Timeout(statsTimeout))
+	group.Add(http.MethodDelete, "/keys/:id", authHandler.RevokeKeyHandler, admin, middleware.Timeout(statsTimeout))
+
+	//usage handler, any authenticated client may read its own usage
+	group.Add(http.MethodGet, "/usage", usageHandler.UsageHandler, middleware.Timeout(statsTimeout))
+
+	//log level handler, changes the level of this instance until it restarts
+	group.Add(http.MethodGet, "/log/level", logLevelHandler.GetLevelHandler, admin, middleware.Timeout(statsTimeout))
+	group.Add(http.MethodPut, "/log/level", logLevelHandler.SetLevelHandler, admin, bodyLimit, middleware.Timeout(statsTimeout))
+
+	//retry handler, the transaction retry counters of this instance
+	group.Add(http.MethodGet, "/debug/retries", retryHandler.StatsHandler, admin, middleware.Timeout(statsTimeout))
+
+	//audit handler
+	group.Add(http.MethodGet, "/audit", auditHandler.ListHandler, admin, middleware.Timeout(statsTimeout))
+
+	//import handler
+	group.Add(http.MethodPost, "/import", importHandler.ImportHandler, write, importBodyLimit, middleware.Timeout(importTimeout))
+
+	//export handler
+	group.Add(http.MethodGet, "/export/games", exportHandler.GamesExportHandler, read, middleware.Timeout(exportTimeout))
+	group.Add(http.MethodGet, "/export/players", exportHandler.PlayersExportHandler, read, middleware.Timeout(exportTimeout))
+	group.Add(http.MethodGet, "/export/teams", exportHandler.TeamsExportHandler, read, middleware.Timeout(exportTimeout))
+
+	//player handler
+	group.Add(http.MethodGet, "/players/season/:player_id", handler.PlayerSeasonStatsHandler, read, middleware.Timeout(statsTimeout))
+
+	//team handler
+
+	group.Add(http.MethodGet, "/teams/stats/season/:team_id", handler.TeamSeasonStatsHandler, read, middleware.Timeout(statsTimeout))
+
+	e.Server.ReadHeaderTimeout = cfg.Server.ReadHeaderTimeout
+	e.Server.ReadTimeout = cfg.Server.ReadTimeout
+	e.Server.WriteTimeout = cfg.Server.WriteTimeout
+	e.Server.IdleTimeout = cfg.Server.IdleTimeout
+	//shutdown waits for open live feeds, ending them lets it finish and their clients reconnect elsewhere
+	e.Server.RegisterOnShutdown(background.hub.Close)
+
+	ctx, stop := signal.NotifyContext(context.Background(), syscall.SIGINT, syscall.SIGTERM)
+	defer stop()
+
+	started := make(chan error, 1)
+	go func() {
+		started <- e.Start(cfg.Server.Addr)
+	}()
+
+	//migrate database with the migrations built into the binary, or only check it is migrated when MIGRATIONS_ON_START=check.
+	//the probes already listen, so /startupz and /readyz report the schema behind until this is done
+	if cfg.Migrations.OnStart == "check" {
+		err = app.migrations.Check(context.Background())
+	} else {
+		err = app.migrations.Run()
+	}
+	if err != nil {
+		_ = e.Close()
+		background.Close()
+		return err
+	}
+	close(migrated)
+	background.start(app.service)
+
+	var failed error
+	select {
+	case err = <-started:
+		if !errors.Is(err, http.ErrServerClosed) {
+			logger.Error("server failed", zap.Error(err))
+			failed = err
+		}
+	case <-ctx.Done():
+		//stop accepting connections and let in-flight requests finish within the deadline
+		logger.Info("shutting down", zap.Duration("timeout", cfg.Server.ShutdownTimeout))
+		shutdownCtx, cancel := context.WithTimeout(context.Background(), cfg.Server.ShutdownTimeout)
+		if err = e.Shutdown(shutdownCtx); err != nil {
+			logger.Warn("requests still in flight at the shutdown deadline", zap.Error(err))
+			_ = e.Close()
+		}
+		cancel()
+	}
+
+	background.Close()
+	//flush the spans still buffered, bounded so an unreachable collector does not hold the exit up
+	flushCtx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	if err = shutdownTracing(flushCtx); err != nil {
+		logger.Warn("failed flushing spans", zap.Error(err))
+	}
+	cancel()
+	logger.Info("stopped")
+
+	return failed
+}
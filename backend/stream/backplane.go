@@ -0,0 +1,121 @@
+package stream
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"time"
+
+	"github.com/redis/go-redis/v9"
+	"go.uber.org/zap"
+
+	"skyhawk/backend/retry"
+	"skyhawk/backend/stream/domain"
+)
+
+// Backplane - carries updates to the hub of every backend instance
+type Backplane interface {
+	Publish(ctx context.Context, update domain.Update) error
+	// Run - delivers updates published by any instance to hub until ctx is done
+	Run(ctx context.Context, hub *Hub) error
+}
+
+// LocalBackplane - a single instance, updates go straight to its own hub
+type LocalBackplane struct {
+	hub *Hub
+}
+
+func NewLocalBackplane(hub *Hub) *LocalBackplane {
+	return &LocalBackplane{hub: hub}
+}
+
+func (b *LocalBackplane) Publish(_ context.Context, update domain.Update) error {
+	b.hub.Deliver(update)
+
+	return nil
+}
+
+func (b *LocalBackplane) Run(ctx context.Context, _ *Hub) error {
+	<-ctx.Done()
+
+	return nil
+}
+
+// RedisBackplane - shares updates between instances over a redis pub/sub channel
+type RedisBackplane struct {
+	client  *redis.Client
+	channel string
+	backoff *retry.Retrier
+	logger  *zap.Logger
+}
+
+func NewRedisBackplane(client *redis.Client, channel string, logger *zap.Logger) *RedisBackplane {
+	return &RedisBackplane{
+		client:  client,
+		channel: channel,
+		backoff: retry.New(retry.Policy{BaseDelay: 100 * time.Millisecond, MaxDelay: 5 * time.Second}, logger, nil),
+		logger:  logger,
+	}
+}
+
+func (b *RedisBackplane) Publish(ctx context.Context, update domain.Update) error {
+	payload, err := json.Marshal(update)
+	if err != nil {
+		return err
+	}
+
+	return b.client.Publish(ctx, b.channel, payload).Err()
+}
+
+// Run - subscribes again with backoff whenever the subscription cannot be made or ends, until ctx is done.
+// Updates published while it is down are missed, the next update of a game carries its whole box score
+func (b *RedisBackplane) Run(ctx context.Context, hub *Hub) error {
+	failures := 0
+	for {
+		subscribed, err := b.subscribe(ctx, hub)
+		if ctx.Err() != nil {
+			return nil
+		}
+		if subscribed {
+			failures = 0
+		}
+		failures++
+		wait := b.backoff.Backoff(failures)
+		b.logger.Warn("game update subscription lost", zap.Int("failures", failures), zap.Duration("retry_in", wait), zap.Error(err))
+
+		select {
+		case <-ctx.Done():
+			return nil
+		case <-time.After(wait):
+		}
+	}
+}
+
+// subscribe - delivers updates to hub until the subscription ends or ctx is done, subscribed reports whether it was made
+func (b *RedisBackplane) subscribe(ctx context.Context, hub *Hub) (bool, error) {
+	pubsub := b.client.Subscribe(ctx, b.channel)
+	defer pubsub.Close()
+
+	// wait for the subscription so nothing published after Run starts is missed
+	if _, err := pubsub.Receive(ctx); err != nil {
+		return false, err
+	}
+
+	messages := pubsub.Channel()
+	for {
+		select {
+		case <-ctx.Done():
+			return true, nil
+		case message, ok := <-messages:
+			if !ok {
+				return true, errors.New("subscription closed")
+			}
+			var update domain.Update
+			if err := json.Unmarshal([]byte(message.Payload), &update); err != nil {
+				b.logger.Warn("dropping malformed game update", zap.Error(err))
+				continue
+			}
+			hub.Deliver(update)
+		}
+	}
+}
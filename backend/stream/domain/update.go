@@ -0,0 +1,37 @@
+package domain
+
+import (
+	"time"
+
+	game_domain "skyhawk/backend/game/domain"
+)
+
+const (
+	// EventBoxScore - the full box score after a change
+	EventBoxScore = "box_score"
+	// EventScore - team points changed, sent after the box score that changed them
+	EventScore = "score"
+	// EventPing - keeps idle connections open through proxies
+	EventPing = "ping"
+)
+
+// Update - the box score of a game after a change, carried to every instance by the backplane
+type Update struct {
+	GameID   string               `json:"game_id"`
+	BoxScore game_domain.BoxScore `json:"box_score"`
+	At       time.Time            `json:"at"`
+}
+
+// ScoreChange - points per team id before and after an update
+type ScoreChange struct {
+	GameID   string         `json:"game_id"`
+	Score    map[string]int `json:"score"`
+	Previous map[string]int `json:"previous"`
+}
+
+// Message - what a subscriber receives, Event names the type of Data
+type Message struct {
+	ID    uint64      `json:"id,omitempty"`
+	Event string      `json:"event"`
+	Data  interface{} `json:"data,omitempty"`
+}
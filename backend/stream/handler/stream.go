@@ -0,0 +1,193 @@
+package handler
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+
+	"github.com/labstack/echo/v4"
+	"go.uber.org/zap"
+	"golang.org/x/net/websocket"
+
+	game_domain "skyhawk/backend/game/domain"
+	"skyhawk/backend/stream"
+	"skyhawk/backend/stream/domain"
+)
+
+type Handler struct {
+	boxScores stream.BoxScores
+	hub       *stream.Hub
+	heartbeat time.Duration
+	origins   []string
+	logger    *zap.Logger
+}
+
+// NewHandler - origins are the origins, besides the api's own, whose pages may open websocket feeds
+func NewHandler(boxScores stream.BoxScores, hub *stream.Hub, heartbeat time.Duration, origins []string, logger *zap.Logger) *Handler {
+	return &Handler{boxScores: boxScores, hub: hub, heartbeat: heartbeat, origins: origins, logger: logger}
+}
+
+// open - subscribes before reading the current box score, so no update can fall between the two
+func (h *Handler) open(ctx context.Context, gameID string) (*stream.Subscription, domain.Message, error) {
+	sub := h.hub.Subscribe(gameID)
+
+	box, err := h.boxScores.GetBoxScore(ctx, gameID)
+	if err != nil {
+		sub.Close()
+		return nil, domain.Message{}, err
+	}
+
+	return sub, domain.Message{Event: domain.EventBoxScore, Data: domain.Update{GameID: gameID, BoxScore: box, At: time.Now().UTC()}}, nil
+}
+
+// SSEHandler - streams a game as server sent events until the client leaves or the game ends
+func (h *Handler) SSEHandler(c echo.Context) error {
+	ctx := c.Request().Context()
+
+	sub, first, err := h.open(ctx, c.Param("id"))
+	if err != nil {
+		return err
+	}
+	defer sub.Close()
+
+	res := c.Response()
+	res.Header().Set(echo.HeaderContentType, "text/event-stream")
+	res.Header().Set(echo.HeaderCacheControl, "no-cache")
+	res.Header().Set(echo.HeaderConnection, "keep-alive")
+	res.Header().Set("X-Accel-Buffering", "no")
+	res.WriteHeader(http.StatusOK)
+
+	return h.pump(ctx, sub, first, func(message domain.Message) error {
+		if err := writeEvent(res, message); err != nil {
+			return err
+		}
+		res.Flush()
+		return nil
+	})
+}
+
+// WebSocketHandler - streams a game as JSON messages over a websocket until the client leaves or the game ends
+func (h *Handler) WebSocketHandler(c echo.Context) error {
+	ctx := c.Request().Context()
+
+	sub, first, err := h.open(ctx, c.Param("id"))
+	if err != nil {
+		return err
+	}
+	defer sub.Close()
+
+	server := websocket.Server{
+		// the feed may be authenticated by a cookie, so pages of other sites must not open it on the visitor's behalf
+		Handshake: func(_ *websocket.Config, req *http.Request) error { return h.checkOrigin(req) },
+		Handler: func(ws *websocket.Conn) {
+			ctx, cancel := context.WithCancel(ctx)
+			defer cancel()
+
+			// the feed is one way, reading only notices the client going away
+			go func() {
+				defer cancel()
+				var discard string
+				for {
+					if err := websocket.Message.Receive(ws, &discard); err != nil {
+						return
+					}
+				}
+			}()
+
+			if err := h.pump(ctx, sub, first, func(message domain.Message) error {
+				return websocket.JSON.Send(ws, message)
+			}); err != nil {
+				h.logger.Debug("websocket closed", zap.Error(err))
+			}
+		},
+	}
+	server.ServeHTTP(c.Response(), c.Request())
+
+	return nil
+}
+
+// checkOrigin - allows clients sending no Origin (not browsers), the api's own origin and the configured ones
+func (h *Handler) checkOrigin(req *http.Request) error {
+	origin := req.Header.Get(echo.HeaderOrigin)
+	if origin == "" {
+		return nil
+	}
+	if u, err := url.Parse(origin); err == nil && u.Host == req.Host {
+		return nil
+	}
+	for _, allowed := range h.origins {
+		if allowed == "*" || strings.EqualFold(allowed, origin) {
+			return nil
+		}
+	}
+
+	h.logger.Warn("websocket feed refused for origin", zap.String("origin", origin))
+	return fmt.Errorf("origin %q is not allowed", origin)
+}
+
+// pump - writes first and then every message of sub, with pings while idle
+func (h *Handler) pump(ctx context.Context, sub *stream.Subscription, first domain.Message, write func(domain.Message) error) error {
+	if err := write(first); err != nil {
+		return err
+	}
+	if ended(first) {
+		return nil
+	}
+
+	ticker := time.NewTicker(h.heartbeat)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return nil
+		case <-ticker.C:
+			if err := write(domain.Message{Event: domain.EventPing}); err != nil {
+				return err
+			}
+		case message, ok := <-sub.C:
+			if !ok {
+				return nil
+			}
+			if err := write(message); err != nil {
+				return err
+			}
+			if ended(message) {
+				return nil
+			}
+		}
+	}
+}
+
+// ended - a final or voided game changes no more, its feed ends after announcing it
+func ended(message domain.Message) bool {
+	update, ok := message.Data.(domain.Update)
+
+	return ok && (update.BoxScore.Status == game_domain.StatusFinal || update.BoxScore.Status == game_domain.StatusVoided)
+}
+
+func writeEvent(w io.Writer, message domain.Message) error {
+	if message.Event == domain.EventPing {
+		_, err := io.WriteString(w, ": ping\n\n")
+		return err
+	}
+
+	data, err := json.Marshal(message.Data)
+	if err != nil {
+		return err
+	}
+
+	if message.ID > 0 {
+		if _, err = fmt.Fprintf(w, "id: %d\n", message.ID); err != nil {
+			return err
+		}
+	}
+	_, err = fmt.Fprintf(w, "event: %s\ndata: %s\n\n", message.Event, data)
+
+	return err
+}
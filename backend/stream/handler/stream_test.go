@@ -0,0 +1,138 @@
+package handler
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/labstack/echo/v4"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap/zaptest"
+
+	game_domain "skyhawk/backend/game/domain"
+	"skyhawk/backend/stream"
+	"skyhawk/backend/stream/domain"
+)
+
+// fakeBoxScores - answers with a game in status and tells opened once the feed has subscribed
+type fakeBoxScores struct {
+	status game_domain.Status
+	opened chan struct{}
+}
+
+func (f *fakeBoxScores) GetBoxScore(_ context.Context, id string) (game_domain.BoxScore, error) {
+	defer close(f.opened)
+	return game_domain.BoxScore{Game: game_domain.Game{ID: id, Status: f.status}, Lines: []game_domain.GameStats{}}, nil
+}
+
+// serveSSE - runs the SSE feed of g1 in the background, the returned channel is closed when the feed ends
+func serveSSE(t *testing.T, hub *stream.Hub, boxScores *fakeBoxScores) (*httptest.ResponseRecorder, chan struct{}) {
+	t.Helper()
+	handler := NewHandler(boxScores, hub, time.Hour, nil, zaptest.NewLogger(t))
+
+	e := echo.New()
+	rec := httptest.NewRecorder()
+	ctx, cancel := context.WithCancel(context.Background())
+	t.Cleanup(cancel)
+	c := e.NewContext(httptest.NewRequest(http.MethodGet, "/api/v1/games/g1/stream", nil).WithContext(ctx), rec)
+	c.SetParamNames("id")
+	c.SetParamValues("g1")
+
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		assert.NoError(t, handler.SSEHandler(c))
+	}()
+
+	return rec, done
+}
+
+func waitEnded(t *testing.T, done chan struct{}) {
+	t.Helper()
+	select {
+	case <-done:
+	case <-time.After(time.Second):
+		t.Fatal("the feed did not end")
+	}
+}
+
+func TestHandler_SSEHandler(t *testing.T) {
+	t.Run("ends when the game is finalized", func(t *testing.T) {
+		// Setup
+		hub := stream.NewHub(8)
+		boxScores := &fakeBoxScores{status: game_domain.StatusLive, opened: make(chan struct{})}
+		rec, done := serveSSE(t, hub, boxScores)
+		<-boxScores.opened
+
+		// Test
+		hub.Deliver(domain.Update{GameID: "g1", BoxScore: game_domain.BoxScore{Game: game_domain.Game{ID: "g1", Status: game_domain.StatusFinal}}})
+
+		// Assert
+		waitEnded(t, done)
+		assert.Equal(t, 2, strings.Count(rec.Body.String(), "event: box_score"))
+		assert.Contains(t, rec.Body.String(), `"status":"final"`)
+	})
+
+	t.Run("ends when the game is voided", func(t *testing.T) {
+		// Setup
+		hub := stream.NewHub(8)
+		boxScores := &fakeBoxScores{status: game_domain.StatusLive, opened: make(chan struct{})}
+		rec, done := serveSSE(t, hub, boxScores)
+		<-boxScores.opened
+
+		// Test
+		hub.Deliver(domain.Update{GameID: "g1", BoxScore: game_domain.BoxScore{Game: game_domain.Game{ID: "g1", Status: game_domain.StatusVoided}}})
+
+		// Assert
+		waitEnded(t, done)
+		assert.Equal(t, 2, strings.Count(rec.Body.String(), "event: box_score"))
+		assert.Contains(t, rec.Body.String(), `"status":"voided"`)
+	})
+
+	t.Run("a voided game sends its box score and ends", func(t *testing.T) {
+		// Setup
+		hub := stream.NewHub(8)
+		boxScores := &fakeBoxScores{status: game_domain.StatusVoided, opened: make(chan struct{})}
+
+		// Test
+		rec, done := serveSSE(t, hub, boxScores)
+
+		// Assert
+		waitEnded(t, done)
+		require.Equal(t, 1, strings.Count(rec.Body.String(), "event: box_score"))
+		assert.Contains(t, rec.Body.String(), `"status":"voided"`)
+	})
+}
+
+func TestHandler_checkOrigin(t *testing.T) {
+	handler := NewHandler(nil, nil, time.Hour, []string{"https://scores.example.com"}, zaptest.NewLogger(t))
+	cases := []struct {
+		name    string
+		origin  string
+		allowed bool
+	}{
+		{name: "no origin", origin: "", allowed: true},
+		{name: "own origin", origin: "http://api.example.com", allowed: true},
+		{name: "configured origin", origin: "https://scores.example.com", allowed: true},
+		{name: "other site", origin: "https://evil.example.net", allowed: false},
+	}
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			// Setup
+			req := httptest.NewRequest(http.MethodGet, "http://api.example.com/api/v1/games/g1/ws", nil)
+			if tc.origin != "" {
+				req.Header.Set(echo.HeaderOrigin, tc.origin)
+			}
+
+			// Test
+			err := handler.checkOrigin(req)
+
+			// Assert
+			assert.Equal(t, tc.allowed, err == nil)
+		})
+	}
+}
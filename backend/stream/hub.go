@@ -0,0 +1,157 @@
+package stream
+
+import (
+	"sync"
+
+	game_domain "skyhawk/backend/game/domain"
+	"skyhawk/backend/stream/domain"
+)
+
+// Hub - fans updates out to the subscribers of a game within this instance
+type Hub struct {
+	mu     sync.Mutex
+	subs   map[string]map[*Subscription]struct{}
+	scores map[string]map[string]int
+	buffer int
+	seq    uint64
+	closed bool
+}
+
+// Subscription - receives the messages of one game until closed
+type Subscription struct {
+	C      <-chan domain.Message
+	ch     chan domain.Message
+	gameID string
+	hub    *Hub
+}
+
+// NewHub - buffer is the number of messages a subscriber may fall behind before the oldest are dropped
+func NewHub(buffer int) *Hub {
+	if buffer < 2 {
+		buffer = 2
+	}
+
+	return &Hub{
+		subs:   make(map[string]map[*Subscription]struct{}),
+		scores: make(map[string]map[string]int),
+		buffer: buffer,
+	}
+}
+
+func (h *Hub) Subscribe(gameID string) *Subscription {
+	ch := make(chan domain.Message, h.buffer)
+	sub := &Subscription{C: ch, ch: ch, gameID: gameID, hub: h}
+
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	if h.closed {
+		close(ch)
+		return sub
+	}
+	if h.subs[gameID] == nil {
+		h.subs[gameID] = make(map[*Subscription]struct{})
+	}
+	h.subs[gameID][sub] = struct{}{}
+
+	return sub
+}
+
+// Close - stops the subscription and closes C, safe to call more than once
+func (s *Subscription) Close() {
+	h := s.hub
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	subs, ok := h.subs[s.gameID]
+	if !ok {
+		return
+	}
+	if _, ok = subs[s]; !ok {
+		return
+	}
+	delete(subs, s)
+	close(s.ch)
+	if len(subs) == 0 {
+		delete(h.subs, s.gameID)
+	}
+}
+
+// Close - ends every subscription so live feeds finish and their clients reconnect to another instance,
+// the server waits for open feeds on shutdown otherwise. Subscriptions opened afterwards end right away
+func (h *Hub) Close() {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	h.closed = true
+	for gameID, subs := range h.subs {
+		for sub := range subs {
+			close(sub.ch)
+		}
+		delete(h.subs, gameID)
+	}
+}
+
+// Subscribers - the number of open subscriptions of a game
+func (h *Hub) Subscribers(gameID string) int {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	return len(h.subs[gameID])
+}
+
+// Deliver - sends the box score of update to the game's subscribers, followed by a score message when team points changed
+func (h *Hub) Deliver(update domain.Update) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	score := update.BoxScore.Score()
+	previous := h.scores[update.GameID]
+	// a finished game has no score to compare against any more
+	if update.BoxScore.Status == game_domain.StatusFinal || update.BoxScore.Status == game_domain.StatusVoided {
+		delete(h.scores, update.GameID)
+	} else {
+		h.scores[update.GameID] = score
+	}
+
+	h.seq++
+	messages := []domain.Message{{ID: h.seq, Event: domain.EventBoxScore, Data: update}}
+	if !sameScore(previous, score) {
+		h.seq++
+		messages = append(messages, domain.Message{ID: h.seq, Event: domain.EventScore, Data: domain.ScoreChange{GameID: update.GameID, Score: score, Previous: previous}})
+	}
+
+	for sub := range h.subs[update.GameID] {
+		for _, message := range messages {
+			sub.send(message)
+		}
+	}
+}
+
+// send - never blocks the hub, a subscriber that fell behind loses its oldest message, the latest box score always arrives
+func (s *Subscription) send(message domain.Message) {
+	for {
+		select {
+		case s.ch <- message:
+			return
+		default:
+		}
+
+		select {
+		case <-s.ch:
+		default:
+		}
+	}
+}
+
+func sameScore(a, b map[string]int) bool {
+	if len(a) != len(b) {
+		return false
+	}
+	for team, points := range a {
+		if other, ok := b[team]; !ok || other != points {
+			return false
+		}
+	}
+
+	return true
+}
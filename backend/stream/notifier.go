@@ -0,0 +1,85 @@
+package stream
+
+import (
+	"context"
+	"sync"
+	"time"
+
+	"go.uber.org/zap"
+
+	game_domain "skyhawk/backend/game/domain"
+	"skyhawk/backend/stream/domain"
+)
+
+// BoxScores - loads the current box score of a game
+type BoxScores interface {
+	GetBoxScore(ctx context.Context, id string) (game_domain.BoxScore, error)
+}
+
+// Notifier - publishes the box score of every updated game without holding up the request that changed it.
+// A game is queued at most once, it is loaded when its turn comes so subscribers always end on the latest state.
+type Notifier struct {
+	backplane Backplane
+	queue     chan string
+	mu        sync.Mutex
+	pending   map[string]bool
+	timeout   time.Duration
+	logger    *zap.Logger
+}
+
+func NewNotifier(backplane Backplane, logger *zap.Logger) *Notifier {
+	return &Notifier{
+		backplane: backplane,
+		queue:     make(chan string, 1024),
+		pending:   make(map[string]bool),
+		timeout:   5 * time.Second,
+		logger:    logger,
+	}
+}
+
+func (n *Notifier) GameUpdated(_ context.Context, gameID string) {
+	n.mu.Lock()
+	defer n.mu.Unlock()
+
+	if n.pending[gameID] {
+		return
+	}
+
+	select {
+	case n.queue <- gameID:
+		n.pending[gameID] = true
+	default:
+		n.logger.Warn("game update queue is full, dropping update", zap.String("game_id", gameID))
+	}
+}
+
+// Run - publishes queued games until ctx is done
+func (n *Notifier) Run(ctx context.Context, boxScores BoxScores) {
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case gameID := <-n.queue:
+			n.mu.Lock()
+			delete(n.pending, gameID)
+			n.mu.Unlock()
+
+			n.publish(ctx, boxScores, gameID)
+		}
+	}
+}
+
+func (n *Notifier) publish(ctx context.Context, boxScores BoxScores, gameID string) {
+	ctx, cancel := context.WithTimeout(ctx, n.timeout)
+	defer cancel()
+
+	box, err := boxScores.GetBoxScore(ctx, gameID)
+	if err != nil {
+		n.logger.Warn("failed loading box score for subscribers", zap.String("game_id", gameID), zap.Error(err))
+		return
+	}
+
+	if err = n.backplane.Publish(ctx, domain.Update{GameID: gameID, BoxScore: box, At: time.Now().UTC()}); err != nil {
+		n.logger.Warn("failed publishing game update", zap.String("game_id", gameID), zap.Error(err))
+	}
+}
@@ -0,0 +1,274 @@
+package stream
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/alicebob/miniredis/v2"
+	"github.com/redis/go-redis/v9"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap/zaptest"
+
+	game_domain "skyhawk/backend/game/domain"
+	"skyhawk/backend/retry"
+	"skyhawk/backend/stream/domain"
+)
+
+func update(gameID string, status game_domain.Status, points ...int) domain.Update {
+	box := game_domain.BoxScore{Game: game_domain.Game{ID: gameID, Status: status}}
+	for i, p := range points {
+		team := "home"
+		if i%2 == 1 {
+			team = "away"
+		}
+		box.Lines = append(box.Lines, game_domain.GameStats{ID: gameID, TeamID: team, Points: p})
+	}
+
+	return domain.Update{GameID: gameID, BoxScore: box}
+}
+
+func receive(t *testing.T, sub *Subscription) domain.Message {
+	t.Helper()
+	select {
+	case message := <-sub.C:
+		return message
+	case <-time.After(time.Second):
+		t.Fatal("no message received")
+		return domain.Message{}
+	}
+}
+
+func TestHub_Deliver(t *testing.T) {
+	t.Run("box score then score change", func(t *testing.T) {
+		// Setup
+		hub := NewHub(8)
+		sub := hub.Subscribe("g1")
+		other := hub.Subscribe("g2")
+		defer sub.Close()
+		defer other.Close()
+
+		// Test
+		hub.Deliver(update("g1", game_domain.StatusLive, 2, 0))
+		hub.Deliver(update("g1", game_domain.StatusLive, 2, 0))
+
+		// Assert
+		first := receive(t, sub)
+		assert.Equal(t, domain.EventBoxScore, first.Event)
+		change := receive(t, sub)
+		assert.Equal(t, domain.EventScore, change.Event)
+		assert.Equal(t, map[string]int{"home": 2, "away": 0}, change.Data.(domain.ScoreChange).Score)
+		assert.Greater(t, change.ID, first.ID)
+
+		// the same score again only sends the box score
+		assert.Equal(t, domain.EventBoxScore, receive(t, sub).Event)
+		assert.Empty(t, sub.C)
+		assert.Empty(t, other.C)
+	})
+
+	t.Run("finished games forget their score", func(t *testing.T) {
+		// Setup
+		hub := NewHub(8)
+
+		// Test
+		for _, status := range []game_domain.Status{game_domain.StatusFinal, game_domain.StatusVoided} {
+			hub.Deliver(update("g1", game_domain.StatusLive, 2, 0))
+			hub.Deliver(update("g1", status, 2, 0))
+
+			// Assert
+			assert.Empty(t, hub.scores, status)
+		}
+	})
+
+	t.Run("slow subscriber keeps the latest", func(t *testing.T) {
+		// Setup
+		hub := NewHub(2)
+		sub := hub.Subscribe("g1")
+		defer sub.Close()
+
+		// Test
+		for points := 1; points <= 5; points++ {
+			hub.Deliver(update("g1", game_domain.StatusLive, points))
+		}
+
+		// Assert
+		assert.Len(t, sub.C, 2)
+		receive(t, sub)
+		last := receive(t, sub)
+		assert.Equal(t, map[string]int{"home": 5}, last.Data.(domain.ScoreChange).Score)
+	})
+
+	t.Run("close", func(t *testing.T) {
+		// Setup
+		hub := NewHub(8)
+		sub := hub.Subscribe("g1")
+
+		// Test
+		sub.Close()
+		sub.Close()
+		hub.Deliver(update("g1", game_domain.StatusLive, 2))
+
+		// Assert
+		_, open := <-sub.C
+		assert.False(t, open)
+		assert.Equal(t, 0, hub.Subscribers("g1"))
+	})
+
+	t.Run("hub close ends every subscription", func(t *testing.T) {
+		// Setup
+		hub := NewHub(8)
+		sub := hub.Subscribe("g1")
+		other := hub.Subscribe("g2")
+
+		// Test
+		hub.Close()
+		sub.Close()
+		late := hub.Subscribe("g1")
+
+		// Assert
+		for _, s := range []*Subscription{sub, other, late} {
+			_, open := <-s.C
+			assert.False(t, open)
+		}
+		assert.Equal(t, 0, hub.Subscribers("g1"))
+	})
+}
+
+func TestRedisBackplane(t *testing.T) {
+	// Setup
+	server := miniredis.RunT(t)
+	logger := zaptest.NewLogger(t)
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	// two instances sharing one redis
+	hubs := []*Hub{NewHub(8), NewHub(8)}
+	var backplanes []*RedisBackplane
+	var wg sync.WaitGroup
+	for _, hub := range hubs {
+		client := redis.NewClient(&redis.Options{Addr: server.Addr()})
+		t.Cleanup(func() { client.Close() })
+		backplane := NewRedisBackplane(client, "game_updates", logger)
+		backplanes = append(backplanes, backplane)
+
+		wg.Add(1)
+		go func(hub *Hub) {
+			defer wg.Done()
+			assert.NoError(t, backplane.Run(ctx, hub))
+		}(hub)
+	}
+	subs := []*Subscription{hubs[0].Subscribe("g1"), hubs[1].Subscribe("g1")}
+
+	// Test
+	require.Eventually(t, func() bool {
+		return len(server.PubSubChannels("game_updates")) == 1 && server.PubSubNumSub("game_updates")["game_updates"] == 2
+	}, time.Second, 10*time.Millisecond)
+	require.NoError(t, backplanes[0].Publish(ctx, update("g1", game_domain.StatusLive, 3)))
+
+	// Assert
+	for _, sub := range subs {
+		message := receive(t, sub)
+		assert.Equal(t, domain.EventBoxScore, message.Event)
+		assert.Equal(t, 3, message.Data.(domain.Update).BoxScore.Lines[0].Points)
+	}
+
+	cancel()
+	wg.Wait()
+}
+
+func TestRedisBackplane_Resubscribes(t *testing.T) {
+	// Setup - redis is down when the backplane starts
+	server := miniredis.RunT(t)
+	addr := server.Addr()
+	server.Close()
+	logger := zaptest.NewLogger(t)
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	hub := NewHub(8)
+	client := redis.NewClient(&redis.Options{Addr: addr, MaxRetries: -1})
+	t.Cleanup(func() { client.Close() })
+	backplane := NewRedisBackplane(client, "game_updates", logger)
+	backplane.backoff = retry.New(retry.Policy{BaseDelay: time.Millisecond, MaxDelay: 10 * time.Millisecond}, logger, nil)
+
+	done := make(chan error, 1)
+	go func() { done <- backplane.Run(ctx, hub) }()
+	sub := hub.Subscribe("g1")
+
+	// Test
+	time.Sleep(30 * time.Millisecond)
+	require.NoError(t, server.Restart())
+	require.Eventually(t, func() bool {
+		return server.PubSubNumSub("game_updates")["game_updates"] == 1
+	}, time.Second, 10*time.Millisecond)
+	require.NoError(t, backplane.Publish(ctx, update("g1", game_domain.StatusLive, 4)))
+
+	// Assert
+	message := receive(t, sub)
+	assert.Equal(t, 4, message.Data.(domain.Update).BoxScore.Lines[0].Points)
+	cancel()
+	assert.NoError(t, <-done)
+}
+
+type fakeBoxScores struct {
+	mu    sync.Mutex
+	calls map[string]int
+	fail  bool
+}
+
+func (f *fakeBoxScores) GetBoxScore(_ context.Context, id string) (game_domain.BoxScore, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.calls[id]++
+	if f.fail {
+		return game_domain.BoxScore{}, errors.New("boom")
+	}
+	return update(id, game_domain.StatusLive, f.calls[id]).BoxScore, nil
+}
+
+func TestNotifier(t *testing.T) {
+	t.Run("coalesces queued updates", func(t *testing.T) {
+		// Setup
+		hub := NewHub(8)
+		sub := hub.Subscribe("g1")
+		defer sub.Close()
+		notifier := NewNotifier(NewLocalBackplane(hub), zaptest.NewLogger(t))
+		boxScores := &fakeBoxScores{calls: map[string]int{}}
+
+		// Test
+		notifier.GameUpdated(context.Background(), "g1")
+		notifier.GameUpdated(context.Background(), "g1")
+		notifier.GameUpdated(context.Background(), "g1")
+
+		ctx, cancel := context.WithCancel(context.Background())
+		done := make(chan struct{})
+		go func() {
+			notifier.Run(ctx, boxScores)
+			close(done)
+		}()
+
+		// Assert
+		message := receive(t, sub)
+		assert.Equal(t, "g1", message.Data.(domain.Update).GameID)
+		cancel()
+		<-done
+		assert.Equal(t, 1, boxScores.calls["g1"])
+	})
+
+	t.Run("failed load publishes nothing", func(t *testing.T) {
+		// Setup
+		hub := NewHub(8)
+		sub := hub.Subscribe("g1")
+		defer sub.Close()
+		notifier := NewNotifier(NewLocalBackplane(hub), zaptest.NewLogger(t))
+
+		// Test
+		notifier.publish(context.Background(), &fakeBoxScores{calls: map[string]int{}, fail: true}, "g1")
+
+		// Assert
+		assert.Empty(t, sub.C)
+	})
+}
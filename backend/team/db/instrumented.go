@@ -0,0 +1,59 @@
+package db
+
+import (
+	"context"
+	"database/sql"
+
+	"skyhawk/backend/metrics"
+	"skyhawk/backend/team/domain"
+	"skyhawk/backend/tracing"
+)
+
+// Instrumented - a Repository tracing every method and recording its latency, outcome and cache hits
+type Instrumented struct {
+	next    Repository
+	metrics *metrics.Metrics
+}
+
+func NewInstrumented(next Repository, metrics *metrics.Metrics) Repository {
+	return &Instrumented{next: next, metrics: metrics}
+}
+
+// observe - a span and a latency observation around method, done ends both
+func (i *Instrumented) observe(ctx context.Context, method string) (context.Context, func(err error)) {
+	ctx, span := tracing.Start(ctx, "team_db."+method)
+	ctx, done := i.metrics.Query(ctx, "team", method)
+
+	return ctx, func(err error) {
+		done(err)
+		tracing.End(span, err)
+	}
+}
+
+func (i *Instrumented) Save(ctx context.Context, tx *sql.Tx, team domain.Team) (id string, created bool, err error) {
+	ctx, done := i.observe(ctx, "Save")
+	defer func() { done(err) }()
+
+	return i.next.Save(ctx, tx, team)
+}
+
+func (i *Instrumented) Remember(ctx context.Context, teams []domain.Team) (err error) {
+	ctx, done := i.observe(ctx, "Remember")
+	defer func() { done(err) }()
+
+	return i.next.Remember(ctx, teams)
+}
+
+func (i *Instrumented) Find(ctx context.Context, id string) (team domain.Team, err error) {
+	ctx, done := i.observe(ctx, "Find")
+	defer func() { done(err) }()
+
+	return i.next.Find(ctx, id)
+}
+
+func (i *Instrumented) GetStats(ctx context.Context, id string) (stats domain.SeasonStats, err error) {
+	ctx, done := i.observe(ctx, "GetStats")
+	defer func() { done(err) }()
+
+	return i.next.GetStats(ctx, id)
+}
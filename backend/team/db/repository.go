@@ -10,15 +10,18 @@ import (
 	"github.com/redis/go-redis/v9"
 	"go.uber.org/zap"
 
+	"skyhawk/backend/apperror"
+	"skyhawk/backend/logging"
 	"skyhawk/backend/team/domain"
 )
 
 const timeTtl = time.Minute * 5
 
 type Repository interface {
-	Save(ctx context.Context, tx *sql.Tx, team domain.Team) (string, error)
-	Find(id string) (domain.Team, error)
-	GetStats(id string) (domain.SeasonStats, error)
+	Save(ctx context.Context, tx *sql.Tx, team domain.Team) (string, bool, error)
+	Remember(ctx context.Context, teams []domain.Team) error
+	Find(ctx context.Context, id string) (domain.Team, error)
+	GetStats(ctx context.Context, id string) (domain.SeasonStats, error)
 }
 
 type Repo struct {
@@ -36,70 +39,78 @@ func New(db *sqlx.DB, redis *redis.Client, logger *zap.Logger) Repository {
 	}
 }
 
-func (r *Repo) Save(ctx context.Context, tx *sql.Tx, team domain.Team) (string, error) {
+// Save - the id of the team named team.Name, created reports whether it was inserted by tx.
+// Nothing is cached here, tx may still roll back, the caller remembers the ids once it commits
+func (r *Repo) Save(ctx context.Context, tx *sql.Tx, team domain.Team) (string, bool, error) {
 
 	//check if exists in redis - to reduce lattency and db overload
 	// Check if exists in Redis
 	res, err := r.redis.Get(ctx, team.Name).Result()
 	// Key exists in Redis
 	if err == nil {
-		return res, nil
+		return res, false, nil
 	}
 
 	if err != redis.Nil {
 		// Unexpected Redis error
-		r.logger.Warn("Redis error", zap.Error(err), zap.String("team", team.Name))
+		logging.From(ctx, r.logger).Warn("Redis error", zap.Error(err), zap.String("team", team.Name))
 	}
 
-	r.logger.Info("Redis miss, checking DB", zap.String("team", team.Name))
+	logging.From(ctx, r.logger).Debug("Redis miss, checking DB", zap.String("team", team.Name))
 
 	// Check if exists in DB
-	row, err := r.db.Query("SELECT id, name FROM teams WHERE name = ?", team.Name)
+	row, err := r.db.QueryContext(ctx, "SELECT id, name FROM teams WHERE name = ?", team.Name)
 	if err != nil {
-		return "", err
+		return "", false, apperror.FromDB(err, nil)
 	}
 	defer row.Close() // Ensure rows are closed
 
 	if !row.Next() {
-		// Team doesn't exist, create new
+		// Team doesn't exist, create new. A concurrent request may be creating it too,
+		// the upsert waits for it and keeps its row, so the stored id tells who created the team
 		id := uuid.New().String()
-		_, err = tx.Exec("INSERT INTO teams (id, name) VALUES (?,?)", id, team.Name)
+		_, err = tx.ExecContext(ctx, "INSERT INTO teams (id, name) VALUES (?,?) ON DUPLICATE KEY UPDATE id = id", id, team.Name)
 		if err != nil {
-			r.logger.Error("Failed inserting team", zap.Error(err))
-			return "", err
+			logging.From(ctx, r.logger).Error("Failed inserting team", zap.Error(err))
+			return "", false, apperror.FromDB(err, nil)
 		}
 
-		// Set in Redis
-		err = r.redis.Set(ctx, team.Name, id, timeTtl).Err()
-		if err != nil {
-			r.logger.Warn("Failed inserting to Redis", zap.Error(err))
+		// a locking read sees the row committed by the other request, which a consistent read of tx may not
+		var stored string
+		if err = tx.QueryRowContext(ctx, "SELECT id FROM teams WHERE name = ? FOR UPDATE", team.Name).Scan(&stored); err != nil {
+			return "", false, apperror.FromDB(err, nil)
 		}
 
-		return id, nil
+		return stored, stored == id, nil
 	}
 
 	// Team exists, get ID from DB
 	var teamDB Team
 	if err = row.Scan(&teamDB.ID, &teamDB.Name); err != nil {
-		return "", err
+		return "", false, apperror.FromDB(err, nil)
 	}
 
-	// Set in Redis
-	err = r.redis.Set(ctx, team.Name, teamDB.ID, timeTtl).Err()
-	if err != nil {
-		r.logger.Warn("Failed inserting to Redis", zap.Error(err))
+	return teamDB.ID, false, nil
+}
+
+// Remember - caches the ids of teams by name, only ids of committed rows may be remembered
+func (r *Repo) Remember(ctx context.Context, teams []domain.Team) error {
+	pipe := r.redis.Pipeline()
+	for _, team := range teams {
+		pipe.Set(ctx, team.Name, team.ID, timeTtl)
 	}
+	_, err := pipe.Exec(ctx)
 
-	return teamDB.ID, nil
+	return err
 }
 
-func (r *Repo) Find(id string) (domain.Team, error) {
+func (r *Repo) Find(ctx context.Context, id string) (domain.Team, error) {
 	var teamDB Team
 
-	row := r.db.QueryRow("select id, name  from teams where name = ?", id)
+	row := r.db.QueryRowContext(ctx, "select id, name  from teams where name = ?", id)
 
 	if err := row.Scan(&teamDB.ID, &teamDB.Name); err != nil {
-		return domain.Team{}, err
+		return domain.Team{}, apperror.FromDB(err, apperror.NotFound("team_not_found", "team not found", nil))
 	}
 
 	return domain.Team{
@@ -108,10 +119,10 @@ func (r *Repo) Find(id string) (domain.Team, error) {
 	}, nil
 }
 
-func (r *Repo) GetStats(id string) (domain.SeasonStats, error) {
+func (r *Repo) GetStats(ctx context.Context, id string) (domain.SeasonStats, error) {
 	var seasonDB SeasonStats
 
-	row := r.db.QueryRow("select team_id, team_name, avg_rebounds, avg_assists, avg_steals, avg_blocks, avg_fouls, avg_turnovers, avg_minutes_played  from player_season_stats where team_id = ?", id)
+	row := r.db.QueryRowContext(ctx, "select team_id, team_name, avg_rebounds, avg_assists, avg_steals, avg_blocks, avg_fouls, avg_turnovers, avg_minutes_played  from player_season_stats where team_id = ?", id)
 
 	if err := row.Scan(
 		&seasonDB.TeamID,
@@ -124,7 +135,7 @@ func (r *Repo) GetStats(id string) (domain.SeasonStats, error) {
 		&seasonDB.AvgTurnovers,
 		&seasonDB.AvgMinutesPlayed,
 	); err != nil {
-		return domain.SeasonStats{}, err
+		return domain.SeasonStats{}, apperror.FromDB(err, apperror.NotFound("team_not_found", "team not found", nil))
 	}
 
 	return toDomain(seasonDB), nil
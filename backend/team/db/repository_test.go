@@ -3,6 +3,7 @@ package db
 import (
 	"context"
 	"database/sql"
+	"database/sql/driver"
 	"errors"
 	"github.com/DATA-DOG/go-sqlmock"
 	"github.com/alicebob/miniredis/v2"
@@ -14,6 +15,7 @@ import (
 	"go.uber.org/zap/zaptest"
 	"skyhawk/backend/team/domain"
 	"testing"
+	"time"
 )
 
 func TestRepo_New(t *testing.T) {
@@ -49,11 +51,12 @@ func TestRepo_Save(t *testing.T) {
 		require.NoError(t, rdb.Set(ctx, teamName, teamID, 0).Err())
 
 		// Test
-		id, err := repo.Save(ctx, nil, domain.Team{Name: teamName})
+		id, created, err := repo.Save(ctx, nil, domain.Team{Name: teamName})
 
 		// Assert
 		assert.NoError(t, err)
 		assert.Equal(t, teamID, id)
+		assert.False(t, created)
 
 		// Verify Redis was used (this GET is just for verification)
 		val, err := rdb.Get(ctx, teamName).Result()
@@ -95,11 +98,12 @@ func TestRepo_Save(t *testing.T) {
 			WillReturnRows(rows)
 
 		// Test
-		id, err := repo.Save(ctx, nil, domain.Team{Name: teamName})
+		id, created, err := repo.Save(ctx, nil, domain.Team{Name: teamName})
 
 		// Assert
 		assert.NoError(t, err)
 		assert.Equal(t, teamID, id)
+		assert.False(t, created)
 
 		// After finding in DB, code should try to update Redis cache (which will fail due to our forced error)
 		// Because this will fail silently, we just confirm the result was still returned properly
@@ -129,16 +133,16 @@ func TestRepo_Save(t *testing.T) {
 			WillReturnRows(rows)
 
 		// Test
-		id, err := repo.Save(ctx, nil, domain.Team{Name: teamName})
+		id, created, err := repo.Save(ctx, nil, domain.Team{Name: teamName})
 
 		// Assert
 		assert.NoError(t, err)
 		assert.Equal(t, teamID, id)
+		assert.False(t, created)
 
-		// Verify Redis has been updated after DB lookup
-		val, err := rdb.Get(ctx, teamName).Result()
-		assert.NoError(t, err)
-		assert.Equal(t, teamID, val, "Redis should be updated with team ID from DB")
+		// Verify Redis is left alone until the caller commits
+		_, err = rdb.Get(ctx, teamName).Result()
+		assert.Equal(t, redis.Nil, err, "Redis should not be updated by Save")
 	})
 
 	t.Run("team not found, creating new", func(t *testing.T) {
@@ -163,22 +167,61 @@ func TestRepo_Save(t *testing.T) {
 			WithArgs(teamName).
 			WillReturnRows(rows)
 
-		// Expect INSERT
-		dbMock.ExpectExec("INSERT INTO teams \\(id, name\\) VALUES \\(\\?,\\?\\)").
-			WithArgs(sqlmock.AnyArg(), teamName).
+		// Expect INSERT, the row read back is the one it inserted
+		generated := make(capturedID, 36)
+		dbMock.ExpectExec("INSERT INTO teams \\(id, name\\) VALUES \\(\\?,\\?\\) ON DUPLICATE KEY UPDATE id = id").
+			WithArgs(generated, teamName).
 			WillReturnResult(sqlmock.NewResult(1, 1))
+		dbMock.ExpectQuery("SELECT id FROM teams WHERE name = \\? FOR UPDATE").
+			WithArgs(teamName).
+			WillReturnRows(sqlmock.NewRows([]string{"id"}).AddRow([]byte(generated)))
 
 		// Test
-		id, err := repo.Save(ctx, mockTx, domain.Team{Name: teamName})
+		id, created, err := repo.Save(ctx, mockTx, domain.Team{Name: teamName})
 
 		// Assert
 		assert.NoError(t, err)
-		assert.NotEmpty(t, id)
+		assert.Equal(t, string(generated), id)
+		assert.True(t, created)
 
-		// Verify Redis has been updated
-		val, err := rdb.Get(ctx, teamName).Result()
+		// Verify Redis is left alone, the insert may still roll back
+		_, err = rdb.Get(ctx, teamName).Result()
+		assert.Equal(t, redis.Nil, err, "Redis should not be updated by Save")
+	})
+
+	t.Run("team created concurrently", func(t *testing.T) {
+		// Setup
+		db, dbMock, _ := createMockDB(t)
+		rdb := createMockRedis(t)
+		repo := New(db, rdb, zaptest.NewLogger(t))
+
+		ctx := context.Background()
+		dbMock.ExpectBegin()
+		mockTx, err := db.Begin()
+		require.NoError(t, err)
+
+		teamName := "New Team"
+		otherID := uuid.New().String()
+
+		// not there when looked up, inserted by another request before the upsert
+		dbMock.ExpectQuery("SELECT id, name FROM teams WHERE name = ?").
+			WithArgs(teamName).
+			WillReturnRows(sqlmock.NewRows([]string{"id", "name"}))
+		dbMock.ExpectExec("INSERT INTO teams \\(id, name\\) VALUES \\(\\?,\\?\\) ON DUPLICATE KEY UPDATE id = id").
+			WithArgs(sqlmock.AnyArg(), teamName).
+			WillReturnResult(sqlmock.NewResult(0, 0))
+		dbMock.ExpectQuery("SELECT id FROM teams WHERE name = \\? FOR UPDATE").
+			WithArgs(teamName).
+			WillReturnRows(sqlmock.NewRows([]string{"id"}).AddRow(otherID))
+
+		// Test
+		id, created, err := repo.Save(ctx, mockTx, domain.Team{Name: teamName})
+
+		// Assert
 		assert.NoError(t, err)
-		assert.Equal(t, id, val)
+		assert.Equal(t, otherID, id, "the id of the row that won")
+		assert.False(t, created)
+		assert.NoError(t, dbMock.ExpectationsWereMet())
 	})
 
 	t.Run("DB query error", func(t *testing.T) {
@@ -197,11 +240,12 @@ func TestRepo_Save(t *testing.T) {
 			WillReturnError(errors.New("db query error"))
 
 		// Test
-		id, err := repo.Save(ctx, nil, domain.Team{Name: teamName})
+		id, created, err := repo.Save(ctx, nil, domain.Team{Name: teamName})
 
 		// Assert
 		assert.Error(t, err)
 		assert.Empty(t, id)
+		assert.False(t, created)
 	})
 
 	t.Run("insert error", func(t *testing.T) {
@@ -232,11 +276,12 @@ func TestRepo_Save(t *testing.T) {
 			WillReturnError(errors.New("insert error"))
 
 		// Test
-		id, err := repo.Save(ctx, mockTx, domain.Team{Name: teamName})
+		id, created, err := repo.Save(ctx, mockTx, domain.Team{Name: teamName})
 
 		// Assert
 		assert.Error(t, err)
 		assert.Empty(t, id)
+		assert.False(t, created)
 	})
 
 	t.Run("scan error", func(t *testing.T) {
@@ -262,11 +307,12 @@ func TestRepo_Save(t *testing.T) {
 			WillReturnRows(rows)
 
 		// Test
-		id, err := repo.Save(ctx, nil, domain.Team{Name: teamName})
+		id, created, err := repo.Save(ctx, nil, domain.Team{Name: teamName})
 
 		// Assert
 		assert.Error(t, err)
 		assert.Empty(t, id)
+		assert.False(t, created)
 
 		// Verify Redis still doesn't have the value
 		_, err = rdb.Get(ctx, teamName).Result()
@@ -274,6 +320,27 @@ func TestRepo_Save(t *testing.T) {
 	})
 }
 
+func TestRepo_Remember(t *testing.T) {
+	// Setup
+	db, _, _ := createMockDB(t)
+	rdb := createMockRedis(t)
+	repo := New(db, rdb, zaptest.NewLogger(t))
+	ctx := context.Background()
+	teams := []domain.Team{{ID: uuid.New().String(), Name: "Lakers"}, {ID: uuid.New().String(), Name: "Celtics"}}
+
+	// Test
+	err := repo.Remember(ctx, teams)
+
+	// Assert
+	require.NoError(t, err)
+	for _, team := range teams {
+		val, err := rdb.Get(ctx, team.Name).Result()
+		assert.NoError(t, err)
+		assert.Equal(t, team.ID, val)
+		assert.Greater(t, rdb.TTL(ctx, team.Name).Val(), time.Duration(0))
+	}
+}
+
 func TestRepo_Find(t *testing.T) {
 	t.Run("team found", func(t *testing.T) {
 		// Setup
@@ -294,7 +361,7 @@ func TestRepo_Find(t *testing.T) {
 			WillReturnRows(rows)
 
 		// Test
-		team, err := repo.Find(teamID)
+		team, err := repo.Find(context.Background(), teamID)
 
 		// Assert
 		assert.NoError(t, err)
@@ -317,7 +384,7 @@ func TestRepo_Find(t *testing.T) {
 			WillReturnError(sql.ErrNoRows)
 
 		// Test
-		team, err := repo.Find(teamID)
+		team, err := repo.Find(context.Background(), teamID)
 
 		// Assert
 		assert.Error(t, err)
@@ -366,7 +433,7 @@ func TestRepo_GetStats(t *testing.T) {
 			WillReturnRows(rows)
 
 		// Test
-		result, err := repo.GetStats(teamID)
+		result, err := repo.GetStats(context.Background(), teamID)
 
 		// Assert
 		assert.NoError(t, err)
@@ -396,7 +463,7 @@ func TestRepo_GetStats(t *testing.T) {
 			WillReturnError(sql.ErrNoRows)
 
 		// Test
-		stats, err := repo.GetStats(teamID)
+		stats, err := repo.GetStats(context.Background(), teamID)
 
 		// Assert
 		assert.Error(t, err)
@@ -404,6 +471,14 @@ func TestRepo_GetStats(t *testing.T) {
 	})
 }
 
+// capturedID - a sqlmock argument copying the id it matches into itself, rows added with it return that id
+type capturedID []byte
+
+func (c capturedID) Match(v driver.Value) bool {
+	id, ok := v.(string)
+	return ok && copy(c, id) == len(c)
+}
+
 // Helper functions for creating mocks
 func createMockDB(t *testing.T) (*sqlx.DB, sqlmock.Sqlmock, error) {
 	db, mock, err := sqlmock.New()
@@ -0,0 +1,98 @@
+package tracing
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/alicebob/miniredis/v2"
+	"github.com/redis/go-redis/v9"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/codes"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/sdk/trace/tracetest"
+
+	"skyhawk/backend/config"
+)
+
+// record - installs a provider keeping every ended span in memory for the test
+func record(t *testing.T) *tracetest.SpanRecorder {
+	t.Helper()
+	recorder := tracetest.NewSpanRecorder()
+	previous := otel.GetTracerProvider()
+	otel.SetTracerProvider(sdktrace.NewTracerProvider(sdktrace.WithSpanProcessor(recorder)))
+	t.Cleanup(func() { otel.SetTracerProvider(previous) })
+
+	return recorder
+}
+
+func TestSetup(t *testing.T) {
+	for _, exporter := range []string{"none", "stdout", "otlp"} {
+		t.Run(exporter, func(t *testing.T) {
+			// Setup
+			previous := otel.GetTracerProvider()
+			defer otel.SetTracerProvider(previous)
+
+			// Test
+			shutdown, err := Setup(context.Background(), config.Tracing{Exporter: exporter, ServiceName: "skyhawk", SampleRatio: 1})
+
+			// Assert
+			require.NoError(t, err)
+			assert.NoError(t, shutdown(context.Background()))
+		})
+	}
+}
+
+func TestStartEnd(t *testing.T) {
+	// Setup
+	recorder := record(t)
+
+	// Test
+	ctx, parent := Start(context.Background(), "game_usecase.LogGame")
+	_, child := Start(ctx, "player_db.Save")
+	End(child, errors.New("deadlock"))
+	End(parent, nil)
+
+	// Assert
+	spans := recorder.Ended()
+	require.Len(t, spans, 2)
+	assert.Equal(t, "player_db.Save", spans[0].Name())
+	assert.Equal(t, parent.SpanContext().SpanID(), spans[0].Parent().SpanID())
+	assert.Equal(t, codes.Error, spans[0].Status().Code)
+	assert.Equal(t, codes.Unset, spans[1].Status().Code)
+
+	fields := Fields(ctx)
+	require.Len(t, fields, 2)
+	assert.Equal(t, parent.SpanContext().TraceID().String(), fields[0].String)
+	assert.Empty(t, Fields(context.Background()))
+}
+
+func TestRedisHook(t *testing.T) {
+	// Setup
+	recorder := record(t)
+	server := miniredis.RunT(t)
+	client := redis.NewClient(&redis.Options{Addr: server.Addr()})
+	client.AddHook(RedisHook())
+
+	// Test
+	client.Get(context.Background(), "untraced")
+	ctx, parent := Start(context.Background(), "player_db.Save")
+	client.Get(ctx, "player:missing")
+	pipe := client.Pipeline()
+	pipe.Get(ctx, "player:a")
+	pipe.Set(ctx, "player:b", "p2", 0)
+	_, _ = pipe.Exec(ctx)
+	End(parent, nil)
+
+	// Assert
+	spans := recorder.Ended()
+	require.Len(t, spans, 3, "commands outside a trace get no span")
+	assert.Equal(t, "redis get", spans[0].Name())
+	assert.Equal(t, codes.Unset, spans[0].Status().Code, "a miss is not an error")
+	assert.Equal(t, "redis pipeline", spans[1].Name())
+	for _, span := range spans[:2] {
+		assert.Equal(t, parent.SpanContext().SpanID(), span.Parent().SpanID())
+	}
+}
@@ -0,0 +1,353 @@
+package db
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/jmoiron/sqlx"
+	"go.uber.org/zap"
+
+	"skyhawk/backend/apperror"
+	"skyhawk/backend/webhook/domain"
+)
+
+type Repository interface {
+	Create(ctx context.Context, webhook domain.Webhook) error
+	List(ctx context.Context) ([]domain.Webhook, error)
+	Find(ctx context.Context, id string) (domain.Webhook, error)
+	Deactivate(ctx context.Context, id string) error
+	Subscribers(ctx context.Context, tx *sql.Tx, eventType domain.EventType) ([]domain.Webhook, error)
+	InsertDeliveries(ctx context.Context, tx *sql.Tx, deliveries []domain.Delivery) error
+	Deliveries(ctx context.Context, webhookID string, status domain.DeliveryStatus, limit int) ([]domain.Delivery, error)
+	Claim(ctx context.Context, now time.Time, limit int, lease time.Duration) ([]domain.Claimed, error)
+	MarkDelivered(ctx context.Context, id string, attempts, statusCode int, at time.Time) error
+	MarkFailed(ctx context.Context, id string, attempts, statusCode int, reason string, next time.Time, dead bool) error
+	Requeue(ctx context.Context, webhookID, deliveryID string, at time.Time) error
+}
+
+type Repo struct {
+	db     *sqlx.DB
+	logger *zap.Logger
+}
+
+func NewRepo(db *sqlx.DB, logger *zap.Logger) Repository {
+
+	return &Repo{db: db, logger: logger}
+}
+
+func webhookNotFound() error {
+	return apperror.NotFound("webhook_not_found", "webhook not found", nil)
+}
+
+func (r *Repo) Create(ctx context.Context, webhook domain.Webhook) error {
+	eventTypes, err := json.Marshal(eventTypesOrEmpty(webhook.EventTypes))
+	if err != nil {
+		return apperror.Internal(err)
+	}
+
+	_, err = r.db.ExecContext(ctx, "INSERT INTO webhooks (id, url, secret, event_types, active, created_at) VALUES (?, ?, ?, ?, ?, ?)",
+		webhook.ID, webhook.URL, webhook.Secret, eventTypes, webhook.Active, webhook.CreatedAt)
+	if err != nil {
+		r.logger.Error("failed inserting webhook", zap.Error(err))
+		return apperror.FromDB(err, nil)
+	}
+
+	return nil
+}
+
+func (r *Repo) List(ctx context.Context) ([]domain.Webhook, error) {
+	rows, err := r.db.QueryContext(ctx, "SELECT id, url, secret, event_types, active, created_at FROM webhooks WHERE active = TRUE ORDER BY created_at, id")
+	if err != nil {
+		return nil, apperror.FromDB(err, nil)
+	}
+
+	return scanWebhooks(rows)
+}
+
+func (r *Repo) Find(ctx context.Context, id string) (domain.Webhook, error) {
+	rows, err := r.db.QueryContext(ctx, "SELECT id, url, secret, event_types, active, created_at FROM webhooks WHERE id = ?", id)
+	if err != nil {
+		return domain.Webhook{}, apperror.FromDB(err, nil)
+	}
+
+	webhooks, err := scanWebhooks(rows)
+	if err != nil {
+		return domain.Webhook{}, err
+	}
+	if len(webhooks) == 0 {
+		return domain.Webhook{}, webhookNotFound()
+	}
+
+	return webhooks[0], nil
+}
+
+// Deactivate - stops a webhook, its undelivered events are dead lettered
+func (r *Repo) Deactivate(ctx context.Context, id string) error {
+	tx, err := r.db.BeginTx(ctx, nil)
+	if err != nil {
+		return apperror.FromDB(err, nil)
+	}
+	defer tx.Rollback()
+
+	res, err := tx.ExecContext(ctx, "UPDATE webhooks SET active = FALSE WHERE id = ? AND active = TRUE", id)
+	if err != nil {
+		return apperror.FromDB(err, nil)
+	}
+	if affected, err := res.RowsAffected(); err != nil || affected == 0 {
+		return webhookNotFound()
+	}
+
+	if _, err = tx.ExecContext(ctx, "UPDATE webhook_deliveries SET status = ?, last_error = ? WHERE webhook_id = ? AND status = ?",
+		domain.DeliveryDead, "webhook deleted", id, domain.DeliveryPending); err != nil {
+		return apperror.FromDB(err, nil)
+	}
+
+	return apperror.FromDB(tx.Commit(), nil)
+}
+
+// Subscribers - the active webhooks receiving eventType, read inside the transaction of the change
+func (r *Repo) Subscribers(ctx context.Context, tx *sql.Tx, eventType domain.EventType) ([]domain.Webhook, error) {
+	rows, err := tx.QueryContext(ctx, "SELECT id, url, secret, event_types, active, created_at FROM webhooks WHERE active = TRUE")
+	if err != nil {
+		return nil, apperror.FromDB(err, nil)
+	}
+
+	webhooks, err := scanWebhooks(rows)
+	if err != nil {
+		return nil, err
+	}
+
+	subscribers := webhooks[:0]
+	for _, webhook := range webhooks {
+		if webhook.Wants(eventType) {
+			subscribers = append(subscribers, webhook)
+		}
+	}
+
+	return subscribers, nil
+}
+
+func (r *Repo) InsertDeliveries(ctx context.Context, tx *sql.Tx, deliveries []domain.Delivery) error {
+	if len(deliveries) == 0 {
+		return nil
+	}
+
+	placeHolders := make([]string, 0, len(deliveries))
+	values := make([]interface{}, 0, len(deliveries)*8)
+	for _, delivery := range deliveries {
+		placeHolders = append(placeHolders, "(?, ?, ?, ?, ?, ?, ?, ?)")
+		values = append(values, delivery.ID, delivery.WebhookID, delivery.EventID, delivery.EventType, delivery.GameID, []byte(delivery.Payload), delivery.Status, delivery.NextAttemptAt)
+	}
+
+	q := fmt.Sprintf("INSERT INTO webhook_deliveries (id, webhook_id, event_id, event_type, game_id, payload, status, next_attempt_at) VALUES %s", strings.Join(placeHolders, ","))
+	if _, err := tx.ExecContext(ctx, q, values...); err != nil {
+		r.logger.Error("failed inserting webhook deliveries", zap.Error(err))
+		return apperror.FromDB(err, nil)
+	}
+
+	return nil
+}
+
+// Deliveries - the latest deliveries of a webhook, newest first, status is optional
+func (r *Repo) Deliveries(ctx context.Context, webhookID string, status domain.DeliveryStatus, limit int) ([]domain.Delivery, error) {
+	q := "SELECT id, webhook_id, event_id, event_type, game_id, payload, status, attempts, next_attempt_at, last_status_code, last_error, created_at, delivered_at FROM webhook_deliveries WHERE webhook_id = ?"
+	args := []interface{}{webhookID}
+	if status != "" {
+		q += " AND status = ?"
+		args = append(args, status)
+	}
+	q += " ORDER BY created_at DESC, id DESC LIMIT ?"
+	args = append(args, limit)
+
+	rows, err := r.db.QueryContext(ctx, q, args...)
+	if err != nil {
+		return nil, apperror.FromDB(err, nil)
+	}
+	defer rows.Close()
+
+	var deliveries []domain.Delivery
+	for rows.Next() {
+		delivery, err := scanDelivery(rows)
+		if err != nil {
+			return nil, err
+		}
+		deliveries = append(deliveries, delivery)
+	}
+	if err = rows.Err(); err != nil {
+		return nil, apperror.FromDB(err, nil)
+	}
+
+	return deliveries, nil
+}
+
+// Claim - takes up to limit due deliveries and pushes them lease into the future,
+// so another worker only picks them up again if this one dies before recording the outcome
+func (r *Repo) Claim(ctx context.Context, now time.Time, limit int, lease time.Duration) ([]domain.Claimed, error) {
+	tx, err := r.db.BeginTx(ctx, nil)
+	if err != nil {
+		return nil, apperror.FromDB(err, nil)
+	}
+	defer tx.Rollback()
+
+	rows, err := tx.QueryContext(ctx, "SELECT d.id, d.webhook_id, d.event_id, d.event_type, d.game_id, d.payload, d.attempts, w.url, w.secret "+
+		"FROM webhook_deliveries d JOIN webhooks w ON d.webhook_id = w.id "+
+		"WHERE d.status = ? AND d.next_attempt_at <= ? ORDER BY d.next_attempt_at, d.id LIMIT ? FOR UPDATE SKIP LOCKED",
+		domain.DeliveryPending, now, limit)
+	if err != nil {
+		return nil, apperror.FromDB(err, nil)
+	}
+
+	var claimed []domain.Claimed
+	for rows.Next() {
+		var c domain.Claimed
+		var payload []byte
+		if err = rows.Scan(&c.ID, &c.WebhookID, &c.EventID, &c.EventType, &c.GameID, &payload, &c.Attempts, &c.URL, &c.Secret); err != nil {
+			rows.Close()
+			return nil, apperror.FromDB(err, nil)
+		}
+		c.Payload = payload
+		c.Status = domain.DeliveryPending
+		claimed = append(claimed, c)
+	}
+	rows.Close()
+	if err = rows.Err(); err != nil {
+		return nil, apperror.FromDB(err, nil)
+	}
+	if len(claimed) == 0 {
+		return nil, nil
+	}
+
+	placeHolders := make([]string, len(claimed))
+	args := []interface{}{now.Add(lease)}
+	for i := range claimed {
+		placeHolders[i] = "?"
+		args = append(args, claimed[i].ID)
+	}
+	if _, err = tx.ExecContext(ctx, fmt.Sprintf("UPDATE webhook_deliveries SET next_attempt_at = ? WHERE id IN (%s)", strings.Join(placeHolders, ",")), args...); err != nil {
+		return nil, apperror.FromDB(err, nil)
+	}
+
+	if err = tx.Commit(); err != nil {
+		return nil, apperror.FromDB(err, nil)
+	}
+
+	return claimed, nil
+}
+
+func (r *Repo) MarkDelivered(ctx context.Context, id string, attempts, statusCode int, at time.Time) error {
+	_, err := r.db.ExecContext(ctx, "UPDATE webhook_deliveries SET status = ?, attempts = ?, last_status_code = ?, last_error = NULL, delivered_at = ? WHERE id = ?",
+		domain.DeliveryDelivered, attempts, statusCode, at, id)
+
+	return apperror.FromDB(err, nil)
+}
+
+// MarkFailed - records a failed attempt, the delivery is retried at next unless it is dead
+func (r *Repo) MarkFailed(ctx context.Context, id string, attempts, statusCode int, reason string, next time.Time, dead bool) error {
+	status := domain.DeliveryPending
+	if dead {
+		status = domain.DeliveryDead
+	}
+
+	var code interface{}
+	if statusCode > 0 {
+		code = statusCode
+	}
+
+	_, err := r.db.ExecContext(ctx, "UPDATE webhook_deliveries SET status = ?, attempts = ?, last_status_code = ?, last_error = ?, next_attempt_at = ? WHERE id = ?",
+		status, attempts, code, truncate(reason, 1024), next, id)
+
+	return apperror.FromDB(err, nil)
+}
+
+// Requeue - gives a dead delivery a fresh set of attempts
+func (r *Repo) Requeue(ctx context.Context, webhookID, deliveryID string, at time.Time) error {
+	res, err := r.db.ExecContext(ctx, "UPDATE webhook_deliveries d JOIN webhooks w ON d.webhook_id = w.id SET d.status = ?, d.attempts = 0, d.next_attempt_at = ? WHERE d.id = ? AND d.webhook_id = ? AND d.status = ? AND w.active = TRUE",
+		domain.DeliveryPending, at, deliveryID, webhookID, domain.DeliveryDead)
+	if err != nil {
+		return apperror.FromDB(err, nil)
+	}
+	if affected, err := res.RowsAffected(); err != nil || affected == 0 {
+		return apperror.NotFound("delivery_not_found", "no dead delivery with this id", nil)
+	}
+
+	return nil
+}
+
+func scanWebhooks(rows *sql.Rows) ([]domain.Webhook, error) {
+	defer rows.Close()
+
+	var webhooks []domain.Webhook
+	for rows.Next() {
+		var webhook domain.Webhook
+		var eventTypes []byte
+		var createdAt string
+		if err := rows.Scan(&webhook.ID, &webhook.URL, &webhook.Secret, &eventTypes, &webhook.Active, &createdAt); err != nil {
+			return nil, apperror.FromDB(err, nil)
+		}
+		if err := json.Unmarshal(eventTypes, &webhook.EventTypes); err != nil {
+			return nil, apperror.Internal(err)
+		}
+		webhook.CreatedAt = parseTimestamp(createdAt)
+		webhooks = append(webhooks, webhook)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, apperror.FromDB(err, nil)
+	}
+
+	return webhooks, nil
+}
+
+func scanDelivery(rows *sql.Rows) (domain.Delivery, error) {
+	var delivery domain.Delivery
+	var payload []byte
+	var nextAttemptAt, createdAt string
+	var statusCode sql.NullInt64
+	var lastError, deliveredAt sql.NullString
+
+	if err := rows.Scan(&delivery.ID, &delivery.WebhookID, &delivery.EventID, &delivery.EventType, &delivery.GameID, &payload, &delivery.Status,
+		&delivery.Attempts, &nextAttemptAt, &statusCode, &lastError, &createdAt, &deliveredAt); err != nil {
+		return domain.Delivery{}, apperror.FromDB(err, nil)
+	}
+
+	delivery.Payload = payload
+	delivery.NextAttemptAt = parseTimestamp(nextAttemptAt)
+	delivery.CreatedAt = parseTimestamp(createdAt)
+	delivery.LastStatusCode = int(statusCode.Int64)
+	delivery.LastError = lastError.String
+	if deliveredAt.Valid {
+		at := parseTimestamp(deliveredAt.String)
+		delivery.DeliveredAt = &at
+	}
+
+	return delivery, nil
+}
+
+func eventTypesOrEmpty(eventTypes []domain.EventType) []domain.EventType {
+	if eventTypes == nil {
+		return []domain.EventType{}
+	}
+
+	return eventTypes
+}
+
+// parseTimestamp - the driver returns timestamps as "2006-01-02 15:04:05" text
+func parseTimestamp(value string) time.Time {
+	parsed, err := time.Parse(time.DateTime, value)
+	if err != nil {
+		return time.Time{}
+	}
+
+	return parsed
+}
+
+func truncate(value string, max int) string {
+	if len(value) <= max {
+		return value
+	}
+
+	return value[:max]
+}
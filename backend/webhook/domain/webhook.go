@@ -0,0 +1,149 @@
+package domain
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"net/url"
+	"strconv"
+	"time"
+
+	"skyhawk/backend/apperror"
+)
+
+// EventType - what happened to a game
+type EventType string
+
+const (
+	EventGameLogged    EventType = "game.logged"
+	EventGameCorrected EventType = "game.corrected"
+	EventGameVoided    EventType = "game.voided"
+)
+
+var EventTypes = []EventType{EventGameLogged, EventGameCorrected, EventGameVoided}
+
+func (t EventType) Valid() bool {
+	for _, known := range EventTypes {
+		if t == known {
+			return true
+		}
+	}
+
+	return false
+}
+
+// Event - the body posted to every webhook subscribed to its type
+type Event struct {
+	ID         string      `json:"id"`
+	Type       EventType   `json:"type"`
+	GameID     string      `json:"game_id"`
+	OccurredAt time.Time   `json:"occurred_at"`
+	Data       interface{} `json:"data,omitempty"`
+}
+
+// Webhook - a registered receiver. EventTypes filters what it receives, empty receives everything.
+// Secret signs the deliveries and is only returned when the webhook is created
+type Webhook struct {
+	ID         string      `json:"id"`
+	URL        string      `json:"url"`
+	Secret     string      `json:"secret,omitempty"`
+	EventTypes []EventType `json:"event_types"`
+	Active     bool        `json:"active"`
+	CreatedAt  time.Time   `json:"created_at"`
+}
+
+func (w Webhook) Wants(eventType EventType) bool {
+	if len(w.EventTypes) == 0 {
+		return true
+	}
+	for _, t := range w.EventTypes {
+		if t == eventType {
+			return true
+		}
+	}
+
+	return false
+}
+
+type CreateReq struct {
+	URL        string      `json:"url"`
+	EventTypes []EventType `json:"event_types"`
+	Secret     string      `json:"secret"`
+}
+
+const minSecretLength = 16
+
+func (r CreateReq) Validate() error {
+	var fields []apperror.FieldError
+
+	if parsed, err := url.Parse(r.URL); err != nil || (parsed.Scheme != "http" && parsed.Scheme != "https") || parsed.Host == "" {
+		fields = append(fields, apperror.FieldError{Field: "url", Message: "url must be an absolute http or https url"})
+	}
+	for i, t := range r.EventTypes {
+		if !t.Valid() {
+			fields = append(fields, apperror.FieldError{Field: fmt.Sprintf("event_types[%d]", i), Message: fmt.Sprintf("unknown event type %q", t)})
+		}
+	}
+	if r.Secret != "" && len(r.Secret) < minSecretLength {
+		fields = append(fields, apperror.FieldError{Field: "secret", Message: fmt.Sprintf("secret must be at least %d characters", minSecretLength)})
+	}
+
+	if len(fields) > 0 {
+		return apperror.Validation("invalid_webhook", "webhook is invalid", fields...)
+	}
+
+	return nil
+}
+
+// DeliveryStatus - pending deliveries are retried until delivered or dead
+type DeliveryStatus string
+
+const (
+	DeliveryPending   DeliveryStatus = "pending"
+	DeliveryDelivered DeliveryStatus = "delivered"
+	DeliveryDead      DeliveryStatus = "dead"
+)
+
+// Delivery - one event queued for one webhook
+type Delivery struct {
+	ID             string          `json:"id"`
+	WebhookID      string          `json:"webhook_id"`
+	EventID        string          `json:"event_id"`
+	EventType      EventType       `json:"event_type"`
+	GameID         string          `json:"game_id"`
+	Payload        json.RawMessage `json:"payload,omitempty"`
+	Status         DeliveryStatus  `json:"status"`
+	Attempts       int             `json:"attempts"`
+	NextAttemptAt  time.Time       `json:"next_attempt_at"`
+	LastStatusCode int             `json:"last_status_code,omitempty"`
+	LastError      string          `json:"last_error,omitempty"`
+	CreatedAt      time.Time       `json:"created_at"`
+	DeliveredAt    *time.Time      `json:"delivered_at,omitempty"`
+}
+
+// Claimed - a due delivery with what is needed to send it
+type Claimed struct {
+	Delivery
+	URL    string
+	Secret string
+}
+
+const (
+	HeaderEvent     = "X-Skyhawk-Event"
+	HeaderDelivery  = "X-Skyhawk-Delivery"
+	HeaderTimestamp = "X-Skyhawk-Timestamp"
+	HeaderSignature = "X-Skyhawk-Signature"
+)
+
+// Sign - the X-Skyhawk-Signature of a delivery, "sha256=" and the hex HMAC-SHA256 of "<timestamp>.<body>".
+// Receivers recompute it with their secret and should reject stale timestamps
+func Sign(secret string, timestamp int64, body []byte) string {
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write([]byte(strconv.FormatInt(timestamp, 10)))
+	mac.Write([]byte("."))
+	mac.Write(body)
+
+	return "sha256=" + hex.EncodeToString(mac.Sum(nil))
+}
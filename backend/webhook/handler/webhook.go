@@ -0,0 +1,85 @@
+package handler
+
+import (
+	"net/http"
+
+	"github.com/labstack/echo/v4"
+	"go.uber.org/zap"
+
+	"skyhawk/backend/apperror"
+	"skyhawk/backend/webhook/domain"
+	"skyhawk/backend/webhook/usecase"
+)
+
+type Handler struct {
+	useCase *usecase.UseCase
+	logger  *zap.Logger
+}
+
+func NewHandler(useCase *usecase.UseCase, logger *zap.Logger) *Handler {
+	return &Handler{useCase: useCase, logger: logger}
+}
+
+func (h *Handler) CreateHandler(c echo.Context) error {
+	var req domain.CreateReq
+
+	if err := c.Bind(&req); err != nil {
+		return apperror.Validation("invalid_body", "request body is not a valid webhook")
+	}
+
+	webhook, err := h.useCase.Create(c.Request().Context(), req)
+
+	if err != nil {
+		return err
+	}
+
+	return c.JSON(http.StatusCreated, webhook)
+}
+
+func (h *Handler) ListHandler(c echo.Context) error {
+	webhooks, err := h.useCase.List(c.Request().Context())
+
+	if err != nil {
+		return err
+	}
+
+	return c.JSON(http.StatusOK, webhooks)
+}
+
+func (h *Handler) GetHandler(c echo.Context) error {
+	webhook, err := h.useCase.Get(c.Request().Context(), c.Param("id"))
+
+	if err != nil {
+		return err
+	}
+
+	return c.JSON(http.StatusOK, webhook)
+}
+
+func (h *Handler) DeleteHandler(c echo.Context) error {
+	if err := h.useCase.Delete(c.Request().Context(), c.Param("id")); err != nil {
+		return err
+	}
+
+	return c.NoContent(http.StatusNoContent)
+}
+
+func (h *Handler) DeliveriesHandler(c echo.Context) error {
+	status := domain.DeliveryStatus(c.QueryParam("status"))
+
+	deliveries, err := h.useCase.Deliveries(c.Request().Context(), c.Param("id"), status)
+
+	if err != nil {
+		return err
+	}
+
+	return c.JSON(http.StatusOK, deliveries)
+}
+
+func (h *Handler) RedeliverHandler(c echo.Context) error {
+	if err := h.useCase.Redeliver(c.Request().Context(), c.Param("id"), c.Param("delivery_id")); err != nil {
+		return err
+	}
+
+	return c.NoContent(http.StatusAccepted)
+}
@@ -0,0 +1,166 @@
+package usecase
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"io"
+	"net/http"
+	"strconv"
+	"sync"
+	"time"
+
+	"go.uber.org/zap"
+
+	"skyhawk/backend/retry"
+	"skyhawk/backend/webhook/domain"
+)
+
+// DispatcherOptions - how deliveries are sent and retried
+type DispatcherOptions struct {
+	// Retry - MaxAttempts before a delivery is dead lettered, BaseDelay and MaxDelay space the attempts
+	Retry        retry.Policy
+	PollInterval time.Duration
+	BatchSize    int
+	Parallelism  int
+	Timeout      time.Duration
+}
+
+func DefaultDispatcherOptions() DispatcherOptions {
+	return DispatcherOptions{
+		Retry:        retry.Policy{MaxAttempts: 8, BaseDelay: 30 * time.Second, MaxDelay: time.Hour},
+		PollInterval: 2 * time.Second,
+		BatchSize:    50,
+		Parallelism:  4,
+		Timeout:      10 * time.Second,
+	}
+}
+
+// Dispatcher - sends queued deliveries, several dispatchers may share the queue
+type Dispatcher struct {
+	repo    Repository
+	client  *http.Client
+	backoff *retry.Retrier
+	options DispatcherOptions
+	now     func() time.Time
+	logger  *zap.Logger
+}
+
+func NewDispatcher(repo Repository, options DispatcherOptions, logger *zap.Logger) *Dispatcher {
+	if options.Parallelism < 1 {
+		options.Parallelism = 1
+	}
+
+	return &Dispatcher{
+		repo:    repo,
+		client:  &http.Client{Timeout: options.Timeout},
+		backoff: retry.New(options.Retry, logger, nil),
+		options: options,
+		now:     func() time.Time { return time.Now().UTC() },
+		logger:  logger,
+	}
+}
+
+// Run - polls for due deliveries until ctx is done
+func (d *Dispatcher) Run(ctx context.Context) {
+	ticker := time.NewTicker(d.options.PollInterval)
+	defer ticker.Stop()
+
+	for {
+		// keep draining while full batches come back
+		for {
+			sent, err := d.DispatchOnce(ctx)
+			if err != nil {
+				d.logger.Warn("failed dispatching webhooks", zap.Error(err))
+			}
+			if err != nil || sent < d.options.BatchSize {
+				break
+			}
+		}
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// DispatchOnce - claims one batch of due deliveries and sends it, returning how many were claimed
+func (d *Dispatcher) DispatchOnce(ctx context.Context) (int, error) {
+	// a claim outlives the longest send, so a delivery is not sent twice while still in flight
+	lease := d.options.Timeout*time.Duration(1+d.options.BatchSize/d.options.Parallelism) + time.Minute
+	claimed, err := d.repo.Claim(ctx, d.now(), d.options.BatchSize, lease)
+	if err != nil {
+		return 0, err
+	}
+
+	sem := make(chan struct{}, d.options.Parallelism)
+	var wg sync.WaitGroup
+	for _, delivery := range claimed {
+		wg.Add(1)
+		sem <- struct{}{}
+		go func(delivery domain.Claimed) {
+			defer wg.Done()
+			defer func() { <-sem }()
+			d.deliver(ctx, delivery)
+		}(delivery)
+	}
+	wg.Wait()
+
+	return len(claimed), nil
+}
+
+func (d *Dispatcher) deliver(ctx context.Context, delivery domain.Claimed) {
+	attempts := delivery.Attempts + 1
+	statusCode, err := d.send(ctx, delivery)
+
+	if err == nil {
+		if err = d.repo.MarkDelivered(ctx, delivery.ID, attempts, statusCode, d.now()); err != nil {
+			d.logger.Error("failed recording webhook delivery", zap.String("delivery_id", delivery.ID), zap.Error(err))
+		}
+		return
+	}
+
+	dead := attempts >= d.options.Retry.MaxAttempts
+	next := d.now().Add(d.backoff.Backoff(attempts))
+	d.logger.Warn("webhook delivery failed",
+		zap.String("delivery_id", delivery.ID),
+		zap.String("webhook_id", delivery.WebhookID),
+		zap.Int("attempts", attempts),
+		zap.Bool("dead", dead),
+		zap.Error(err))
+
+	if err = d.repo.MarkFailed(ctx, delivery.ID, attempts, statusCode, err.Error(), next, dead); err != nil {
+		d.logger.Error("failed recording webhook failure", zap.String("delivery_id", delivery.ID), zap.Error(err))
+	}
+}
+
+// send - posts the signed payload, any 2xx answer counts as delivered
+func (d *Dispatcher) send(ctx context.Context, delivery domain.Claimed) (int, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, delivery.URL, bytes.NewReader(delivery.Payload))
+	if err != nil {
+		return 0, err
+	}
+
+	timestamp := d.now().Unix()
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("User-Agent", "skyhawk-webhooks")
+	req.Header.Set(domain.HeaderEvent, string(delivery.EventType))
+	req.Header.Set(domain.HeaderDelivery, delivery.ID)
+	req.Header.Set(domain.HeaderTimestamp, strconv.FormatInt(timestamp, 10))
+	req.Header.Set(domain.HeaderSignature, domain.Sign(delivery.Secret, timestamp, delivery.Payload))
+
+	res, err := d.client.Do(req)
+	if err != nil {
+		return 0, err
+	}
+	defer res.Body.Close()
+	_, _ = io.Copy(io.Discard, io.LimitReader(res.Body, 64<<10))
+
+	if res.StatusCode < 200 || res.StatusCode > 299 {
+		return res.StatusCode, fmt.Errorf("receiver answered %d", res.StatusCode)
+	}
+
+	return res.StatusCode, nil
+}
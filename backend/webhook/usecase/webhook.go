@@ -0,0 +1,168 @@
+package usecase
+
+import (
+	"context"
+	"crypto/rand"
+	"database/sql"
+	"encoding/hex"
+	"encoding/json"
+	"time"
+
+	"github.com/google/uuid"
+	"go.uber.org/zap"
+
+	"skyhawk/backend/apperror"
+	"skyhawk/backend/webhook/domain"
+)
+
+const deliveriesLimit = 100
+
+type Repository interface {
+	Create(ctx context.Context, webhook domain.Webhook) error
+	List(ctx context.Context) ([]domain.Webhook, error)
+	Find(ctx context.Context, id string) (domain.Webhook, error)
+	Deactivate(ctx context.Context, id string) error
+	Subscribers(ctx context.Context, tx *sql.Tx, eventType domain.EventType) ([]domain.Webhook, error)
+	InsertDeliveries(ctx context.Context, tx *sql.Tx, deliveries []domain.Delivery) error
+	Deliveries(ctx context.Context, webhookID string, status domain.DeliveryStatus, limit int) ([]domain.Delivery, error)
+	Claim(ctx context.Context, now time.Time, limit int, lease time.Duration) ([]domain.Claimed, error)
+	MarkDelivered(ctx context.Context, id string, attempts, statusCode int, at time.Time) error
+	MarkFailed(ctx context.Context, id string, attempts, statusCode int, reason string, next time.Time, dead bool) error
+	Requeue(ctx context.Context, webhookID, deliveryID string, at time.Time) error
+}
+
+type UseCase struct {
+	repo   Repository
+	logger *zap.Logger
+}
+
+func NewUseCase(repo Repository, logger *zap.Logger) *UseCase {
+
+	return &UseCase{repo: repo, logger: logger}
+}
+
+// Create - registers a webhook, a secret is generated when none is given and returned only here
+func (s *UseCase) Create(ctx context.Context, req domain.CreateReq) (domain.Webhook, error) {
+	if err := req.Validate(); err != nil {
+		return domain.Webhook{}, err
+	}
+
+	webhook := domain.Webhook{
+		ID:         uuid.New().String(),
+		URL:        req.URL,
+		Secret:     req.Secret,
+		EventTypes: req.EventTypes,
+		Active:     true,
+		CreatedAt:  time.Now().UTC().Truncate(time.Second),
+	}
+	if webhook.Secret == "" {
+		secret := make([]byte, 32)
+		if _, err := rand.Read(secret); err != nil {
+			return domain.Webhook{}, apperror.Internal(err)
+		}
+		webhook.Secret = hex.EncodeToString(secret)
+	}
+
+	if err := s.repo.Create(ctx, webhook); err != nil {
+		s.logger.Error("UseCase.Create failed saving webhook", zap.Error(err))
+		return domain.Webhook{}, err
+	}
+
+	return webhook, nil
+}
+
+func (s *UseCase) List(ctx context.Context) ([]domain.Webhook, error) {
+	webhooks, err := s.repo.List(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	for i := range webhooks {
+		webhooks[i].Secret = ""
+	}
+	if webhooks == nil {
+		webhooks = []domain.Webhook{}
+	}
+
+	return webhooks, nil
+}
+
+func (s *UseCase) Get(ctx context.Context, id string) (domain.Webhook, error) {
+	webhook, err := s.repo.Find(ctx, id)
+	if err != nil {
+		return domain.Webhook{}, err
+	}
+	webhook.Secret = ""
+
+	return webhook, nil
+}
+
+func (s *UseCase) Delete(ctx context.Context, id string) error {
+	return s.repo.Deactivate(ctx, id)
+}
+
+// Deliveries - the delivery log of a webhook, newest first
+func (s *UseCase) Deliveries(ctx context.Context, webhookID string, status domain.DeliveryStatus) ([]domain.Delivery, error) {
+	switch status {
+	case "", domain.DeliveryPending, domain.DeliveryDelivered, domain.DeliveryDead:
+	default:
+		return nil, apperror.Validation("invalid_status", "status must be pending, delivered or dead")
+	}
+
+	if _, err := s.repo.Find(ctx, webhookID); err != nil {
+		return nil, err
+	}
+
+	deliveries, err := s.repo.Deliveries(ctx, webhookID, status, deliveriesLimit)
+	if err != nil {
+		return nil, err
+	}
+	if deliveries == nil {
+		deliveries = []domain.Delivery{}
+	}
+
+	return deliveries, nil
+}
+
+// Redeliver - moves a dead lettered delivery back to the queue
+func (s *UseCase) Redeliver(ctx context.Context, webhookID, deliveryID string) error {
+	return s.repo.Requeue(ctx, webhookID, deliveryID, time.Now().UTC())
+}
+
+// Enqueue - queues event for every webhook subscribed to it inside tx, the deliveries commit or roll back with the change
+func (s *UseCase) Enqueue(ctx context.Context, tx *sql.Tx, event domain.Event) error {
+	webhooks, err := s.repo.Subscribers(ctx, tx, event.Type)
+	if err != nil {
+		return err
+	}
+	if len(webhooks) == 0 {
+		return nil
+	}
+
+	if event.ID == "" {
+		event.ID = uuid.New().String()
+	}
+	if event.OccurredAt.IsZero() {
+		event.OccurredAt = time.Now().UTC()
+	}
+	payload, err := json.Marshal(event)
+	if err != nil {
+		return apperror.Internal(err)
+	}
+
+	deliveries := make([]domain.Delivery, 0, len(webhooks))
+	for _, webhook := range webhooks {
+		deliveries = append(deliveries, domain.Delivery{
+			ID:            uuid.New().String(),
+			WebhookID:     webhook.ID,
+			EventID:       event.ID,
+			EventType:     event.Type,
+			GameID:        event.GameID,
+			Payload:       payload,
+			Status:        domain.DeliveryPending,
+			NextAttemptAt: event.OccurredAt,
+		})
+	}
+
+	return s.repo.InsertDeliveries(ctx, tx, deliveries)
+}
@@ -0,0 +1,267 @@
+package usecase
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap/zaptest"
+
+	"skyhawk/backend/apperror"
+	"skyhawk/backend/webhook/domain"
+)
+
+// fakeRepo - keeps webhooks and deliveries in memory, Claim hands out every pending delivery
+type fakeRepo struct {
+	mu         sync.Mutex
+	webhooks   []domain.Webhook
+	deliveries map[string]domain.Delivery
+}
+
+func newFakeRepo(webhooks ...domain.Webhook) *fakeRepo {
+	return &fakeRepo{webhooks: webhooks, deliveries: make(map[string]domain.Delivery)}
+}
+
+func (f *fakeRepo) Create(ctx context.Context, webhook domain.Webhook) error {
+	f.webhooks = append(f.webhooks, webhook)
+	return nil
+}
+
+func (f *fakeRepo) List(ctx context.Context) ([]domain.Webhook, error) {
+	return f.webhooks, nil
+}
+
+func (f *fakeRepo) Find(ctx context.Context, id string) (domain.Webhook, error) {
+	for _, webhook := range f.webhooks {
+		if webhook.ID == id {
+			return webhook, nil
+		}
+	}
+	return domain.Webhook{}, apperror.NotFound("webhook_not_found", "webhook not found", nil)
+}
+
+func (f *fakeRepo) Deactivate(ctx context.Context, id string) error {
+	return nil
+}
+
+func (f *fakeRepo) Subscribers(ctx context.Context, tx *sql.Tx, eventType domain.EventType) ([]domain.Webhook, error) {
+	var subscribed []domain.Webhook
+	for _, webhook := range f.webhooks {
+		if webhook.Active && webhook.Wants(eventType) {
+			subscribed = append(subscribed, webhook)
+		}
+	}
+	return subscribed, nil
+}
+
+func (f *fakeRepo) InsertDeliveries(ctx context.Context, tx *sql.Tx, deliveries []domain.Delivery) error {
+	for _, delivery := range deliveries {
+		f.deliveries[delivery.ID] = delivery
+	}
+	return nil
+}
+
+func (f *fakeRepo) Deliveries(ctx context.Context, webhookID string, status domain.DeliveryStatus, limit int) ([]domain.Delivery, error) {
+	return nil, nil
+}
+
+func (f *fakeRepo) Claim(ctx context.Context, now time.Time, limit int, lease time.Duration) ([]domain.Claimed, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	var claimed []domain.Claimed
+	for _, delivery := range f.deliveries {
+		if delivery.Status != domain.DeliveryPending || delivery.NextAttemptAt.After(now) {
+			continue
+		}
+		webhook, _ := f.Find(ctx, delivery.WebhookID)
+		claimed = append(claimed, domain.Claimed{Delivery: delivery, URL: webhook.URL, Secret: webhook.Secret})
+	}
+	return claimed, nil
+}
+
+func (f *fakeRepo) MarkDelivered(ctx context.Context, id string, attempts, statusCode int, at time.Time) error {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	delivery := f.deliveries[id]
+	delivery.Status = domain.DeliveryDelivered
+	delivery.Attempts = attempts
+	delivery.LastStatusCode = statusCode
+	delivery.DeliveredAt = &at
+	f.deliveries[id] = delivery
+	return nil
+}
+
+func (f *fakeRepo) MarkFailed(ctx context.Context, id string, attempts, statusCode int, reason string, next time.Time, dead bool) error {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	delivery := f.deliveries[id]
+	delivery.Attempts = attempts
+	delivery.LastStatusCode = statusCode
+	delivery.LastError = reason
+	delivery.NextAttemptAt = next
+	if dead {
+		delivery.Status = domain.DeliveryDead
+	}
+	f.deliveries[id] = delivery
+	return nil
+}
+
+func (f *fakeRepo) Requeue(ctx context.Context, webhookID, deliveryID string, at time.Time) error {
+	return nil
+}
+
+func TestUseCase_Create(t *testing.T) {
+	t.Run("generates a secret", func(t *testing.T) {
+		// Setup
+		repo := newFakeRepo()
+		useCase := NewUseCase(repo, zaptest.NewLogger(t))
+
+		// Test
+		webhook, err := useCase.Create(context.Background(), domain.CreateReq{URL: "https://example.com/hooks"})
+
+		// Assert
+		require.NoError(t, err)
+		assert.Len(t, webhook.Secret, 64)
+		assert.True(t, webhook.Active)
+		require.Len(t, repo.webhooks, 1)
+		assert.Equal(t, webhook.Secret, repo.webhooks[0].Secret)
+	})
+
+	t.Run("invalid request", func(t *testing.T) {
+		// Setup
+		useCase := NewUseCase(newFakeRepo(), zaptest.NewLogger(t))
+
+		// Test
+		_, err := useCase.Create(context.Background(), domain.CreateReq{URL: "ftp://example.com", EventTypes: []domain.EventType{"game.deleted"}, Secret: "short"})
+
+		// Assert
+		appErr := apperror.From(err)
+		assert.Equal(t, apperror.KindValidation, appErr.Kind)
+		assert.Len(t, appErr.Fields, 3)
+	})
+}
+
+func TestUseCase_Enqueue(t *testing.T) {
+	// Setup
+	repo := newFakeRepo(
+		domain.Webhook{ID: "all", Active: true},
+		domain.Webhook{ID: "voided", Active: true, EventTypes: []domain.EventType{domain.EventGameVoided}},
+		domain.Webhook{ID: "logged", Active: true, EventTypes: []domain.EventType{domain.EventGameLogged}},
+	)
+	useCase := NewUseCase(repo, zaptest.NewLogger(t))
+
+	// Test
+	err := useCase.Enqueue(context.Background(), nil, domain.Event{Type: domain.EventGameLogged, GameID: "g1", Data: map[string]string{"id": "g1"}})
+
+	// Assert
+	require.NoError(t, err)
+	require.Len(t, repo.deliveries, 2)
+	var eventID string
+	for _, delivery := range repo.deliveries {
+		assert.Contains(t, []string{"all", "logged"}, delivery.WebhookID)
+		assert.Equal(t, domain.DeliveryPending, delivery.Status)
+		var event struct {
+			Type domain.EventType `json:"type"`
+			Data json.RawMessage  `json:"data"`
+		}
+		require.NoError(t, json.Unmarshal(delivery.Payload, &event))
+		assert.Equal(t, domain.EventGameLogged, event.Type)
+		assert.JSONEq(t, `{"id":"g1"}`, string(event.Data))
+		if eventID != "" {
+			assert.Equal(t, eventID, delivery.EventID, "every delivery of an event shares its id")
+		}
+		eventID = delivery.EventID
+	}
+}
+
+func TestDispatcher_DispatchOnce(t *testing.T) {
+	t.Run("signed delivery", func(t *testing.T) {
+		// Setup
+		var got *http.Request
+		var body []byte
+		server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			got = r
+			body, _ = io.ReadAll(r.Body)
+			w.WriteHeader(http.StatusNoContent)
+		}))
+		defer server.Close()
+
+		repo := newFakeRepo(domain.Webhook{ID: "w1", URL: server.URL, Secret: "0123456789abcdef", Active: true})
+		require.NoError(t, NewUseCase(repo, zaptest.NewLogger(t)).Enqueue(context.Background(), nil, domain.Event{Type: domain.EventGameVoided, GameID: "g1"}))
+		dispatcher := NewDispatcher(repo, DefaultDispatcherOptions(), zaptest.NewLogger(t))
+
+		// Test
+		sent, err := dispatcher.DispatchOnce(context.Background())
+
+		// Assert
+		require.NoError(t, err)
+		assert.Equal(t, 1, sent)
+		require.NotNil(t, got)
+		assert.Equal(t, string(domain.EventGameVoided), got.Header.Get(domain.HeaderEvent))
+		timestamp, err := strconv.ParseInt(got.Header.Get(domain.HeaderTimestamp), 10, 64)
+		require.NoError(t, err)
+		assert.Equal(t, domain.Sign("0123456789abcdef", timestamp, body), got.Header.Get(domain.HeaderSignature))
+
+		for _, delivery := range repo.deliveries {
+			assert.Equal(t, got.Header.Get(domain.HeaderDelivery), delivery.ID)
+			assert.Equal(t, domain.DeliveryDelivered, delivery.Status)
+			assert.Equal(t, 1, delivery.Attempts)
+			assert.Equal(t, http.StatusNoContent, delivery.LastStatusCode)
+		}
+	})
+
+	t.Run("failures back off until dead lettered", func(t *testing.T) {
+		// Setup
+		server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			w.WriteHeader(http.StatusServiceUnavailable)
+		}))
+		defer server.Close()
+
+		repo := newFakeRepo(domain.Webhook{ID: "w1", URL: server.URL, Secret: "0123456789abcdef", Active: true})
+		require.NoError(t, NewUseCase(repo, zaptest.NewLogger(t)).Enqueue(context.Background(), nil, domain.Event{Type: domain.EventGameLogged, GameID: "g1"}))
+
+		options := DefaultDispatcherOptions()
+		options.Retry.MaxAttempts = 3
+		dispatcher := NewDispatcher(repo, options, zaptest.NewLogger(t))
+		now := time.Now().UTC()
+		dispatcher.now = func() time.Time { return now }
+
+		// Test
+		var sends []int
+		for i := 0; i < 3; i++ {
+			sent, err := dispatcher.DispatchOnce(context.Background())
+			require.NoError(t, err)
+			sends = append(sends, sent)
+
+			// nothing is due until the backoff has passed
+			sent, err = dispatcher.DispatchOnce(context.Background())
+			require.NoError(t, err)
+			assert.Zero(t, sent)
+			now = now.Add(time.Hour)
+		}
+		sent, err := dispatcher.DispatchOnce(context.Background())
+
+		// Assert
+		require.NoError(t, err)
+		assert.Equal(t, []int{1, 1, 1}, sends)
+		assert.Zero(t, sent)
+		for _, delivery := range repo.deliveries {
+			assert.Equal(t, domain.DeliveryDead, delivery.Status)
+			assert.Equal(t, 3, delivery.Attempts)
+			assert.Equal(t, http.StatusServiceUnavailable, delivery.LastStatusCode)
+			assert.Equal(t, "receiver answered 503", delivery.LastError)
+		}
+	})
+}
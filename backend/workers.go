@@ -0,0 +1,114 @@
+package main
+
+import (
+	"context"
+	"sync"
+
+	"go.uber.org/zap"
+
+	"skyhawk/backend/config"
+	"skyhawk/backend/outbox"
+	outboxrepo "skyhawk/backend/outbox/db"
+	"skyhawk/backend/stream"
+	webhookrepo "skyhawk/backend/webhook/db"
+	webhookusecase "skyhawk/backend/webhook/usecase"
+)
+
+// workers - the background work of a server: live box score updates, webhook deliveries and the outbox relay.
+// Operator commands never run them, so a short lived command cannot take the relay or claim deliveries from the servers
+type workers struct {
+	logger     *zap.Logger
+	hub        *stream.Hub
+	notifier   *stream.Notifier
+	backplane  stream.Backplane
+	dispatcher *webhookusecase.Dispatcher
+	relay      *outbox.Relay
+	publisher  outbox.Publisher
+	stop       context.CancelFunc
+	running    sync.WaitGroup
+}
+
+// gameUpdatesChannel - the redis channel of the backplane, commands that change games publish on it too
+const gameUpdatesChannel = "skyhawk:game_updates"
+
+// newWorkers - builds the workers over the connections of a, they start with start once the services are wired
+func newWorkers(cfg config.Config, a *app) (*workers, error) {
+	logger := a.logger
+
+	//live box scores, shared between instances over redis when STREAM_BACKPLANE=redis
+	hub := stream.NewHub(cfg.Stream.Buffer)
+	var backplane stream.Backplane = stream.NewLocalBackplane(hub)
+	if cfg.Stream.Backplane == "redis" {
+		backplane = stream.NewRedisBackplane(a.redis, gameUpdatesChannel, logger)
+	}
+
+	//webhook deliveries queued by any instance or command are sent from here
+	dispatcherOptions := webhookusecase.DefaultDispatcherOptions()
+	dispatcherOptions.Retry.MaxAttempts = cfg.Webhook.MaxAttempts
+	dispatcherOptions.Retry.BaseDelay = cfg.Webhook.BaseDelay
+	dispatcherOptions.Retry.MaxDelay = cfg.Webhook.MaxDelay
+	dispatcherOptions.PollInterval = cfg.Webhook.PollInterval
+	dispatcherOptions.Timeout = cfg.Webhook.Timeout
+	dispatcher := webhookusecase.NewDispatcher(webhookrepo.NewRepo(a.db, logger), dispatcherOptions, logger)
+
+	//domain events are relayed to OUTBOX_PUBLISHER (redis stream or ndjson file)
+	var publisher outbox.Publisher
+	switch cfg.Outbox.Publisher {
+	case "file":
+		file, err := outbox.NewFilePublisher(cfg.Outbox.File)
+		if err != nil {
+			return nil, err
+		}
+		publisher = file
+	default:
+		publisher = outbox.NewRedisPublisher(a.redis, "skyhawk:events", cfg.Outbox.StreamMaxLen)
+	}
+	relayOptions := outbox.DefaultRelayOptions()
+	relayOptions.PollInterval = cfg.Outbox.PollInterval
+	relayOptions.Retention = cfg.Outbox.Retention
+	relay := outbox.NewRelay(outboxrepo.NewRepo(a.db, logger), a.metrics.Publisher(publisher), relayOptions, logger)
+
+	return &workers{
+		logger:     logger,
+		hub:        hub,
+		notifier:   stream.NewNotifier(backplane, logger),
+		backplane:  backplane,
+		dispatcher: dispatcher,
+		relay:      relay,
+		publisher:  publisher,
+	}, nil
+}
+
+// start - runs every worker until Close, boxScores loads the games the notifier publishes
+func (w *workers) start(boxScores stream.BoxScores) {
+	ctx, stop := context.WithCancel(context.Background())
+	w.stop = stop
+
+	run := func(worker func()) {
+		w.running.Add(1)
+		go func() {
+			defer w.running.Done()
+			worker()
+		}()
+	}
+	run(func() { w.notifier.Run(ctx, boxScores) })
+	run(func() { w.dispatcher.Run(ctx) })
+	run(func() { w.relay.Run(ctx) })
+	run(func() {
+		if err := w.backplane.Run(ctx, w.hub); err != nil {
+			w.logger.Error("game update backplane stopped", zap.Error(err))
+		}
+	})
+}
+
+// Close - stops the workers and waits for them, so a delivery or relay in flight finishes
+// before the connections it uses are closed
+func (w *workers) Close() {
+	if w.stop != nil {
+		w.stop()
+	}
+	w.running.Wait()
+	if err := w.publisher.Close(); err != nil {
+		w.logger.Warn("failed closing outbox publisher", zap.Error(err))
+	}
+}